@@ -89,7 +89,14 @@ type LogResponseWriter struct {
 	BytesWritten int64
 }
 
+// GetClientIP returns the IP address of the client that sent the request.
+// The standard Forwarded header is consulted first, followed by X-Real-IP and X-Forwarded-For.
 func GetClientIP(req *http.Request) string {
+	if elements := makross.ParseForwarded(req.Header); len(elements) > 0 {
+		if ip := elements[0].ForIP(); ip != "" {
+			return ip
+		}
+	}
 	ip := req.Header.Get("X-Real-IP")
 	if ip == "" {
 		ip = req.Header.Get("X-Forwarded-For")
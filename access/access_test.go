@@ -46,6 +46,42 @@ func TestLogger(t *testing.T) {
 	assert.Contains(t, buf.String(), "GET http://127.0.0.1/users")
 }
 
+func TestFormatLogger(t *testing.T) {
+	RegisterToken("tenant", func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+		tenant, _ := c.Get("tenant").(string)
+		return tenant
+	})
+	var buf bytes.Buffer
+	m := makross.New()
+	m.Use(FormatLogger("${tenant} ${method} ${url} ${status} ${bytes} ${header:X-Plan} [${unknown}]\n", getLogger(&buf)))
+	m.Get("/users", func(c *makross.Context) error {
+		c.Set("tenant", "acme")
+		return c.String("users")
+	})
+	m.Get("/error", handler1)
+
+	req := httptest.NewRequest("GET", "/users?page=2", nil)
+	req.Header.Set("X-Plan", "pro")
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "acme GET /users?page=2 200 5 pro []\n", buf.String())
+
+	// the status of the error response is logged
+	buf.Reset()
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/error", nil))
+	assert.Equal(t, " GET /error 500 3  []\n", buf.String())
+
+	buf.Reset()
+	m = makross.New()
+	m.Use(FormatLogger("", getLogger(&buf)))
+	m.Get("/", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.100.3:8080"
+	m.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Regexp(t, `^\[192\.168\.100\.3\] \[\d+\.\d{3}ms\] GET / HTTP/1\.1 200 2$`, buf.String())
+}
+
 func TestGetClientIP(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/users/", nil)
 	req.Header.Set("X-Real-IP", "192.168.100.1")
@@ -60,6 +96,12 @@ func TestGetClientIP(t *testing.T) {
 
 	req.RemoteAddr = "192.168.100.3:8080"
 	assert.Equal(t, "192.168.100.3", GetClientIP(req))
+
+	req.Header.Set("X-Real-IP", "192.168.100.1")
+	req.Header.Set("Forwarded", `for="[2001:db8:cafe::17]:4711"`)
+	assert.Equal(t, "2001:db8:cafe::17", GetClientIP(req))
+	req.Header.Set("Forwarded", "for=unknown")
+	assert.Equal(t, "192.168.100.1", GetClientIP(req))
 }
 
 func getLogger(buf *bytes.Buffer) LogFunc {
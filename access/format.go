@@ -0,0 +1,136 @@
+package access
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/valyala/fasttemplate"
+)
+
+// TokenFunc returns the value of a token of the FormatLogger format, for the request of the context,
+// its response and the time used to serve it, in milliseconds.
+type TokenFunc func(c *makross.Context, res *LogResponseWriter, elapsed float64) string
+
+// DefaultFormat is the format of FormatLogger when none is given, the same as Logger.
+const DefaultFormat = "[${remote_ip}] [${elapsed}ms] ${method} ${url} ${proto} ${status} ${bytes}"
+
+var (
+	tokensMu sync.RWMutex
+	tokens   = map[string]TokenFunc{
+		"remote_ip": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return GetClientIP(c.Request)
+		},
+		"elapsed": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return strconv.FormatFloat(elapsed, 'f', 3, 64)
+		},
+		"method": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.Request.Method
+		},
+		"url": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.Request.URL.String()
+		},
+		"path": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.Request.URL.Path
+		},
+		"raw_path": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.RawPath()
+		},
+		"raw_query": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.RawQuery()
+		},
+		"host": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.Request.Host
+		},
+		"proto": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.Request.Proto
+		},
+		"referer": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.Request.Referer()
+		},
+		"user_agent": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return c.Request.UserAgent()
+		},
+		"status": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return strconv.Itoa(res.Status)
+		},
+		"bytes": func(c *makross.Context, res *LogResponseWriter, elapsed float64) string {
+			return strconv.FormatInt(res.BytesWritten, 10)
+		},
+	}
+)
+
+// RegisterToken registers a token of the FormatLogger format, e.g. for the tenant of the request:
+//
+//	access.RegisterToken("tenant", func(c *makross.Context, res *access.LogResponseWriter, elapsed float64) string {
+//		return c.Get("tenant").(string)
+//	})
+//	m.Use(access.FormatLogger("${tenant} ${method} ${url} ${status}", log.Printf))
+//
+// A token registered with the name of a built-in one replaces it. It is safe to call while serving.
+func RegisterToken(name string, fn TokenFunc) {
+	tokensMu.Lock()
+	tokens[name] = fn
+	tokensMu.Unlock()
+}
+
+// FormatLogger returns a handler that logs a message in the format for every request.
+// The format contains tokens such as ${status}, replaced with their value:
+//
+//	remote_ip  the client IP
+//	elapsed    the time used to serve the request, in milliseconds
+//	method     the request method
+//	url        the request URL
+//	path       the request path
+//	host       the request host
+//	proto      the request protocol, e.g. HTTP/1.1
+//	referer    the Referer header
+//	user_agent the User-Agent header
+//	status     the response status
+//	bytes      the size of the response body
+//	header:X   the X request header
+//
+// and the tokens registered with RegisterToken. The unknown tokens are replaced with nothing.
+// If the format is empty, DefaultFormat is used.
+func FormatLogger(format string, log LogFunc) makross.Handler {
+	if format == "" {
+		format = DefaultFormat
+	}
+	t := fasttemplate.New(format, "${", "}")
+	pool := &sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+
+	return func(c *makross.Context) error {
+		start := time.Now()
+		if err := c.Next(); err != nil {
+			c.HandleError(err)
+		}
+		elapsed := float64(time.Now().Sub(start).Nanoseconds()) / 1e6
+		rw := &LogResponseWriter{c.Response, c.Response.Status, c.Response.Size}
+
+		buf := pool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer pool.Put(buf)
+		t.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+			tokensMu.RLock()
+			fn := tokens[tag]
+			tokensMu.RUnlock()
+			switch {
+			case fn != nil:
+				return buf.WriteString(fn(c, rw, elapsed))
+			case strings.HasPrefix(tag, "header:"):
+				return buf.WriteString(c.Request.Header.Get(tag[7:]))
+			}
+			return 0, nil
+		})
+		log("%s", buf.String())
+		return nil
+	}
+}
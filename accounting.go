@@ -0,0 +1,47 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+// ResponseFunc is notified of the body bytes sent for every response, e.g. to bill egress per tenant.
+// The route is the name of the matching route, or its path if the route is unnamed.
+type ResponseFunc func(route string, tenant string, bytes int64, status int)
+
+// OnResponse registers functions which are called after every response, including error responses,
+// streams and the requests whose handler panicked, accounted as a 500 unless a response was sent.
+// The bytes are those actually written to the connection, so an aborted transfer reports what was
+// sent until then. HEAD requests and 304 responses report zero bytes.
+// The tenant is resolved by Makross.TenantFunc, it is empty if TenantFunc isn't set.
+//
+// The functions run on the request goroutine, they should only record the figures.
+// See the egress package for an aggregator.
+func (m *Makross) OnResponse(fns ...ResponseFunc) {
+	m.responseFns = append(m.responseFns, fns...)
+}
+
+func (m *Makross) accountResponse(c *Context) {
+	var tenant string
+	if m.TenantFunc != nil {
+		tenant = m.TenantFunc(c)
+	}
+	res := c.Response
+	bytes := res.Size
+	if c.Request.Method == HEAD || res.Status == StatusNotModified {
+		bytes = 0
+	}
+	route := c.routeName()
+	for _, fn := range m.responseFns {
+		fn(route, tenant, bytes, res.Status)
+	}
+}
+
+// routeName returns the name of the matching route, or its path if the route is unnamed.
+func (c *Context) routeName() string {
+	r := c.route
+	if r == nil {
+		return ""
+	}
+	if r.name != "" {
+		return r.name
+	}
+	return r.Path()
+}
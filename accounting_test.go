@@ -0,0 +1,70 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type accounted struct {
+	route, tenant string
+	bytes         int64
+	status        int
+}
+
+func TestOnResponse(t *testing.T) {
+	var records []accounted
+	m := New()
+	m.TenantFunc = func(c *Context) string {
+		return c.Request.Header.Get("X-Tenant")
+	}
+	m.OnResponse(func(route, tenant string, bytes int64, status int) {
+		records = append(records, accounted{route, tenant, bytes, status})
+	})
+	m.Get("/users", func(c *Context) error {
+		return c.String("users")
+	}).Name("users")
+	m.Get("/fail", func(c *Context) error {
+		return NewHTTPError(StatusBadRequest, "bad")
+	})
+	m.Get("/cached", func(c *Context) error {
+		return c.NoContent(StatusNotModified)
+	})
+	m.Head("/users", func(c *Context) error {
+		return c.String("users")
+	})
+	m.Get("/panic", func(c *Context) error {
+		panic("boom")
+	})
+	m.Get("/partial", func(c *Context) error {
+		c.Response.WriteHeader(StatusOK)
+		c.Response.Write([]byte("part"))
+		panic("boom")
+	})
+
+	serve := func(method, path string) {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-Tenant", "acme")
+		m.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve("GET", "/users")
+	serve("GET", "/fail")
+	serve("GET", "/cached")
+	serve("HEAD", "/users")
+	// the panics, not recovered, are accounted too
+	assert.Panics(t, func() { serve("GET", "/panic") })
+	assert.Panics(t, func() { serve("GET", "/partial") })
+
+	assert.Equal(t, []accounted{
+		{"users", "acme", 5, StatusOK},
+		{"/fail", "acme", 3, StatusBadRequest},
+		{"/cached", "acme", 0, StatusNotModified},
+		{"/users", "acme", 0, StatusOK},
+		{"/panic", "acme", 0, StatusInternalServerError},
+		{"/partial", "acme", 4, StatusOK},
+	}, records)
+}
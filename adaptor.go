@@ -5,9 +5,127 @@ import (
 )
 
 // WrapHTTPHandler wraps `http.Handler` into `makross.Handler`.
+//
+// Deprecated: use WrapHandler.
 func WrapHTTPHandler(handler http.Handler) Handler {
+	return WrapHandler(handler)
+}
+
+// WrapHandler adapts a standard http.Handler, served with the response and the request of the context.
+// If it writes the response, the rest of the handlers are skipped, as with Abort. Otherwise they run
+// after it, so that a handler setting headers or checking the request can be used as a middleware.
+func WrapHandler(h http.Handler) Handler {
 	return func(c *Context) error {
-		handler.ServeHTTP(c.Response, c.Request)
+		h.ServeHTTP(c.Response, c.Request)
+		if c.Response.Committed {
+			return c.Abort()
+		}
 		return nil
 	}
 }
+
+// HTTPHandler returns a standard http.Handler running the handlers, with a context acquired from the pool
+// of the application, e.g. to serve a handler chain from an http.ServeMux of an existing server. The errors
+// are handled by HandleError, and the middlewares registered with Use aren't run. Without handlers, it
+// returns the application itself, which routes the requests:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/api/", http.StripPrefix("/api", m.HTTPHandler()))
+//	mux.Handle("/hook", m.HTTPHandler(recover.Recover(), receiveHook))
+//
+// The route of the context is nil, and its parameters are the ones set with SetParam.
+func (m *Makross) HTTPHandler(handlers ...Handler) http.Handler {
+	if len(handlers) == 0 {
+		return m
+	}
+	handlers = append([]Handler(nil), handlers...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := m.AcquireContext()
+		c.Reset(w, r)
+		panicked := true
+		defer func() { m.releaseRequest(c, panicked) }()
+		c.handlers = handlers
+		if err := c.Next(); err != nil {
+			m.HandleError(c, err)
+		}
+		if len(c.deferred) > 0 {
+			m.runDeferred(c, false)
+		}
+		panicked = false
+	})
+}
+
+// WrapHandlerFunc adapts a standard http.HandlerFunc. See `WrapHandler()`.
+func WrapHandlerFunc(h http.HandlerFunc) Handler {
+	return WrapHandler(h)
+}
+
+// WrapMiddleware adapts a standard middleware, such as the ones of the net/http ecosystem:
+//
+//	m.Use(makross.WrapMiddleware(handlers.ProxyHeaders))
+//
+// The http.Handler passed to the middleware as the next handler calls Next, with the request and
+// the response writer it is given: a request derived by the middleware, e.g. with a context value,
+// is the one of the context for the rest of the handlers, and a response writer wrapped by the middleware,
+// e.g. to compress the body, is the one they write to. If the middleware doesn't call the next handler,
+// e.g. to refuse the request, the rest of the handlers are skipped, as with Abort.
+//
+// The error of the rest of the handlers is returned as is. Yet if the middleware wrapped the response writer,
+// the error is handled by HandleError within the middleware instead, for its response to go through the writer,
+// and nil is returned.
+func WrapMiddleware(mw func(http.Handler) http.Handler) Handler {
+	return func(c *Context) error {
+		var (
+			err    error
+			called bool
+		)
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			req, res := c.Request, c.Response
+			c.Request = r
+			if w != http.ResponseWriter(res) {
+				c.Response = NewResponse(w, c.makross)
+			}
+			err = c.Next()
+			if err != nil && c.Response != res {
+				c.HandleError(err)
+				err = nil
+			}
+			c.Request, c.Response = req, res
+		})
+		mw(next).ServeHTTP(c.Response, c.Request)
+		if !called {
+			return c.Abort()
+		}
+		return err
+	}
+}
+
+// JSONHandler adapts a handler returning the data of the response, which is written as JSON,
+// or as XML if the Accept header of the request prefers it. The error is returned as is, to be handled
+// by HandleError, and a nil data gets a "204 - No Content" response. The status set with SetStatus is kept,
+// and nothing is written if the handler already responded.
+//
+//	m.Get("/users/<id>", makross.JSONHandler(func(c *makross.Context) (interface{}, error) {
+//		return users.Find(c.Param("id").String())
+//	}))
+func JSONHandler(h func(c *Context) (interface{}, error)) Handler {
+	return func(c *Context) error {
+		data, err := h(c)
+		if err != nil || c.Response.Committed {
+			return err
+		}
+		if data == nil {
+			return c.NoContent(StatusNoContent)
+		}
+		return c.negotiated(data)
+	}
+}
+
+// negotiated writes the data as JSON, or as XML if the Accept header of the request prefers it.
+func (c *Context) negotiated(data interface{}, status ...int) error {
+	if c.Negotiate(MIMEApplicationJSON, MIMEApplicationXML) == MIMEApplicationXML {
+		return c.XML(data, status...)
+	}
+	return c.JSON(data, status...)
+}
@@ -0,0 +1,220 @@
+package makross
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONHandler(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id" xml:"id"`
+		Name string `json:"name" xml:"name"`
+	}
+	m := New()
+	m.Get("/users/<id>", JSONHandler(func(c *Context) (interface{}, error) {
+		if c.Param("id").String() != "1" {
+			return nil, NewHTTPError(StatusNotFound, "no such user")
+		}
+		return user{1, "makross"}, nil
+	}))
+	m.Post("/users", JSONHandler(func(c *Context) (interface{}, error) {
+		c.SetStatus(StatusCreated)
+		return user{2, "new"}, nil
+	}))
+	m.Delete("/users/<id>", JSONHandler(func(c *Context) (interface{}, error) {
+		return nil, nil
+	}))
+	serve := func(method, path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if accept != "" {
+			req.Header.Set(HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(GET, "/users/1", "")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, `{"id":1,"name":"makross"}`, strings.TrimSpace(res.Body.String()))
+	assert.Equal(t, MIMEApplicationJSONCharsetUTF8, res.Header().Get(HeaderContentType))
+
+	res = serve(GET, "/users/1", "application/xml, application/json;q=0.5")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "<name>makross</name>")
+
+	res = serve(GET, "/users/2", MIMEApplicationJSON)
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Contains(t, res.Body.String(), "no such user")
+
+	res = serve(POST, "/users", "")
+	assert.Equal(t, StatusCreated, res.Code)
+	assert.Contains(t, res.Body.String(), `"name":"new"`)
+
+	res = serve(DELETE, "/users/1", "")
+	assert.Equal(t, StatusNoContent, res.Code)
+	assert.Equal(t, "", res.Body.String())
+}
+
+func TestWrapHandler(t *testing.T) {
+	m := New()
+	var trace []string
+	m.Use(WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace = append(trace, "filter")
+		w.Header().Set("X-Filter", "1")
+		if r.URL.Query().Get("deny") != "" {
+			http.Error(w, "denied", StatusForbidden)
+		}
+	}))
+	m.Get("/", WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace = append(trace, "handler")
+		w.Write([]byte("hello"))
+	}), func(c *Context) error {
+		trace = append(trace, "after")
+		return nil
+	})
+	m.Get("/handler", WrapHandler(http.NotFoundHandler()))
+
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/", nil))
+	assert.Equal(t, "hello", res.Body.String())
+	assert.Equal(t, "1", res.Header().Get("X-Filter"))
+	assert.Equal(t, []string{"filter", "handler"}, trace)
+
+	trace = nil
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/?deny=1", nil))
+	assert.Equal(t, StatusForbidden, res.Code)
+	assert.Equal(t, []string{"filter"}, trace)
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/handler", nil))
+	assert.Equal(t, StatusNotFound, res.Code)
+}
+
+type wrapKey struct{}
+
+// upperWriter is a response writer of a standard middleware, writing the body in upper case.
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (w upperWriter) Write(b []byte) (int, error) {
+	return w.ResponseWriter.Write(bytes.ToUpper(b))
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	withValue := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(HeaderAuthorization) == "" {
+				http.Error(w, "unauthorized", StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Before", "1")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), wrapKey{}, "value")))
+			w.Header().Set("X-After", "1")
+		})
+	}
+	upper := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(upperWriter{w}, r)
+		})
+	}
+	m := New()
+	m.Get("/value", WrapMiddleware(withValue), func(c *Context) error {
+		return c.String(c.Request.Context().Value(wrapKey{}).(string))
+	})
+	m.Get("/error", WrapMiddleware(withValue), func(c *Context) error {
+		return NewHTTPError(StatusConflict, "conflict")
+	})
+	m.Get("/upper", WrapMiddleware(upper), func(c *Context) error {
+		return c.String("hello")
+	})
+	m.Get("/upper-error", WrapMiddleware(upper), func(c *Context) error {
+		return NewHTTPError(StatusConflict, "conflict")
+	})
+	serve := func(path string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(GET, path, nil)
+		req.Header.Set(HeaderAccept, MIMETextPlain)
+		if auth {
+			req.Header.Set(HeaderAuthorization, "Bearer token")
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("/value", true)
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "value", res.Body.String())
+	assert.Equal(t, "1", res.Header().Get("X-Before"))
+
+	// the middleware refuses the request
+	res = serve("/value", false)
+	assert.Equal(t, StatusUnauthorized, res.Code)
+	assert.Equal(t, "unauthorized\n", res.Body.String())
+
+	// the error of the handler is returned by the wrapped middleware
+	res = serve("/error", true)
+	assert.Equal(t, StatusConflict, res.Code)
+	assert.Equal(t, "conflict", res.Body.String())
+
+	// the handlers write to the response writer of the middleware, including the errors
+	res = serve("/upper", false)
+	assert.Equal(t, "HELLO", res.Body.String())
+	res = serve("/upper-error", false)
+	assert.Equal(t, StatusConflict, res.Code)
+	assert.Equal(t, "CONFLICT", res.Body.String())
+}
+
+func TestMakrossHTTPHandler(t *testing.T) {
+	m := New()
+	m.Get("/users/<id>", func(c *Context) error {
+		return c.String("user " + c.Param("id").String())
+	})
+	assert.Equal(t, m, m.HTTPHandler())
+
+	var trace []string
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", m.HTTPHandler()))
+	mux.Handle("/hook", m.HTTPHandler(func(c *Context) error {
+		trace = append(trace, "middleware")
+		c.Set("source", "mux")
+		return c.Next()
+	}, func(c *Context) error {
+		trace = append(trace, "handler")
+		if c.Query("fail") != "" {
+			return NewHTTPError(StatusConflict, "conflict")
+		}
+		return c.String("hook from " + c.Get("source").(string))
+	}))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+		return res
+	}
+
+	res := serve(GET, "/api/users/7")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "user 7", res.Body.String())
+	assert.Equal(t, StatusNotFound, serve(GET, "/api/orders").Code)
+
+	res = serve(POST, "/hook")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "hook from mux", res.Body.String())
+	assert.Equal(t, []string{"middleware", "handler"}, trace)
+
+	res = serve(POST, "/hook?fail=1")
+	assert.Equal(t, StatusConflict, res.Code)
+	assert.Equal(t, "conflict", res.Body.String())
+
+	// the contexts are reused from the pool, without the data of the previous request
+	res = serve(POST, "/hook")
+	assert.Equal(t, "hook from mux", res.Body.String())
+}
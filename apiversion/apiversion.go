@@ -0,0 +1,128 @@
+package apiversion
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// APIVersionConfig defines the config for APIVersion middleware.
+	APIVersionConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Vendor is the vendor of the media types, e.g. "myapp" for "application/vnd.myapp.v2+json".
+		// Required.
+		Vendor string `json:"vendor"`
+
+		// Default is the version of the requests without versioned media type, e.g. with
+		// "Accept: application/json" or "*/*", or without Accept header.
+		// Optional. Default value "1".
+		Default string `json:"default"`
+
+		// Supported are the versions served. The requests for another version are rejected with
+		// "406 - Not Acceptable". All the versions are accepted if empty.
+		// Optional.
+		Supported []string `json:"supported"`
+	}
+)
+
+var (
+	// DefaultAPIVersionConfig is the default APIVersion middleware config.
+	DefaultAPIVersionConfig = APIVersionConfig{
+		Skipper: skipper.DefaultSkipper,
+		Default: "1",
+	}
+
+	// ErrUnsupportedVersion is returned when the requested version isn't supported.
+	ErrUnsupportedVersion = makross.NewHTTPError(makross.StatusNotAcceptable, "unsupported API version")
+)
+
+// APIVersion returns an APIVersion middleware for the vendor.
+//
+// APIVersion middleware parses the API version from the vendor media type of the Accept header,
+// e.g. "2" from "Accept: application/vnd.myapp.v2+json", and stores it in the context,
+// so that the handlers can branch on `Context#APIVersion()`:
+//
+//	m.Use(apiversion.APIVersion("myapp"))
+//	m.Get("/users", func(c *makross.Context) error {
+//		if c.APIVersion() == "1" {
+//			return c.JSON(legacyUsers())
+//		}
+//		return c.JSON(users())
+//	})
+//
+// The requests without versioned media type get the default version "1".
+func APIVersion(vendor string) makross.Handler {
+	c := DefaultAPIVersionConfig
+	c.Vendor = vendor
+	return APIVersionWithConfig(c)
+}
+
+// APIVersionWithConfig returns an APIVersion middleware with config.
+// See: `APIVersion()`.
+func APIVersionWithConfig(config APIVersionConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultAPIVersionConfig.Skipper
+	}
+	if config.Vendor == "" {
+		panic("apiversion: vendor is required")
+	}
+	if config.Default == "" {
+		config.Default = DefaultAPIVersionConfig.Default
+	}
+	supported := make(map[string]bool, len(config.Supported))
+	for _, v := range config.Supported {
+		supported[v] = true
+	}
+	mediaType := regexp.MustCompile(`^application/vnd\.` + regexp.QuoteMeta(strings.ToLower(config.Vendor)) +
+		`\.v([0-9]+(?:\.[0-9]+)*)(?:\+[a-z0-9.-]+)?$`)
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		c.Response.Header().Add(makross.HeaderVary, makross.HeaderAccept)
+		version := parse(c.Request.Header[makross.HeaderAccept], mediaType)
+		if version == "" {
+			version = config.Default
+		}
+		if len(supported) > 0 && !supported[version] {
+			return ErrUnsupportedVersion
+		}
+		c.Set(makross.APIVersionKey, version)
+		return c.Next()
+	}
+}
+
+// parse returns the version of the first versioned media type of the Accept headers which isn't
+// refused with q=0, or "" if there is none.
+func parse(accept []string, mediaType *regexp.Regexp) string {
+	for _, header := range accept {
+		for _, r := range strings.Split(header, ",") {
+			params := strings.Split(r, ";")
+			m := mediaType.FindStringSubmatch(strings.ToLower(strings.TrimSpace(params[0])))
+			if m == nil || refused(params[1:]) {
+				continue
+			}
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// refused reports whether the parameters of a media range have a zero quality.
+func refused(params []string) bool {
+	for _, p := range params {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "q=") {
+			return strings.Trim(p[2:], "0.") == ""
+		}
+	}
+	return false
+}
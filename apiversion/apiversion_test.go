@@ -0,0 +1,56 @@
+package apiversion
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersion(t *testing.T) {
+	handler := func(c *makross.Context) error {
+		return c.String("v" + c.APIVersion())
+	}
+	m := makross.New()
+	m.Get("/users", APIVersion("myapp"), handler)
+	m.Get("/strict", APIVersionWithConfig(APIVersionConfig{
+		Vendor:    "MyApp",
+		Default:   "2",
+		Supported: []string{"2", "3"},
+	}), handler)
+
+	tests := []struct {
+		path, accept string
+		code         int
+		body         string
+	}{
+		{"/users", "application/vnd.myapp.v2+json", makross.StatusOK, "v2"},
+		{"/users", "application/vnd.myapp.v3", makross.StatusOK, "v3"},
+		{"/users", "application/vnd.myapp.v2.1+xml; charset=utf-8", makross.StatusOK, "v2.1"},
+		{"/users", "text/html, application/vnd.myapp.v4+json;q=0.9", makross.StatusOK, "v4"},
+		{"/users", "application/vnd.myapp.v4+json;q=0, application/vnd.myapp.v3+json", makross.StatusOK, "v3"},
+		{"/users", "application/vnd.other.v2+json", makross.StatusOK, "v1"},
+		{"/users", "application/json", makross.StatusOK, "v1"},
+		{"/users", "*/*", makross.StatusOK, "v1"},
+		{"/users", "", makross.StatusOK, "v1"},
+		{"/strict", "application/vnd.myapp.v3+json", makross.StatusOK, "v3"},
+		{"/strict", "application/json", makross.StatusOK, "v2"},
+		{"/strict", "application/vnd.myapp.v1+json", makross.StatusNotAcceptable, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(makross.GET, test.path, nil)
+		if test.accept != "" {
+			req.Header.Set(makross.HeaderAccept, test.accept)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, test.code, res.Code, test.accept)
+		if test.code == makross.StatusOK {
+			assert.Equal(t, test.body, res.Body.String(), test.accept)
+			assert.Equal(t, makross.HeaderAccept, res.Header().Get(makross.HeaderVary))
+		}
+	}
+
+	assert.Panics(t, func() { APIVersion("") })
+}
@@ -2,6 +2,7 @@ package makross
 
 import (
 	"github.com/insionng/makross/libraries/com"
+	"sort"
 	"strconv"
 	"time"
 )
@@ -104,6 +105,49 @@ func (c *Context) Param(name string) *Args {
 	return a
 }
 
+// SetParam sets the value of the named route parameter, adding the parameter if it does not exist yet.
+// It is mainly used by unit tests and by handlers that rewrite routing decisions.
+// Note that SetParam only changes what Param, Args and Parameter return; the request URL is not affected.
+// The parameters set by the pre handlers, before the request is routed, are kept after routing and
+// override the parameters of the route with the same name.
+func (c *Context) SetParam(name, value string) {
+	if !c.routed {
+		c.preParams = append(c.preParams, name, value)
+	}
+	for i, n := range c.pnames {
+		if n == name {
+			c.pvalues[i] = value
+			return
+		}
+	}
+
+	// pnames may be shared with the route store, so never append to it in place.
+	n := len(c.pnames)
+	pnames := make([]string, n+1)
+	copy(pnames, c.pnames)
+	pnames[n] = name
+	c.pnames = pnames
+
+	if n >= len(c.pvalues) {
+		pvalues := make([]string, n+1)
+		copy(pvalues, c.pvalues)
+		c.pvalues = pvalues
+	}
+	c.pvalues[n] = value
+}
+
+// SetParams sets multiple route parameters at once. See SetParam.
+func (c *Context) SetParams(params map[string]string) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.SetParam(name, params[name])
+	}
+}
+
 func (c *Context) FormArgs(key ...string) *Args {
 	var a = new(Args)
 	var k string
@@ -0,0 +1,217 @@
+// Package assets fingerprints static assets with a hash of their content, so that they can be cached forever:
+//
+//	a, err := assets.New(os.DirFS("public"), "/static")
+//	a.Register(m)
+//
+//	<link rel="stylesheet" href="{{asset "css/app.css"}}"> <!-- /static/css/app.3fa9c1e2.css -->
+//
+// The manifest of the fingerprinted names can be computed at build time with WriteManifest, and loaded
+// at startup with Load instead of hashing the assets again.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+// CacheControl is the Cache-Control header of the fingerprinted assets.
+const CacheControl = "public, max-age=31536000, immutable"
+
+// hashLen is the number of hexadecimal digits of the hash in the fingerprinted names.
+const hashLen = 8
+
+// Assets maps the names of the assets to their fingerprinted names and serves them.
+type Assets struct {
+	// Prefix is the URL path the assets are served under, e.g. "/static".
+	Prefix string
+
+	// Debug makes URL fail for the missing assets, rather than returning their un-hashed URL.
+	// Register also fails in the debug mode of Makross.
+	Debug bool
+
+	fsys         fs.FS
+	mu           sync.RWMutex
+	fingerprints map[string]string // by name
+	names        map[string]string // by fingerprinted name
+}
+
+// New returns the assets of the file system served under the prefix, hashing all its files.
+func New(fsys fs.FS, prefix string) (*Assets, error) {
+	a := newAssets(fsys, prefix)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(b)
+		a.add(name, Fingerprint(name, hex.EncodeToString(sum[:])[:hashLen]))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Load returns the assets of the file system served under the prefix, with the fingerprinted names
+// of the manifest written by WriteManifest.
+func Load(fsys fs.FS, prefix string, manifest io.Reader) (*Assets, error) {
+	var fingerprints map[string]string
+	if err := json.NewDecoder(manifest).Decode(&fingerprints); err != nil {
+		return nil, fmt.Errorf("assets: invalid manifest: %v", err)
+	}
+	a := newAssets(fsys, prefix)
+	for name, fingerprinted := range fingerprints {
+		a.add(name, fingerprinted)
+	}
+	return a, nil
+}
+
+func newAssets(fsys fs.FS, prefix string) *Assets {
+	return &Assets{
+		Prefix:       strings.TrimSuffix(prefix, "/"),
+		fsys:         fsys,
+		fingerprints: make(map[string]string),
+		names:        make(map[string]string),
+	}
+}
+
+func (a *Assets) add(name, fingerprinted string) {
+	a.mu.Lock()
+	a.fingerprints[name] = fingerprinted
+	a.names[fingerprinted] = name
+	a.mu.Unlock()
+}
+
+// Fingerprint returns the name with the hash inserted before its extension, e.g. "css/app.3fa9c1e2.css".
+func Fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	if ext == "" || strings.HasPrefix(path.Base(name), ".") && path.Base(name) == ext {
+		return name + "." + hash
+	}
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// WriteManifest writes the JSON manifest of the fingerprinted names, by name.
+func (a *Assets) WriteManifest(w io.Writer) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	b, err := json.MarshalIndent(a.fingerprints, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Names returns the sorted names of the assets.
+func (a *Assets) Names() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.fingerprints))
+	for name := range a.fingerprints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// URL returns the URL of the fingerprinted asset, e.g. "/static/css/app.3fa9c1e2.css" for "css/app.css".
+// The URL of a missing asset is its un-hashed one, or an error in debug mode.
+func (a *Assets) URL(name string) (string, error) {
+	return a.url(name, a.Debug)
+}
+
+func (a *Assets) url(name string, debug bool) (string, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	a.mu.RLock()
+	fingerprinted, ok := a.fingerprints[name]
+	a.mu.RUnlock()
+	if !ok {
+		if debug {
+			return "", fmt.Errorf("assets: missing asset %q", name)
+		}
+		fingerprinted = name
+	}
+	return a.Prefix + "/" + fingerprinted, nil
+}
+
+// FuncMap returns the "asset" template function, see URL.
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{"asset": a.URL}
+}
+
+// Register adds the "asset" template function to the Makross, which fails for the missing assets
+// in its debug mode too, and serves the assets with the Handler middleware.
+func (a *Assets) Register(m *makross.Makross) {
+	m.AddTemplateFunc("asset", func(name string) (string, error) {
+		return a.url(name, a.Debug || m.Debug)
+	})
+	m.Use(a.Handler())
+}
+
+// Handler returns a middleware serving the assets under the prefix: the fingerprinted names
+// with the immutable CacheControl, and the un-hashed names as is.
+func (a *Assets) Handler() makross.Handler {
+	return func(c *makross.Context) error {
+		method := c.Request.Method
+		p := c.Request.URL.Path
+		if method != makross.GET && method != makross.HEAD || !strings.HasPrefix(p, a.Prefix+"/") {
+			return c.Next()
+		}
+		name := strings.TrimPrefix(p, a.Prefix+"/")
+		a.mu.RLock()
+		original, fingerprinted := a.names[name]
+		_, known := a.fingerprints[name]
+		a.mu.RUnlock()
+		switch {
+		case fingerprinted:
+			c.Response.Header().Set(makross.HeaderCacheControl, CacheControl)
+			name = original
+		case !known:
+			return c.Next()
+		}
+		return a.serve(c, name)
+	}
+}
+
+func (a *Assets) serve(c *makross.Context, name string) error {
+	f, err := a.fsys.Open(name)
+	if err != nil {
+		return makross.ErrNotFound
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(b)
+	}
+	modtime := fi.ModTime()
+	if modtime.IsZero() {
+		modtime = time.Unix(0, 0)
+	}
+	return c.ServeContent(content, name, modtime)
+}
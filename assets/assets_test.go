@@ -0,0 +1,119 @@
+package assets
+
+import (
+	"bytes"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/app.css":   {Data: []byte("body { color: red }")},
+		"js/app.min.js": {Data: []byte("console.log(1)")},
+		"robots.txt":    {Data: []byte("User-agent: *")},
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, "css/app.3fa9c1e2.css", Fingerprint("css/app.css", "3fa9c1e2"))
+	assert.Equal(t, "app.min.3fa9c1e2.js", Fingerprint("app.min.js", "3fa9c1e2"))
+	assert.Equal(t, "LICENSE.3fa9c1e2", Fingerprint("LICENSE", "3fa9c1e2"))
+	assert.Equal(t, ".htaccess.3fa9c1e2", Fingerprint(".htaccess", "3fa9c1e2"))
+}
+
+func TestAssets(t *testing.T) {
+	a, err := New(testFS(), "/static/")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"css/app.css", "js/app.min.js", "robots.txt"}, a.Names())
+	url, err := a.URL("css/app.css")
+	assert.Nil(t, err)
+	assert.Regexp(t, `^/static/css/app\.[0-9a-f]{8}\.css$`, url)
+	url2, _ := a.URL("/css/app.css")
+	assert.Equal(t, url, url2)
+
+	// the hash changes with the content
+	fsys := testFS()
+	fsys["css/app.css"] = &fstest.MapFile{Data: []byte("body { color: blue }")}
+	b, _ := New(fsys, "/static")
+	url3, _ := b.URL("css/app.css")
+	assert.NotEqual(t, url, url3)
+
+	// missing assets
+	url, err = a.URL("missing.css")
+	assert.Nil(t, err)
+	assert.Equal(t, "/static/missing.css", url)
+	a.Debug = true
+	_, err = a.URL("missing.css")
+	assert.EqualError(t, err, `assets: missing asset "missing.css"`)
+}
+
+func TestAssetsManifest(t *testing.T) {
+	a, _ := New(testFS(), "/static")
+	var manifest bytes.Buffer
+	assert.Nil(t, a.WriteManifest(&manifest))
+
+	b, err := Load(testFS(), "/static", &manifest)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, name := range a.Names() {
+		u1, _ := a.URL(name)
+		u2, _ := b.URL(name)
+		assert.Equal(t, u1, u2, name)
+	}
+
+	_, err = Load(testFS(), "/static", bytes.NewBufferString("{"))
+	assert.NotNil(t, err)
+}
+
+func TestAssetsRegister(t *testing.T) {
+	a, _ := New(testFS(), "/static")
+	m := makross.New()
+	a.Register(m)
+	m.Get("/", func(c *makross.Context) error {
+		return c.String("home")
+	})
+	tmpl := template.Must(template.New("page").Funcs(m.TemplateFuncs()).Parse(`<link href="{{asset .}}">`))
+	render := func(name string) (string, error) {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, name)
+		return buf.String(), err
+	}
+
+	url, _ := a.URL("css/app.css")
+	page, err := render("css/app.css")
+	assert.Nil(t, err)
+	assert.Equal(t, `<link href="`+url+`">`, page)
+	page, err = render("missing.css")
+	assert.Nil(t, err)
+	assert.Equal(t, `<link href="/static/missing.css">`, page)
+	m.Debug = true
+	_, err = render("missing.css")
+	assert.NotNil(t, err)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res
+	}
+	res := serve(url)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, "body { color: red }", res.Body.String())
+	assert.Equal(t, CacheControl, res.Header().Get(makross.HeaderCacheControl))
+	assert.Equal(t, "text/css; charset=utf-8", res.Header().Get(makross.HeaderContentType))
+
+	res = serve("/static/robots.txt")
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, "User-agent: *", res.Body.String())
+	assert.Empty(t, res.Header().Get(makross.HeaderCacheControl))
+
+	assert.Equal(t, makross.StatusNotFound, serve("/static/css/app.00000000.css").Code)
+	assert.Equal(t, "home", serve("/").Body.String())
+}
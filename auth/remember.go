@@ -0,0 +1,231 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+type (
+	// RememberToken is a remember-me token, as kept by a RememberStore. The token itself is only
+	// stored hashed, so that a leak of the store doesn't let the users be impersonated.
+	RememberToken struct {
+		// Series identifies the token across its rotations.
+		Series string
+		// Hash is the SHA-256 hash of the current token of the series.
+		Hash []byte
+		// UserID is the ID of the user logged in by the token.
+		UserID string
+		// Expires is the time after which the token is refused.
+		Expires time.Time
+	}
+
+	// RememberStore stores the remember-me tokens, e.g. in a database table keyed by series.
+	// It must be safe for concurrent use.
+	RememberStore interface {
+		// Get returns the token of the series, or nil if there is none.
+		Get(ctx context.Context, series string) (*RememberToken, error)
+		// Save creates or replaces the token of its series.
+		Save(ctx context.Context, t *RememberToken) error
+		// Delete deletes the token of the series.
+		Delete(ctx context.Context, series string) error
+		// DeleteUser deletes all the tokens of the user.
+		DeleteUser(ctx context.Context, userID string) error
+	}
+
+	// RememberMeConfig defines the config of a RememberMe.
+	RememberMeConfig struct {
+		// Store stores the tokens.
+		// Required.
+		Store RememberStore
+
+		// Keys sign the cookie, the newest first, see `Context#SetSignedCookie()`.
+		// Required.
+		Keys [][]byte
+
+		// CookieName is the name of the remember-me cookie.
+		// Optional. Default value "remember_me".
+		CookieName string
+
+		// MaxAge is the lifetime of a token, extended each time it is used.
+		// Optional. Default value 30 days.
+		MaxAge time.Duration
+	}
+
+	// RememberMe logs the users in from a long-lived remember-me cookie once their session
+	// has expired, following the "series and token" scheme: the cookie holds the series of
+	// the token, which identifies it, and the token itself, which is replaced each time it is
+	// used. A token presented again after its rotation reveals that the cookie was stolen:
+	// all the tokens of the user are then deleted.
+	RememberMe struct {
+		config RememberMeConfig
+	}
+)
+
+var (
+	// DefaultRememberMeConfig is the default RememberMe config.
+	DefaultRememberMeConfig = RememberMeConfig{
+		CookieName: "remember_me",
+		MaxAge:     30 * 24 * time.Hour,
+	}
+)
+
+// NewRememberMe returns a RememberMe with config. It panics if the store or the keys are missing.
+//
+//	remember := auth.NewRememberMe(auth.RememberMeConfig{Store: tokens, Keys: keys})
+//	m.Use(session.Sessioner(), remember.Recall())
+//	m.Post("/login", func(c *makross.Context) error {
+//		...
+//		if err := auth.Login(c, user.ID); err != nil {
+//			return err
+//		}
+//		if c.FormValue("remember") != "" {
+//			if err := remember.Remember(c, user.ID); err != nil {
+//				return err
+//			}
+//		}
+//		return c.Redirect(auth.ReturnURL(c, "/"))
+//	})
+func NewRememberMe(config RememberMeConfig) *RememberMe {
+	// Defaults
+	if config.Store == nil {
+		panic("auth: remember-me requires a store")
+	}
+	if len(config.Keys) == 0 {
+		panic("auth: remember-me requires a cookie signing key")
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultRememberMeConfig.CookieName
+	}
+	if config.MaxAge == 0 {
+		config.MaxAge = DefaultRememberMeConfig.MaxAge
+	}
+	return &RememberMe{config: config}
+}
+
+// Remember sets the remember-me cookie of the user, with a new series, typically after Login
+// when the user asked to be remembered.
+func (r *RememberMe) Remember(c *makross.Context, userID string) error {
+	series, err := randomToken()
+	if err != nil {
+		return err
+	}
+	return r.issue(c, &RememberToken{Series: series, UserID: userID})
+}
+
+// Forget deletes the token of the remember-me cookie of the request and expires the cookie.
+// Call it along with Logout.
+func (r *RememberMe) Forget(c *makross.Context) error {
+	r.expire(c)
+	series, _, ok := r.cookie(c)
+	if !ok {
+		return nil
+	}
+	return r.config.Store.Delete(c.Kontext(), series)
+}
+
+// Recall returns a makross.Handler that logs in with Login the users who aren't logged in and have
+// a valid remember-me cookie, rotating its token. It requires the session middleware.
+//
+// As the token changes with each use, the concurrent requests of a browser without session, both
+// presenting the old token, are seen as a theft: the user has to log in again.
+func (r *RememberMe) Recall() makross.Handler {
+	return func(c *makross.Context) error {
+		if _, ok := UserID(c); ok || c.Session == nil {
+			return nil
+		}
+		series, token, ok := r.cookie(c)
+		if !ok {
+			if _, err := c.Request.Cookie(r.config.CookieName); err == nil {
+				r.expire(c)
+			}
+			return nil
+		}
+		ctx := c.Kontext()
+		t, err := r.config.Store.Get(ctx, series)
+		if err != nil {
+			return err
+		}
+		if t == nil || time.Now().After(t.Expires) {
+			r.expire(c)
+			if t != nil {
+				return r.config.Store.Delete(ctx, series)
+			}
+			return nil
+		}
+		if subtle.ConstantTimeCompare(hashToken(token), t.Hash) != 1 {
+			// the token was already rotated: the cookie was stolen, by the client or the previous one
+			c.Makross().Logger().Warnf("auth: reused remember-me token of user %s, logging out all its devices", t.UserID)
+			r.expire(c)
+			return r.config.Store.DeleteUser(ctx, t.UserID)
+		}
+		if err = r.issue(c, t); err != nil {
+			return err
+		}
+		return Login(c, t.UserID)
+	}
+}
+
+// issue saves the token with a new value and sets it in the cookie.
+func (r *RememberMe) issue(c *makross.Context, t *RememberToken) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	t.Hash = hashToken(token)
+	t.Expires = time.Now().Add(r.config.MaxAge)
+	if err = r.config.Store.Save(c.Kontext(), t); err != nil {
+		return err
+	}
+	return c.SetSignedCookie(r.newCookie(c, t.Series+":"+token, int(r.config.MaxAge/time.Second)), r.config.Keys)
+}
+
+// cookie returns the series and the token of the remember-me cookie of the request.
+func (r *RememberMe) cookie(c *makross.Context) (series, token string, ok bool) {
+	cookie, err := c.GetSignedCookie(r.config.CookieName, r.config.Keys)
+	if err != nil {
+		return "", "", false
+	}
+	series, token, ok = strings.Cut(cookie.Value, ":")
+	return series, token, ok && series != "" && token != ""
+}
+
+// expire expires the remember-me cookie.
+func (r *RememberMe) expire(c *makross.Context) {
+	c.SetCookie(r.newCookie(c, "", -1))
+}
+
+func (r *RememberMe) newCookie(c *makross.Context, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     r.config.CookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   c.Scheme() == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// randomToken returns a random token of 256 bits.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) []byte {
+	h := sha256.Sum256([]byte(token))
+	return h[:]
+}
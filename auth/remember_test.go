@@ -0,0 +1,159 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+// memTokens is a RememberStore in memory.
+type memTokens map[string]RememberToken
+
+func (s memTokens) Get(ctx context.Context, series string) (*RememberToken, error) {
+	if t, ok := s[series]; ok {
+		return &t, nil
+	}
+	return nil, nil
+}
+
+func (s memTokens) Save(ctx context.Context, t *RememberToken) error {
+	s[t.Series] = *t
+	return nil
+}
+
+func (s memTokens) Delete(ctx context.Context, series string) error {
+	delete(s, series)
+	return nil
+}
+
+func (s memTokens) DeleteUser(ctx context.Context, userID string) error {
+	for series, t := range s {
+		if t.UserID == userID {
+			delete(s, series)
+		}
+	}
+	return nil
+}
+
+func TestRememberMe(t *testing.T) {
+	sessions := &memSessions{stores: map[string]*memSession{}}
+	tokens := memTokens{}
+	remember := NewRememberMe(RememberMeConfig{Store: tokens, Keys: [][]byte{[]byte("secret")}})
+	m := makross.New()
+
+	// serve runs Recall for a browser without session, returning the logged in user and the response
+	serve := func(cookie *http.Cookie) (string, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		res := httptest.NewRecorder()
+		c := m.NewContext(req, res)
+		c.Session = sessions.start("")
+		assert.Nil(t, remember.Recall()(c))
+		id, _ := UserID(c)
+		return id, res
+	}
+	cookieOf := func(res *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range res.Result().Cookies() {
+			if cookie.Name == "remember_me" {
+				return cookie
+			}
+		}
+		return nil
+	}
+
+	// logging in with remember me
+	req, _ := http.NewRequest("GET", "/login", nil)
+	res := httptest.NewRecorder()
+	c := m.NewContext(req, res)
+	c.Session = sessions.start("")
+	assert.Nil(t, Login(c, "u1"))
+	assert.Nil(t, remember.Remember(c, "u1"))
+	first := cookieOf(res)
+	if !assert.NotNil(t, first) {
+		return
+	}
+	assert.True(t, first.HttpOnly)
+	assert.Equal(t, 30*24*3600, first.MaxAge)
+	assert.Len(t, tokens, 1)
+
+	// the session expired: the cookie logs the user in, with a new token
+	id, res := serve(first)
+	assert.Equal(t, "u1", id)
+	second := cookieOf(res)
+	if !assert.NotNil(t, second) {
+		return
+	}
+	assert.NotEqual(t, first.Value, second.Value)
+	assert.Len(t, tokens, 1)
+
+	id, res = serve(second)
+	assert.Equal(t, "u1", id)
+	third := cookieOf(res)
+
+	// the reuse of a rotated token, e.g. by a thief, logs out all the devices of the user
+	remember.Remember(m.NewContext(req, httptest.NewRecorder()), "u1")
+	remember.Remember(m.NewContext(req, httptest.NewRecorder()), "u2")
+	assert.Len(t, tokens, 3)
+	id, res = serve(second)
+	assert.Equal(t, "", id)
+	assert.Equal(t, -1, cookieOf(res).MaxAge)
+	assert.Len(t, tokens, 1)
+	id, _ = serve(third)
+	assert.Equal(t, "", id)
+
+	// logged in users are left alone
+	req, _ = http.NewRequest("GET", "/", nil)
+	res = httptest.NewRecorder()
+	c = m.NewContext(req, res)
+	c.Session = sessions.start("")
+	c.Session.Set(SessionUser, "u3")
+	assert.Nil(t, remember.Remember(c, "u3"))
+	cookie := cookieOf(res)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	c = m.NewContext(req, res)
+	c.Session = sessions.start("")
+	c.Session.Set(SessionUser, "u3")
+	assert.Nil(t, remember.Recall()(c))
+	assert.Nil(t, cookieOf(res))
+
+	// forgotten
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.Nil(t, remember.Forget(c))
+	assert.Len(t, tokens, 1)
+	id, _ = serve(cookie)
+	assert.Equal(t, "", id)
+
+	// expired, tampered or unsigned cookies are refused and expired
+	res = httptest.NewRecorder()
+	c = m.NewContext(req, res)
+	assert.Nil(t, remember.Remember(c, "u4"))
+	cookie = cookieOf(res)
+	for series, token := range tokens {
+		if token.UserID == "u4" {
+			token.Expires = time.Now().Add(-time.Second)
+			tokens[series] = token
+		}
+	}
+	id, res = serve(cookie)
+	assert.Equal(t, "", id)
+	assert.Equal(t, -1, cookieOf(res).MaxAge)
+	assert.Len(t, tokens, 1)
+	for _, value := range []string{"series:token", cookie.Value + "x"} {
+		id, res = serve(&http.Cookie{Name: "remember_me", Value: value})
+		assert.Equal(t, "", id, value)
+		assert.Equal(t, -1, cookieOf(res).MaxAge, value)
+	}
+
+	assert.Panics(t, func() { NewRememberMe(RememberMeConfig{Keys: [][]byte{[]byte("secret")}}) })
+	assert.Panics(t, func() { NewRememberMe(RememberMeConfig{Store: tokens}) })
+}
@@ -0,0 +1,124 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/insionng/makross"
+)
+
+const (
+	// SessionUser is the session key used to store the ID of the logged in user.
+	SessionUser = "_AUTH_USER"
+	// SessionReturnURL is the session key used to remember the URL requested before logging in.
+	SessionReturnURL = "_AUTH_RETURN_URL"
+)
+
+// ErrNoSession is returned by the session based helpers when no session is attached to the context.
+var ErrNoSession = errors.New("auth: no session, use the session middleware first")
+
+// regenerated is the session of the context after its ID has been regenerated.
+// The session data calls are forwarded to the new store while the management calls
+// still go to the original session.
+type regenerated struct {
+	makross.Sessioner
+	store makross.RawStore
+}
+
+func (s *regenerated) Set(key, value interface{}) error { return s.store.Set(key, value) }
+func (s *regenerated) Get(key interface{}) interface{}  { return s.store.Get(key) }
+func (s *regenerated) Delete(key interface{}) error     { return s.store.Delete(key) }
+func (s *regenerated) ID() string                       { return s.store.ID() }
+func (s *regenerated) Release(c *makross.Context) error { return s.store.Release(c) }
+func (s *regenerated) Flush() error                     { return s.store.Flush() }
+
+// Login stores the given user ID in the session of the current request.
+// The session ID is regenerated first, so that a session ID known before logging in can't be used
+// to hijack the authenticated session (session fixation).
+// It requires the session middleware.
+func Login(c *makross.Context, userID string) error {
+	if c.Session == nil {
+		return ErrNoSession
+	}
+	store, err := c.Session.RegenerateId(c)
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		c.Session = &regenerated{Sessioner: c.Session, store: store}
+	}
+	c.Set(User, Identity(userID))
+	return c.Session.Set(SessionUser, userID)
+}
+
+// Logout clears the session of the current request and expires its cookie.
+func Logout(c *makross.Context) error {
+	if c.Session == nil {
+		return ErrNoSession
+	}
+	c.Set(User, nil)
+	if err := c.Session.Flush(); err != nil {
+		return err
+	}
+	return c.Session.Destory(c)
+}
+
+// UserID returns the ID of the user logged in with Login.
+func UserID(c *makross.Context) (string, bool) {
+	if c.Session == nil {
+		return "", false
+	}
+	id, ok := c.Session.Get(SessionUser).(string)
+	return id, ok && id != ""
+}
+
+// ReturnURL returns the URL that was requested before Required sent the client to the login page,
+// or defaultURL if there is none. The remembered URL is removed from the session.
+// Call it after Login to redirect the user back:
+//
+//	if err := auth.Login(c, user.ID); err != nil {
+//		return err
+//	}
+//	return c.Redirect(auth.ReturnURL(c, "/"))
+func ReturnURL(c *makross.Context, defaultURL string) string {
+	if c.Session == nil {
+		return defaultURL
+	}
+	u, _ := c.Session.Get(SessionReturnURL).(string)
+	c.Session.Delete(SessionReturnURL)
+	if !isLocalURL(u) {
+		return defaultURL
+	}
+	return u
+}
+
+// Required returns a makross.Handler that only lets logged in users through.
+// The ID of the logged in user is made available in the context under the User key.
+//
+// Browsers of anonymous users are redirected to redirectURL with a 302 status, after the requested URL
+// has been remembered in the session for ReturnURL. API clients, which prefer JSON over HTML according
+// to their Accept header, receive an http.StatusUnauthorized error instead.
+func Required(redirectURL string) makross.Handler {
+	return func(c *makross.Context) error {
+		if id, ok := UserID(c); ok {
+			c.Set(User, Identity(id))
+			return nil
+		}
+		if c.Negotiate(makross.MIMETextHTML, makross.MIMEApplicationJSON) == makross.MIMEApplicationJSON {
+			return makross.NewHTTPError(http.StatusUnauthorized)
+		}
+		if c.Session != nil && c.Request.Method == makross.GET {
+			c.Session.Set(SessionReturnURL, c.Request.URL.RequestURI())
+		}
+		c.Redirect(redirectURL, http.StatusFound)
+		return c.Abort()
+	}
+}
+
+// isLocalURL reports whether u is a path on the current site, to avoid open redirects.
+func isLocalURL(u string) bool {
+	return strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") && !strings.HasPrefix(u, "/\\")
+}
@@ -0,0 +1,170 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+// memSessions is a minimal session provider keyed by session ID.
+type memSessions struct {
+	stores map[string]*memSession
+	seq    int
+}
+
+type memSession struct {
+	sessions  *memSessions
+	id        string
+	values    map[interface{}]interface{}
+	destroyed bool
+}
+
+func (p *memSessions) start(id string) *memSession {
+	if s, ok := p.stores[id]; ok {
+		return s
+	}
+	p.seq++
+	s := &memSession{sessions: p, id: "sid" + strconv.Itoa(p.seq), values: map[interface{}]interface{}{}}
+	p.stores[s.id] = s
+	return s
+}
+
+func (s *memSession) Set(key, value interface{}) error { s.values[key] = value; return nil }
+func (s *memSession) Get(key interface{}) interface{}  { return s.values[key] }
+func (s *memSession) Delete(key interface{}) error     { delete(s.values, key); return nil }
+func (s *memSession) ID() string                       { return s.id }
+func (s *memSession) Release(*makross.Context) error   { return nil }
+func (s *memSession) Flush() error {
+	s.values = map[interface{}]interface{}{}
+	return nil
+}
+func (s *memSession) Read(id string) (makross.RawStore, error) { return s.sessions.stores[id], nil }
+func (s *memSession) Destory(*makross.Context) error {
+	delete(s.sessions.stores, s.id)
+	s.destroyed = true
+	return nil
+}
+func (s *memSession) RegenerateId(*makross.Context) (makross.RawStore, error) {
+	delete(s.sessions.stores, s.id)
+	n := s.sessions.start("")
+	for k, v := range s.values {
+		n.values[k] = v
+	}
+	return n, nil
+}
+func (s *memSession) Count() int { return len(s.sessions.stores) }
+func (s *memSession) GC()        {}
+
+func TestLoginLogout(t *testing.T) {
+	sessions := &memSessions{stores: map[string]*memSession{}}
+	m := makross.New()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+
+	_, ok := UserID(c)
+	assert.False(t, ok)
+	assert.Equal(t, ErrNoSession, Login(c, "u1"))
+
+	before := sessions.start("")
+	before.Set("cart", 3)
+	c.Session = before
+	assert.Nil(t, Login(c, "u1"))
+	id, ok := UserID(c)
+	assert.True(t, ok)
+	assert.Equal(t, "u1", id)
+	assert.Equal(t, Identity("u1"), c.Get(User))
+
+	// the session ID known before logging in must not give access to the logged in session
+	assert.NotEqual(t, before.ID(), c.Session.ID())
+	stale, _ := before.Read(before.ID())
+	assert.Nil(t, stale)
+	assert.Nil(t, before.Get(SessionUser))
+	assert.Equal(t, 3, c.Session.Get("cart"))
+
+	// next request
+	current := sessions.stores[c.Session.ID()]
+	c = m.NewContext(req, httptest.NewRecorder())
+	c.Session = current
+	id, _ = UserID(c)
+	assert.Equal(t, "u1", id)
+	assert.Nil(t, Logout(c))
+	_, ok = UserID(c)
+	assert.False(t, ok)
+	assert.True(t, current.destroyed)
+	assert.Nil(t, c.Get(User))
+}
+
+func TestRequired(t *testing.T) {
+	sessions := &memSessions{stores: map[string]*memSession{}}
+	m := makross.New()
+	sess := sessions.start("")
+	var reached bool
+	m.Use(func(c *makross.Context) error {
+		c.Session = sess
+		return nil
+	})
+	m.Get("/account/orders", Required("/login"), func(c *makross.Context) error {
+		reached = true
+		return c.String(c.Get(User).(Identity).(string))
+	})
+	m.Post("/login", func(c *makross.Context) error {
+		if err := Login(c, "u1"); err != nil {
+			return err
+		}
+		return c.Redirect(ReturnURL(c, "/"))
+	})
+
+	// browsers are sent to the login page
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/account/orders?page=2", nil)
+	req.Header.Set(makross.HeaderAccept, "text/html,application/xhtml+xml,*/*;q=0.8")
+	m.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/login", res.Header().Get(makross.HeaderLocation))
+	assert.False(t, reached)
+
+	// API clients get a 401
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/account/orders", nil)
+	req.Header.Set(makross.HeaderAccept, "application/json")
+	m.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.False(t, reached)
+
+	// logging in redirects back to the original URL
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/login", nil)
+	m.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/account/orders?page=2", res.Header().Get(makross.HeaderLocation))
+
+	sess = sessions.stores["sid2"]
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/account/orders", nil)
+	m.ServeHTTP(res, req)
+	assert.True(t, reached)
+	assert.Equal(t, "u1", res.Body.String())
+}
+
+func TestReturnURL(t *testing.T) {
+	sessions := &memSessions{stores: map[string]*memSession{}}
+	m := makross.New()
+	req, _ := http.NewRequest("GET", "/", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, "/home", ReturnURL(c, "/home"))
+
+	c.Session = sessions.start("")
+	for _, u := range []string{"//evil.com/", "http://evil.com/", "/\\evil.com", ""} {
+		c.Session.Set(SessionReturnURL, u)
+		assert.Equal(t, "/home", ReturnURL(c, "/home"), u)
+	}
+	c.Session.Set(SessionReturnURL, "/a?b=c")
+	assert.Equal(t, "/a?b=c", ReturnURL(c, "/home"))
+	assert.Equal(t, "/home", ReturnURL(c, "/home"))
+}
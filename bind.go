@@ -25,15 +25,62 @@ type (
 		// UnmarshalParam decodes and assigns a value from an form or query param.
 		UnmarshalParam(param string) error
 	}
+
+	// SliceConversionError is returned when some values of a slice field, e.g. ?ids=1&ids=x,
+	// can't be converted to the element type.
+	SliceConversionError struct {
+		Field  string
+		Source string // the binding source, e.g. BindSourceQuery
+		Type   reflect.Type
+		Errors []SliceElementError // in index order
+	}
+
+	// SliceElementError is the conversion error of a value of a slice field.
+	SliceElementError struct {
+		Index int
+		Value string
+		Err   error
+	}
 )
 
+// Error returns the error message, naming the failing indexes, e.g.
+// `cannot convert ids to []int: ids[1]="x": invalid syntax`.
+func (e *SliceConversionError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, ee := range e.Errors {
+		err := ee.Err
+		if ne, ok := err.(*strconv.NumError); ok {
+			err = ne.Err
+		}
+		msgs[i] = fmt.Sprintf("%s[%d]=%q: %v", e.Field, ee.Index, ee.Value, err)
+	}
+	return fmt.Sprintf("cannot convert %s to %v: %s", e.Field, e.Type, strings.Join(msgs, "; "))
+}
+
+// setSlice sets the slice to the values converted by set. All the values are converted,
+// so that the returned *SliceConversionError names every failing index.
+func setSlice(field, source string, slice reflect.Value, values []string, set func(reflect.Value, string) error) error {
+	s := reflect.MakeSlice(slice.Type(), len(values), len(values))
+	var errs []SliceElementError
+	for i, value := range values {
+		if err := set(s.Index(i), value); err != nil {
+			errs = append(errs, SliceElementError{Index: i, Value: value, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return &SliceConversionError{Field: field, Source: source, Type: slice.Type(), Errors: errs}
+	}
+	slice.Set(s)
+	return nil
+}
+
 // Bind implements the `Binder#Bind` function.
 func (b *DefaultBinder) Bind(i interface{}, c *Context) (err error) {
 	req := c.Request
 	if req.ContentLength == 0 {
 		if req.Method == GET || req.Method == DELETE {
-			if err = b.bindData(i, c.QueryParams(), "query"); err != nil {
-				return NewHTTPError(http.StatusBadRequest, err.Error())
+			if err = b.bindData(i, c.QueryParams(), BindSourceQuery); err != nil {
+				return badRequest(err.Error(), err)
 			}
 			return
 		}
@@ -44,19 +91,24 @@ func (b *DefaultBinder) Bind(i interface{}, c *Context) (err error) {
 	case strings.HasPrefix(ctype, MIMEApplicationJSON):
 		if err = json.NewDecoder(req.Body).Decode(i); err != nil {
 			if ute, ok := err.(*json.UnmarshalTypeError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, offset=%v", ute.Type, ute.Value, ute.Offset))
+				return badRequest(fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, offset=%v", ute.Type, ute.Value, ute.Offset), jsonBindError(ute))
 			} else if se, ok := err.(*json.SyntaxError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Syntax error: offset=%v, error=%v", se.Offset, se.Error()))
+				return badRequest(fmt.Sprintf("Syntax error: offset=%v, error=%v", se.Offset, se.Error()), jsonBindError(se))
+			} else if be := jsonBindError(err); be != err {
+				return badRequest(err.Error(), be)
 			} else {
 				return NewHTTPError(http.StatusBadRequest, err.Error())
 			}
 		}
 	case strings.HasPrefix(ctype, MIMEApplicationXML), strings.HasPrefix(ctype, MIMETextXML):
-		if err = xml.NewDecoder(req.Body).Decode(i); err != nil {
+		d := xml.NewDecoder(req.Body)
+		if err = d.Decode(i); err != nil {
 			if ute, ok := err.(*xml.UnsupportedTypeError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unsupported type error: type=%v, error=%v", ute.Type, ute.Error()))
+				return badRequest(fmt.Sprintf("Unsupported type error: type=%v, error=%v", ute.Type, ute.Error()), xmlBindError(d, ute))
 			} else if se, ok := err.(*xml.SyntaxError); ok {
-				return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Syntax error: line=%v, error=%v", se.Line, se.Error()))
+				return badRequest(fmt.Sprintf("Syntax error: line=%v, error=%v", se.Line, se.Error()), xmlBindError(d, se))
+			} else if be := xmlBindError(d, err); be != err {
+				return badRequest(err.Error(), be)
 			} else {
 				return NewHTTPError(http.StatusBadRequest, err.Error())
 			}
@@ -66,8 +118,8 @@ func (b *DefaultBinder) Bind(i interface{}, c *Context) (err error) {
 		if err != nil {
 			return NewHTTPError(http.StatusBadRequest, err.Error())
 		}
-		if err = b.bindData(i, params, "form"); err != nil {
-			return NewHTTPError(http.StatusBadRequest, err.Error())
+		if err = b.bindData(i, params, BindSourceForm); err != nil {
+			return badRequest(err.Error(), err)
 		}
 	default:
 		return ErrUnsupportedMediaType
@@ -75,6 +127,8 @@ func (b *DefaultBinder) Bind(i interface{}, c *Context) (err error) {
 	return
 }
 
+// bindData binds the values of the source named by the tag to the fields of the struct,
+// and returns the BindError of each field which can't be set.
 func (b *DefaultBinder) bindData(ptr interface{}, data map[string][]string, tag string) error {
 	typ := reflect.TypeOf(ptr).Elem()
 	val := reflect.ValueOf(ptr).Elem()
@@ -83,6 +137,7 @@ func (b *DefaultBinder) bindData(ptr interface{}, data map[string][]string, tag
 		return errors.New("Binding element must be a struct")
 	}
 
+	var errs BindErrors
 	for i := 0; i < typ.NumField(); i++ {
 		typeField := typ.Field(i)
 		structField := val.Field(i)
@@ -98,41 +153,46 @@ func (b *DefaultBinder) bindData(ptr interface{}, data map[string][]string, tag
 			if _, ok := bindUnmarshaler(structField); !ok && structFieldKind == reflect.Struct {
 				err := b.bindData(structField.Addr().Interface(), data, tag)
 				if err != nil {
-					return err
+					errs = appendBindError(errs, err)
 				}
 				continue
 			}
 		}
+		if tag == BindSourceHeader {
+			// the header names are case insensitive
+			inputFieldName = strings.ToLower(inputFieldName)
+		}
 		inputValue, exists := data[inputFieldName]
 		if !exists {
 			continue
 		}
+		bindError := func(err error) {
+			errs = append(errs, &BindError{Field: inputFieldName, Source: tag, Value: inputValue[0], Type: typeField.Type, Err: err})
+		}
 
 		// Call this first, in case we're dealing with an alias to an array type
 		if ok, err := unmarshalField(typeField.Type.Kind(), inputValue[0], structField); ok {
 			if err != nil {
-				return err
+				bindError(err)
 			}
 			continue
 		}
 
-		numElems := len(inputValue)
-		if structFieldKind == reflect.Slice && numElems > 0 {
+		if structFieldKind == reflect.Slice && len(inputValue) > 0 {
 			sliceOf := structField.Type().Elem().Kind()
-			slice := reflect.MakeSlice(structField.Type(), numElems, numElems)
-			for j := 0; j < numElems; j++ {
-				if err := setWithProperType(sliceOf, inputValue[j], slice.Index(j)); err != nil {
-					return err
-				}
+			err := setSlice(inputFieldName, tag, structField, inputValue, func(v reflect.Value, s string) error {
+				return setWithProperType(sliceOf, s, v)
+			})
+			if err != nil {
+				errs = append(errs, err)
 			}
-			val.Field(i).Set(slice)
 		} else {
 			if err := setWithProperType(typeField.Type.Kind(), inputValue[0], structField); err != nil {
-				return err
+				bindError(err)
 			}
 		}
 	}
-	return nil
+	return joinBindErrors(errs)
 }
 
 func setWithProperType(valueKind reflect.Kind, val string, structField reflect.Value) error {
@@ -0,0 +1,188 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Binding sources, the Source of a BindError.
+const (
+	BindSourceJSON   = "json"
+	BindSourceXML    = "xml"
+	BindSourceForm   = "form"
+	BindSourceQuery  = "query"
+	BindSourceParam  = "param"
+	BindSourceHeader = "header"
+)
+
+type (
+	// BindError is the error binding a request value to a field, e.g. "abc" from the "page"
+	// query parameter to an int. It is returned by Bind, wrapped in a "400 - Bad Request" HTTPError,
+	// and by the DataReaders, and found with errors.As or `BindErrorsOf()`:
+	//
+	//	if err := c.Bind(&filter); err != nil {
+	//		if be := makross.FieldBindError(err, "page"); be != nil {
+	//			return makross.NewHTTPError(makross.StatusBadRequest, "page must be a number")
+	//		}
+	//		return err
+	//	}
+	//
+	// HandleError sends the binding errors as a 400 response, listing them under "errors" in JSON.
+	BindError struct {
+		Field  string       // the name of the value in its source, e.g. "page" or "address.city"
+		Source string       // BindSourceJSON, BindSourceForm, BindSourceQuery, ...
+		Value  string       // the raw value, or its JSON type for the JSON source, e.g. "string"
+		Type   reflect.Type // the expected type
+		Offset int64        // the offset of the error in the body, for the JSON and XML sources
+		Err    error
+	}
+
+	// BindErrors are the binding errors of several fields, in field order.
+	BindErrors []error
+)
+
+// Error returns the error message, e.g. `cannot bind query page="abc" to int: invalid syntax`,
+// or `cannot bind json body at offset 12: invalid character '}' looking for beginning of value`
+// if the error isn't bound to a field, e.g. for a malformed body.
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("cannot bind %s body at offset %d: %v", e.Source, e.Offset, e.Message())
+	}
+	return fmt.Sprintf("cannot bind %s %s=%q to %v: %v", e.Source, e.Field, e.Value, e.Type, e.Message())
+}
+
+// Message returns the message of the cause, without the conversion details.
+func (e *BindError) Message() string {
+	err := e.Err
+	if ne, ok := err.(*strconv.NumError); ok {
+		err = ne.Err
+	}
+	if err == nil {
+		return "invalid value"
+	}
+	return err.Error()
+}
+
+// Unwrap returns the cause.
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// Error returns the messages of the errors.
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As.
+func (e BindErrors) Unwrap() []error {
+	return e
+}
+
+// Unwrap returns the BindError of each failing value, for errors.As and `BindErrorsOf()`.
+func (e *SliceConversionError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, ee := range e.Errors {
+		errs[i] = &BindError{Field: e.Field, Source: e.Source, Value: ee.Value, Type: e.Type.Elem(), Err: ee.Err}
+	}
+	return errs
+}
+
+// BindErrorsOf returns the binding errors found in the chain of err, in order,
+// e.g. of the error returned by Bind.
+func BindErrorsOf(err error) []*BindError {
+	var found []*BindError
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case nil:
+		case *BindError:
+			found = append(found, e)
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				walk(err)
+			}
+		default:
+			walk(errors.Unwrap(err))
+		}
+	}
+	walk(err)
+	return found
+}
+
+// FieldBindError returns the first binding error of the field found in the chain of err, or nil.
+func FieldBindError(err error, field string) *BindError {
+	for _, be := range BindErrorsOf(err) {
+		if be.Field == field {
+			return be
+		}
+	}
+	return nil
+}
+
+// joinBindErrors returns nil, the error, or the BindErrors of several errors.
+func joinBindErrors(errs BindErrors) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+// appendBindError appends the error to errs, flattening BindErrors.
+func appendBindError(errs BindErrors, err error) BindErrors {
+	if be, ok := err.(BindErrors); ok {
+		return append(errs, be...)
+	}
+	return append(errs, err)
+}
+
+// jsonBindError returns the BindError of a JSON type or syntax error, or err.
+func jsonBindError(err error) error {
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		return &BindError{Field: e.Field, Source: BindSourceJSON, Value: e.Value, Type: e.Type, Offset: e.Offset, Err: e}
+	case *json.SyntaxError:
+		return &BindError{Source: BindSourceJSON, Offset: e.Offset, Err: e}
+	}
+	if err == io.ErrUnexpectedEOF {
+		// a truncated body
+		return &BindError{Source: BindSourceJSON, Err: err}
+	}
+	return err
+}
+
+// xmlBindError returns the BindError of an XML type or syntax error of the decoder, or err.
+// As encoding/xml doesn't name the field of a conversion error, the BindError has no Field.
+func xmlBindError(d *xml.Decoder, err error) error {
+	switch e := err.(type) {
+	case *xml.SyntaxError:
+		return &BindError{Source: BindSourceXML, Offset: d.InputOffset(), Err: e}
+	case *xml.UnsupportedTypeError:
+		return &BindError{Source: BindSourceXML, Type: e.Type, Offset: d.InputOffset(), Err: e}
+	case *strconv.NumError:
+		return &BindError{Source: BindSourceXML, Value: e.Num, Offset: d.InputOffset(), Err: e}
+	}
+	if err == io.ErrUnexpectedEOF {
+		return &BindError{Source: BindSourceXML, Offset: d.InputOffset(), Err: err}
+	}
+	return err
+}
+
+// badRequest returns the "400 - Bad Request" HTTPError of a binding error.
+func badRequest(message string, err error) *HTTPError {
+	return &HTTPError{Status: StatusBadRequest, Message: message, Err: err}
+}
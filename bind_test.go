@@ -2,6 +2,7 @@ package makross
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -153,6 +154,50 @@ func TestBindQueryParams(t *testing.T) {
 	}
 }
 
+func TestBindQueryParamsSlices(t *testing.T) {
+	type filter struct {
+		IDs     []int     `query:"ids"`
+		Tags    []string  `query:"tags"`
+		Big     []int64   `query:"big"`
+		Prices  []float64 `query:"prices"`
+		Flags   []bool    `query:"flags"`
+		Missing []int     `query:"missing"`
+	}
+	e := New()
+	req := httptest.NewRequest(GET, "/?ids=1&ids=2&ids=3&tags=a&tags=b&big=9007199254740993&prices=1.5&prices=2&flags=true&flags=0", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	f := new(filter)
+	if assert.NoError(t, c.Bind(f)) {
+		assert.Equal(t, []int{1, 2, 3}, f.IDs)
+		assert.Equal(t, []string{"a", "b"}, f.Tags)
+		assert.Equal(t, []int64{9007199254740993}, f.Big)
+		assert.Equal(t, []float64{1.5, 2}, f.Prices)
+		assert.Equal(t, []bool{true, false}, f.Flags)
+		assert.Nil(t, f.Missing)
+	}
+
+	// every failing index is reported
+	f = new(filter)
+	err := new(DefaultBinder).bindData(f, map[string][]string{"ids": {"1", "x", "3", "4.5"}}, "query")
+	if assert.IsType(t, &SliceConversionError{}, err) {
+		sce := err.(*SliceConversionError)
+		assert.Equal(t, "ids", sce.Field)
+		assert.Len(t, sce.Errors, 2)
+		assert.Equal(t, 1, sce.Errors[0].Index)
+		assert.Equal(t, 3, sce.Errors[1].Index)
+		assert.Equal(t, `cannot convert ids to []int: ids[1]="x": invalid syntax; ids[3]="4.5": invalid syntax`, err.Error())
+	}
+	assert.Nil(t, f.IDs)
+
+	req = httptest.NewRequest(GET, "/?flags=true&flags=maybe", nil)
+	c = e.NewContext(req, httptest.NewRecorder())
+	err = c.Bind(new(filter))
+	if assert.IsType(t, &HTTPError{}, err) {
+		assert.Equal(t, StatusBadRequest, err.(*HTTPError).Status)
+		assert.Contains(t, err.(*HTTPError).Message, `flags[1]="maybe"`)
+	}
+}
+
 func TestBindUnmarshalParam(t *testing.T) {
 	e := New()
 	req := httptest.NewRequest(GET, "/?ts=2016-12-06T19:09:05Z&sa=one,two,three&ta=2016-12-06T19:09:05Z&ta=2016-12-06T19:09:05Z&ST=baz", nil)
@@ -332,3 +377,82 @@ func testBindError(t *testing.T, r io.Reader, ctype string) {
 		}
 	}
 }
+
+func TestBindErrors(t *testing.T) {
+	type page struct {
+		Page    int    `query:"page" form:"page" param:"page" header:"X-Page"`
+		Size    uint   `query:"size" form:"size" param:"size" header:"X-Page-Size"`
+		Sort    string `query:"sort" form:"sort" param:"sort" header:"X-Sort"`
+		Enabled bool   `json:"enabled"`
+	}
+	m := New()
+	check := func(err error, source string) {
+		he, ok := err.(*HTTPError)
+		if !assert.True(t, ok, source) {
+			return
+		}
+		assert.Equal(t, StatusBadRequest, he.Status, source)
+		var be *BindError
+		if assert.True(t, errors.As(err, &be), source) {
+			assert.Equal(t, "page", strings.ToLower(strings.TrimPrefix(be.Field, "x-")), source)
+			assert.Equal(t, source, be.Source)
+			assert.Equal(t, "abc", be.Value, source)
+			assert.Equal(t, reflect.TypeOf(0), be.Type, source)
+			assert.Equal(t, "invalid syntax", be.Message(), source)
+		}
+		errs := BindErrorsOf(err)
+		if assert.Len(t, errs, 2, source) {
+			assert.Equal(t, "-1", errs[1].Value, source)
+			assert.Equal(t, reflect.TypeOf(uint(0)), errs[1].Type, source)
+		}
+	}
+
+	// query
+	req := httptest.NewRequest(GET, "/?page=abc&size=-1&sort=name", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+	check(c.Bind(new(page)), BindSourceQuery)
+	check(c.BindQuery(new(page)), BindSourceQuery)
+	assert.Equal(t, `cannot bind query page="abc" to int: invalid syntax; cannot bind query size="-1" to uint: invalid syntax`, c.Bind(new(page)).Error())
+
+	// form
+	req = httptest.NewRequest(POST, "/", strings.NewReader("page=abc&size=-1"))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	c = m.NewContext(req, httptest.NewRecorder())
+	check(c.Bind(new(page)), BindSourceForm)
+
+	// param
+	m.Get("/pages/<page>/<size>", func(c *Context) error {
+		check(c.BindParams(new(page)), BindSourceParam)
+		return nil
+	})
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/pages/abc/-1", nil))
+
+	// header
+	req = httptest.NewRequest(GET, "/", nil)
+	req.Header.Set("X-Page", "abc")
+	req.Header.Set("x-page-size", "-1")
+	c = m.NewContext(req, httptest.NewRecorder())
+	check(c.BindHeaders(new(page)), BindSourceHeader)
+
+	// json
+	req = httptest.NewRequest(POST, "/", strings.NewReader(`{"enabled": "yes"}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	c = m.NewContext(req, httptest.NewRecorder())
+	err := c.Bind(new(page))
+	assert.Equal(t, StatusBadRequest, err.(*HTTPError).Status)
+	if be := FieldBindError(err, "enabled"); assert.NotNil(t, be) {
+		assert.Equal(t, BindSourceJSON, be.Source)
+		assert.Equal(t, "string", be.Value)
+		assert.Equal(t, reflect.TypeOf(true), be.Type)
+	}
+	assert.Nil(t, FieldBindError(err, "page"))
+
+	// the valid values are bound
+	p := new(page)
+	req = httptest.NewRequest(GET, "/", nil)
+	req.Header.Set("X-Page", "3")
+	req.Header.Set("X-Sort", "name")
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.Nil(t, c.BindHeaders(p))
+	assert.Equal(t, page{Page: 3, Sort: "name"}, *p)
+}
@@ -3,6 +3,8 @@ package blimit
 import (
 	"fmt"
 	"io"
+	"log"
+	"os"
 	"sync"
 
 	"github.com/insionng/makross"
@@ -20,6 +22,11 @@ type (
 		// as `4x` or `4xB`, where x is one of the multiple from K, M, G, T or P.
 		Limit string `json:"limit"`
 		limit int64
+
+		// Unlimited lists the names of the routes whose bodies aren't limited, such as the streaming
+		// uploads of large files. The routes can also be exempted with the UnlimitedKey metadata set to true.
+		// Optional.
+		Unlimited []string `json:"unlimited"`
 	}
 
 	limitedReader struct {
@@ -30,6 +37,18 @@ type (
 	}
 )
 
+// UnlimitedKey is the route metadata key exempting a route from the limit when set to true, e.g.
+// `m.Post("/videos", upload).Meta(blimit.UnlimitedKey, true)`. It's also the context data key set to true
+// for the requests to the exempted routes, e.g. for the access logger to flag the potentially huge uploads:
+//
+//	access.RegisterToken("unlimited", func(c *makross.Context, res *access.LogResponseWriter, elapsed float64) string {
+//		if c.Get(blimit.UnlimitedKey) == true {
+//			return "unlimited"
+//		}
+//		return "-"
+//	})
+const UnlimitedKey = "blimit.unlimited"
+
 var (
 	// DefaultBodyLimitConfig is the default Gzip middleware config.
 	DefaultBodyLimitConfig = BodyLimitConfig{
@@ -45,12 +64,29 @@ var (
 // header and actual content read, which makes it super secure.
 // Limit can be specified as `4x` or `4xB`, where x is one of the multiple from K, M,
 // G, T or P.
+// A too large Content-Length is refused before the body is read, so the clients sending
+// "Expect: 100-continue" don't transmit the body, see `Context#ExpectsContinue()`.
 func BodyLimit(limit string) makross.Handler {
 	c := DefaultBodyLimitConfig
 	c.Limit = limit
 	return BodyLimitWithConfig(c)
 }
 
+// BodyLimitFromEnv returns a BodyLimit middleware with the limit read from the environment variable,
+// e.g. `blimit.BodyLimitFromEnv("BODY_LIMIT", "2M")`. The fallback limit is used when the variable
+// is unset or empty, or when its value isn't a valid limit, which is logged.
+func BodyLimitFromEnv(key, fallback string) makross.Handler {
+	limit := fallback
+	if v := os.Getenv(key); v != "" {
+		if n, err := lbytes.Parse(v); err != nil || n <= 0 {
+			log.Printf("[blimit] invalid %s=%q, using %s", key, v, fallback)
+		} else {
+			limit = v
+		}
+	}
+	return BodyLimit(limit)
+}
+
 // BodyLimitWithConfig returns a BodyLimit middleware with config.
 // See: `BodyLimit()`.
 func BodyLimitWithConfig(config BodyLimitConfig) makross.Handler {
@@ -65,11 +101,19 @@ func BodyLimitWithConfig(config BodyLimitConfig) makross.Handler {
 	}
 	config.limit = limit
 	pool := limitedReaderPool(config)
+	unlimited := make(map[string]bool, len(config.Unlimited))
+	for _, name := range config.Unlimited {
+		unlimited[name] = true
+	}
 
 	return func(c *makross.Context) error {
 		if config.Skipper(c) {
 			return c.Next()
 		}
+		if route := c.Route(); route != nil && (unlimited[route.GetName()] || route.GetMeta(UnlimitedKey) == true) {
+			c.Set(UnlimitedKey, true)
+			return c.Next()
+		}
 
 		req := c.Request
 
@@ -105,6 +149,7 @@ func (r *limitedReader) Close() error {
 func (r *limitedReader) Reset(reader io.ReadCloser, context *makross.Context) {
 	r.reader = reader
 	r.context = context
+	r.read = 0
 }
 
 func limitedReaderPool(c BodyLimitConfig) sync.Pool {
@@ -1,10 +1,23 @@
 package blimit_test
 
 import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/insionng/makross"
+	"github.com/insionng/makross/bauth"
 	"github.com/insionng/makross/blimit"
 	"github.com/insionng/makross/skipper"
-	"testing"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestBodyLimit(t *testing.T) {
@@ -16,3 +29,123 @@ func TestBodyLimit(t *testing.T) {
 	m.Use(blimit.BodyLimitWithConfig(blimit.BodyLimitConfig{Skipper: skipper.DefaultSkipper, Limit: "4M"}))
 	go m.Listen(":7777")
 }
+
+func TestBodyLimitFromEnv(t *testing.T) {
+	status := func(size int) int {
+		m := makross.New()
+		m.Use(blimit.BodyLimitFromEnv("TEST_BODY_LIMIT", "1K"))
+		m.Post("/", func(c *makross.Context) error {
+			_, err := ioutil.ReadAll(c.Request.Body)
+			return err
+		})
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.POST, "/", strings.NewReader(strings.Repeat("x", size))))
+		return res.Code
+	}
+
+	t.Setenv("TEST_BODY_LIMIT", "2K")
+	assert.Equal(t, makross.StatusOK, status(1500))
+	assert.Equal(t, makross.StatusRequestEntityTooLarge, status(2500))
+	t.Setenv("TEST_BODY_LIMIT", "")
+	assert.Equal(t, makross.StatusRequestEntityTooLarge, status(1500))
+	assert.Equal(t, makross.StatusOK, status(500))
+	for _, v := range []string{"2 lots", "0"} {
+		t.Setenv("TEST_BODY_LIMIT", v)
+		assert.Equal(t, makross.StatusRequestEntityTooLarge, status(1500), v)
+	}
+}
+
+func TestBodyLimitExpectContinue(t *testing.T) {
+	m := makross.New()
+	m.Use(bauth.BasicAuth(func(user, password string) bool {
+		return user == "joe" && password == "secret"
+	}), blimit.BodyLimit("1K"))
+	m.Post("/upload", func(c *makross.Context) error {
+		assert.True(t, c.ExpectsContinue())
+		b, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		return c.String(string(b))
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	// upload sends the headers of the request, and the body only after a "100 Continue",
+	// returning the first response and the final one.
+	upload := func(auth string, length int) (first, final *http.Response) {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if !assert.Nil(t, err) {
+			return nil, nil
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		fmt.Fprintf(conn, "POST /upload HTTP/1.1\r\nHost: example.com\r\nAuthorization: Basic %s\r\n"+
+			"Content-Length: %d\r\nExpect: 100-continue\r\n\r\n", auth, length)
+		r := bufio.NewReader(conn)
+		first, err = http.ReadResponse(r, nil)
+		if !assert.Nil(t, err) || first.StatusCode != makross.StatusContinue {
+			return first, first
+		}
+		conn.Write([]byte(strings.Repeat("x", length)))
+		final, err = http.ReadResponse(r, nil)
+		assert.Nil(t, err)
+		return first, final
+	}
+	joe := base64.StdEncoding.EncodeToString([]byte("joe:secret"))
+
+	first, _ := upload(base64.StdEncoding.EncodeToString([]byte("joe:wrong")), 512)
+	assert.Equal(t, makross.StatusUnauthorized, first.StatusCode)
+	first, _ = upload(joe, 1<<20)
+	assert.Equal(t, makross.StatusRequestEntityTooLarge, first.StatusCode)
+
+	first, final := upload(joe, 512)
+	assert.Equal(t, makross.StatusContinue, first.StatusCode)
+	if assert.NotNil(t, final) {
+		assert.Equal(t, makross.StatusOK, final.StatusCode)
+		b, _ := ioutil.ReadAll(final.Body)
+		assert.Equal(t, strings.Repeat("x", 512), string(b))
+	}
+}
+
+func TestBodyLimitUnlimited(t *testing.T) {
+	m := makross.New()
+	m.Use(blimit.BodyLimitWithConfig(blimit.BodyLimitConfig{Limit: "1K", Unlimited: []string{"videos"}}))
+	upload := func(c *makross.Context) error {
+		b, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		return c.String(fmt.Sprintf("%d %v", len(b), c.Get(blimit.UnlimitedKey)))
+	}
+	m.Post("/videos", upload).Name("videos")
+	m.Post("/audios", upload).Meta(blimit.UnlimitedKey, true)
+	m.Post("/avatars", upload)
+
+	post := func(path string, size int, chunked bool) *httptest.ResponseRecorder {
+		var body io.Reader = strings.NewReader(strings.Repeat("x", size))
+		if chunked {
+			body = io.MultiReader(body)
+		}
+		req := httptest.NewRequest(makross.POST, path, body)
+		if chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	for _, path := range []string{"/videos", "/audios"} {
+		res := post(path, 4096, true)
+		assert.Equal(t, makross.StatusOK, res.Code, path)
+		assert.Equal(t, "4096 true", res.Body.String(), path)
+		assert.Equal(t, makross.StatusOK, post(path, 4096, false).Code, path)
+	}
+	assert.Equal(t, makross.StatusRequestEntityTooLarge, post("/avatars", 4096, true).Code)
+	assert.Equal(t, makross.StatusRequestEntityTooLarge, post("/avatars", 4096, false).Code)
+	res := post("/avatars", 512, true)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, "512 <nil>", res.Body.String())
+}
@@ -0,0 +1,126 @@
+package buffer
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// Response is the buffered response, which the Processor may change before it's sent.
+	Response struct {
+		// Status is the status code of the response.
+		Status int
+
+		// Header is the header of the response. The Content-Length is set after the Processor.
+		Header http.Header
+
+		// Body is the complete body of the response.
+		Body []byte
+	}
+
+	// Processor post-processes the buffered response, e.g. to sign its body. The error is returned
+	// by the middleware, nothing being sent.
+	Processor func(c *makross.Context, res *Response) error
+
+	// BufferConfig defines the config for Buffer middleware.
+	BufferConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Processor post-processes the buffered responses.
+		// Required.
+		Processor Processor
+
+		// MaxSize is the size of the body over which the response is streamed as is, without being
+		// buffered nor processed. A flush of the response by the handler also streams it.
+		// Optional. Default value 1MB.
+		MaxSize int64 `json:"max_size"`
+	}
+)
+
+var (
+	// DefaultBufferConfig is the default Buffer middleware config.
+	DefaultBufferConfig = BufferConfig{
+		Skipper: skipper.DefaultSkipper,
+		MaxSize: 1 << 20,
+	}
+)
+
+// Buffer returns a Buffer middleware.
+//
+// Buffer middleware buffers the complete response, its status, header and body, and runs the processor
+// on it before sending it, with the Content-Length of the processed body, e.g. to inject a nonce in the pages:
+//
+//	m.Use(buffer.Buffer(func(c *makross.Context, res *buffer.Response) error {
+//		res.Body = bytes.Replace(res.Body, []byte("{{nonce}}"), []byte(c.Get("nonce").(string)), -1)
+//		return nil
+//	}))
+//
+// The responses larger than BufferConfig.MaxSize, and the flushed ones such as the streams, are sent as is.
+// Buffer must run after the middlewares changing the encoding: register it after compress.Gzip,
+// otherwise it gets the compressed response.
+func Buffer(processor Processor) makross.Handler {
+	c := DefaultBufferConfig
+	c.Processor = processor
+	return BufferWithConfig(c)
+}
+
+// BufferWithConfig returns a Buffer middleware with config.
+// See: `Buffer()`.
+func BufferWithConfig(config BufferConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultBufferConfig.Skipper
+	}
+	if config.Processor == nil {
+		panic("buffer middleware requires a processor")
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultBufferConfig.MaxSize
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		res := c.Response
+		rw := res.Writer
+		w := NewWriter(rw, config.MaxSize, true)
+		res.Writer = w
+		err := c.Next()
+		res.Writer = rw
+		if !res.Committed || w.Streaming() {
+			return err
+		}
+		if err != nil {
+			// let the error handler respond, unless the handler already did
+			w.Send(w.Status(), w.Body())
+			return err
+		}
+
+		br := &Response{Status: w.Status(), Header: rw.Header(), Body: w.Body()}
+		if err = config.Processor(c, br); err != nil {
+			// nothing was sent yet
+			res.Committed = false
+			res.Size = 0
+			rw.Header().Del(makross.HeaderContentLength)
+			return err
+		}
+		if bodyAllowed(br.Status) {
+			rw.Header().Set(makross.HeaderContentLength, strconv.Itoa(len(br.Body)))
+		}
+		res.Status = br.Status
+		res.Size = int64(len(br.Body))
+		w.Send(br.Status, br.Body)
+		return nil
+	}
+}
+
+// bodyAllowed reports whether a response with the status has a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != makross.StatusNoContent && status != makross.StatusNotModified
+}
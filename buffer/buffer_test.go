@@ -0,0 +1,99 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	page := `<script nonce="{{nonce}}">run()</script>`
+	m := makross.New()
+	var processed int
+	m.Use(BufferWithConfig(BufferConfig{
+		MaxSize: 100,
+		Processor: func(c *makross.Context, res *Response) error {
+			processed++
+			if c.Request.URL.Path == "/fail" {
+				return errors.New("signing failed")
+			}
+			res.Body = bytes.Replace(res.Body, []byte("{{nonce}}"), []byte("r4nd0m-n0nce"), -1)
+			res.Header.Set("X-Signature", strconv.Itoa(len(res.Body)))
+			return nil
+		},
+	}))
+	m.Get("/", func(c *makross.Context) error {
+		c.Response.Header().Set(makross.HeaderContentLength, strconv.Itoa(len(page)))
+		return c.Blob(makross.MIMETextHTMLCharsetUTF8, []byte(page), makross.StatusAccepted)
+	})
+	m.Get("/large", func(c *makross.Context) error {
+		for i := 0; i < 30; i++ {
+			c.Response.Write([]byte("{{nonce}}"))
+		}
+		return nil
+	})
+	m.Get("/stream", func(c *makross.Context) error {
+		c.Response.Write([]byte("{{nonce}}"))
+		c.Response.Flush()
+		c.Response.Write([]byte("{{nonce}}"))
+		return nil
+	})
+	m.Get("/fail", func(c *makross.Context) error {
+		return c.String(page)
+	})
+	m.Get("/error", func(c *makross.Context) error {
+		return makross.ErrForbidden
+	})
+	m.Get("/empty", func(c *makross.Context) error {
+		return c.NoContent(makross.StatusNoContent)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res
+	}
+
+	res := serve("/")
+	body := `<script nonce="r4nd0m-n0nce">run()</script>`
+	assert.Equal(t, makross.StatusAccepted, res.Code)
+	assert.Equal(t, body, res.Body.String())
+	assert.Equal(t, strconv.Itoa(len(body)), res.Header().Get(makross.HeaderContentLength))
+	assert.Equal(t, strconv.Itoa(len(body)), res.Header().Get("X-Signature"))
+	assert.Equal(t, makross.MIMETextHTMLCharsetUTF8, res.Header().Get(makross.HeaderContentType))
+	assert.Equal(t, 1, processed)
+
+	// too large and flushed responses are sent as is
+	res = serve("/large")
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, strings.Repeat("{{nonce}}", 30), res.Body.String())
+	res = serve("/stream")
+	assert.Equal(t, "{{nonce}}{{nonce}}", res.Body.String())
+	assert.True(t, res.Flushed)
+	assert.Equal(t, 1, processed)
+
+	res = serve("/fail")
+	assert.Equal(t, makross.StatusInternalServerError, res.Code)
+	assert.NotContains(t, res.Body.String(), "nonce")
+	assert.Equal(t, 2, processed)
+
+	res = serve("/error")
+	assert.Equal(t, makross.StatusForbidden, res.Code)
+	assert.Equal(t, 2, processed)
+
+	res = serve("/empty")
+	assert.Equal(t, makross.StatusNoContent, res.Code)
+	assert.Empty(t, res.Header().Get(makross.HeaderContentLength))
+}
+
+func TestBufferRequiresProcessor(t *testing.T) {
+	assert.Panics(t, func() {
+		BufferWithConfig(BufferConfig{})
+	})
+}
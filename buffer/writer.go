@@ -0,0 +1,107 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+)
+
+// Writer is a response writer buffering the status and the body of the response written by the
+// handlers, up to a maximum size, until the middleware which installed it sends them with Send.
+// It is used by the Buffer middleware, and by the other middlewares post-processing the complete
+// responses, such as transform.
+type Writer struct {
+	http.ResponseWriter
+	status    int
+	buf       bytes.Buffer
+	max       int64
+	stream    bool // the responses exceeding max, or flushed, are streamed rather than failed
+	streaming bool // the response is streamed as is
+	tooLarge  bool // the body exceeded max, it is discarded
+}
+
+// ErrTooLarge is returned by the writes exceeding the maximum size of a Writer which doesn't stream.
+var ErrTooLarge = errors.New("buffer: body too large")
+
+// NewWriter returns a Writer buffering up to max bytes of the body written to w. The responses
+// exceeding max, or flushed by the handler, are streamed as is if stream is true. Otherwise the
+// writes exceeding max fail with ErrTooLarge, discarding the body, and the flushes are ignored.
+func NewWriter(w http.ResponseWriter, max int64, stream bool) *Writer {
+	return &Writer{ResponseWriter: w, status: http.StatusOK, max: max, stream: stream}
+}
+
+// Status returns the status code written by the handler, 200 by default.
+func (w *Writer) Status() int {
+	return w.status
+}
+
+// Body returns the buffered body.
+func (w *Writer) Body() []byte {
+	return w.buf.Bytes()
+}
+
+// Streaming reports whether the response is streamed as is rather than buffered.
+func (w *Writer) Streaming() bool {
+	return w.streaming
+}
+
+// TooLarge reports whether the body exceeded the maximum size, the Writer not streaming.
+func (w *Writer) TooLarge() bool {
+	return w.tooLarge
+}
+
+func (w *Writer) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if w.tooLarge {
+		return 0, ErrTooLarge
+	}
+	if !w.streaming && int64(w.buf.Len()+len(b)) > w.max {
+		if !w.stream {
+			w.tooLarge = true
+			w.buf = bytes.Buffer{}
+			return 0, ErrTooLarge
+		}
+		w.streamBuffered()
+	}
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush streams the response if the Writer streams, or is a no-op.
+func (w *Writer) Flush() {
+	if !w.stream {
+		return
+	}
+	if !w.streaming {
+		w.streamBuffered()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped response writer.
+func (w *Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Send sends the status and the body to the wrapped response writer, e.g. the processed buffered
+// response. The Content-Length header, if any, is left to the caller.
+func (w *Writer) Send(status int, body []byte) {
+	w.ResponseWriter.WriteHeader(status)
+	if len(body) > 0 {
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// streamBuffered sends what was buffered, and the rest of the response as it's written.
+func (w *Writer) streamBuffered() {
+	w.streaming = true
+	w.Send(w.status, w.buf.Bytes())
+	w.buf = bytes.Buffer{}
+}
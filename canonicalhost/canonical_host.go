@@ -0,0 +1,112 @@
+package canonicalhost
+
+import (
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// CanonicalHostConfig defines the config for CanonicalHost middleware.
+	CanonicalHostConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Host is the canonical host, e.g. "www.example.com".
+		// Required.
+		Host string `json:"host"`
+
+		// Scheme is the canonical scheme, "http" or "https".
+		// Optional. The scheme of the request is kept if it's empty.
+		Scheme string `json:"scheme"`
+
+		// Status code to be used when redirecting the request.
+		// Optional. Default value http.StatusMovedPermanently.
+		Code int `json:"code"`
+
+		// ExcludePaths lists the paths that are never redirected, e.g. health checks.
+		// Optional. Default value []string{"/health", "/healthz"}.
+		ExcludePaths []string `json:"exclude_paths"`
+
+		// TrustProxy makes the middleware use the host and scheme forwarded by a proxy
+		// in the Forwarded, X-Forwarded-Host and X-Forwarded-Proto headers.
+		// Once trusted proxies are set with `Makross#SetTrustedProxies()`, the headers are only honored from them.
+		// Optional. Default value false.
+		TrustProxy bool `json:"trust_proxy"`
+	}
+)
+
+var (
+	// DefaultCanonicalHostConfig is the default CanonicalHost middleware config.
+	DefaultCanonicalHostConfig = CanonicalHostConfig{
+		Skipper:      skipper.DefaultSkipper,
+		Code:         makross.StatusMovedPermanently,
+		ExcludePaths: []string{"/health", "/healthz"},
+	}
+)
+
+// CanonicalHost returns a CanonicalHost middleware.
+//
+// CanonicalHost middleware redirects the requests made to any other host than the canonical one,
+// e.g. to the bare domain or an IP address, to the same path and query on the canonical host.
+//
+// Usage `makross#Pre(CanonicalHost("www.example.com"))`
+func CanonicalHost(host string) makross.Handler {
+	c := DefaultCanonicalHostConfig
+	c.Host = host
+	return CanonicalHostWithConfig(c)
+}
+
+// CanonicalHostWithConfig returns a CanonicalHost middleware with config.
+// See: `CanonicalHost()`.
+func CanonicalHostWithConfig(config CanonicalHostConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCanonicalHostConfig.Skipper
+	}
+	if config.Code == 0 {
+		config.Code = DefaultCanonicalHostConfig.Code
+	}
+	if config.ExcludePaths == nil {
+		config.ExcludePaths = DefaultCanonicalHostConfig.ExcludePaths
+	}
+	if config.Host == "" {
+		panic("canonicalhost: host is required")
+	}
+	config.Scheme = strings.ToLower(config.Scheme)
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		req := c.Request
+		for _, path := range config.ExcludePaths {
+			if req.URL.Path == path {
+				return c.Next()
+			}
+		}
+
+		host, scheme := req.Host, "http"
+		if config.TrustProxy {
+			host, scheme = c.Host(), c.Scheme()
+		} else if c.IsTLS() {
+			scheme = "https"
+		}
+		if i := strings.IndexByte(host, ','); i >= 0 {
+			// the first of a list of forwarded hosts is the one requested by the client
+			host = host[:i]
+		}
+		host = strings.TrimSpace(host)
+
+		target := scheme
+		if config.Scheme != "" {
+			target = config.Scheme
+		}
+		if strings.EqualFold(host, config.Host) && scheme == target {
+			return c.Next()
+		}
+		return c.Redirect(target+"://"+config.Host+req.URL.RequestURI(), config.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package canonicalhost
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalHost(t *testing.T) {
+	e := makross.New()
+	e.SetTrustedProxies("192.0.2.1")
+	next := func(c *makross.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	tests := []struct {
+		config   CanonicalHostConfig
+		host     string
+		url      string
+		header   map[string]string
+		tls      bool
+		code     int
+		location string
+	}{
+		// redirects
+		{CanonicalHostConfig{Host: "www.at3.net"}, "at3.net", "/a/b?c=d", nil, false, http.StatusMovedPermanently, "http://www.at3.net/a/b?c=d"},
+		{CanonicalHostConfig{Host: "www.at3.net"}, "10.0.0.1:8080", "/", nil, true, http.StatusMovedPermanently, "https://www.at3.net/"},
+		{CanonicalHostConfig{Host: "www.at3.net", Scheme: "https"}, "at3.net", "/x", nil, false, http.StatusMovedPermanently, "https://www.at3.net/x"},
+		{CanonicalHostConfig{Host: "www.at3.net", Scheme: "https"}, "www.at3.net", "/x", nil, false, http.StatusMovedPermanently, "https://www.at3.net/x"},
+		{CanonicalHostConfig{Host: "www.at3.net", Code: http.StatusFound}, "at3.net", "/", nil, false, http.StatusFound, "http://www.at3.net/"},
+		// forwarded headers are ignored unless the proxy is trusted
+		{CanonicalHostConfig{Host: "www.at3.net"}, "10.0.0.1", "/", map[string]string{makross.HeaderXForwardedHost: "www.at3.net"}, false, http.StatusMovedPermanently, "http://www.at3.net/"},
+		{CanonicalHostConfig{Host: "www.at3.net", TrustProxy: true}, "10.0.0.1", "/", map[string]string{makross.HeaderXForwardedHost: "at3.net"}, false, http.StatusMovedPermanently, "http://www.at3.net/"},
+
+		// pass-through
+		{CanonicalHostConfig{Host: "www.at3.net"}, "www.at3.net", "/", nil, false, http.StatusOK, ""},
+		{CanonicalHostConfig{Host: "www.at3.net"}, "WWW.AT3.NET", "/", nil, true, http.StatusOK, ""},
+		{CanonicalHostConfig{Host: "www.at3.net", Scheme: "https"}, "www.at3.net", "/", nil, true, http.StatusOK, ""},
+		{CanonicalHostConfig{Host: "www.at3.net"}, "10.0.0.1", "/healthz", nil, false, http.StatusOK, ""},
+		{CanonicalHostConfig{Host: "www.at3.net", ExcludePaths: []string{"/ping"}}, "10.0.0.1", "/ping", nil, false, http.StatusOK, ""},
+		{CanonicalHostConfig{Host: "www.at3.net", TrustProxy: true}, "10.0.0.1", "/", map[string]string{makross.HeaderXForwardedHost: "www.at3.net, 10.0.0.1"}, false, http.StatusOK, ""},
+		{CanonicalHostConfig{Host: "www.at3.net", Scheme: "https", TrustProxy: true}, "10.0.0.1", "/", map[string]string{makross.HeaderForwarded: "host=www.at3.net;proto=https"}, false, http.StatusOK, ""},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest(makross.GET, test.url, nil)
+		req.Host = test.host
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		res := httptest.NewRecorder()
+		c := e.NewContext(req, res, next)
+		CanonicalHostWithConfig(test.config)(c)
+		assert.Equal(t, test.code, res.Code, i)
+		assert.Equal(t, test.location, res.Header().Get(makross.HeaderLocation), i)
+	}
+}
+
+func TestCanonicalHostRequiresHost(t *testing.T) {
+	assert.Panics(t, func() {
+		CanonicalHostWithConfig(CanonicalHostConfig{})
+	})
+}
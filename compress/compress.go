@@ -27,6 +27,7 @@ type (
 	gzipResponseWriter struct {
 		io.Writer
 		http.ResponseWriter
+		passthrough bool // the body is encoded already
 	}
 )
 
@@ -73,8 +74,11 @@ func GzipWithConfig(config GzipConfig) makross.Handler {
 			if err != nil {
 				return err
 			}
+			grw := &gzipResponseWriter{Writer: w, ResponseWriter: rw}
 			defer func() {
-				if res.Size == 0 {
+				if grw.passthrough {
+					w.Reset(ioutil.Discard)
+				} else if res.Size == 0 {
 					if res.Header().Get(makross.HeaderContentEncoding) == gzipScheme {
 						res.Header().Del(makross.HeaderContentEncoding)
 					}
@@ -86,7 +90,6 @@ func GzipWithConfig(config GzipConfig) makross.Handler {
 				}
 				w.Close()
 			}()
-			grw := &gzipResponseWriter{Writer: w, ResponseWriter: rw}
 			res.Writer = grw
 		}
 		return c.Next()
@@ -101,18 +104,41 @@ func (w *gzipResponseWriter) WriteHeader(code int) {
 }
 
 func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
 	if w.Header().Get(makross.HeaderContentType) == "" {
 		w.Header().Set(makross.HeaderContentType, http.DetectContentType(b))
 	}
 	return w.Writer.Write(b)
 }
 
+// Passthrough implements makross.PassthroughWriter, the body written next is encoded already,
+// e.g. by the render cache.
+func (w *gzipResponseWriter) Passthrough() {
+	w.passthrough = true
+}
+
+// Unwrap returns the wrapped response writer.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (w *gzipResponseWriter) Flush() {
+	if w.passthrough {
+		if f, ok := w.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+		return
+	}
 	w.Writer.(*gzip.Writer).Flush()
 }
 
 func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	return w.ResponseWriter.(http.Hijacker).Hijack()
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, makross.ErrHijackNotSupported
 }
 
 func (w *gzipResponseWriter) CloseNotify() <-chan bool {
@@ -3,12 +3,15 @@ package compress
 import (
 	"bytes"
 	"compress/gzip"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/insionng/makross"
+	"github.com/insionng/makross/sniff"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -95,3 +98,61 @@ func TestGzipWithStatic(t *testing.T) {
 		assert.Equal(t, want, buf.Bytes())
 	}
 }
+
+type testRenderer struct{}
+
+func (r *testRenderer) Render(w io.Writer, name string, c *makross.Context) error {
+	_, err := io.WriteString(w, "<p>"+name+"</p>")
+	return err
+}
+
+func TestGzipRenderCached(t *testing.T) {
+	m := makross.New()
+	m.Use(Gzip())
+	m.SetRenderer(&testRenderer{})
+	m.SetRenderCache(makross.NewRenderCache(time.Minute))
+	m.Get("/", func(c *makross.Context) error {
+		return c.RenderCached("home", "")
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(makross.GET, "/", nil)
+		req.Header.Set(makross.HeaderAcceptEncoding, gzipScheme)
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		assert.Equal(t, gzipScheme, rec.Header().Get(makross.HeaderContentEncoding))
+		assert.Equal(t, []string{makross.HeaderAcceptEncoding}, rec.Header()[makross.HeaderVary])
+
+		// the cached variant isn't compressed again
+		r, err := gzip.NewReader(rec.Body)
+		if assert.Nil(t, err) {
+			body, _ := ioutil.ReadAll(r)
+			assert.Equal(t, "<p>home</p>", string(body))
+		}
+	}
+}
+
+func TestGzipHijack(t *testing.T) {
+	m := makross.New()
+	m.Use(Gzip(), sniff.Sniff())
+	m.Get("/", func(c *makross.Context) error {
+		conn, rw, err := makross.Hijack(c)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		return rw.Flush()
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	req, _ := http.NewRequest(makross.GET, server.URL, nil)
+	req.Header.Set(makross.HeaderAcceptEncoding, gzipScheme)
+	res, err := http.DefaultClient.Do(req)
+	if assert.Nil(t, err) {
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, "hijacked", string(body))
+	}
+}
@@ -0,0 +1,375 @@
+// Package config builds a middleware chain from a declarative configuration, so that services
+// can share the same stack with their own settings:
+//
+//	stack, err := config.Parse([]byte(`{
+//		"logger":     {"format": "${method} ${uri} ${status}\n"},
+//		"recover":    {},
+//		"request_id": {},
+//		"body_limit": {"limit": "2M"},
+//		"cors":       {"allow_origins": ["https://example.com"], "max_age": 600},
+//		"gzip":       {"level": 5}
+//	}`))
+//	if err != nil {
+//		log.Fatal(err) // config: cors.max_age: must not be negative
+//	}
+//	handlers, err := stack.Handlers()
+//	m.Use(handlers...)
+//
+// The middlewares are configured with their own config types and keys. An absent section disables
+// the middleware and an empty one enables it with its default config. YAML and other formats are
+// decoded by the caller into a map and given to FromMap.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/blimit"
+	"github.com/insionng/makross/compress"
+	"github.com/insionng/makross/cors"
+	lbytes "github.com/insionng/makross/libraries/gommon/bytes"
+	"github.com/insionng/makross/logger"
+	"github.com/insionng/makross/recover"
+	"github.com/insionng/makross/requestid"
+	"github.com/valyala/fasttemplate"
+)
+
+// The keys of the middlewares, in the configuration and in Stack.Order.
+const (
+	KeyLogger    = "logger"
+	KeyRecover   = "recover"
+	KeyRequestID = "request_id"
+	KeyBodyLimit = "body_limit"
+	KeyCORS      = "cors"
+	KeyGzip      = "gzip"
+)
+
+type (
+	// Stack is the declarative configuration of a middleware chain.
+	// A nil middleware config disables the middleware.
+	Stack struct {
+		// Order lists the keys of the middlewares, the first one being the outermost.
+		// Every configured middleware must be listed.
+		// Optional. Default value DefaultOrder.
+		Order []string `json:"order"`
+
+		Logger    *logger.LoggerConfig       `json:"logger"`
+		Recover   *recover.RecoverConfig     `json:"recover"`
+		RequestID *requestid.RequestIDConfig `json:"request_id"`
+		BodyLimit *blimit.BodyLimitConfig    `json:"body_limit"`
+		CORS      *cors.CORSConfig           `json:"cors"`
+		Gzip      *compress.GzipConfig       `json:"gzip"`
+	}
+
+	// FieldError describes an invalid field of the configuration.
+	FieldError struct {
+		// Field is the path of the field, e.g. "cors.max_age" or "order[2]".
+		Field   string
+		Message string
+	}
+
+	// Errors lists the invalid fields of a configuration.
+	Errors []*FieldError
+)
+
+// DefaultOrder is the order of the middlewares when Stack.Order is empty: the request ID is set
+// before the request is logged, the panics are recovered inside the logger so that their 500 is
+// logged, and the responses are compressed last.
+var DefaultOrder = []string{KeyRequestID, KeyLogger, KeyRecover, KeyBodyLimit, KeyCORS, KeyGzip}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "config: " + strings.Join(messages, "; ")
+}
+
+func (e *Errors) add(field, format string, args ...interface{}) {
+	*e = append(*e, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Parse decodes and validates the JSON configuration. The omitted fields of a middleware config
+// keep their default values, e.g. compress.DefaultGzipConfig.Level.
+func Parse(data []byte) (*Stack, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	return FromMap(values)
+}
+
+// FromMap decodes and validates the configuration decoded by the caller, e.g. from YAML.
+// The maps may have interface{} keys, as decoded by gopkg.in/yaml.v2.
+func FromMap(values map[string]interface{}) (*Stack, error) {
+	var errs Errors
+	v, _ := normalize("", values, &errs).(map[string]interface{})
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	checkKeys("", v, reflect.TypeOf(Stack{}), &errs)
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+
+	s := &Stack{}
+	// start from the default configs, so that the omitted fields keep their default values
+	for key := range v {
+		switch key {
+		case KeyLogger:
+			c := logger.DefaultLoggerConfig
+			s.Logger = &c
+		case KeyRecover:
+			c := recover.DefaultRecoverConfig
+			s.Recover = &c
+		case KeyRequestID:
+			c := requestid.DefaultRequestIDConfig
+			s.RequestID = &c
+		case KeyBodyLimit:
+			c := blimit.DefaultBodyLimitConfig
+			s.BodyLimit = &c
+		case KeyCORS:
+			c := cors.DefaultCORSConfig
+			// the decoder reuses the slices, which must not alter the defaults
+			c.AllowOrigins = append([]string(nil), c.AllowOrigins...)
+			c.AllowMethods = append([]string(nil), c.AllowMethods...)
+			c.AllowHeaders = append([]string(nil), c.AllowHeaders...)
+			c.ExposeHeaders = append([]string(nil), c.ExposeHeaders...)
+			s.CORS = &c
+		case KeyGzip:
+			c := compress.DefaultGzipConfig
+			s.Gzip = &c
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		if e, ok := err.(*json.UnmarshalTypeError); ok {
+			errs.add(e.Field, "expected %s, got %s", e.Type, e.Value)
+			return nil, errs
+		}
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// normalize converts the maps with interface{} keys to maps with string keys.
+func normalize(path string, v interface{}, errs *Errors) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			key, ok := k.(string)
+			if !ok {
+				errs.add(join(path, fmt.Sprint(k)), "key must be a string")
+				continue
+			}
+			m[key] = normalize(join(path, key), value, errs)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = normalize(join(path, key), value, errs)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = normalize(fmt.Sprintf("%s[%d]", path, i), value, errs)
+		}
+		return s
+	}
+	return v
+}
+
+// checkKeys reports the keys of the map which aren't JSON fields of the struct type,
+// such as the misspelled ones and the functions of the middleware configs.
+func checkKeys(path string, values map[string]interface{}, t reflect.Type, errs *Errors) {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := values[key]
+		ft, ok := fields[key]
+		if !ok {
+			errs.add(join(path, key), "unknown key")
+			continue
+		}
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if m, ok := value.(map[string]interface{}); ok && ft.Kind() == reflect.Struct {
+			checkKeys(join(path, key), m, ft, errs)
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Validate checks the order and the values of the middleware configs.
+func (s *Stack) Validate() error {
+	var errs Errors
+
+	configured := s.configured()
+	if len(s.Order) > 0 {
+		listed := make(map[string]bool, len(s.Order))
+		for i, key := range s.Order {
+			field := fmt.Sprintf("order[%d]", i)
+			switch _, known := configured[key]; {
+			case !known:
+				errs.add(field, "unknown middleware %q", key)
+			case listed[key]:
+				errs.add(field, "duplicate middleware %q", key)
+			case !configured[key]:
+				errs.add(field, "middleware %q is not configured", key)
+			}
+			listed[key] = true
+		}
+		for _, key := range DefaultOrder {
+			if configured[key] && !listed[key] {
+				errs.add("order", "missing configured middleware %q", key)
+			}
+		}
+	}
+
+	if c := s.Logger; c != nil {
+		if _, err := fasttemplate.NewTemplate(c.Format, "${", "}"); err != nil {
+			errs.add("logger.format", "%v", err)
+		}
+	}
+	if c := s.Recover; c != nil {
+		if c.StackSize < 0 {
+			errs.add("recover.stack_size", "must not be negative")
+		}
+	}
+	if c := s.BodyLimit; c != nil {
+		if c.Limit == "" {
+			errs.add("body_limit.limit", "is required")
+		} else if n, err := lbytes.Parse(c.Limit); err != nil || n <= 0 {
+			errs.add("body_limit.limit", "invalid size %q, expected e.g. 512K or 4MB", c.Limit)
+		}
+	}
+	if c := s.CORS; c != nil {
+		for i, origin := range c.AllowOrigins {
+			if origin == "" {
+				errs.add(fmt.Sprintf("cors.allow_origins[%d]", i), "must not be empty")
+			}
+			if origin == "*" && c.AllowCredentials {
+				errs.add(fmt.Sprintf("cors.allow_origins[%d]", i), "the wildcard can't be used with allow_credentials")
+			}
+		}
+		for i, method := range c.AllowMethods {
+			if !isMethod(method) {
+				errs.add(fmt.Sprintf("cors.allow_methods[%d]", i), "unknown method %q", method)
+			}
+		}
+		for i, header := range c.AllowHeaders {
+			if header == "" || strings.ContainsAny(header, " ,") {
+				errs.add(fmt.Sprintf("cors.allow_headers[%d]", i), "invalid header %q", header)
+			}
+		}
+		for i, header := range c.ExposeHeaders {
+			if header == "" || strings.ContainsAny(header, " ,") {
+				errs.add(fmt.Sprintf("cors.expose_headers[%d]", i), "invalid header %q", header)
+			}
+		}
+		if c.MaxAge < 0 {
+			errs.add("cors.max_age", "must not be negative")
+		}
+	}
+	if c := s.Gzip; c != nil {
+		// 0 stands for the default level, see compress.GzipWithConfig
+		if c.Level < -2 || c.Level > 9 {
+			errs.add("gzip.level", "must be between -2 and 9")
+		}
+	}
+	return errs.err()
+}
+
+// configured maps the keys of the middlewares to whether they are configured.
+func (s *Stack) configured() map[string]bool {
+	return map[string]bool{
+		KeyLogger:    s.Logger != nil,
+		KeyRecover:   s.Recover != nil,
+		KeyRequestID: s.RequestID != nil,
+		KeyBodyLimit: s.BodyLimit != nil,
+		KeyCORS:      s.CORS != nil,
+		KeyGzip:      s.Gzip != nil,
+	}
+}
+
+// Handlers validates the configuration and returns the configured middlewares, in order.
+// Fields which can't be decoded, such as logger.LoggerConfig.Output, can be set before.
+func (s *Stack) Handlers() ([]makross.Handler, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	order := s.Order
+	if len(order) == 0 {
+		order = DefaultOrder
+	}
+	var handlers []makross.Handler
+	for _, key := range order {
+		switch {
+		case key == KeyLogger && s.Logger != nil:
+			handlers = append(handlers, logger.LoggerWithConfig(*s.Logger))
+		case key == KeyRecover && s.Recover != nil:
+			handlers = append(handlers, recover.RecoverWithConfig(*s.Recover))
+		case key == KeyRequestID && s.RequestID != nil:
+			handlers = append(handlers, requestid.RequestIDWithConfig(*s.RequestID))
+		case key == KeyBodyLimit && s.BodyLimit != nil:
+			handlers = append(handlers, blimit.BodyLimitWithConfig(*s.BodyLimit))
+		case key == KeyCORS && s.CORS != nil:
+			handlers = append(handlers, cors.CORSWithConfig(*s.CORS))
+		case key == KeyGzip && s.Gzip != nil:
+			handlers = append(handlers, compress.GzipWithConfig(*s.Gzip))
+		}
+	}
+	return handlers, nil
+}
+
+func isMethod(method string) bool {
+	for _, m := range makross.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
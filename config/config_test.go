@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/compress"
+	"github.com/insionng/makross/cors"
+	"github.com/insionng/makross/recover"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"order":      ["recover", "logger", "request_id", "body_limit", "cors", "gzip"],
+		"logger":     {"format": "${method} ${path} ${status}\n"},
+		"recover":    {"stack_size": 1024, "disable_stack_all": true, "disable_print_stack": true},
+		"request_id": {},
+		"body_limit": {"limit": "2M"},
+		"cors": {
+			"allow_origins": ["https://example.com"],
+			"allow_methods": ["GET", "POST"],
+			"allow_headers": ["X-Token"],
+			"allow_credentials": true,
+			"expose_headers": ["X-Total"],
+			"max_age": 600
+		},
+		"gzip": {"level": 5}
+	}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"recover", "logger", "request_id", "body_limit", "cors", "gzip"}, s.Order)
+	assert.Equal(t, "${method} ${path} ${status}\n", s.Logger.Format)
+	assert.Equal(t, 1024, s.Recover.StackSize)
+	assert.True(t, s.Recover.DisableStackAll)
+	assert.True(t, s.Recover.DisablePrintStack)
+	assert.NotNil(t, s.RequestID)
+	assert.NotNil(t, s.RequestID.Generator)
+	assert.Equal(t, "2M", s.BodyLimit.Limit)
+	assert.Equal(t, []string{"https://example.com"}, s.CORS.AllowOrigins)
+	assert.Equal(t, []string{"GET", "POST"}, s.CORS.AllowMethods)
+	assert.Equal(t, []string{"X-Token"}, s.CORS.AllowHeaders)
+	assert.True(t, s.CORS.AllowCredentials)
+	assert.Equal(t, []string{"X-Total"}, s.CORS.ExposeHeaders)
+	assert.Equal(t, 600, s.CORS.MaxAge)
+	assert.Equal(t, 5, s.Gzip.Level)
+}
+
+func TestParseDefaults(t *testing.T) {
+	s, err := Parse([]byte(`{"recover": {}, "cors": {"max_age": 60}, "gzip": {}}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	// the omitted fields keep their default values
+	assert.Equal(t, recover.DefaultRecoverConfig.StackSize, s.Recover.StackSize)
+	assert.Equal(t, cors.DefaultCORSConfig.AllowOrigins, s.CORS.AllowOrigins)
+	assert.Equal(t, cors.DefaultCORSConfig.AllowMethods, s.CORS.AllowMethods)
+	assert.Equal(t, compress.DefaultGzipConfig.Level, s.Gzip.Level)
+	// the absent sections disable the middlewares
+	assert.Nil(t, s.Logger)
+	assert.Nil(t, s.RequestID)
+	assert.Nil(t, s.BodyLimit)
+
+	handlers, err := s.Handlers()
+	assert.Nil(t, err)
+	assert.Len(t, handlers, 3)
+
+	// the defaults aren't altered
+	_, err = Parse([]byte(`{"cors": {"allow_methods": ["PUT"]}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, makross.GET, cors.DefaultCORSConfig.AllowMethods[0])
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		errors []string
+	}{
+		{`{"gzip": {"levle": 5}}`, []string{"gzip.levle: unknown key"}},
+		{`{"gzipp": {}}`, []string{"gzipp: unknown key"}},
+		{`{"request_id": {"generator": "uuid"}}`, []string{"request_id.generator: unknown key"}},
+		{`{"cors": {"max_age": "10m"}}`, []string{"cors.max_age: expected int, got string"}},
+		{`{"gzip": {"level": 10}}`, []string{"gzip.level: must be between -2 and 9"}},
+		{`{"recover": {"stack_size": -1}}`, []string{"recover.stack_size: must not be negative"}},
+		{`{"body_limit": {}}`, []string{"body_limit.limit: is required"}},
+		{`{"body_limit": {"limit": "2 lots"}}`, []string{`body_limit.limit: invalid size "2 lots", expected e.g. 512K or 4MB`}},
+		{`{"logger": {"format": "${status"}}`, []string{"logger.format: "}},
+		{`{"cors": {"allow_origins": ["*", ""], "allow_credentials": true}}`, []string{
+			"cors.allow_origins[0]: the wildcard can't be used with allow_credentials",
+			"cors.allow_origins[1]: must not be empty",
+		}},
+		{`{"cors": {"allow_methods": ["GET", "FETCH"], "allow_headers": ["X-A, X-B"], "expose_headers": [""], "max_age": -1}}`, []string{
+			`cors.allow_methods[1]: unknown method "FETCH"`,
+			`cors.allow_headers[0]: invalid header "X-A, X-B"`,
+			`cors.expose_headers[0]: invalid header ""`,
+			"cors.max_age: must not be negative",
+		}},
+		{`{"order": ["gzip", "gzip", "csrf", "logger"], "gzip": {}, "cors": {}}`, []string{
+			`order[1]: duplicate middleware "gzip"`,
+			`order[2]: unknown middleware "csrf"`,
+			`order[3]: middleware "logger" is not configured`,
+			`order: missing configured middleware "cors"`,
+		}},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.config))
+		var errs Errors
+		if !assert.True(t, errors.As(err, &errs), test.config) {
+			continue
+		}
+		if assert.Len(t, errs, len(test.errors), test.config) {
+			for i, e := range errs {
+				assert.True(t, strings.HasPrefix(e.Error(), test.errors[i]), e.Error())
+			}
+		}
+		assert.True(t, strings.HasPrefix(err.Error(), "config: "), err.Error())
+	}
+
+	_, err := Parse([]byte(`{"gzip": `))
+	assert.NotNil(t, err)
+}
+
+func TestFromMap(t *testing.T) {
+	// as decoded by gopkg.in/yaml.v2
+	s, err := FromMap(map[string]interface{}{
+		"cors": map[interface{}]interface{}{
+			"allow_origins": []interface{}{"https://example.com"},
+			"max_age":       300,
+		},
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"https://example.com"}, s.CORS.AllowOrigins)
+		assert.Equal(t, 300, s.CORS.MaxAge)
+	}
+
+	_, err = FromMap(map[string]interface{}{
+		"cors": map[interface{}]interface{}{1: true},
+	})
+	assert.Equal(t, "config: cors.1: key must be a string", err.Error())
+}
+
+func TestHandlers(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"logger":     {"format": "${method} ${path} ${status}\n"},
+		"recover":    {},
+		"request_id": {},
+		"body_limit": {"limit": "1K"},
+		"cors":       {"allow_origins": ["https://example.com"], "max_age": 600},
+		"gzip":       {"level": 9}
+	}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	out := new(bytes.Buffer)
+	s.Logger.Output = out
+	handlers, err := s.Handlers()
+	if !assert.Nil(t, err) || !assert.Len(t, handlers, 6) {
+		return
+	}
+
+	m := makross.New()
+	m.Use(handlers...)
+	m.Get("/", func(c *makross.Context) error {
+		return c.String(strings.Repeat("makross ", 100))
+	})
+	m.Get("/panic", func(c *makross.Context) error {
+		panic("boom")
+	})
+	m.Post("/", func(c *makross.Context) error {
+		_, err := ioutil.ReadAll(c.Request.Body)
+		return err
+	})
+
+	req := httptest.NewRequest(makross.GET, "/", nil)
+	req.Header.Set(makross.HeaderAcceptEncoding, "gzip")
+	req.Header.Set(makross.HeaderOrigin, "https://example.com")
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, "gzip", res.Header().Get(makross.HeaderContentEncoding))
+	assert.Equal(t, "https://example.com", res.Header().Get(makross.HeaderAccessControlAllowOrigin))
+	assert.Len(t, res.Header().Get(makross.HeaderXRequestID), 32)
+	assert.Equal(t, "GET / 200\n", out.String())
+
+	// preflight
+	req = httptest.NewRequest(makross.OPTIONS, "/", nil)
+	req.Header.Set(makross.HeaderOrigin, "https://example.com")
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, "600", res.Header().Get(makross.HeaderAccessControlMaxAge))
+
+	// the panic is recovered inside the logger
+	out.Reset()
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/panic", nil))
+	assert.Equal(t, makross.StatusInternalServerError, res.Code)
+	assert.Equal(t, "GET /panic 500\n", out.String())
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.POST, "/", strings.NewReader(strings.Repeat("x", 2048))))
+	assert.Equal(t, makross.StatusRequestEntityTooLarge, res.Code)
+}
+
+func TestHandlersValidate(t *testing.T) {
+	s := &Stack{Gzip: &compress.GzipConfig{Level: 12}}
+	_, err := s.Handlers()
+	assert.Equal(t, "config: gzip.level: must be between -2 and 9", err.Error())
+}
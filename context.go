@@ -9,14 +9,15 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"math"
 	"mime"
 	"mime/multipart"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -39,13 +40,33 @@ type (
 		Flash      *Flash
 		Session    Sessioner
 		makross    *Makross
+		route      *Route                 // the route matching the current request
 		pnames     []string               // list of route parameter names
 		pvalues    []string               // list of parameter values corresponding to pnames
+		pbuf       [8]string              // the storage of pvalues for the routes with up to 8 parameters
 		data       map[string]interface{} // data items managed by Get and Set
+		spare      map[string]interface{} // the cleared data map of the previous request, reused by Set
+		escaped    bool                   // the data map was returned by GetStore, so it isn't reused
 		FiltersMap *sync.Map              //map[string][]byte      // Not Global Filters, only in Context
 		index      int                    // the index of the currently executing handler in handlers
 		handlers   []Handler              // the handlers associated with the current route
+		chain      []Handler              // buffer of the handlers prepended with the middlewares
 		writer     DataWriter
+		filter     RouteFilter // the routes served by the listener of the request, see StartMultiple
+		logger     Logger      // the logger of the request, see SetLogger
+		routed     bool        // whether the request has been routed
+		preParams  []string    // name and value pairs set with SetParam before the request was routed
+		requestURI string      // the request URI as received, see RawPath
+		deadline   time.Time   // the write deadline, see SetWriteDeadline
+		timings    []Timing    // the sections measured with StartTimer, see ServerTiming
+		timingMu   sync.Mutex
+		sendTiming bool   // whether the Server-Timing header is sent, see AddServerTiming
+		links      []link // the links set with SetLink
+		tempBytes  int64  // the size of the temporary files of the multipart form, see MultipartTempBytes
+
+		deferred      []deferredFunc // the functions registered with OnSuccess and OnFailure
+		fp            fingerprint    // the cached fingerprint, see Fingerprint
+		errorReported bool
 	}
 
 	// Localer reprents a localization interface.
@@ -63,9 +84,38 @@ type (
 func (c *Context) Reset(w http.ResponseWriter, r *http.Request) {
 	c.Response.reset(w)
 	c.Request = r
+	c.requestURI = ""
+	c.deadline = time.Time{}
+	c.timings = c.timings[:0]
+	c.sendTiming = false
+	c.links = c.links[:0]
+	clear(c.deferred)
+	c.deferred = c.deferred[:0]
+	if r != nil {
+		c.requestURI = r.RequestURI
+	}
 	c.ktx = ktx.Background()
+	c.route = nil
+	c.pnames = nil
+	c.routed = false
+	c.preParams = c.preParams[:0]
+	c.filter = nil
+	c.logger = nil
+	c.errorReported = false
+	c.fp = fingerprint{}
+	if c.data != nil && !c.escaped {
+		// reused by the next request, see newData
+		clear(c.data)
+		c.spare = c.data
+	}
 	c.data = nil
-	c.FiltersMap = new(sync.Map)
+	c.escaped = false
+	if c.FiltersMap == nil {
+		c.FiltersMap = new(sync.Map)
+	} else {
+		// Clear allocates
+		c.FiltersMap.Range(c.deleteFilter)
+	}
 	c.index = -1
 	c.writer = DefaultDataWriter
 }
@@ -104,6 +154,12 @@ func (c *Context) Close() error {
 	return c.makross.Server.Close()
 }
 
+// Route returns the route matching the current request.
+// Nil is returned if no route matches the request.
+func (c *Context) Route() *Route {
+	return c.route
+}
+
 func (c *Context) Kontext() ktx.Context {
 	return c.ktx
 }
@@ -112,6 +168,42 @@ func (c *Context) SetKontext(ktx ktx.Context) {
 	c.ktx = ktx
 }
 
+// WithValue stores a value in the standard context of the request, so that it can be read
+// by code which only gets the context returned by Kontext.
+func (c *Context) WithValue(key, value interface{}) {
+	c.ktx = ktx.WithValue(c.ktx, key, value)
+}
+
+// SetStatus sets the status code of the response without writing it, so that the next write uses it:
+// Response.Write, or a helper such as JSON or String called without status. An explicit status argument
+// of a helper takes precedence.
+//
+//	c.SetStatus(makross.StatusCreated)
+//	return c.JSON(user) // 201 Created
+func (c *Context) SetStatus(code int) *Context {
+	c.Response.SetStatus(code)
+	return c
+}
+
+// WithTimeout returns a context of the request which is canceled after d, so that a handler can
+// bound its own waits, e.g. in long polling. The context is also canceled when the client goes away,
+// and d is capped by the MaxHandlerTimeout of the server config.
+//
+//	ctx, cancel := c.WithTimeout(30 * time.Second)
+//	defer cancel()
+//	select {
+//	case msg := <-messages:
+//		return c.JSON(msg)
+//	case <-ctx.Done():
+//		return c.NoContent(makross.StatusNoContent)
+//	}
+func (c *Context) WithTimeout(d time.Duration) (ktx.Context, ktx.CancelFunc) {
+	if max := c.makross.handlerTimeout; max > 0 && d > max {
+		d = max
+	}
+	return ktx.WithTimeout(c.Request.Context(), d)
+}
+
 func (c *Context) Handler() Handler {
 	return c.handlers[c.index]
 }
@@ -120,6 +212,12 @@ func (c *Context) SetHandler(h Handler) {
 	c.handlers[c.index] = h
 }
 
+// OnBeforeResponse registers a function which is called just before the response header is written.
+// See Response.Before.
+func (c *Context) OnBeforeResponse(fn func()) {
+	c.Response.Before(fn)
+}
+
 func (c *Context) NewCookie() *http.Cookie {
 	return new(http.Cookie)
 }
@@ -128,6 +226,11 @@ func (c *Context) GetCookie(name string) (*http.Cookie, error) {
 	return c.Request.Cookie(name)
 }
 
+// SetCookie adds a Set-Cookie header to the response, keeping the cookies set before, e.g. by
+// the middlewares. It is the way to set cookies: it goes through `Response#Header()`, whereas the
+// header map of a wrapping writer may be a copy. Like any header, a cookie must be set before the
+// header is sent: after the handler, a middleware can set it once the response is buffered, e.g. by
+// the buffer middleware, or else from a function registered with `Response#Before()`.
 func (c *Context) SetCookie(cookie *http.Cookie) {
 	http.SetCookie(c.Response, cookie)
 }
@@ -156,16 +259,34 @@ func (c *Context) IsWebSocket() bool {
 }
 
 // RealIP implements `Context#RealIP` function.
+// The client IP is taken from the Forwarded, X-Forwarded-For or X-Real-IP headers, in that order
+// unless Makross.LegacyForwardedFirst is set, and falls back to the remote address of the request.
+// Once trusted proxies are set, the headers are only honored from them, see Makross.SetTrustedProxies.
 func (c *Context) RealIP() string {
-	ra := c.Request.RemoteAddr
-	if ip := c.Request.Header.Get(HeaderXForwardedFor); len(ip) > 0 {
-		ra = ip
-	} else if ip := c.Request.Header.Get(HeaderXRealIP); len(ip) > 0 {
-		ra = ip
-	} else {
-		ra, _, _ = net.SplitHostPort(ra)
+	return c.makross.ClientIP(c.Request)
+}
+
+// Host returns the host requested by the client.
+// The host is taken from the Forwarded or X-Forwarded-Host headers, following the same precedence
+// as RealIP, and from the trusted proxies only once they are set, and falls back to the Host of the request.
+func (c *Context) Host() string {
+	if !c.trustsForwarded() {
+		return c.Request.Host
+	}
+	var fhost string
+	if fwd, ok := c.forwarded(); ok {
+		fhost = fwd.Host
+	}
+	if len(fhost) > 0 && c.preferForwarded() {
+		return fhost
 	}
-	return ra
+	if host := c.Request.Header.Get(HeaderXForwardedHost); host != "" {
+		return host
+	}
+	if len(fhost) > 0 {
+		return fhost
+	}
+	return c.Request.Host
 }
 
 // Param returns the named parameter value that is found in the URL path matching the current route.
@@ -190,24 +311,47 @@ func (c *Context) Get(name string) interface{} {
 // Set stores the named data item in the context so that it can be retrieved later.
 func (c *Context) Set(name string, value interface{}) {
 	if c.data == nil {
-		c.data = make(map[string]interface{})
+		c.data = c.newData(1)
 	}
 	c.data[name] = value
 }
 
 func (c *Context) SetStore(data map[string]interface{}) {
 	if c.data == nil {
-		c.data = make(map[string]interface{})
+		c.data = c.newData(len(data))
 	}
 	for k, v := range data {
 		c.data[k] = v
 	}
 }
 
+// GetStore returns the data items set with Set and SetStore, or nil if there are none.
+// The map may be retained after the request: the context then allocates a new one for
+// the next request instead of reusing it.
 func (c *Context) GetStore() map[string]interface{} {
+	c.escaped = c.data != nil
 	return c.data
 }
 
+func (c *Context) deleteFilter(key, _ interface{}) bool {
+	c.FiltersMap.Delete(key)
+	return true
+}
+
+// newData returns the map of the data items, the one of the previous request of the context if any,
+// or a new one sized for n items, and at least the DataSizeHint of the Makross.
+func (c *Context) newData(n int) map[string]interface{} {
+	if c.spare != nil {
+		data := c.spare
+		c.spare = nil
+		return data
+	}
+	if n < c.makross.DataSizeHint {
+		n = c.makross.DataSizeHint
+	}
+	return make(map[string]interface{}, n)
+}
+
 func (c *Context) Pull(key string) interface{} {
 	return c.makross.data[key]
 }
@@ -236,6 +380,212 @@ func (c *Context) Bind(i interface{}) error {
 	return c.makross.binder.Bind(i, c)
 }
 
+// BindQuery binds the query parameters to the fields of the struct pointed by i, by their "query" tag,
+// whatever the method of the request. A value which can't be converted to its field is reported by
+// a *BindError, wrapped in a "400 - Bad Request" HTTPError.
+func (c *Context) BindQuery(i interface{}) error {
+	return c.bindValues(i, c.QueryParams(), BindSourceQuery)
+}
+
+// BindParams binds the route parameters to the fields of the struct pointed by i, by their "param" tag.
+// See `Context#BindQuery()`.
+func (c *Context) BindParams(i interface{}) error {
+	params := make(map[string][]string, len(c.pnames))
+	for i, name := range c.pnames {
+		params[name] = []string{c.pvalues[i]}
+	}
+	return c.bindValues(i, params, BindSourceParam)
+}
+
+// BindHeaders binds the request headers to the fields of the struct pointed by i, by their "header" tag,
+// e.g. `header:"X-Page-Size"`, compared case-insensitively. See `Context#BindQuery()`.
+func (c *Context) BindHeaders(i interface{}) error {
+	headers := make(map[string][]string, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		headers[strings.ToLower(name)] = values
+	}
+	return c.bindValues(i, headers, BindSourceHeader)
+}
+
+func (c *Context) bindValues(i interface{}, values map[string][]string, source string) error {
+	if err := new(DefaultBinder).bindData(i, values, source); err != nil {
+		return badRequest(err.Error(), err)
+	}
+	return nil
+}
+
+// Path returns the path of the requested URL.
+// In a NotFound handler, it is the path for which no route could be found.
+func (c *Context) Path() string {
+	return c.Request.URL.Path
+}
+
+// Negotiate returns the offered media type that best matches the Accept header of the request.
+// Offers are tried in order, so the first one is preferred on equal quality and returned
+// when the request has no Accept header or none of the offers is acceptable.
+func (c *Context) Negotiate(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if offer := c.Accepts(offers...); offer != "" {
+		return offer
+	}
+	return offers[0]
+}
+
+// Accepts returns the offered media type that best matches the Accept header of the request,
+// or an empty string if none of the offers is acceptable. Offers are tried in order, so the first
+// one is preferred on equal quality and returned when the request has no Accept header.
+// Media ranges with parameters, such as "text/csv;header=present", only match offers with the same parameters.
+func (c *Context) Accepts(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	var ranges []mediaRange
+	for _, accept := range strings.Split(strings.Join(c.Request.Header[HeaderAccept], ","), ",") {
+		if r, ok := parseMediaRange(accept); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	// the quality of an offer is given by the most specific media range matching it
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, spec := 0.0, -1
+		for _, r := range ranges {
+			if s := r.match(offer); s > spec {
+				q, spec = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptsExplicitly reports whether the Accept header of the request lists the media type, rather than
+// accepting it through a wildcard.
+func (c *Context) acceptsExplicitly(mediaType string) bool {
+	for _, accept := range strings.Split(strings.Join(c.Request.Header[HeaderAccept], ","), ",") {
+		if r, ok := parseMediaRange(accept); ok && r.q > 0 && r.match(mediaType) >= 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// APIVersionKey is the key of the context data holding the API version of the request, see APIVersion.
+const APIVersionKey = "api_version"
+
+// APIVersion returns the API version requested by the client, e.g. "2" for
+// "Accept: application/vnd.myapp.v2+json", as parsed by the apiversion middleware.
+// It returns "" if the version wasn't parsed.
+func (c *Context) APIVersion() string {
+	v, _ := c.Get(APIVersionKey).(string)
+	return v
+}
+
+// TenantKey is the key of the context data holding the tenant of the request, see Tenant.
+const TenantKey = "tenant"
+
+// Tenant returns the tenant of the request, e.g. "acme" for "acme.app.com", as resolved by the tenant
+// middleware. It returns "" if the tenant wasn't resolved.
+func (c *Context) Tenant() string {
+	t, _ := c.Get(TenantKey).(string)
+	return t
+}
+
+// MatchMediaType reports whether the media type, e.g. the Content-Type of a request, matches the pattern.
+// The pattern may be a wildcard such as "*/*" or "application/*", and the parameters of the pattern
+// must be present in the media type, e.g. "text/plain;charset=utf-8" matches "text/plain" but not the reverse.
+func MatchMediaType(pattern, mediaType string) bool {
+	r, ok := parseMediaRange(pattern)
+	return ok && r.match(mediaType) >= 0
+}
+
+// mediaRange is a media range of an Accept header.
+type mediaRange struct {
+	mediaType string
+	params    map[string]string
+	q         float64
+}
+
+// parseMediaRange parses a single media range of an Accept header.
+// The parameters following the quality are accept extensions, they are ignored.
+func parseMediaRange(accept string) (r mediaRange, ok bool) {
+	parts := strings.Split(accept, ";")
+	r.mediaType = strings.ToLower(strings.TrimSpace(parts[0]))
+	if r.mediaType == "" {
+		return r, false
+	}
+	r.q = 1
+	for _, param := range parts[1:] {
+		name, value := splitMediaParam(param)
+		if name == "q" {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return r, false
+			}
+			r.q = v
+			break
+		}
+		if name != "" {
+			if r.params == nil {
+				r.params = make(map[string]string)
+			}
+			r.params[name] = value
+		}
+	}
+	return r, true
+}
+
+// splitMediaParam splits a media type parameter into its lower case name and unquoted value.
+func splitMediaParam(param string) (name, value string) {
+	i := strings.IndexByte(param, '=')
+	if i < 0 {
+		return "", ""
+	}
+	name = strings.ToLower(strings.TrimSpace(param[:i]))
+	value = strings.Trim(strings.TrimSpace(param[i+1:]), `"`)
+	return name, value
+}
+
+// match returns how specifically the media range matches the offer:
+// 0 for */*, 1 for type/*, 2 for an exact match, 3 for an exact match with parameters,
+// or -1 if it doesn't match.
+func (r mediaRange) match(offer string) int {
+	parts := strings.Split(offer, ";")
+	offerType := strings.ToLower(strings.TrimSpace(parts[0]))
+	for name, value := range r.params {
+		found := false
+		for _, param := range parts[1:] {
+			if n, v := splitMediaParam(param); n == name && strings.EqualFold(v, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return -1
+		}
+	}
+	switch {
+	case r.mediaType == "*/*" || r.mediaType == "*":
+		return 0
+	case r.mediaType == offerType:
+		if len(r.params) > 0 {
+			return 3
+		}
+		return 2
+	case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(offerType, r.mediaType[:len(r.mediaType)-1]):
+		return 1
+	}
+	return -1
+}
+
 func (c *Context) UserAgent() string {
 	return c.Request.UserAgent()
 }
@@ -244,18 +594,30 @@ func (c *Context) RequestURI() string {
 	return c.Request.RequestURI
 }
 
+// ExpectsContinue reports whether the client sent "Expect: 100-continue" and waits for the
+// "100 Continue" interim response before sending the request body. The server sends it on the first
+// read of the body, so a handler or a middleware responding before reading the body, such as an
+// authentication middleware or BodyLimit refusing a too large Content-Length, rejects the upload
+// without it being transmitted. Such middlewares must be registered before the ones reading the body,
+// e.g. the ones parsing a form.
+func (c *Context) ExpectsContinue() bool {
+	return c.Request.ProtoAtLeast(1, 1) && strings.EqualFold(c.Request.Header.Get(HeaderExpect), "100-continue")
+}
+
 func (c *Context) RequestBody() io.Reader {
 	rb, _ := c.Request.GetBody()
 	return rb
 }
 
 func (c *Context) MultipartForm() (*multipart.Form, error) {
-	err := c.Request.ParseMultipartForm(defaultMemory)
+	err := c.parseMultipartForm()
 	return c.Request.MultipartForm, err
 }
 
+// QueryString returns the query string as received, without the "?", even if the pre handlers
+// rewrote the request URL. See `Context#RawQuery()`.
 func (c *Context) QueryString() string {
-	return c.Request.URL.RawQuery
+	return c.RawQuery()
 }
 
 func (c *Context) QueryParam(name string) string {
@@ -266,18 +628,116 @@ func (c *Context) QueryParams() url.Values {
 	return c.Request.URL.Query()
 }
 
+// QueryParam is a parameter of the query string, see `Context#QueryOrdered()`.
+type QueryParam struct {
+	Key, Value string
+}
+
+// RawQuery returns the query string as sent by the client, without the "?", e.g. to verify
+// the signature of a request: the bytes are the ones received, not re-encoded nor reordered as by
+// URL.Query(). Like RawPath, it isn't affected by the pre handlers rewriting the request URL.
+func (c *Context) RawQuery() string {
+	if _, query, ok := c.requestTarget(); ok {
+		return query
+	}
+	return c.Request.URL.RawQuery
+}
+
+// RawPath returns the path of the request as sent by the client, still escaped, e.g. to verify
+// the signature of a request. It isn't affected by the pre handlers rewriting the request path, such
+// as StripPrefix or the slash middlewares, and falls back to the escaped path of the request URL
+// if the request wasn't received by a server, e.g. in the tests.
+func (c *Context) RawPath() string {
+	if path, _, ok := c.requestTarget(); ok {
+		return path
+	}
+	return c.Request.URL.EscapedPath()
+}
+
+// requestTarget splits the request URI received, in origin or absolute form, into its path and query.
+func (c *Context) requestTarget() (path, query string, ok bool) {
+	uri := c.requestURI
+	if uri == "" {
+		return "", "", false
+	}
+	if uri[0] != '/' {
+		// absolute form, sent to the proxies
+		if i := strings.Index(uri, "://"); i >= 0 {
+			uri = uri[i+3:]
+			if i = strings.IndexAny(uri, "/?"); i >= 0 {
+				uri = uri[i:]
+			} else {
+				uri = ""
+			}
+		}
+	}
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		return uri[:i], uri[i+1:], true
+	}
+	return uri, "", true
+}
+
+// QueryOrdered returns the parameters of the query string in order, with the duplicate keys,
+// which url.Values loses. The keys and values are unescaped, or left as they are if they can't be.
+// Anything after a "#" is ignored, as the fragment isn't part of the query.
+func (c *Context) QueryOrdered() []QueryParam {
+	query := c.Request.URL.RawQuery
+	if i := strings.IndexByte(query, '#'); i >= 0 {
+		query = query[:i]
+	}
+	var params []QueryParam
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		var p QueryParam
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			p.Key, p.Value = pair[:i], pair[i+1:]
+		} else {
+			p.Key = pair
+		}
+		if k, err := url.QueryUnescape(p.Key); err == nil {
+			p.Key = k
+		}
+		if v, err := url.QueryUnescape(p.Value); err == nil {
+			p.Value = v
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
 func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
-	_, fh, err := c.Request.FormFile(name)
+	c.parseMultipartForm()
+	f, fh, err := c.Request.FormFile(name)
+	if f != nil {
+		f.Close()
+	}
 	return fh, err
 }
 
+// FormFiles returns the headers of all the files uploaded under the form field, in order,
+// or http.ErrMissingFile if there are none. See `Context#SaveUploadedFiles()`.
+func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files, nil
+}
+
 func (c *Context) FormValue(name string) string {
+	c.parseMultipartForm()
 	return c.Request.FormValue(name)
 }
 
 func (c *Context) FormParams() (url.Values, error) {
 	if strings.HasPrefix(c.Request.Header.Get(HeaderContentType), MIMEMultipartForm) {
-		if err := c.Request.ParseMultipartForm(defaultMemory); err != nil {
+		if err := c.parseMultipartForm(); err != nil {
 			return nil, err
 		}
 	} else {
@@ -306,7 +766,7 @@ func (c *Context) Query(name string, defaultValue ...string) string {
 // If key is not present, it returns the specified default value or an empty string.
 func (c *Context) Form(key string, defaultValue ...string) string {
 	r := c.Request
-	r.ParseMultipartForm(32 << 20)
+	c.parseMultipartForm()
 	if vs := r.Form[key]; len(vs) > 0 {
 		return vs[0]
 	}
@@ -317,11 +777,83 @@ func (c *Context) Form(key string, defaultValue ...string) string {
 	return ""
 }
 
+// FormInt returns the form value of the key, see Form, as an int, or the default value, if any, or 0 when
+// the key is absent or empty. A "400 - Bad Request" HTTPError is returned if the value isn't an integer.
+func (c *Context) FormInt(key string, defaultValue ...int) (int, error) {
+	v, err := c.formValue(key)
+	if v == "" || err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0], err
+		}
+		return 0, err
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, invalidFormValue(key, v, "an integer")
+	}
+	return i, nil
+}
+
+// FormBool returns the form value of the key, see Form, as a bool, or the default value, if any, or false when
+// the key is absent or empty. A "400 - Bad Request" HTTPError is returned if the value isn't a boolean, such as
+// "true", "false", "1", "0", "on" or "off".
+func (c *Context) FormBool(key string, defaultValue ...bool) (bool, error) {
+	v, err := c.formValue(key)
+	if v == "" || err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0], err
+		}
+		return false, err
+	}
+	switch strings.ToLower(v) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, invalidFormValue(key, v, "a boolean")
+	}
+	return b, nil
+}
+
+// FormFloat64 returns the form value of the key, see Form, as a float64, or the default value, if any, or 0 when
+// the key is absent or empty. A "400 - Bad Request" HTTPError is returned if the value isn't a number.
+func (c *Context) FormFloat64(key string, defaultValue ...float64) (float64, error) {
+	v, err := c.formValue(key)
+	if v == "" || err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0], err
+		}
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, invalidFormValue(key, v, "a number")
+	}
+	return f, nil
+}
+
+// formValue returns the first form value of the key, parsing the form once per request.
+func (c *Context) formValue(key string) (string, error) {
+	if c.Request.Form == nil || c.Request.MultipartForm == nil && strings.HasPrefix(c.Request.Header.Get(HeaderContentType), MIMEMultipartForm) {
+		if _, err := c.FormParams(); err != nil {
+			return "", NewHTTPError(StatusBadRequest, err.Error())
+		}
+	}
+	return c.Request.Form.Get(key), nil
+}
+
+func invalidFormValue(key, value, expected string) error {
+	return NewHTTPError(StatusBadRequest, fmt.Sprintf("invalid %s %q, expected %s", key, value, expected))
+}
+
 // PostForm returns the first value for the named component from POST and PUT body parameters.
 // If key is not present, it returns the specified default value or an empty string.
 func (c *Context) PostForm(key string, defaultValue ...string) string {
 	r := c.Request
-	r.ParseMultipartForm(32 << 20)
+	c.parseMultipartForm()
 	if vs := r.PostForm[key]; len(vs) > 0 {
 		return vs[0]
 	}
@@ -346,6 +878,32 @@ func (c *Context) Next() error {
 	return nil
 }
 
+// NextCollect calls the rest of the handlers associated with the current route, like Next, but doesn't
+// stop at the first error: it returns the errors of all the handlers, in order, or nil if none failed.
+// It is meant for the pipelines of independent handlers, such as validators each checking a part of
+// the request, so that all their errors are reported at once:
+//
+//	m.Post("/orders", func(c *makross.Context) error {
+//		if errs := c.NextCollect(); len(errs) > 0 {
+//			return errors.Join(errs...)
+//		}
+//		return createOrder(c)
+//	}, validateItems, validateAddress)
+//
+// Under collect semantics, a handler runs even when a previous one has failed, so it must not rely on
+// the work of the previous handlers nor write the response, and must not call Next, which would run
+// the following handlers with the short-circuit semantics. A handler calling Abort stops the collection.
+func (c *Context) NextCollect() []error {
+	var errs []error
+	c.index++
+	for n := len(c.handlers); c.index < n; c.index++ {
+		if err := c.handlers[c.index](c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
 // Abort skips the rest of the handlers associated with the current route.
 // Abort is normally used when a handler handles the request normally and wants to skip the rest of the handlers.
 // If a handler wants to indicate an error condition, it should simply return the error without calling Abort.
@@ -354,13 +912,35 @@ func (c *Context) Abort() error {
 	return nil
 }
 
+// Stop responds with the status and the data, written as JSON, or as XML if the Accept header of the
+// request prefers it, and skips the rest of the handlers, like Abort. A nil data gets an empty response.
+// Unlike returning an error, it doesn't go through HandleError: it is meant for the middlewares
+// answering the request themselves, such as a cache hit or a refused authentication with a custom body:
+//
+//	func auth(c *makross.Context) error {
+//		user, err := users.FromToken(c.Request.Header.Get(makross.HeaderAuthorization))
+//		if err != nil {
+//			return c.Stop(makross.StatusUnauthorized, map[string]string{"error": "invalid token"})
+//		}
+//		c.Set("user", user)
+//		return c.Next()
+//	}
+//
+// It returns nil, so that Next halts cleanly, or the error writing the response.
+func (c *Context) Stop(status int, data interface{}) error {
+	c.Abort()
+	if data == nil {
+		return c.NoContent(status)
+	}
+	return c.negotiated(data, status)
+}
+
 // Break 中断继续执行后续动作，返回指定状态及错误，不设置错误亦可.
 func (c *Context) Break(status int, err ...error) error {
-	var e error
-	if len(err) > 0 {
-		e = err[0]
+	e := NewHTTPError(status)
+	if len(err) > 0 && err[0] != nil {
+		e.Message = err[0].Error()
 	}
-	c.Response.WriteHeader(status)
 	c.HandleError(e)
 	return c.Abort()
 }
@@ -386,11 +966,37 @@ func (c *Context) Read(data interface{}) error {
 	if c.Request.Method != "GET" {
 		t := getContentType(c.Request)
 		if reader, ok := DataReaders[t]; ok {
-			return reader.Read(c.Request, data)
+			return c.read(reader, data)
 		}
 	}
 
-	return DefaultFormDataReader.Read(c.Request, data)
+	return c.read(DefaultFormDataReader, data)
+}
+
+// read reads the request data into data with the reader, parsing the multipart form for
+// the FormDataReader, which would otherwise parse it without the limits of the Makross.
+func (c *Context) read(reader DataReader, data interface{}) error {
+	if _, ok := reader.(*FormDataReader); ok && strings.HasPrefix(c.Request.Header.Get(HeaderContentType), MIMEMultipartForm) {
+		c.parseMultipartForm()
+	}
+	return reader.Read(c.Request, data)
+}
+
+// BindWith reads the request data into data with the reader, whatever the Content-Type header
+// of the request, e.g. for the JSON body of a webhook sent as "text/plain":
+//
+//	err := c.BindWith(&event, &makross.JSONDataReader{})
+//
+// An error of the reader is wrapped in a "400 - Bad Request" HTTPError.
+func (c *Context) BindWith(data interface{}, reader DataReader) error {
+	err := c.read(reader, data)
+	if err == nil {
+		return nil
+	}
+	if he, ok := err.(*HTTPError); ok {
+		return he
+	}
+	return badRequest(err.Error(), err)
 }
 
 // Write writes the given data of arbitrary type to the response.
@@ -416,12 +1022,18 @@ func (c *Context) Redirect(url string, status ...int) error {
 	return nil
 }
 
+// Render renders the named template with the registered renderer and writes it to the response.
+// If the response has already been written, Render does nothing and returns ErrResponseAlreadyCommitted.
+// The same applies to String, JSON, XML, Blob and NoContent.
 func (c *Context) Render(name string, status ...int) (err error) {
 	var code int
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
 	}
 	if c.makross.renderer == nil {
 		return ErrRendererNotRegistered
@@ -437,12 +1049,112 @@ func (c *Context) Render(name string, status ...int) (err error) {
 	return
 }
 
+// RenderString renders the named template to a string, e.g. the body of an email, without touching the response.
+// The data is passed to the template like with RenderStream, but the context store is restored afterwards.
+func (c *Context) RenderString(name string, data interface{}) (string, error) {
+	if c.makross.renderer == nil {
+		return "", ErrRendererNotRegistered
+	}
+	saved := c.data
+	c.data = make(map[string]interface{}, len(saved)+1)
+	for k, v := range saved {
+		c.data[k] = v
+	}
+	c.setRenderData(data)
+	defer func() {
+		c.data = saved
+	}()
+	buf := new(bytes.Buffer)
+	if err := c.makross.renderer.Render(buf, name, c); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// TemplateData returns the data the renderers pass to the templates: the context store, which holds
+// the values set by the middlewares, such as the CSRF token, with the data of the render merged into it,
+// and the well-known accessors "Ctx", the context, and "Flash", the flash messages of the session.
+//
+// On key conflicts, the data of the render wins over the store values, which win over the accessors:
+// a store value named "Ctx" hides the context. The returned map is a copy of the store.
+func (c *Context) TemplateData() map[string]interface{} {
+	data := make(map[string]interface{}, len(c.data)+2)
+	data["Ctx"] = c
+	if c.Flash != nil {
+		data["Flash"] = c.Flash
+	}
+	for k, v := range c.data {
+		data[k] = v
+	}
+	return data
+}
+
+// setRenderData merges the data into the context store when it's a map[string]interface{},
+// otherwise it stores it under the RenderDataKey key.
+func (c *Context) setRenderData(data interface{}) {
+	switch data := data.(type) {
+	case nil:
+	case map[string]interface{}:
+		c.SetStore(data)
+	default:
+		c.Set(RenderDataKey, data)
+	}
+}
+
+// RenderStream renders the named template like Render, but lets the renderer write directly to the response,
+// which is flushed every RenderStreamFlushSize bytes. Large pages use less memory and reach the client sooner.
+// The data is merged into the context store when it's a map[string]interface{}, otherwise it's stored
+// under the RenderDataKey key.
+//
+// Unlike Render, the status and the headers are sent with the first byte of the page, so an error in the
+// middle of the template can't be turned into an error response: the client gets a truncated page.
+// The error is appended to the page as an HTML comment in Debug mode, logged otherwise, and returned.
+// Prefer Render unless the output is large.
+func (c *Context) RenderStream(name string, data interface{}, status ...int) (err error) {
+	var code int
+	if len(status) > 0 {
+		code = status[0]
+	} else {
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
+	}
+	if c.makross.renderer == nil {
+		return ErrRendererNotRegistered
+	}
+	c.setRenderData(data)
+	c.Response.Header().Set(HeaderContentType, MIMETextHTMLCharsetUTF8)
+	w := &flushWriter{r: c.Response, code: code, size: RenderStreamFlushSize}
+	err = c.makross.renderer.Render(w, name, c)
+	if err != nil && !c.Response.Committed {
+		// nothing was sent, the error can still be handled as usual
+		return err
+	}
+	if err != nil {
+		if c.makross.Debug {
+			fmt.Fprintf(c.Response, "\n<!-- render error: %s -->\n", strings.Replace(err.Error(), "--", "- -", -1))
+		} else {
+			c.Logger().Errorf("render %s: %v", name, err)
+		}
+	}
+	if !c.Response.Committed {
+		c.Response.WriteHeader(code)
+	}
+	c.Response.flush()
+	c.Abort()
+	return
+}
+
 func (c *Context) String(s string, status ...int) (err error) {
 	var code int
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
 	}
 	c.Response.Header().Set(HeaderContentType, MIMETextPlainCharsetUTF8)
 	c.Response.WriteHeader(code)
@@ -456,7 +1168,7 @@ func (c *Context) JSON(i interface{}, status ...int) (err error) {
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
 	}
 	b, err := json.Marshal(i)
 	if err != nil {
@@ -470,7 +1182,7 @@ func (c *Context) JSONPretty(i interface{}, indent string, status ...int) (err e
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
 	}
 	b, err := json.MarshalIndent(i, "", indent)
 	if err != nil {
@@ -484,7 +1196,7 @@ func (c *Context) JSONBlob(b []byte, status ...int) (err error) {
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
 	}
 	return c.Blob(MIMEApplicationJSONCharsetUTF8, b, code)
 }
@@ -494,7 +1206,7 @@ func (c *Context) JSONP(callback string, i interface{}, status ...int) (err erro
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
 	}
 	b, err := json.Marshal(i)
 	if err != nil {
@@ -508,7 +1220,10 @@ func (c *Context) JSONPBlob(callback string, b []byte, status ...int) (err error
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
 	}
 	c.Response.Header().Set(HeaderContentType, MIMEApplicationJavaScriptCharsetUTF8)
 	c.Response.WriteHeader(code)
@@ -528,7 +1243,7 @@ func (c *Context) XML(i interface{}, status ...int) (err error) {
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
 	}
 	b, err := xml.Marshal(i)
 	if err != nil {
@@ -542,7 +1257,7 @@ func (c *Context) XMLPretty(i interface{}, indent string, status ...int) (err er
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
 	}
 	b, err := xml.MarshalIndent(i, "", indent)
 	if err != nil {
@@ -556,7 +1271,10 @@ func (c *Context) XMLBlob(b []byte, status ...int) (err error) {
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
 	}
 	c.Response.Header().Set(HeaderContentType, MIMEApplicationXMLCharsetUTF8)
 	c.Response.WriteHeader(code)
@@ -573,7 +1291,10 @@ func (c *Context) Blob(contentType string, b []byte, status ...int) (err error)
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
 	}
 
 	c.Response.Header().Set(HeaderContentType, contentType)
@@ -588,11 +1309,13 @@ func (c *Context) Stream(contentType string, r io.Reader, status ...int) (err er
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
 	}
+	s, untrack := c.makross.trackStream(c, false)
+	defer untrack()
 	c.Response.Header().Set(HeaderContentType, contentType)
 	c.Response.WriteHeader(code)
-	_, err = io.Copy(c.Response, r)
+	err = c.streamCopy(s, r)
 	c.Abort()
 	return
 }
@@ -641,7 +1364,7 @@ func (c *Context) SendFile(filename string, destinationName string) error {
 	c.Response.Header().Set(HeaderContentDisposition, "attachment;filename="+destinationName)
 	_, err = io.Copy(c.Response, f)
 	c.Abort()
-	return err
+	return c.slowClient(err)
 }
 
 func (c *Context) Attachment(file, name string) (err error) {
@@ -664,7 +1387,10 @@ func (c *Context) NoContent(status ...int) error {
 	if len(status) > 0 {
 		code = status[0]
 	} else {
-		code = StatusOK
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
 	}
 	c.Response.WriteHeader(code)
 	return c.Abort()
@@ -731,6 +1457,67 @@ func (c *Context) RequestHeader(key string) string {
 	return c.Request.Header.Get(key)
 }
 
+// CheckPreconditions sets the Last-Modified and ETag headers of the response and evaluates the
+// If-None-Match and If-Modified-Since headers of the request against them. The zero time and an empty
+// etag are ignored. The etag is quoted if needed, a weak etag keeps its "W/" prefix.
+//
+// It returns true if the response is complete: "304 - Not Modified" for GET and HEAD requests,
+// "412 - Precondition Failed" for the others, so the handler can stop:
+//
+//	if c.CheckPreconditions(post.Updated, post.Version) {
+//		return nil
+//	}
+//	return c.JSON(post)
+func (c *Context) CheckPreconditions(lastModified time.Time, etag string) (done bool) {
+	header := c.Response.Header()
+	if etag != "" {
+		if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = `"` + etag + `"`
+		}
+		header.Set(HeaderETag, etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set(HeaderLastModified, lastModified.UTC().Format(TimeFormat))
+	}
+
+	method := c.Request.Method
+	safe := method == GET || method == HEAD
+	// If-None-Match takes precedence over If-Modified-Since, see RFC 7232, 3.3
+	if inm := c.RequestHeader(HeaderIfNoneMatch); inm != "" {
+		if etag == "" || !etagMatch(inm, etag) {
+			return false
+		}
+	} else if ims := c.RequestHeader(HeaderIfModifiedSince); ims != "" && safe && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil || lastModified.Truncate(time.Second).After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	if !safe {
+		c.Response.WriteHeader(StatusPreconditionFailed)
+		return true
+	}
+	header.Del(HeaderContentType)
+	header.Del(HeaderContentLength)
+	c.Response.WriteHeader(StatusNotModified)
+	return true
+}
+
+// etagMatch reports whether the etag matches the If-None-Match list, using the weak comparison.
+func etagMatch(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // ServeContent serves content, headers are autoset
 // receives three parameters, it's low-level function, instead you can use .ServeFile(string,bool)/SendFile(string,string)
 //
@@ -763,7 +1550,7 @@ func (c *Context) ServeContent(content io.ReadSeeker, filename string, modtime t
 	c.Response.WriteHeader(StatusOK)
 	_, err := io.Copy(c.Response, content)
 	c.Abort()
-	return err
+	return c.slowClient(err)
 }
 
 // IsTLS implements `Context#TLS` function.
@@ -778,6 +1565,16 @@ func (c *Context) Scheme() string {
 	if c.IsTLS() {
 		return "https"
 	}
+	if !c.trustsForwarded() {
+		return "http"
+	}
+	var fproto string
+	if fwd, ok := c.forwarded(); ok {
+		fproto = fwd.Proto
+	}
+	if fproto != "" && c.preferForwarded() {
+		return fproto
+	}
 	if scheme := c.Request.Header.Get(HeaderXForwardedProto); scheme != "" {
 		return scheme
 	}
@@ -790,5 +1587,8 @@ func (c *Context) Scheme() string {
 	if scheme := c.Request.Header.Get(HeaderXUrlScheme); scheme != "" {
 		return scheme
 	}
+	if fproto != "" {
+		return fproto
+	}
 	return "http"
 }
@@ -3,12 +3,17 @@
 package makross
 
 import (
+	"bytes"
+	ktx "context"
 	"errors"
 	"fmt"
+	"html/template"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -30,6 +35,28 @@ func TestContextParam(t *testing.T) {
 	assert.Equal(t, "", c.Param("Xyz").String())
 }
 
+func TestContextSetParam(t *testing.T) {
+	m := New()
+	c := m.NewContext(nil, nil)
+	c.SetParam("Name", "a")
+	assert.Equal(t, "a", c.Param("Name").String())
+	assert.Equal(t, "a", c.Parameter(0))
+
+	c.SetParams(map[string]string{"Name": "b", "Age": "18"})
+	assert.Equal(t, "b", c.Param("Name").String())
+	assert.Equal(t, 18, c.Param("Age").MustInt())
+	assert.Equal(t, "", c.Param("Xyz").String())
+
+	// the route's own parameter names must not be modified
+	pnames := []string{"id"}
+	c.pnames = pnames
+	c.pvalues = []string{"1"}
+	c.SetParam("action", "edit")
+	assert.Equal(t, []string{"id"}, pnames)
+	assert.Equal(t, "1", c.Param("id").String())
+	assert.Equal(t, "edit", c.Param("action").String())
+}
+
 func TestContextInit(t *testing.T) {
 	m := New()
 	c := m.NewContext(nil, nil)
@@ -119,6 +146,253 @@ func TestContextNextAbort(t *testing.T) {
 	assert.Equal(t, "<a><b/></a>", res.Body.String())
 }
 
+func TestContextNextCollect(t *testing.T) {
+	c, res := testNewContext(
+		testErrorHandler("a"),
+		testNormalHandler("b"),
+		testErrorHandler("c"),
+	)
+	errs := c.NextCollect()
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "error:a", errs[0].Error())
+		assert.Equal(t, "error:c", errs[1].Error())
+	}
+	assert.Equal(t, "<a/><b/><c/>", res.Body.String())
+
+	c, res = testNewContext(
+		testNormalHandler("a"),
+		testAbortHandler("b"),
+		testErrorHandler("c"),
+	)
+	assert.Nil(t, c.NextCollect())
+	assert.Equal(t, "<a/><b/>", res.Body.String())
+
+	// from a handler, collecting the errors of the following ones
+	c, res = testNewContext(
+		func(c *Context) error {
+			errs := c.NextCollect()
+			fmt.Fprintf(c.Response, "<%d errors/>", len(errs))
+			return errors.Join(errs...)
+		},
+		testErrorHandler("a"),
+		testErrorHandler("b"),
+	)
+	err := c.Next()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "error:a\nerror:b", err.Error())
+	}
+	assert.Equal(t, "<a/><b/><2 errors/>", res.Body.String())
+}
+
+func TestContextTimings(t *testing.T) {
+	c, _ := testNewContext()
+	assert.Nil(t, c.Timings())
+	assert.Equal(t, "", c.ServerTiming())
+
+	stopDB := c.StartTimer("db")
+	stopRender := c.StartTimer("render")
+	time.Sleep(2 * time.Millisecond)
+	stopDB()
+	stopDB()
+	time.Sleep(time.Millisecond)
+	stopRender()
+	stop := c.StartTimer("db")
+	time.Sleep(time.Millisecond)
+	stop()
+
+	timings := c.Timings()
+	assert.Len(t, timings, 2)
+	assert.True(t, timings["db"] >= 3*time.Millisecond)
+	assert.True(t, timings["render"] >= 3*time.Millisecond)
+	assert.Regexp(t, `^db;dur=[0-9.]+, render;dur=[0-9.]+$`, c.ServerTiming())
+
+	c.Reset(nil, nil)
+	assert.Nil(t, c.Timings())
+}
+
+func TestContextAddServerTiming(t *testing.T) {
+	m := New()
+	m.Get("/", func(c *Context) error {
+		c.AddServerTiming("db", 53200*time.Microsecond, "Database")
+		c.AddServerTiming("cache", 0, `cache "miss"`)
+		c.AddServerTiming("db", 800*time.Microsecond, "")
+		return c.String("ok")
+	})
+	m.Get("/none", func(c *Context) error {
+		c.StartTimer("db")()
+		return c.String("ok")
+	})
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/", nil))
+	assert.Equal(t, `db;dur=54;desc="Database", cache;dur=0;desc="cache \"miss\""`, res.Header().Get(HeaderServerTiming))
+
+	// the timers alone aren't sent
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/none", nil))
+	assert.Equal(t, "", res.Header().Get(HeaderServerTiming))
+}
+
+type stopError struct {
+	Error string `json:"error"`
+}
+
+func TestContextStop(t *testing.T) {
+	m := New()
+	var after bool
+	m.Get("/", func(c *Context) error {
+		if c.Query("token") != "secret" {
+			return c.Stop(StatusUnauthorized, stopError{"invalid token"})
+		}
+		return c.Next()
+	}, func(c *Context) error {
+		after = true
+		return c.String("welcome")
+	})
+	m.Get("/empty", func(c *Context) error {
+		return c.Stop(StatusTooManyRequests, nil)
+	}, func(c *Context) error {
+		after = true
+		return nil
+	})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(GET, path, nil)
+		req.Header.Set(HeaderAccept, accept)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+	res := serve("/", "")
+	assert.False(t, after)
+	assert.Equal(t, StatusUnauthorized, res.Code)
+	assert.Equal(t, `{"error":"invalid token"}`, strings.TrimSpace(res.Body.String()))
+
+	res = serve("/", MIMEApplicationXML)
+	assert.False(t, after)
+	assert.Equal(t, StatusUnauthorized, res.Code)
+	assert.Contains(t, res.Header().Get(HeaderContentType), MIMEApplicationXML)
+
+	res = serve("/empty", "")
+	assert.False(t, after)
+	assert.Equal(t, StatusTooManyRequests, res.Code)
+	assert.Equal(t, "", res.Body.String())
+
+	res = serve("/?token=secret", "")
+	assert.True(t, after)
+	assert.Equal(t, "welcome", res.Body.String())
+}
+
+type testRenderer struct{}
+
+func (r *testRenderer) Render(w io.Writer, name string, c *Context) error {
+	_, err := fmt.Fprintf(w, "<%v/>", name)
+	return err
+}
+
+func TestContextDoubleRender(t *testing.T) {
+	c, res := testNewContext()
+	c.Makross().SetRenderer(&testRenderer{})
+	assert.Nil(t, c.Render("a"))
+	assert.Equal(t, ErrResponseAlreadyCommitted, c.Render("b", StatusInternalServerError))
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "<a/>", res.Body.String())
+
+	assert.Equal(t, ErrResponseAlreadyCommitted, c.String("c"))
+	assert.Equal(t, ErrResponseAlreadyCommitted, c.JSON(map[string]string{"d": "e"}))
+	assert.Equal(t, ErrResponseAlreadyCommitted, c.NoContent(StatusNoContent))
+	assert.Equal(t, "<a/>", res.Body.String())
+	assert.Equal(t, MIMETextHTMLCharsetUTF8, res.Header().Get(HeaderContentType))
+}
+
+type dataRenderer struct{}
+
+func (r *dataRenderer) Render(w io.Writer, name string, c *Context) error {
+	if name == "missing" {
+		return errors.New("template missing not found")
+	}
+	_, err := fmt.Fprintf(w, "Hello %v, your order %v has shipped.", c.Get("name"), c.Get(RenderDataKey))
+	return err
+}
+
+type templateRenderer struct {
+	t *template.Template
+}
+
+func (r *templateRenderer) Render(w io.Writer, name string, c *Context) error {
+	return r.t.ExecuteTemplate(w, name, c.TemplateData())
+}
+
+func TestContextTemplateData(t *testing.T) {
+	m := New()
+	m.SetRenderer(&templateRenderer{template.Must(template.New("layout").Parse(
+		`{{.user}} {{.csrf}} {{.Ctx.Path}} {{.title}}`))})
+	m.Use(func(c *Context) error {
+		c.Set("user", "ann")
+		c.Set("csrf", "token")
+		c.Set("title", "from the store")
+		return c.Next()
+	})
+	m.Get("/page", func(c *Context) error {
+		return c.Render("layout")
+	})
+	m.Get("/stream", func(c *Context) error {
+		// the data of the render wins over the store
+		return c.RenderStream("layout", map[string]interface{}{"title": "from the render"})
+	})
+
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/page", nil))
+	assert.Equal(t, "ann token /page from the store", res.Body.String())
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/stream", nil))
+	assert.Equal(t, "ann token /stream from the render", res.Body.String())
+
+	// the store wins over the accessors
+	c, _ := testNewContext()
+	assert.Equal(t, c, c.TemplateData()["Ctx"])
+	assert.Nil(t, c.TemplateData()["Flash"])
+	c.Set("Ctx", "mine")
+	c.Flash = &Flash{}
+	assert.Equal(t, "mine", c.TemplateData()["Ctx"])
+	assert.Equal(t, c.Flash, c.TemplateData()["Flash"])
+}
+
+func TestContextRenderString(t *testing.T) {
+	c, res := testNewContext()
+	_, err := c.RenderString("email", nil)
+	assert.Equal(t, ErrRendererNotRegistered, err)
+
+	c.Makross().SetRenderer(&dataRenderer{})
+	c.Set("name", "Ann")
+	s, err := c.RenderString("email", 42)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello Ann, your order 42 has shipped.", s)
+	s, err = c.RenderString("email", map[string]interface{}{"name": "Bob"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello Bob, your order <nil> has shipped.", s)
+	_, err = c.RenderString("missing", nil)
+	assert.EqualError(t, err, "template missing not found")
+
+	// neither the response nor the store are touched
+	assert.False(t, c.Response.Committed)
+	assert.Empty(t, res.Body.String())
+	assert.Empty(t, res.Header().Get(HeaderContentType))
+	assert.Equal(t, "Ann", c.Get("name"))
+	assert.Nil(t, c.Get(RenderDataKey))
+}
+
+func TestContextBreak(t *testing.T) {
+	c, res := testNewContext()
+	assert.Nil(t, c.Break(StatusForbidden))
+	assert.Equal(t, StatusForbidden, res.Code)
+	assert.Equal(t, StatusText(StatusForbidden), res.Body.String())
+
+	c, res = testNewContext()
+	assert.Nil(t, c.Break(StatusBadRequest, errors.New("abc")))
+	assert.Equal(t, StatusBadRequest, res.Code)
+	assert.Equal(t, "abc", res.Body.String())
+}
+
 func testNewContext(handlers ...Handler) (*Context, *httptest.ResponseRecorder) {
 	res := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
@@ -160,3 +434,336 @@ func testNormalHandler(tag string) Handler {
 		return nil
 	}
 }
+
+func TestContextWithValue(t *testing.T) {
+	c, _ := testNewContext()
+	c.WithValue("a", 1)
+	c.WithValue("b", 2)
+	assert.Equal(t, 1, c.Kontext().Value("a"))
+	assert.Equal(t, 2, c.Kontext().Value("b"))
+}
+
+func TestContextQueryOrdered(t *testing.T) {
+	m := New()
+	req := httptest.NewRequest(GET, "/?b=2&a=1&b=3&name=J%C3%BCrgen+M&empty=&flag&&bad=%zz&a%26b=c%3Dd", nil)
+	c := m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, "b=2&a=1&b=3&name=J%C3%BCrgen+M&empty=&flag&&bad=%zz&a%26b=c%3Dd", c.RawQuery())
+	assert.Equal(t, []QueryParam{
+		{"b", "2"},
+		{"a", "1"},
+		{"b", "3"},
+		{"name", "Jürgen M"},
+		{"empty", ""},
+		{"flag", ""},
+		{"bad", "%zz"},
+		{"a&b", "c=d"},
+	}, c.QueryOrdered())
+
+	req = httptest.NewRequest(GET, "/", nil)
+	req.URL.RawQuery = "a=1#frag=2"
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, []QueryParam{{"a", "1"}}, c.QueryOrdered())
+
+	c = m.NewContext(httptest.NewRequest(GET, "/", nil), httptest.NewRecorder())
+	assert.Empty(t, c.QueryOrdered())
+}
+
+func TestContextRawPath(t *testing.T) {
+	m := New()
+	m.Pre(func(c *Context) error {
+		// rewrite the request URL before routing, like StripPrefix
+		c.Request.URL.Path = strings.TrimPrefix(c.Request.URL.Path, "/app")
+		c.Request.URL.RawPath = ""
+		c.Request.URL.RawQuery = c.Request.URL.Query().Encode()
+		return c.Next()
+	})
+	m.Get("/files/<name>", func(c *Context) error {
+		return c.String(c.RawPath() + " " + c.RawQuery() + " " + c.QueryString())
+	})
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/app/files/a%20b%2Bc?z=a+b&y=%2B1&z=%7e&sig=x%3D", nil))
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "/app/files/a%20b%2Bc z=a+b&y=%2B1&z=%7e&sig=x%3D z=a+b&y=%2B1&z=%7e&sig=x%3D", res.Body.String())
+
+	// the request URI in absolute form, as sent to a proxy
+	req := httptest.NewRequest(GET, "http://example.com/a+b?q=1+2", nil)
+	req.RequestURI = "http://example.com/a+b?q=1+2"
+	c := m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, "/a+b", c.RawPath())
+	assert.Equal(t, "q=1+2", c.RawQuery())
+
+	// without request URI, the request URL is used
+	req = httptest.NewRequest(GET, "/a%2Fb?q=%41", nil)
+	req.RequestURI = ""
+	c = m.NewContext(req, httptest.NewRecorder())
+	assert.Equal(t, "/a%2Fb", c.RawPath())
+	assert.Equal(t, "q=%41", c.RawQuery())
+}
+
+func TestContextFormTyped(t *testing.T) {
+	m := New()
+	req := httptest.NewRequest(POST, "/?page=3", strings.NewReader("n=42&neg=-7&bad=4x&agree=on&off=off&admin=true&f=1.5&nan=NaN&empty="))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	c := m.NewContext(req, httptest.NewRecorder())
+
+	i, err := c.FormInt("n")
+	assert.Nil(t, err)
+	assert.Equal(t, 42, i)
+	i, _ = c.FormInt("neg", 10)
+	assert.Equal(t, -7, i)
+	i, _ = c.FormInt("page")
+	assert.Equal(t, 3, i)
+	i, err = c.FormInt("missing", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, i)
+	i, err = c.FormInt("empty")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, i)
+	_, err = c.FormInt("bad", 10)
+	if assert.IsType(t, &HTTPError{}, err) {
+		assert.Equal(t, StatusBadRequest, err.(*HTTPError).Status)
+		assert.Equal(t, `invalid bad "4x", expected an integer`, err.(*HTTPError).Message)
+	}
+
+	b, err := c.FormBool("agree")
+	assert.Nil(t, err)
+	assert.True(t, b)
+	b, _ = c.FormBool("admin")
+	assert.True(t, b)
+	b, _ = c.FormBool("off", true)
+	assert.False(t, b)
+	b, err = c.FormBool("missing", true)
+	assert.Nil(t, err)
+	assert.True(t, b)
+	_, err = c.FormBool("n")
+	assert.Equal(t, `invalid n "42", expected a boolean`, err.(*HTTPError).Message)
+
+	f, err := c.FormFloat64("f")
+	assert.Nil(t, err)
+	assert.Equal(t, 1.5, f)
+	f, _ = c.FormFloat64("n")
+	assert.Equal(t, 42.0, f)
+	f, _ = c.FormFloat64("missing", 0.5)
+	assert.Equal(t, 0.5, f)
+	_, err = c.FormFloat64("nan")
+	assert.Equal(t, `invalid nan "NaN", expected a number`, err.(*HTTPError).Message)
+
+	// a malformed body
+	req = httptest.NewRequest(POST, "/", strings.NewReader("n=%zz"))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	c = m.NewContext(req, httptest.NewRecorder())
+	i, err = c.FormInt("n", 5)
+	assert.Equal(t, 5, i)
+	if assert.IsType(t, &HTTPError{}, err) {
+		assert.Equal(t, StatusBadRequest, err.(*HTTPError).Status)
+	}
+}
+
+func TestContextExpectsContinue(t *testing.T) {
+	m := New()
+	req := httptest.NewRequest(POST, "/", strings.NewReader("x"))
+	assert.False(t, m.NewContext(req, httptest.NewRecorder()).ExpectsContinue())
+	req.Header.Set(HeaderExpect, "100-Continue")
+	assert.True(t, m.NewContext(req, httptest.NewRecorder()).ExpectsContinue())
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/1.0", 1, 0
+	assert.False(t, m.NewContext(req, httptest.NewRecorder()).ExpectsContinue())
+}
+
+func TestContextSetStatus(t *testing.T) {
+	c, res := testNewContext()
+	c.SetStatus(StatusCreated).String("created")
+	assert.Equal(t, StatusCreated, res.Code)
+	assert.Equal(t, "created", res.Body.String())
+
+	// the explicit status wins
+	c, res = testNewContext()
+	c.SetStatus(StatusCreated)
+	c.JSON("accepted", StatusAccepted)
+	assert.Equal(t, StatusAccepted, res.Code)
+
+	// plain writes use it too
+	c, res = testNewContext()
+	c.SetStatus(StatusCreated)
+	c.Response.Write([]byte("created"))
+	assert.Equal(t, StatusCreated, res.Code)
+}
+
+func TestContextWithTimeout(t *testing.T) {
+	m := New()
+	parent, disconnect := ktx.WithCancel(ktx.Background())
+	req := httptest.NewRequest(GET, "/poll", nil).WithContext(parent)
+	c := m.NewContext(req, httptest.NewRecorder())
+
+	ctx, cancel := c.WithTimeout(time.Minute)
+	defer cancel()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		disconnect()
+	}()
+	select {
+	case <-ctx.Done():
+		assert.Equal(t, ktx.Canceled, ctx.Err())
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client disconnect didn't cancel the context")
+	}
+
+	// the server caps the timeout
+	m.Configure(ServerConfig{MaxHandlerTimeout: time.Second})
+	c = m.NewContext(httptest.NewRequest(GET, "/poll", nil), httptest.NewRecorder())
+	ctx, cancel = c.WithTimeout(time.Hour)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.True(t, time.Until(deadline) <= time.Second)
+
+	ctx, cancel = c.WithTimeout(10 * time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	assert.Equal(t, ktx.DeadlineExceeded, ctx.Err())
+}
+
+func TestContextCheckPreconditions(t *testing.T) {
+	modified := time.Date(2017, time.March, 1, 10, 30, 0, 500, time.UTC)
+	tests := []struct {
+		method, header, value string
+		etag                  string
+		done                  bool
+		code                  int
+	}{
+		{GET, "", "", "v1", false, StatusOK},
+		{GET, HeaderIfNoneMatch, `"v1"`, "v1", true, StatusNotModified},
+		{HEAD, HeaderIfNoneMatch, `"v0", "v1"`, "v1", true, StatusNotModified},
+		{GET, HeaderIfNoneMatch, `W/"v1"`, "v1", true, StatusNotModified},
+		{GET, HeaderIfNoneMatch, `"v1"`, `W/"v1"`, true, StatusNotModified},
+		{GET, HeaderIfNoneMatch, `*`, "v1", true, StatusNotModified},
+		{GET, HeaderIfNoneMatch, `"v0"`, "v1", false, StatusOK},
+		{PUT, HeaderIfNoneMatch, `*`, "v1", true, StatusPreconditionFailed},
+		{GET, HeaderIfModifiedSince, "Wed, 01 Mar 2017 10:30:00 GMT", "", true, StatusNotModified},
+		{GET, HeaderIfModifiedSince, "Wed, 01 Mar 2017 11:00:00 GMT", "", true, StatusNotModified},
+		{GET, HeaderIfModifiedSince, "Wed, 01 Mar 2017 10:29:59 GMT", "", false, StatusOK},
+		{GET, HeaderIfModifiedSince, "yesterday", "", false, StatusOK},
+		{POST, HeaderIfModifiedSince, "Wed, 01 Mar 2017 11:00:00 GMT", "", false, StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/posts/1", nil)
+		if test.header != "" {
+			req.Header.Set(test.header, test.value)
+		}
+		res := httptest.NewRecorder()
+		c := New().NewContext(req, res)
+		c.Response.Header().Set(HeaderContentType, MIMEApplicationJSON)
+		msg := test.method + " " + test.header + ": " + test.value
+
+		done := c.CheckPreconditions(modified, test.etag)
+		assert.Equal(t, test.done, done, msg)
+		if !done {
+			assert.Nil(t, c.String("post"), msg)
+		}
+		assert.Equal(t, test.code, res.Code, msg)
+		assert.Equal(t, "Wed, 01 Mar 2017 10:30:00 GMT", res.Header().Get(HeaderLastModified), msg)
+		if test.etag != "" {
+			assert.Contains(t, res.Header().Get(HeaderETag), `"v1"`, msg)
+		}
+		if test.code == StatusNotModified {
+			assert.Equal(t, "", res.Header().Get(HeaderContentType), msg)
+			assert.Equal(t, "", res.Body.String(), msg)
+		}
+	}
+
+	// nothing to compare
+	c, res := testNewContext()
+	c.Request.Header.Set(HeaderIfNoneMatch, `"v1"`)
+	assert.False(t, c.CheckPreconditions(time.Time{}, ""))
+	assert.Equal(t, "", res.Header().Get(HeaderLastModified))
+	assert.Equal(t, "", res.Header().Get(HeaderETag))
+}
+
+// chunkRenderer renders n chunks of 1KB.
+type chunkRenderer struct {
+	n int
+}
+
+func (r *chunkRenderer) Render(w io.Writer, name string, c *Context) error {
+	chunk := bytes.Repeat([]byte("x"), 1<<10)
+	for i := 0; i < r.n; i++ {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	if name == "broken" {
+		return errors.New("broken template")
+	}
+	return nil
+}
+
+func TestContextRenderStream(t *testing.T) {
+	c, res := testNewContext()
+	assert.Equal(t, ErrRendererNotRegistered, c.RenderStream("a", nil))
+	c.Makross().SetRenderer(&chunkRenderer{n: 20})
+	assert.Nil(t, c.RenderStream("a", map[string]interface{}{"title": "report"}, StatusCreated))
+	assert.Equal(t, StatusCreated, res.Code)
+	assert.Equal(t, MIMETextHTMLCharsetUTF8, res.Header().Get(HeaderContentType))
+	assert.Equal(t, 20<<10, res.Body.Len())
+	assert.True(t, res.Flushed)
+	assert.Equal(t, "report", c.Get("title"))
+	assert.Equal(t, ErrResponseAlreadyCommitted, c.RenderStream("a", nil))
+
+	c, _ = testNewContext()
+	c.Makross().SetRenderer(&chunkRenderer{})
+	c.RenderStream("a", []int{1, 2})
+	assert.Equal(t, []int{1, 2}, c.Get(RenderDataKey))
+
+	// errors before the first byte can still be handled
+	c, _ = testNewContext()
+	c.Makross().SetRenderer(&chunkRenderer{})
+	assert.EqualError(t, c.RenderStream("broken", nil), "broken template")
+	assert.False(t, c.Response.Committed)
+
+	// errors can't change the status once the page is streaming
+	c, res = testNewContext()
+	c.Makross().SetRenderer(&chunkRenderer{n: 1})
+	assert.EqualError(t, c.RenderStream("broken", nil), "broken template")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, 1<<10, res.Body.Len())
+
+	c, res = testNewContext()
+	c.Makross().SetRenderer(&chunkRenderer{n: 1})
+	c.Makross().Debug = true
+	assert.EqualError(t, c.RenderStream("broken", nil), "broken template")
+	assert.True(t, strings.HasSuffix(res.Body.String(), "\n<!-- render error: broken template -->\n"))
+}
+
+// discardResponseWriter is a flushable http.ResponseWriter discarding the body.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+func (w *discardResponseWriter) Flush()                      {}
+
+func benchmarkRender(b *testing.B, render func(*Context) error) {
+	m := New()
+	m.SetRenderer(&chunkRenderer{n: 1 << 10}) // 1MB page
+	req, _ := http.NewRequest("GET", "/report", nil)
+	w := &discardResponseWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := m.NewContext(req, w)
+		render(c)
+	}
+}
+
+func BenchmarkContextRender(b *testing.B) {
+	benchmarkRender(b, func(c *Context) error {
+		return c.Render("report")
+	})
+}
+
+func BenchmarkContextRenderStream(b *testing.B) {
+	benchmarkRender(b, func(c *Context) error {
+		return c.RenderStream("report", nil)
+	})
+}
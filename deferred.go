@@ -0,0 +1,175 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+type (
+	// deferredFunc is a function registered with OnSuccess or OnFailure.
+	deferredFunc struct {
+		fn      func(context.Context)
+		success bool // run if the response succeeded, or else if it failed
+	}
+
+	// deferredCall is a deferred function queued for the workers, with the context of its request.
+	deferredCall struct {
+		fn  func(context.Context)
+		ctx context.Context
+	}
+)
+
+const (
+	defaultDeferredWorkers   = 4
+	defaultDeferredQueueSize = 256
+)
+
+// OnSuccess registers a function run after the response, if it succeeded: if it was written with a status
+// below 400. It is meant for the follow-up work of a handler which mustn't happen if the request failed,
+// such as sending an email or publishing an event once the order is stored:
+//
+//	c.OnSuccess(func(ctx context.Context) {
+//		mailer.SendConfirmation(ctx, order)
+//	})
+//
+// The functions run on the worker pool of the application, see DeferredWorkers, with a context keeping
+// the values of the request context, canceled if they are still running when Shutdown gives up waiting
+// for them. They are dropped, with a log line, if the response failed or if the queue is full.
+func (c *Context) OnSuccess(fn func(ctx context.Context)) {
+	c.deferred = append(c.deferred, deferredFunc{fn: fn, success: true})
+}
+
+// OnFailure registers a function run after the response if it failed: if it was written with a status
+// of 400 or above, or not written at all, e.g. to clean up what a handler prepared. See `Context#OnSuccess()`.
+func (c *Context) OnFailure(fn func(ctx context.Context)) {
+	c.deferred = append(c.deferred, deferredFunc{fn: fn})
+}
+
+// WaitDeferred waits for the functions registered with OnSuccess and OnFailure to have run, or for the context
+// to be done, whichever happens first. Shutdown waits for them the same way after stopping the servers.
+func (m *Makross) WaitDeferred(ctx context.Context) error {
+	m.deferMu.Lock()
+	idle := m.deferIdle
+	m.deferMu.Unlock()
+	if idle == nil {
+		return nil
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdownDeferred stops accepting deferred functions and waits for the queued ones,
+// canceling the contexts of the ones still running when the context is done.
+func (m *Makross) shutdownDeferred(ctx context.Context) error {
+	m.deferMu.Lock()
+	if m.deferQueue == nil {
+		m.deferClosed = true
+		m.deferMu.Unlock()
+		return nil
+	}
+	if !m.deferClosed {
+		m.deferClosed = true
+		// the workers stop once the queue is empty
+		close(m.deferQueue)
+	}
+	m.deferMu.Unlock()
+	err := m.WaitDeferred(ctx)
+	m.deferCancel()
+	return err
+}
+
+// runDeferred queues the deferred functions of the request matching the outcome of its response.
+func (m *Makross) runDeferred(c *Context, panicked bool) {
+	success := !panicked && c.Response.Committed && c.Response.Status < StatusBadRequest
+	dropped := 0
+	for _, d := range c.deferred {
+		if d.success != success {
+			if d.success {
+				dropped++
+			}
+			continue
+		}
+		if !m.queueDeferred(deferredCall{fn: d.fn, ctx: context.WithoutCancel(c.Request.Context())}) {
+			m.Logger().Warnf("makross: the deferred function of %s %s was dropped, the queue is full or closed",
+				c.Request.Method, c.Request.URL.Path)
+		}
+	}
+	if dropped > 0 {
+		m.Logger().Infof("makross: %d OnSuccess function(s) of %s %s dropped, the response failed with status %d",
+			dropped, c.Request.Method, c.Request.URL.Path, c.Response.Status)
+	}
+	clear(c.deferred)
+	c.deferred = c.deferred[:0]
+}
+
+// queueDeferred queues the call for the workers, started with the first one,
+// and reports whether it was queued.
+func (m *Makross) queueDeferred(call deferredCall) bool {
+	m.deferMu.Lock()
+	defer m.deferMu.Unlock()
+	if m.deferClosed {
+		return false
+	}
+	if m.deferQueue == nil {
+		size := m.DeferredQueueSize
+		if size <= 0 {
+			size = defaultDeferredQueueSize
+		}
+		workers := m.DeferredWorkers
+		if workers <= 0 {
+			workers = defaultDeferredWorkers
+		}
+		m.deferQueue = make(chan deferredCall, size)
+		m.deferCtx, m.deferCancel = context.WithCancel(context.Background())
+		for i := 0; i < workers; i++ {
+			go m.deferredWorker(m.deferQueue)
+		}
+	}
+	select {
+	case m.deferQueue <- call:
+		if m.deferPending == 0 {
+			m.deferIdle = make(chan struct{})
+		}
+		m.deferPending++
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Makross) deferredWorker(queue chan deferredCall) {
+	for call := range queue {
+		m.callDeferred(call)
+	}
+}
+
+func (m *Makross) callDeferred(call deferredCall) {
+	defer m.doneDeferred()
+	defer func() {
+		if r := recover(); r != nil {
+			m.Logger().Errorf("makross: deferred function panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	ctx, cancel := context.WithCancel(call.ctx)
+	defer cancel()
+	stop := context.AfterFunc(m.deferCtx, cancel)
+	defer stop()
+	call.fn(ctx)
+}
+
+// doneDeferred counts a deferred function as run, closing deferIdle after the last one.
+func (m *Makross) doneDeferred() {
+	m.deferMu.Lock()
+	defer m.deferMu.Unlock()
+	m.deferPending--
+	if m.deferPending == 0 {
+		close(m.deferIdle)
+		m.deferIdle = nil
+	}
+}
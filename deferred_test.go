@@ -0,0 +1,196 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type deferredKey struct{}
+
+func TestContextOnSuccess(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		trace []string
+	)
+	record := func(s string) func(context.Context) {
+		return func(ctx context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			trace = append(trace, s+":"+ctx.Value(deferredKey{}).(string))
+		}
+	}
+	logs := new(bytes.Buffer)
+	m := New()
+	m.SetLogger(NewLogger(logs))
+	m.Use(func(c *Context) error {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), deferredKey{}, c.Request.URL.Path))
+		c.OnSuccess(record("success"))
+		c.OnFailure(record("failure"))
+		return c.Next()
+	})
+	m.Get("/ok", func(c *Context) error {
+		return c.String("ok")
+	})
+	m.Get("/redirect", func(c *Context) error {
+		return c.Redirect("/ok")
+	})
+	m.Get("/error", func(c *Context) error {
+		return errors.New("failed")
+	})
+	m.Get("/notfound", func(c *Context) error {
+		return c.String("not found", StatusNotFound)
+	})
+	m.Get("/unwritten", func(c *Context) error {
+		return nil
+	})
+	m.Get("/panic", func(c *Context) error {
+		c.String("partial")
+		panic("boom")
+	})
+
+	tests := []struct {
+		path  string
+		trace []string
+	}{
+		{"/ok", []string{"success:/ok"}},
+		{"/redirect", []string{"success:/redirect"}},
+		{"/error", []string{"failure:/error"}},
+		{"/notfound", []string{"failure:/notfound"}},
+		{"/unwritten", []string{"failure:/unwritten"}},
+		{"/panic", []string{"failure:/panic"}},
+		{"/missing", []string{"failure:/missing"}},
+	}
+	for _, test := range tests {
+		trace = nil
+		serve := func() {
+			m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, test.path, nil))
+		}
+		if test.path == "/panic" {
+			assert.Panics(t, serve)
+		} else {
+			serve()
+		}
+		assert.Nil(t, m.WaitDeferred(context.Background()))
+		assert.Equal(t, test.trace, trace, test.path)
+	}
+	assert.Contains(t, logs.String(), "1 OnSuccess function(s) of GET /error dropped, the response failed with status 500")
+
+	// the functions of a request aren't kept by its pooled context
+	trace = nil
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/ok", nil))
+	assert.Nil(t, m.WaitDeferred(context.Background()))
+	assert.Equal(t, []string{"success:/ok"}, trace)
+}
+
+func TestContextOnSuccessConcurrency(t *testing.T) {
+	m := New()
+	m.DeferredWorkers = 3
+	var count, running, maxRunning int32
+	m.Get("/", func(c *Context) error {
+		c.OnSuccess(func(ctx context.Context) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&count, 1)
+		})
+		return c.NoContent(StatusNoContent)
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/", nil))
+		}()
+	}
+	wg.Wait()
+	assert.Nil(t, m.WaitDeferred(context.Background()))
+	assert.Equal(t, int32(100), atomic.LoadInt32(&count))
+	assert.True(t, atomic.LoadInt32(&maxRunning) <= 3)
+}
+
+func TestDeferredQueueFull(t *testing.T) {
+	logs := new(bytes.Buffer)
+	m := New()
+	m.SetLogger(NewLogger(logs))
+	m.DeferredWorkers = 1
+	m.DeferredQueueSize = 1
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var count int32
+	m.Get("/", func(c *Context) error {
+		c.OnSuccess(func(ctx context.Context) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+			atomic.AddInt32(&count, 1)
+		})
+		return c.String("ok")
+	})
+	serve := func() {
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/", nil))
+	}
+	serve()
+	<-started
+	serve() // queued
+	serve() // dropped
+	close(release)
+	assert.Nil(t, m.WaitDeferred(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	assert.Contains(t, logs.String(), "the deferred function of GET / was dropped")
+}
+
+func TestShutdownDeferred(t *testing.T) {
+	m := New()
+	var done int32
+	m.Get("/slow", func(c *Context) error {
+		c.OnSuccess(func(ctx context.Context) {
+			time.Sleep(20 * time.Millisecond)
+			atomic.StoreInt32(&done, 1)
+		})
+		return c.String("ok")
+	})
+
+	// Shutdown waits for the deferred functions
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/slow", nil))
+	assert.Nil(t, m.Shutdown())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&done))
+
+	// and refuses the new ones
+	logs := new(bytes.Buffer)
+	m.SetLogger(NewLogger(logs))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/slow", nil))
+	assert.Contains(t, logs.String(), "was dropped")
+
+	// the contexts of the functions still running are canceled when it gives up
+	m = New()
+	m.Get("/stuck", func(c *Context) error {
+		c.OnSuccess(func(ctx context.Context) {
+			<-ctx.Done()
+		})
+		return c.String("ok")
+	})
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/stuck", nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, m.shutdownDeferred(ctx))
+	assert.Nil(t, m.WaitDeferred(context.Background()))
+}
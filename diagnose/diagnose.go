@@ -0,0 +1,146 @@
+package diagnose
+
+import (
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+// The headers of the diagnostic of the 404 and 405 responses.
+const (
+	// HeaderReason is why the request wasn't routed: "no route", "method not allowed",
+	// or "handler" when the route matched and its handlers responded 404 or 405 themselves.
+	HeaderReason = "X-Route-Diagnostic"
+
+	// HeaderAllow lists the methods of the routes matching the path.
+	HeaderAllow = "X-Route-Allow"
+
+	// HeaderPrefix is the longest prefix of the path matching a registered route, e.g. "/users".
+	HeaderPrefix = "X-Route-Prefix"
+
+	// HeaderClosest is the registered route closest to the request, e.g. "GET /users/<id>".
+	HeaderClosest = "X-Route-Closest"
+)
+
+// The reasons of the diagnostic, see HeaderReason.
+const (
+	ReasonNoRoute          = "no route"
+	ReasonMethodNotAllowed = "method not allowed"
+	ReasonHandler          = "handler"
+)
+
+type (
+	// DiagnoseConfig defines the config for Diagnose middleware.
+	DiagnoseConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Always attaches the diagnostic even if Makross.Debug is off. It shouldn't be set in production,
+		// since the diagnostic reveals the routes.
+		// Optional. Default value false.
+		Always bool `json:"always"`
+	}
+)
+
+var (
+	// DefaultDiagnoseConfig is the default Diagnose middleware config.
+	DefaultDiagnoseConfig = DiagnoseConfig{
+		Skipper: skipper.DefaultSkipper,
+	}
+)
+
+// Diagnose returns a Diagnose middleware.
+//
+// Diagnose middleware explains the "404 - Not Found" and "405 - Method Not Allowed" responses in debug mode,
+// see Makross.Debug, with the headers:
+//
+//	X-Route-Diagnostic: method not allowed
+//	X-Route-Allow: GET, PUT
+//	X-Route-Prefix: /users/<id>
+//	X-Route-Closest: GET /users/<id>
+//
+// It must be registered with Use, so that it also runs for the requests which don't match a route.
+func Diagnose() makross.Handler {
+	return DiagnoseWithConfig(DefaultDiagnoseConfig)
+}
+
+// DiagnoseWithConfig returns a Diagnose middleware with config.
+// See: `Diagnose()`.
+func DiagnoseWithConfig(config DiagnoseConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultDiagnoseConfig.Skipper
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) || !config.Always && !c.Makross().Debug {
+			return c.Next()
+		}
+		c.Response.Before(func() {
+			if s := c.Response.Status; s == makross.StatusNotFound || s == makross.StatusMethodNotAllowed {
+				diagnose(c)
+			}
+		})
+		return c.Next()
+	}
+}
+
+// diagnose sets the diagnostic headers of the response.
+func diagnose(c *makross.Context) {
+	path := c.Request.URL.Path
+	header := c.Response.Header()
+
+	// the routes hidden from the listener of the request aren't disclosed
+	allow := c.AllowedMethods()
+	switch {
+	case c.Route() != nil:
+		header.Set(HeaderReason, ReasonHandler)
+	case len(allow) > 0:
+		header.Set(HeaderReason, ReasonMethodNotAllowed)
+	default:
+		header.Set(HeaderReason, ReasonNoRoute)
+	}
+	if len(allow) > 0 {
+		header.Set(HeaderAllow, strings.Join(allow, ", "))
+	}
+
+	segments := split(path)
+	var prefix string
+	var closest *makross.Route
+	best, bestDistance := 0, -1
+	for _, r := range c.Routes() {
+		routeSegments := split(r.Path())
+		if n := matchingSegments(routeSegments, segments); n > best {
+			best, prefix = n, "/"+strings.Join(routeSegments[:n], "/")
+		}
+		d := makross.Levenshtein(c.Request.Method+" "+path, r.Method()+" "+makross.ProjectPath(r.Path(), path))
+		if d < bestDistance || bestDistance < 0 {
+			bestDistance, closest = d, r
+		}
+	}
+	if prefix != "" {
+		header.Set(HeaderPrefix, prefix)
+	}
+	if closest != nil {
+		header.Set(HeaderClosest, closest.String())
+	}
+}
+
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// matchingSegments returns the number of leading segments of the path matching the route,
+// a parameter matching any segment.
+func matchingSegments(route, path []string) int {
+	n := 0
+	for n < len(route) && n < len(path) && (route[n] == path[n] || strings.HasPrefix(route[n], "<") && path[n] != "") {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,102 @@
+package diagnose
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnose(t *testing.T) {
+	m := makross.New()
+	m.Debug = true
+	m.Use(Diagnose())
+	ok := func(c *makross.Context) error {
+		return c.String("ok")
+	}
+	m.Get("/users", ok)
+	m.Get("/users/<id:\\d+>", ok)
+	m.Put("/users/<id:\\d+>", ok)
+	m.Get("/articles/<slug>", func(c *makross.Context) error {
+		return makross.ErrNotFound
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+		return res
+	}
+
+	res := serve(makross.DELETE, "/users/42")
+	assert.Equal(t, makross.StatusMethodNotAllowed, res.Code)
+	assert.Equal(t, ReasonMethodNotAllowed, res.Header().Get(HeaderReason))
+	assert.Equal(t, "GET, PUT", res.Header().Get(HeaderAllow))
+	assert.Equal(t, "/users/<id:\\d+>", res.Header().Get(HeaderPrefix))
+	assert.Equal(t, "GET /users/<id:\\d+>", res.Header().Get(HeaderClosest))
+
+	res = serve(makross.GET, "/usres")
+	assert.Equal(t, makross.StatusNotFound, res.Code)
+	assert.Equal(t, ReasonNoRoute, res.Header().Get(HeaderReason))
+	assert.Empty(t, res.Header().Get(HeaderAllow))
+	assert.Empty(t, res.Header().Get(HeaderPrefix))
+	assert.Equal(t, "GET /users", res.Header().Get(HeaderClosest))
+
+	res = serve(makross.GET, "/users/42/posts")
+	assert.Equal(t, makross.StatusNotFound, res.Code)
+	assert.Equal(t, ReasonNoRoute, res.Header().Get(HeaderReason))
+	assert.Equal(t, "/users/<id:\\d+>", res.Header().Get(HeaderPrefix))
+
+	res = serve(makross.GET, "/articles/missing")
+	assert.Equal(t, makross.StatusNotFound, res.Code)
+	assert.Equal(t, ReasonHandler, res.Header().Get(HeaderReason))
+	assert.Equal(t, "GET", res.Header().Get(HeaderAllow))
+
+	res = serve(makross.GET, "/users/42")
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Empty(t, res.Header().Get(HeaderReason))
+
+	// off without debug mode
+	m.Debug = false
+	res = serve(makross.DELETE, "/users/42")
+	assert.Equal(t, makross.StatusMethodNotAllowed, res.Code)
+	assert.Empty(t, res.Header().Get(HeaderReason))
+}
+
+func TestDiagnoseFilteredRoutes(t *testing.T) {
+	m := makross.New()
+	m.Debug = true
+	m.Use(Diagnose())
+	ok := func(c *makross.Context) error {
+		return c.String("ok")
+	}
+	m.Get("/users", ok)
+	m.Delete("/users", ok).Tag("admin")
+	m.Get("/admin/users", ok).Tag("admin")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go m.StartMultiple([]makross.ListenerSpec{{Listener: l, Filter: makross.RoutesNotTagged("admin")}})
+	defer m.Close()
+
+	// the admin routes aren't disclosed on the public listener
+	serve := func(method, path string) *http.Response {
+		req, _ := http.NewRequest(method, "http://"+l.Addr().String()+path, nil)
+		res, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			return &http.Response{Header: http.Header{}}
+		}
+		res.Body.Close()
+		return res
+	}
+	res := serve(makross.PUT, "/users")
+	assert.Equal(t, makross.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, "GET", res.Header.Get(HeaderAllow))
+	res = serve(makross.GET, "/admin/userz")
+	assert.Equal(t, makross.StatusNotFound, res.StatusCode)
+	assert.Equal(t, "GET /users", res.Header.Get(HeaderClosest))
+}
@@ -0,0 +1,55 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// drainHandlers refuse the requests while draining.
+var drainHandlers = []Handler{func(c *Context) error {
+	return NewServiceUnavailableError(c.makross.DrainRetryAfter)
+}}
+
+// SetDraining switches the drain mode, e.g. from an orchestrator hook before a rolling deploy shuts
+// the instance down. While draining, the new requests get a "503 - Service Unavailable" response with
+// a Retry-After header, except the requests to the DrainExcludePaths, while the requests in flight complete.
+// The Ready handler reports the instance as not ready, so that the load balancers stop routing to it.
+// It is safe to call while serving.
+func (m *Makross) SetDraining(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	atomic.StoreInt32(&m.draining, i)
+}
+
+// Draining reports whether the drain mode is on.
+func (m *Makross) Draining() bool {
+	return atomic.LoadInt32(&m.draining) == 1
+}
+
+// Ready is a readiness endpoint handler, responding "503 - Service Unavailable" while warming up
+// or draining:
+//
+//	m.Get("/readyz", m.Ready)
+func (m *Makross) Ready(c *Context) error {
+	if m.WarmingUp() {
+		return c.String("warming up", StatusServiceUnavailable)
+	}
+	if m.Draining() {
+		return c.String("draining", StatusServiceUnavailable)
+	}
+	return c.String("ok")
+}
+
+// drainExcluded reports whether the path is served while draining.
+func (m *Makross) drainExcluded(path string) bool {
+	for _, p := range m.DrainExcludePaths {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
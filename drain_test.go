@@ -0,0 +1,53 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraining(t *testing.T) {
+	m := New()
+	m.Get("/users", func(c *Context) error {
+		return c.String("users")
+	})
+	m.Get("/healthz", func(c *Context) error {
+		return c.String("ok")
+	})
+	m.Get("/metrics/requests", func(c *Context) error {
+		return c.String("42")
+	})
+	m.Get("/readyz", m.Ready)
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, path, nil))
+		return res
+	}
+
+	assert.False(t, m.Draining())
+	assert.Equal(t, StatusOK, serve("/users").Code)
+	assert.Equal(t, StatusOK, serve("/readyz").Code)
+
+	m.SetDraining(true)
+	assert.True(t, m.Draining())
+	res := serve("/users")
+	assert.Equal(t, StatusServiceUnavailable, res.Code)
+	assert.Equal(t, "30", res.Header().Get(HeaderRetryAfter))
+	assert.Equal(t, StatusServiceUnavailable, serve("/unknown").Code)
+	assert.Equal(t, StatusOK, serve("/healthz").Code)
+	assert.Equal(t, StatusOK, serve("/metrics/requests").Code)
+	res = serve("/readyz")
+	assert.Equal(t, StatusServiceUnavailable, res.Code)
+	assert.Equal(t, "draining", res.Body.String())
+
+	m.DrainRetryAfter = 1500 * time.Millisecond
+	assert.Equal(t, "2", serve("/users").Header().Get(HeaderRetryAfter))
+
+	m.SetDraining(false)
+	assert.Equal(t, StatusOK, serve("/users").Code)
+	assert.Equal(t, StatusOK, serve("/readyz").Code)
+}
@@ -0,0 +1,92 @@
+// Package egress aggregates the response bytes reported by Makross.OnResponse per route and tenant.
+package egress
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Key identifies a usage counter.
+	Key struct {
+		Route  string
+		Tenant string
+	}
+
+	// Usage is the egress of a route and tenant since the last flush.
+	Usage struct {
+		Bytes     int64
+		Responses int64
+	}
+
+	// Aggregator sums the egress in memory and hands it to a flush function periodically.
+	//
+	//	agg := egress.NewAggregator(time.Minute, func(usage map[egress.Key]egress.Usage) {
+	//		// write the usage to the billing database
+	//	})
+	//	defer agg.Stop()
+	//	m.OnResponse(agg.Record)
+	Aggregator struct {
+		mu    sync.Mutex
+		usage map[Key]Usage
+		flush func(map[Key]Usage)
+		stop  chan struct{}
+		done  chan struct{}
+	}
+)
+
+// NewAggregator returns an Aggregator calling flush with the usage collected every interval.
+// The flush function is not called when there is no usage.
+func NewAggregator(interval time.Duration, flush func(map[Key]Usage)) *Aggregator {
+	a := &Aggregator{
+		usage: make(map[Key]Usage),
+		flush: flush,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go a.run(interval)
+	return a
+}
+
+// Record adds the bytes of a response to the usage. It has the signature of a makross.ResponseFunc.
+func (a *Aggregator) Record(route string, tenant string, bytes int64, status int) {
+	key := Key{Route: route, Tenant: tenant}
+	a.mu.Lock()
+	u := a.usage[key]
+	u.Bytes += bytes
+	u.Responses++
+	a.usage[key] = u
+	a.mu.Unlock()
+}
+
+// Flush hands the usage collected since the last flush to the flush function.
+func (a *Aggregator) Flush() {
+	a.mu.Lock()
+	usage := a.usage
+	a.usage = make(map[Key]Usage)
+	a.mu.Unlock()
+	if len(usage) > 0 {
+		a.flush(usage)
+	}
+}
+
+// Stop stops the periodic flushes and flushes the remaining usage.
+func (a *Aggregator) Stop() {
+	close(a.stop)
+	<-a.done
+	a.Flush()
+}
+
+func (a *Aggregator) run(interval time.Duration) {
+	defer close(a.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.Flush()
+		case <-a.stop:
+			return
+		}
+	}
+}
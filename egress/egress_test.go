@@ -0,0 +1,45 @@
+package egress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregator(t *testing.T) {
+	var flushed []map[Key]Usage
+	a := NewAggregator(time.Hour, func(usage map[Key]Usage) {
+		flushed = append(flushed, usage)
+	})
+	a.Record("users", "acme", 100, 200)
+	a.Record("users", "acme", 50, 500)
+	a.Record("users", "globex", 10, 200)
+	a.Flush()
+	a.Flush()
+	a.Record("files", "acme", 7, 200)
+	a.Stop()
+
+	if assert.Len(t, flushed, 2) {
+		assert.Equal(t, map[Key]Usage{
+			{"users", "acme"}:   {Bytes: 150, Responses: 2},
+			{"users", "globex"}: {Bytes: 10, Responses: 1},
+		}, flushed[0])
+		assert.Equal(t, map[Key]Usage{{"files", "acme"}: {Bytes: 7, Responses: 1}}, flushed[1])
+	}
+}
+
+func TestAggregatorInterval(t *testing.T) {
+	flushed := make(chan map[Key]Usage, 1)
+	a := NewAggregator(10*time.Millisecond, func(usage map[Key]Usage) {
+		flushed <- usage
+	})
+	defer a.Stop()
+	a.Record("users", "", 1, 200)
+	select {
+	case usage := <-flushed:
+		assert.Equal(t, int64(1), usage[Key{Route: "users"}].Bytes)
+	case <-time.After(time.Second):
+		t.Error("the usage should be flushed periodically")
+	}
+}
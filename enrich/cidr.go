@@ -0,0 +1,59 @@
+package enrich
+
+import (
+	"context"
+	"net"
+	"sort"
+)
+
+type (
+	// CIDRMap is an Enricher mapping networks to their attributes, e.g. for the private networks
+	// of an office or for tests.
+	CIDRMap struct {
+		networks []cidrNetwork // most specific first
+	}
+
+	cidrNetwork struct {
+		network *net.IPNet
+		size    int
+		fields  map[string]interface{}
+	}
+)
+
+// NewCIDRMap returns a CIDRMap of the networks in the CIDR notation, e.g.
+// {"10.0.0.0/8": {"country": "internal"}}. An IP in several networks gets the attributes of the most specific one.
+func NewCIDRMap(networks map[string]map[string]interface{}) (*CIDRMap, error) {
+	m := new(CIDRMap)
+	for cidr, fields := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ones, bits := network.Mask.Size()
+		// compare the IPv4 and IPv6 networks by their host bits
+		m.networks = append(m.networks, cidrNetwork{network: network, size: bits - ones, fields: fields})
+	}
+	sort.Slice(m.networks, func(i, j int) bool {
+		return m.networks[i].size < m.networks[j].size
+	})
+	return m, nil
+}
+
+// Enrich implements Enricher. It returns a copy of the attributes of the network of the IP,
+// or nil if it's in none of them.
+func (m *CIDRMap) Enrich(ctx context.Context, ip string) (map[string]interface{}, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, nil
+	}
+	for _, n := range m.networks {
+		if n.network.Contains(addr) {
+			fields := make(map[string]interface{}, len(n.fields))
+			for k, v := range n.fields {
+				fields[k] = v
+			}
+			return fields, nil
+		}
+	}
+	return nil, nil
+}
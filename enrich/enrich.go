@@ -0,0 +1,155 @@
+package enrich
+
+import (
+	"context"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// Enricher looks up the attributes of a client IP, such as its country or ASN.
+	Enricher interface {
+		Enrich(ctx context.Context, ip string) (map[string]interface{}, error)
+	}
+
+	// EnrichConfig defines the config for Enrich middleware.
+	EnrichConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Enricher looks up the attributes of the client IPs.
+		// Required.
+		Enricher Enricher
+
+		// Namespace is the key of the attributes in the data store of the context.
+		// Optional. Default value "enrich".
+		Namespace string `json:"namespace"`
+
+		// Timeout bounds the lookups. The requests go on without attributes when it's reached.
+		// Optional. Default value 100ms.
+		Timeout time.Duration `json:"timeout"`
+
+		// CacheSize is the number of IPs whose attributes are cached.
+		// Optional. Default value 10000.
+		CacheSize int `json:"cache_size"`
+
+		// TTL is the time the attributes of an IP are cached.
+		// Optional. Default value 1h.
+		TTL time.Duration `json:"ttl"`
+
+		// FailureTTL is the time a failed lookup is cached, so that a failing service isn't hammered.
+		// Optional. Default value 1m.
+		FailureTTL time.Duration `json:"failure_ttl"`
+
+		// IPFunc returns the client IP of the request.
+		// Optional. Default value Context.RealIP.
+		IPFunc func(*makross.Context) string
+	}
+
+	result struct {
+		fields map[string]interface{}
+		err    error
+	}
+)
+
+var (
+	// DefaultEnrichConfig is the default Enrich middleware config.
+	DefaultEnrichConfig = EnrichConfig{
+		Skipper:    skipper.DefaultSkipper,
+		Namespace:  "enrich",
+		Timeout:    100 * time.Millisecond,
+		CacheSize:  10000,
+		TTL:        time.Hour,
+		FailureTTL: time.Minute,
+		IPFunc: func(c *makross.Context) string {
+			return c.RealIP()
+		},
+	}
+)
+
+// Enrich returns an Enrich middleware.
+//
+// Enrich middleware looks up the attributes of the client IP with the enricher and stores them in the
+// data store of the context under the namespace, e.g. {{.enrich.country}} in a template and
+// ${store:enrich.country} in the logger format.
+//
+// The attributes are cached per IP and shared by its requests, so they must not be modified.
+// A failed or slow lookup is logged and the request goes on without attributes.
+func Enrich(enricher Enricher) makross.Handler {
+	c := DefaultEnrichConfig
+	c.Enricher = enricher
+	return EnrichWithConfig(c)
+}
+
+// EnrichWithConfig returns an Enrich middleware with config.
+// See: `Enrich()`.
+func EnrichWithConfig(config EnrichConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultEnrichConfig.Skipper
+	}
+	if config.Namespace == "" {
+		config.Namespace = DefaultEnrichConfig.Namespace
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultEnrichConfig.Timeout
+	}
+	if config.CacheSize == 0 {
+		config.CacheSize = DefaultEnrichConfig.CacheSize
+	}
+	if config.TTL == 0 {
+		config.TTL = DefaultEnrichConfig.TTL
+	}
+	if config.FailureTTL == 0 {
+		config.FailureTTL = DefaultEnrichConfig.FailureTTL
+	}
+	if config.IPFunc == nil {
+		config.IPFunc = DefaultEnrichConfig.IPFunc
+	}
+	if config.Enricher == nil {
+		panic("enrich: enricher is required")
+	}
+	cache := newLRU(config.CacheSize)
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		ip := config.IPFunc(c)
+		fields, ok := cache.get(ip)
+		if !ok {
+			var err error
+			fields, err = lookup(c, config.Enricher, ip, config.Timeout)
+			if err != nil {
+				c.Makross().Logger().Warnf("enrich: %s: %v", ip, err)
+				cache.add(ip, nil, config.FailureTTL)
+			} else {
+				cache.add(ip, fields, config.TTL)
+			}
+		}
+		if len(fields) > 0 {
+			c.Set(config.Namespace, fields)
+		}
+		return c.Next()
+	}
+}
+
+// lookup calls the enricher, giving up after the timeout even if the enricher ignores its context.
+func lookup(c *makross.Context, e Enricher, ip string, timeout time.Duration) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		fields, err := e.Enrich(ctx, ip)
+		done <- result{fields, err}
+	}()
+	select {
+	case r := <-done:
+		return r.fields, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,157 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCIDRMap(t *testing.T) {
+	m, err := NewCIDRMap(map[string]map[string]interface{}{
+		"10.0.0.0/8":     {"country": "internal"},
+		"10.1.0.0/16":    {"country": "internal", "office": "paris"},
+		"203.0.113.0/24": {"country": "FR", "asn": 64500},
+		"2001:db8::/32":  {"country": "DE"},
+	})
+	assert.Nil(t, err)
+	tests := []struct {
+		ip     string
+		fields map[string]interface{}
+	}{
+		{"10.2.3.4", map[string]interface{}{"country": "internal"}},
+		{"10.1.3.4", map[string]interface{}{"country": "internal", "office": "paris"}},
+		{"203.0.113.9", map[string]interface{}{"country": "FR", "asn": 64500}},
+		{"2001:db8::1", map[string]interface{}{"country": "DE"}},
+		{"192.0.2.1", nil},
+		{"not an ip", nil},
+	}
+	for _, test := range tests {
+		fields, err := m.Enrich(context.Background(), test.ip)
+		assert.Nil(t, err, test.ip)
+		assert.Equal(t, test.fields, fields, test.ip)
+	}
+
+	_, err = NewCIDRMap(map[string]map[string]interface{}{"10.0.0.0/33": nil})
+	assert.NotNil(t, err)
+}
+
+type countingEnricher struct {
+	Enricher
+	calls int32
+}
+
+func (e *countingEnricher) Enrich(ctx context.Context, ip string) (map[string]interface{}, error) {
+	atomic.AddInt32(&e.calls, 1)
+	return e.Enricher.Enrich(ctx, ip)
+}
+
+func TestEnrich(t *testing.T) {
+	cidr, _ := NewCIDRMap(map[string]map[string]interface{}{
+		"203.0.113.0/24": {"country": "FR", "asn": 64500},
+	})
+	e := &countingEnricher{Enricher: cidr}
+	buf := new(bytes.Buffer)
+	tmpl := template.Must(template.New("t").Parse(`{{.enrich.country}}`))
+
+	m := makross.New()
+	m.Use(
+		logger.LoggerWithConfig(logger.LoggerConfig{Format: "${store:enrich.country} ${store:enrich.asn}\n", Output: buf}),
+		Enrich(e),
+	)
+	m.Get("/", func(c *makross.Context) error {
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, c.GetStore()); err != nil {
+			return err
+		}
+		return c.String(out.String())
+	})
+
+	request := func(ip string) string {
+		req := httptest.NewRequest(makross.GET, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, makross.StatusOK, res.Code)
+		return res.Body.String()
+	}
+
+	assert.Equal(t, "FR", request("203.0.113.7"))
+	assert.Equal(t, "FR", request("203.0.113.7"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&e.calls))
+	assert.Equal(t, "FR 64500\nFR 64500\n", buf.String())
+
+	assert.Equal(t, "<no value>", request("192.0.2.1"))
+}
+
+type slowEnricher struct{}
+
+func (slowEnricher) Enrich(ctx context.Context, ip string) (map[string]interface{}, error) {
+	// ignores the context
+	time.Sleep(time.Second)
+	return map[string]interface{}{"country": "FR"}, nil
+}
+
+type failingEnricher struct {
+	calls int32
+}
+
+func (e *failingEnricher) Enrich(ctx context.Context, ip string) (map[string]interface{}, error) {
+	atomic.AddInt32(&e.calls, 1)
+	return nil, errors.New("service unavailable")
+}
+
+func TestEnrichFailures(t *testing.T) {
+	failing := new(failingEnricher)
+	for _, enricher := range []Enricher{slowEnricher{}, failing} {
+		m := makross.New()
+		m.Use(EnrichWithConfig(EnrichConfig{Enricher: enricher, Namespace: "geo", Timeout: 10 * time.Millisecond}))
+		m.Get("/", func(c *makross.Context) error {
+			assert.Nil(t, c.Get("geo"))
+			return c.String("ok")
+		})
+		for i := 0; i < 2; i++ {
+			start := time.Now()
+			res := httptest.NewRecorder()
+			m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/", nil))
+			assert.Equal(t, makross.StatusOK, res.Code)
+			assert.True(t, time.Since(start) < 500*time.Millisecond)
+		}
+	}
+	// the failures are cached too
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failing.calls))
+
+	assert.Panics(t, func() {
+		EnrichWithConfig(EnrichConfig{})
+	})
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	c := newLRU(2)
+	c.now = func() time.Time { return now }
+	c.add("a", map[string]interface{}{"n": 1}, time.Minute)
+	c.add("b", map[string]interface{}{"n": 2}, time.Minute)
+	_, ok := c.get("a")
+	assert.True(t, ok)
+	// b is the least recently used
+	c.add("c", map[string]interface{}{"n": 3}, time.Minute)
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	fields, ok := c.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, fields["n"])
+
+	now = now.Add(time.Minute)
+	_, ok = c.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.order.Len())
+}
@@ -0,0 +1,69 @@
+package enrich
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type (
+	// lru is a least recently used cache whose entries expire.
+	lru struct {
+		size    int
+		mu      sync.Mutex
+		entries map[string]*list.Element
+		order   *list.List // most recently used first
+		now     func() time.Time
+	}
+
+	lruEntry struct {
+		key     string
+		fields  map[string]interface{}
+		expires time.Time
+	}
+)
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// get returns the fields cached for the key, if they haven't expired.
+func (c *lru) get(key string) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.fields, true
+}
+
+// add caches the fields for the key during ttl, evicting the least recently used entry if the cache is full.
+func (c *lru) add(key string, fields map[string]interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &lruEntry{key: key, fields: fields, expires: c.now().Add(ttl)}
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -3,7 +3,10 @@ package makross
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // Errors
@@ -15,9 +18,16 @@ var (
 	ErrForbidden                   = NewHTTPError(http.StatusForbidden)
 	ErrMethodNotAllowed            = NewHTTPError(StatusMethodNotAllowed)
 	ErrStatusRequestEntityTooLarge = NewHTTPError(StatusRequestEntityTooLarge)
+	ErrStatusTooManyRequests       = NewHTTPError(StatusTooManyRequests)
+	ErrServiceUnavailable          = NewHTTPError(StatusServiceUnavailable)
+	ErrRangeNotSatisfiable         = NewHTTPError(StatusRequestedRangeNotSatisfiable)
 	ErrRendererNotRegistered       = errors.New("renderer not registered")
 	ErrInvalidRedirectCode         = errors.New("invalid redirect status code")
 	ErrCookieNotFound              = errors.New("cookie not found")
+	ErrCookieSignature             = errors.New("invalid cookie signature")
+	ErrServerClosing               = errors.New("server closing")
+	ErrResponseAlreadyCommitted    = errors.New("response already committed")
+	ErrHijackNotSupported          = errors.New("response writer doesn't support hijacking")
 )
 
 // Error contains the error information reported by calling Context.Error().
@@ -25,6 +35,12 @@ var (
 type HTTPError struct {
 	Status  int    //`json:"status" xml:"status"`
 	Message string //`json:"message" xml:"message"`
+	Err     error  `json:"-"` // the cause, e.g. the BindError of a binding failure
+
+	// RetryAfter and RetryAt are sent in the Retry-After header of the response, as a number of
+	// seconds and as an HTTP-date respectively. RetryAt takes precedence.
+	RetryAfter time.Duration `json:"-"`
+	RetryAt    time.Time     `json:"-"`
 }
 
 // NewHTTPError creates a new HTTPError instance.
@@ -36,6 +52,18 @@ func NewHTTPError(status int, message ...interface{}) *HTTPError {
 	return he
 }
 
+// NewTooManyRequestsError creates a "429 - Too Many Requests" HTTPError, telling the client
+// to retry after the duration, if positive. It wraps ErrStatusTooManyRequests.
+func NewTooManyRequestsError(retryAfter time.Duration) *HTTPError {
+	return &HTTPError{Status: StatusTooManyRequests, Message: StatusText(StatusTooManyRequests), Err: ErrStatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+// NewServiceUnavailableError creates a "503 - Service Unavailable" HTTPError, telling the client
+// to retry after the duration, if positive. It wraps ErrServiceUnavailable.
+func NewServiceUnavailableError(retryAfter time.Duration) *HTTPError {
+	return &HTTPError{Status: StatusServiceUnavailable, Message: StatusText(StatusServiceUnavailable), Err: ErrServiceUnavailable, RetryAfter: retryAfter}
+}
+
 // Error returns the error message.
 func (e *HTTPError) Error() string {
 	return e.Message
@@ -45,3 +73,36 @@ func (e *HTTPError) Error() string {
 func (e *HTTPError) StatusCode() int {
 	return e.Status
 }
+
+// Unwrap returns the cause, for errors.Is and errors.As.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterHeader returns the Retry-After header of the error, or "" if it has none.
+func (e *HTTPError) RetryAfterHeader() string {
+	if !e.RetryAt.IsZero() {
+		return e.RetryAt.UTC().Format(http.TimeFormat)
+	}
+	if e.RetryAfter > 0 {
+		return FormatRetryAfter(e.RetryAfter)
+	}
+	return ""
+}
+
+// retryAfterSeconds returns the number of seconds to wait before retrying, or 0 if the error has no Retry-After.
+func (e *HTTPError) retryAfterSeconds() int {
+	d := e.RetryAfter
+	if !e.RetryAt.IsZero() {
+		d = time.Until(e.RetryAt)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// FormatRetryAfter formats the duration as a Retry-After header, in seconds rounded up.
+func FormatRetryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -2,7 +2,11 @@ package makross
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,3 +23,73 @@ func TestNewHttpError(t *testing.T) {
 	s, _ := json.Marshal(e)
 	assert.Equal(t, `{"Status":404,"Message":"abc"}`, string(s))
 }
+
+func TestHTTPErrorRetryAfter(t *testing.T) {
+	e := NewTooManyRequestsError(1500 * time.Millisecond)
+	assert.Equal(t, StatusTooManyRequests, e.StatusCode())
+	assert.Equal(t, StatusText(StatusTooManyRequests), e.Error())
+	assert.Equal(t, "2", e.RetryAfterHeader())
+
+	assert.True(t, errors.Is(e, ErrStatusTooManyRequests))
+	assert.False(t, errors.Is(e, ErrServiceUnavailable))
+
+	e = NewServiceUnavailableError(0)
+	assert.Equal(t, StatusServiceUnavailable, e.StatusCode())
+	assert.True(t, errors.Is(e, ErrServiceUnavailable))
+	assert.Equal(t, "", e.RetryAfterHeader())
+
+	at := time.Date(2030, time.January, 2, 15, 4, 5, 0, time.FixedZone("CET", 3600))
+	e.RetryAt = at
+	assert.Equal(t, "Wed, 02 Jan 2030 14:04:05 GMT", e.RetryAfterHeader())
+	parsed, err := http.ParseTime(e.RetryAfterHeader())
+	assert.Nil(t, err)
+	assert.True(t, at.Equal(parsed))
+
+	m := New()
+	m.To("GET,HEAD", "/seconds", func(c *Context) error {
+		return NewTooManyRequestsError(30 * time.Second)
+	})
+	m.Get("/date", func(c *Context) error {
+		e := NewServiceUnavailableError(0)
+		e.RetryAt = time.Now().Add(time.Hour)
+		return e
+	})
+	m.Get("/none", func(c *Context) error {
+		return NewServiceUnavailableError(0)
+	})
+	serve := func(method, path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if accept != "" {
+			req.Header.Set(HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(GET, "/seconds", MIMEApplicationJSON)
+	assert.Equal(t, StatusTooManyRequests, res.Code)
+	assert.Equal(t, "30", res.Header().Get(HeaderRetryAfter))
+	assert.Equal(t, `{"message":"Too Many Requests","retry_after":30,"status":429}`, res.Body.String())
+
+	res = serve(GET, "/seconds", "")
+	assert.Equal(t, "30", res.Header().Get(HeaderRetryAfter))
+	assert.Equal(t, "Too Many Requests", res.Body.String())
+
+	res = serve(HEAD, "/seconds", "")
+	assert.Equal(t, StatusTooManyRequests, res.Code)
+	assert.Equal(t, "30", res.Header().Get(HeaderRetryAfter))
+
+	res = serve(GET, "/date", MIMEApplicationJSON)
+	assert.Equal(t, StatusServiceUnavailable, res.Code)
+	retryAt, err := http.ParseTime(res.Header().Get(HeaderRetryAfter))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), retryAt, 2*time.Second)
+	var body map[string]interface{}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.InDelta(t, 3600, body["retry_after"], 2)
+
+	res = serve(GET, "/none", MIMEApplicationJSON)
+	assert.Equal(t, "", res.Header().Get(HeaderRetryAfter))
+	assert.Equal(t, `{"message":"Service Unavailable","status":503}`, res.Body.String())
+}
@@ -0,0 +1,266 @@
+package experiment
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/libraries/gommon/random"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// ExperimentsConfig defines the config for Experiments middleware.
+	ExperimentsConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Experiments are the running experiments.
+		// Required.
+		Experiments []Experiment `json:"experiments"`
+
+		// Keys sign the visitor cookie, the newest first, see makross.Context.SetSignedCookie.
+		// Required.
+		Keys [][]byte `json:"-"`
+
+		// CookieName is the name of the visitor cookie, holding the visitor ID and its buckets.
+		// Optional. Default value "_experiments".
+		CookieName string `json:"cookie_name"`
+
+		// CookieMaxAge is the lifetime of the visitor cookie, renewed when a bucket is assigned.
+		// Optional. Default value one year.
+		CookieMaxAge time.Duration `json:"cookie_max_age"`
+
+		// ForceParam is the query parameter forcing the bucket of an experiment for the request,
+		// e.g. "?experiment=checkout:b", honored in the debug mode of the application only.
+		// Optional. Default value "experiment".
+		ForceParam string `json:"force_param"`
+
+		// Generator generates the visitor IDs.
+		// Optional. Default value random.String(32).
+		Generator func() string
+	}
+
+	// Experiment is a named experiment, with its buckets.
+	Experiment struct {
+		Name    string   `json:"name"`
+		Buckets []Bucket `json:"buckets"`
+	}
+
+	// Bucket is a variant of an experiment, assigned to a share of the visitors proportional to its weight.
+	// A bucket of weight 0 is closed: no visitor is assigned to it any longer, but the visitors already
+	// in it stay in it.
+	Bucket struct {
+		Name   string `json:"name"`
+		Weight int    `json:"weight"`
+	}
+)
+
+// Key is the context data key of the buckets of the visitor, a map[string]interface{} of the bucket names
+// by experiment name, e.g. for the "${store:experiment.checkout}" tag of the logger. See `Assigned()`.
+const Key = "experiment"
+
+var (
+	// DefaultExperimentsConfig is the default Experiments middleware config.
+	DefaultExperimentsConfig = ExperimentsConfig{
+		Skipper:      skipper.DefaultSkipper,
+		CookieName:   "_experiments",
+		CookieMaxAge: 365 * 24 * time.Hour,
+		ForceParam:   "experiment",
+	}
+)
+
+// Experiments returns an Experiments middleware running the experiments, with the cookie signing keys.
+//
+// Experiments middleware assigns a bucket of each experiment to the visitors on their first visit,
+// server side so that the pages don't flicker, and keeps it in a signed cookie with the visitor ID,
+// so that the visitors stay in their buckets when the weights change. The bucket of a new visitor
+// is drawn from the hash of its ID by weighted rendezvous hashing: the same visitor ID always gets
+// the same bucket for the same weights, and a weight change only moves the new visitors in or out
+// of the changed buckets.
+//
+// The buckets are stored in the context data under Key, returned by Assigned and BucketOf, which is also
+// a template function, and added to the fields of the logger of the request, see makross.Context.Logger:
+//
+//	m.Use(experiment.Experiments(keys, experiment.Experiment{
+//		Name:    "checkout",
+//		Buckets: []experiment.Bucket{{Name: "control", Weight: 90}, {Name: "one-page", Weight: 10}},
+//	}))
+//	m.AddTemplateFunc("bucket", experiment.BucketOf) // {{if eq (bucket .Ctx "checkout") "one-page"}}
+func Experiments(keys [][]byte, experiments ...Experiment) makross.Handler {
+	c := DefaultExperimentsConfig
+	c.Keys = keys
+	c.Experiments = experiments
+	return ExperimentsWithConfig(c)
+}
+
+// ExperimentsWithConfig returns an Experiments middleware with config.
+// See: `Experiments()`.
+func ExperimentsWithConfig(config ExperimentsConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultExperimentsConfig.Skipper
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultExperimentsConfig.CookieName
+	}
+	if config.CookieMaxAge == 0 {
+		config.CookieMaxAge = DefaultExperimentsConfig.CookieMaxAge
+	}
+	if config.ForceParam == "" {
+		config.ForceParam = DefaultExperimentsConfig.ForceParam
+	}
+	if config.Generator == nil {
+		config.Generator = generator
+	}
+	if len(config.Keys) == 0 {
+		panic("experiment: cookie signing keys required")
+	}
+	if len(config.Experiments) == 0 {
+		panic("experiment: experiments required")
+	}
+	names := make(map[string]bool, len(config.Experiments))
+	for _, e := range config.Experiments {
+		validate(e, names)
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		var (
+			visitor string
+			stored  url.Values
+		)
+		if cookie, err := c.GetSignedCookie(config.CookieName, config.Keys); err == nil {
+			if values, err := url.ParseQuery(cookie.Value); err == nil && values.Get("v") != "" {
+				visitor, stored = values.Get("v"), values
+			}
+		}
+		changed := false
+		if visitor == "" {
+			visitor = config.Generator()
+			stored = url.Values{"v": {visitor}}
+			changed = true
+		}
+
+		assigned := make(map[string]interface{}, len(config.Experiments))
+		fields := make(map[string]interface{}, len(config.Experiments))
+		for _, e := range config.Experiments {
+			bucket := stored.Get(e.Name)
+			if !e.has(bucket) {
+				bucket = Assign(e, visitor)
+				stored.Set(e.Name, bucket)
+				changed = true
+			}
+			assigned[e.Name] = bucket
+		}
+		if changed {
+			if err := c.SetSignedCookie(&http.Cookie{
+				Name:     config.CookieName,
+				Value:    stored.Encode(),
+				Path:     "/",
+				MaxAge:   int(config.CookieMaxAge / time.Second),
+				Secure:   c.IsTLS(),
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			}, config.Keys); err != nil {
+				return err
+			}
+		}
+		if c.Makross().Debug {
+			// forced for the request only
+			for _, force := range c.Request.URL.Query()[config.ForceParam] {
+				name, bucket, _ := strings.Cut(force, ":")
+				for _, e := range config.Experiments {
+					if e.Name == name && e.has(bucket) {
+						assigned[name] = bucket
+					}
+				}
+			}
+		}
+		for name, bucket := range assigned {
+			fields[Key+"."+name] = bucket
+		}
+		c.Set(Key, assigned)
+		c.SetLogger(c.Logger().With(fields))
+		return c.Next()
+	}
+}
+
+// Assigned returns the buckets of the visitor by experiment name.
+func Assigned(c *makross.Context) map[string]string {
+	assigned, _ := c.Get(Key).(map[string]interface{})
+	buckets := make(map[string]string, len(assigned))
+	for name, bucket := range assigned {
+		buckets[name], _ = bucket.(string)
+	}
+	return buckets
+}
+
+// BucketOf returns the bucket of the visitor in the named experiment, or "" if there is no such experiment.
+// It serves as a template function too, taking the context, found under the Ctx key of the
+// template data, see `Experiments()`.
+func BucketOf(c *makross.Context, experiment string) string {
+	assigned, _ := c.Get(Key).(map[string]interface{})
+	bucket, _ := assigned[experiment].(string)
+	return bucket
+}
+
+// Assign returns the bucket of the experiment of a new visitor, drawn from the hash of the visitor ID
+// by weighted rendezvous hashing: each open bucket scores -weight / ln(h), h being the SHA-256 hash of the
+// experiment, the bucket and the visitor mapped to (0, 1), and the highest score wins.
+func Assign(e Experiment, visitor string) string {
+	best, bestScore := "", -1.0
+	for _, b := range e.Buckets {
+		if b.Weight <= 0 {
+			continue
+		}
+		sum := sha256.Sum256([]byte(e.Name + "\x00" + b.Name + "\x00" + visitor))
+		u := (float64(binary.BigEndian.Uint64(sum[:])>>11) + 0.5) / (1 << 53)
+		if score := -float64(b.Weight) / math.Log(u); score > bestScore {
+			best, bestScore = b.Name, score
+		}
+	}
+	return best
+}
+
+// has reports whether the experiment has the bucket.
+func (e Experiment) has(bucket string) bool {
+	for _, b := range e.Buckets {
+		if b.Name == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// validate panics if the experiment is invalid, or its name is already in names.
+func validate(e Experiment, names map[string]bool) {
+	if e.Name == "" || e.Name == "v" || names[e.Name] {
+		panic("experiment: invalid or duplicate experiment name " + e.Name)
+	}
+	names[e.Name] = true
+	open := false
+	buckets := make(map[string]bool, len(e.Buckets))
+	for _, b := range e.Buckets {
+		if b.Name == "" || buckets[b.Name] || b.Weight < 0 {
+			panic("experiment: invalid bucket " + b.Name + " of experiment " + e.Name)
+		}
+		buckets[b.Name] = true
+		open = open || b.Weight > 0
+	}
+	if !open {
+		panic("experiment: experiment " + e.Name + " has no bucket of positive weight")
+	}
+}
+
+func generator() string {
+	return random.String(32)
+}
@@ -0,0 +1,161 @@
+package experiment
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+var testKeys = [][]byte{[]byte("secret")}
+
+func checkout(control, onePage int) Experiment {
+	return Experiment{Name: "checkout", Buckets: []Bucket{{"control", control}, {"one-page", onePage}}}
+}
+
+func TestAssign(t *testing.T) {
+	// pins the assignment algorithm: changing it would move the visitors without cookie
+	tests := []struct {
+		visitor      string
+		even, ninety string
+	}{
+		{"visitor-1", "one-page", "control"},
+		{"visitor-2", "control", "control"},
+		{"visitor-3", "control", "control"},
+		{"visitor-4", "one-page", "control"},
+		{"visitor-5", "control", "control"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.even, Assign(checkout(50, 50), test.visitor), test.visitor)
+		assert.Equal(t, test.ninety, Assign(checkout(90, 10), test.visitor), test.visitor)
+	}
+	assert.Equal(t, "control", Assign(checkout(1, 0), "visitor-1"))
+
+	// the buckets get shares proportional to their weights, and a weight change
+	// only moves visitors into the bucket whose weight increased
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		visitor := "visitor-" + strconv.Itoa(i)
+		before, after := Assign(checkout(50, 50), visitor), Assign(checkout(90, 10), visitor)
+		counts[after]++
+		if before == "control" {
+			assert.Equal(t, "control", after, visitor)
+		}
+	}
+	assert.InDelta(t, 9000, counts["control"], 300)
+	assert.InDelta(t, 1000, counts["one-page"], 300)
+}
+
+func TestExperiments(t *testing.T) {
+	newMakross := func(e Experiment) *makross.Makross {
+		m := makross.New()
+		m.Use(ExperimentsWithConfig(ExperimentsConfig{
+			Experiments: []Experiment{e, {Name: "hero", Buckets: []Bucket{{"red", 1}}}},
+			Keys:        testKeys,
+			Generator: func() string {
+				return "visitor-1"
+			},
+		}))
+		m.Get("/", func(c *makross.Context) error {
+			c.Logger().Infof("served")
+			return c.String(BucketOf(c, "checkout") + " " + BucketOf(c, "hero") + " " + BucketOf(c, "none"))
+		})
+		return m
+	}
+	serve := func(m *makross.Makross, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(makross.GET, path, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+	cookieOf := func(res *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range res.Result().Cookies() {
+			if cookie.Name == "_experiments" {
+				return cookie
+			}
+		}
+		return nil
+	}
+
+	// the first visit assigns the buckets and sets the cookie
+	m := newMakross(checkout(50, 50))
+	logs := new(bytes.Buffer)
+	m.SetLogger(makross.NewLogger(logs))
+	res := serve(m, "/")
+	assert.Equal(t, "one-page red ", res.Body.String())
+	cookie := cookieOf(res)
+	if assert.NotNil(t, cookie) {
+		assert.True(t, cookie.HttpOnly)
+		assert.Equal(t, 365*24*3600, cookie.MaxAge)
+	}
+	assert.Contains(t, logs.String(), "served experiment.checkout=one-page experiment.hero=red")
+
+	// the visitor keeps its bucket, without setting the cookie again
+	res = serve(m, "/", cookie)
+	assert.Equal(t, "one-page red ", res.Body.String())
+	assert.Nil(t, cookieOf(res))
+
+	// when the weights change, and even when its bucket is closed
+	res = serve(newMakross(checkout(1, 0)), "/", cookie)
+	assert.Equal(t, "one-page red ", res.Body.String())
+
+	// but not when its bucket is removed
+	res = serve(newMakross(Experiment{Name: "checkout", Buckets: []Bucket{{"control", 1}, {"wizard", 1}}}), "/", cookie)
+	assert.Equal(t, "wizard red ", res.Body.String())
+	assert.NotNil(t, cookieOf(res))
+
+	// a tampered cookie is ignored
+	tampered := *cookie
+	tampered.Value = strings.Replace(tampered.Value, ".", "x.", 1)
+	res = serve(newMakross(checkout(1, 0)), "/", &tampered)
+	assert.Equal(t, "control red ", res.Body.String())
+
+	// the bucket can be forced in debug mode only, for the request only
+	res = serve(m, "/?experiment=checkout:control", cookie)
+	assert.Equal(t, "one-page red ", res.Body.String())
+	m.Debug = true
+	res = serve(m, "/?experiment=checkout:control&experiment=hero:blue", cookie)
+	assert.Equal(t, "control red ", res.Body.String())
+	assert.Nil(t, cookieOf(res))
+}
+
+func TestBucketOfTemplate(t *testing.T) {
+	m := makross.New()
+	m.AddTemplateFunc("bucket", BucketOf)
+	tmpl := template.Must(template.New("page").Funcs(m.TemplateFuncs()).Parse(
+		`{{if eq (bucket .Ctx "checkout") "one-page"}}one page{{else}}steps{{end}}`))
+	m.Use(Experiments(testKeys, checkout(0, 1)))
+	m.Get("/", func(c *makross.Context) error {
+		assert.Equal(t, map[string]string{"checkout": "one-page"}, Assigned(c))
+		buf := new(bytes.Buffer)
+		// the data of the renderers
+		if err := tmpl.Execute(buf, c.TemplateData()); err != nil {
+			return err
+		}
+		return c.String(buf.String())
+	})
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/", nil))
+	assert.Equal(t, "one page", res.Body.String())
+}
+
+func TestExperimentsConfig(t *testing.T) {
+	assert.Panics(t, func() { Experiments(nil, checkout(1, 1)) })
+	assert.Panics(t, func() { Experiments(testKeys) })
+	assert.Panics(t, func() { Experiments(testKeys, checkout(0, 0)) })
+	assert.Panics(t, func() { Experiments(testKeys, checkout(-1, 1)) })
+	assert.Panics(t, func() { Experiments(testKeys, checkout(1, 1), checkout(1, 1)) })
+	assert.Panics(t, func() {
+		Experiments(testKeys, Experiment{Name: "e", Buckets: []Bucket{{"a", 1}, {"a", 1}}})
+	})
+	assert.NotPanics(t, func() { Experiments(testKeys, checkout(1, 0)) })
+}
@@ -12,6 +12,7 @@ import (
 
 // PanicHandler returns a handler that recovers from panics happened in the handlers following this one.
 // When a panic is recovered, it will be converted into an error and returned to the parent handlers.
+// The panic is also passed to the error reporters registered with makross.Makross.OnError.
 //
 // A log function can be provided to log the panic call stack information. If the log function is nil,
 // no message will be logged.
@@ -29,13 +30,15 @@ func PanicHandler(logf LogFunc) makross.Handler {
 	return func(c *makross.Context) (err error) {
 		defer func() {
 			if e := recover(); e != nil {
+				stack := getCallStack(4)
 				if logf != nil {
-					logf("recovered from panic:%v", getCallStack(4))
+					logf("recovered from panic:%v", stack)
 				}
 				var ok bool
 				if err, ok = e.(error); !ok {
 					err = fmt.Errorf("%v", e)
 				}
+				c.ReportError(err, []byte(stack))
 			}
 		}()
 
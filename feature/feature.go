@@ -0,0 +1,206 @@
+package feature
+
+import (
+	ktx "context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// FlagChecker tells whether a feature flag is enabled for a request. The context is passed
+	// so that a percentage rollout can hash a stable key of the client, such as its user ID or IP.
+	FlagChecker interface {
+		Enabled(ctx ktx.Context, flagName string, c *makross.Context) bool
+	}
+
+	// FlagConfig defines the config for Flag middleware.
+	FlagConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Checker tells whether the flags of the routes are enabled.
+		// Required.
+		Checker FlagChecker
+
+		// MetaKey is the route metadata key of the flag name of a route, a string,
+		// or of its flag names, a []string, which must all be enabled.
+		// Optional. Default value "flag".
+		MetaKey string `json:"meta_key"`
+
+		// Status is the status code of the responses of the disabled routes.
+		// Optional. Default value 404, as if the routes didn't exist.
+		Status int `json:"status"`
+
+		// Message is the message of the responses of the disabled routes.
+		// Optional. Default value the status text.
+		Message string `json:"message"`
+	}
+
+	// MemoryChecker is a FlagChecker keeping the flags in memory, which can be toggled at runtime,
+	// for the tests and the simple deployments.
+	MemoryChecker struct {
+		mu    sync.RWMutex
+		flags map[string]memoryFlag
+	}
+
+	memoryFlag struct {
+		percent int
+		key     func(c *makross.Context) string
+	}
+)
+
+// Key is the store key of the evaluated flags of the request, a map[string]bool, e.g. for logging.
+const Key = "flag.evaluations"
+
+var (
+	// DefaultFlagConfig is the default Flag middleware config.
+	DefaultFlagConfig = FlagConfig{
+		Skipper: skipper.DefaultSkipper,
+		MetaKey: "flag",
+		Status:  makross.StatusNotFound,
+	}
+)
+
+// Flag returns a Flag middleware with the checker.
+//
+// Flag middleware gates the routes behind feature flags, named by their metadata: the routes whose
+// flag is disabled respond "404 - Not Found", as if they didn't exist, and the routes without flag
+// go through. The evaluated flags are stored in the context under Key.
+//
+//	flags := feature.NewMemoryChecker()
+//	flags.SetPercentage("new-checkout", 10, nil)
+//	m.Use(feature.Flag(flags))
+//	m.Post("/checkout/v2", checkout).Meta("flag", "new-checkout")
+func Flag(checker FlagChecker) makross.Handler {
+	c := DefaultFlagConfig
+	c.Checker = checker
+	return FlagWithConfig(c)
+}
+
+// FlagWithConfig returns a Flag middleware with config.
+// See: `Flag()`.
+func FlagWithConfig(config FlagConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultFlagConfig.Skipper
+	}
+	if config.Checker == nil {
+		panic("feature: checker is required")
+	}
+	if config.MetaKey == "" {
+		config.MetaKey = DefaultFlagConfig.MetaKey
+	}
+	if config.Status == 0 {
+		config.Status = DefaultFlagConfig.Status
+	}
+	var message []interface{}
+	if config.Message != "" {
+		message = append(message, config.Message)
+	}
+
+	return func(c *makross.Context) error {
+		route := c.Route()
+		if config.Skipper(c) || route == nil {
+			return c.Next()
+		}
+
+		var names []string
+		switch v := route.GetMeta(config.MetaKey).(type) {
+		case string:
+			names = []string{v}
+		case []string:
+			names = v
+		}
+		if len(names) == 0 {
+			return c.Next()
+		}
+
+		evaluations, _ := c.Get(Key).(map[string]bool)
+		if evaluations == nil {
+			evaluations = make(map[string]bool, len(names))
+			c.Set(Key, evaluations)
+		}
+		enabled := true
+		for _, name := range names {
+			on := config.Checker.Enabled(c.Request.Context(), name, c)
+			evaluations[name] = on
+			if !on {
+				enabled = false
+				break
+			}
+		}
+		if !enabled {
+			return makross.NewHTTPError(config.Status, message...)
+		}
+		return c.Next()
+	}
+}
+
+// Evaluations returns the flags evaluated by the Flag middleware for the request, and whether they were enabled.
+func Evaluations(c *makross.Context) map[string]bool {
+	evaluations, _ := c.Get(Key).(map[string]bool)
+	return evaluations
+}
+
+// NewMemoryChecker returns a MemoryChecker without flags, which are all disabled.
+func NewMemoryChecker() *MemoryChecker {
+	return &MemoryChecker{flags: make(map[string]memoryFlag)}
+}
+
+// Set enables or disables the flag for all the requests. It is safe to call while serving.
+func (m *MemoryChecker) Set(flagName string, enabled bool) {
+	percent := 0
+	if enabled {
+		percent = 100
+	}
+	m.SetPercentage(flagName, percent, nil)
+}
+
+// SetPercentage enables the flag for a percentage of the clients, from 0 to 100, identified by the key,
+// such as the user ID. The same key gets the same result, and raising the percentage keeps the flag
+// enabled for the clients it was enabled for. The key defaults to the IP of the client.
+// It is safe to call while serving.
+func (m *MemoryChecker) SetPercentage(flagName string, percent int, key func(c *makross.Context) string) {
+	if key == nil {
+		key = func(c *makross.Context) string {
+			return c.RealIP()
+		}
+	}
+	m.mu.Lock()
+	m.flags[flagName] = memoryFlag{percent, key}
+	m.mu.Unlock()
+}
+
+// Delete removes the flag, which is then disabled. It is safe to call while serving.
+func (m *MemoryChecker) Delete(flagName string) {
+	m.mu.Lock()
+	delete(m.flags, flagName)
+	m.mu.Unlock()
+}
+
+// Enabled implements FlagChecker.
+func (m *MemoryChecker) Enabled(ctx ktx.Context, flagName string, c *makross.Context) bool {
+	m.mu.RLock()
+	f, ok := m.flags[flagName]
+	m.mu.RUnlock()
+	switch {
+	case !ok || f.percent <= 0:
+		return false
+	case f.percent >= 100:
+		return true
+	}
+	return bucket(flagName, f.key(c)) < f.percent
+}
+
+// bucket returns the bucket of the key for the flag, from 0 to 99. The flag name is hashed with the key,
+// so that the clients of a percentage rollout aren't the same for all the flags.
+func bucket(flagName, key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(flagName))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,101 @@
+package feature
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlag(t *testing.T) {
+	flags := NewMemoryChecker()
+	m := makross.New()
+	m.Use(Flag(flags))
+	handler := func(c *makross.Context) error {
+		return c.String("ok")
+	}
+	m.Get("/old", handler)
+	m.Get("/new", handler).Meta("flag", "new")
+	m.Get("/beta", handler).Meta("flag", []string{"new", "beta"})
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res
+	}
+
+	assert.Equal(t, makross.StatusOK, serve("/old").Code)
+	assert.Equal(t, makross.StatusNotFound, serve("/new").Code)
+
+	// toggled at runtime
+	flags.Set("new", true)
+	assert.Equal(t, makross.StatusOK, serve("/new").Code)
+	assert.Equal(t, makross.StatusNotFound, serve("/beta").Code)
+	flags.Set("beta", true)
+	assert.Equal(t, makross.StatusOK, serve("/beta").Code)
+	flags.Delete("new")
+	assert.Equal(t, makross.StatusNotFound, serve("/new").Code)
+	assert.Equal(t, makross.StatusNotFound, serve("/beta").Code)
+}
+
+func TestFlagConfig(t *testing.T) {
+	flags := NewMemoryChecker()
+	flags.Set("on", true)
+	m := makross.New()
+	var evaluations map[string]bool
+	m.Use(func(c *makross.Context) error {
+		err := c.Next()
+		evaluations = Evaluations(c)
+		return err
+	}, FlagWithConfig(FlagConfig{
+		Checker: flags,
+		MetaKey: "feature",
+		Status:  makross.StatusForbidden,
+		Message: "feature disabled",
+	}))
+	m.Get("/", func(c *makross.Context) error {
+		return c.String("ok")
+	}).Meta("feature", []string{"on", "off"})
+
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/", nil))
+	assert.Equal(t, makross.StatusForbidden, res.Code)
+	assert.Contains(t, res.Body.String(), "feature disabled")
+	assert.Equal(t, map[string]bool{"on": true, "off": false}, evaluations)
+
+	assert.Panics(t, func() { FlagWithConfig(FlagConfig{}) })
+}
+
+func TestMemoryCheckerPercentage(t *testing.T) {
+	flags := NewMemoryChecker()
+	user := func(c *makross.Context) string {
+		return c.Request.Header.Get("X-User")
+	}
+	flags.SetPercentage("rollout", 30, user)
+	m := makross.New()
+
+	enabled := func(id int) bool {
+		req := httptest.NewRequest(makross.GET, "/", nil)
+		req.Header.Set("X-User", strconv.Itoa(id))
+		c := m.NewContext(req, httptest.NewRecorder())
+		return flags.Enabled(req.Context(), "rollout", c)
+	}
+	var on []int
+	for id := 0; id < 1000; id++ {
+		if enabled(id) {
+			on = append(on, id)
+		}
+		// stable for a key
+		assert.Equal(t, enabled(id), enabled(id))
+	}
+	assert.InDelta(t, 300, len(on), 60)
+
+	// the clients enabled stay enabled when the rollout grows
+	flags.SetPercentage("rollout", 60, user)
+	for _, id := range on {
+		assert.True(t, enabled(id))
+	}
+	flags.SetPercentage("rollout", 0, user)
+	assert.False(t, enabled(on[0]))
+}
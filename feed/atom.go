@@ -0,0 +1,134 @@
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type (
+	// Atom is an Atom feed.
+	// See: https://tools.ietf.org/html/rfc4287
+	Atom struct {
+		ID       string
+		Title    string
+		Subtitle string // optional
+		Link     string // optional, the alternate link of the feed
+		Self     string // optional, the URL of the feed itself
+		Updated  time.Time
+		Author   string // optional if every entry has an author
+		Entries  []AtomEntry
+	}
+
+	// AtomEntry is an entry of an Atom feed.
+	AtomEntry struct {
+		ID        string
+		Title     string
+		Link      string // optional, the alternate link of the entry
+		Updated   time.Time
+		Published time.Time // optional
+		Author    string    // optional
+		Summary   string    // optional
+		Content   string    // optional, HTML content
+	}
+
+	atomFeed struct {
+		XMLName  xml.Name    `xml:"feed"`
+		Xmlns    string      `xml:"xmlns,attr"`
+		Title    string      `xml:"title"`
+		Subtitle string      `xml:"subtitle,omitempty"`
+		Links    []atomLink  `xml:"link"`
+		Updated  string      `xml:"updated"`
+		Author   *atomPerson `xml:"author,omitempty"`
+		ID       string      `xml:"id"`
+		Entries  []atomEntry `xml:"entry"`
+	}
+
+	atomEntry struct {
+		Title     string       `xml:"title"`
+		Links     []atomLink   `xml:"link"`
+		ID        string       `xml:"id"`
+		Updated   string       `xml:"updated"`
+		Published string       `xml:"published,omitempty"`
+		Author    *atomPerson  `xml:"author,omitempty"`
+		Summary   string       `xml:"summary,omitempty"`
+		Content   *atomContent `xml:"content,omitempty"`
+	}
+
+	atomLink struct {
+		Rel  string `xml:"rel,attr,omitempty"`
+		Href string `xml:"href,attr"`
+	}
+
+	atomPerson struct {
+		Name string `xml:"name"`
+	}
+
+	atomContent struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",chardata"`
+	}
+)
+
+// Add adds entries to the feed.
+func (a *Atom) Add(entries ...AtomEntry) {
+	a.Entries = append(a.Entries, entries...)
+}
+
+// WriteTo writes the feed to the response.
+func (a *Atom) WriteTo(c *makross.Context) error {
+	return write(c, MIMEApplicationAtomCharsetUTF8, a.document())
+}
+
+// Encode writes the feed to w.
+func (a *Atom) Encode(w io.Writer) error {
+	return encode(w, a.document())
+}
+
+// document returns the XML document of the feed. The dates are formatted as RFC 3339 dates.
+func (a *Atom) document() *atomFeed {
+	doc := &atomFeed{
+		Xmlns:    atomNamespace,
+		Title:    a.Title,
+		Subtitle: a.Subtitle,
+		Updated:  formatTime(a.Updated, time.RFC3339),
+		Author:   newAtomPerson(a.Author),
+		ID:       a.ID,
+		Entries:  make([]atomEntry, len(a.Entries)),
+	}
+	if a.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: a.Link})
+	}
+	if a.Self != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "self", Href: a.Self})
+	}
+	for i, e := range a.Entries {
+		entry := atomEntry{
+			Title:     e.Title,
+			ID:        e.ID,
+			Updated:   formatTime(e.Updated, time.RFC3339),
+			Published: formatTime(e.Published, time.RFC3339),
+			Author:    newAtomPerson(e.Author),
+			Summary:   e.Summary,
+		}
+		if e.Link != "" {
+			entry.Links = []atomLink{{Href: e.Link}}
+		}
+		if e.Content != "" {
+			entry.Content = &atomContent{Type: "html", Body: e.Content}
+		}
+		doc.Entries[i] = entry
+	}
+	return doc
+}
+
+func newAtomPerson(name string) *atomPerson {
+	if name == "" {
+		return nil
+	}
+	return &atomPerson{Name: name}
+}
@@ -0,0 +1,50 @@
+// Package feed builds sitemaps, RSS 2.0 and Atom feeds, and writes them to the response.
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+// MIME types
+const (
+	MIMEApplicationRSSCharsetUTF8  = "application/rss+xml; charset=UTF-8"
+	MIMEApplicationAtomCharsetUTF8 = "application/atom+xml; charset=UTF-8"
+)
+
+// encode writes the XML declaration followed by the indented XML encoding of v.
+func encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// write streams the XML encoding of v to the response with the given content type.
+func write(c *makross.Context, contentType string, v interface{}) error {
+	if c.Response.Committed {
+		return makross.ErrResponseAlreadyCommitted
+	}
+	c.Response.Header().Set(makross.HeaderContentType, contentType)
+	c.Response.WriteHeader(makross.StatusOK)
+	err := encode(c.Response, v)
+	c.Abort()
+	return err
+}
+
+// formatTime formats t with the layout, or returns an empty string if t is zero.
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
@@ -0,0 +1,155 @@
+package feed
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func assertGolden(t *testing.T, name string, encode func(*bytes.Buffer) error) {
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if !assert.Nil(t, err) {
+		return
+	}
+	var b bytes.Buffer
+	assert.Nil(t, encode(&b))
+	assert.Equal(t, string(expected), b.String())
+}
+
+// The example of https://www.sitemaps.org/protocol.html
+func TestSitemap(t *testing.T) {
+	s := &Sitemap{}
+	s.Add(
+		SitemapURL{Loc: "http://www.example.com/", LastMod: date("2005-01-01T00:00:00Z"), ChangeFreq: Monthly, Priority: 0.8},
+		SitemapURL{Loc: "http://www.example.com/catalog?item=12&desc=vacation_hawaii", ChangeFreq: Weekly},
+		SitemapURL{Loc: "http://www.example.com/catalog?item=73&desc=vacation_new_zealand", LastMod: date("2004-12-23T00:00:00Z"), ChangeFreq: Weekly},
+		SitemapURL{Loc: "http://www.example.com/catalog?item=74&desc=vacation_newfoundland", LastMod: date("2004-12-23T18:00:15+00:00"), Priority: 0.3},
+		SitemapURL{Loc: "http://www.example.com/catalog?item=83&desc=vacation_usa", LastMod: date("2004-11-23T00:00:00Z")},
+	)
+	assertGolden(t, "sitemap.xml", func(b *bytes.Buffer) error {
+		return s.Encode(b)
+	})
+
+	m := makross.New()
+	req := httptest.NewRequest(makross.GET, "/sitemap.xml", nil)
+	res := httptest.NewRecorder()
+	assert.Nil(t, s.WriteTo(m.NewContext(req, res)))
+	assert.Equal(t, makross.MIMEApplicationXMLCharsetUTF8, res.Header().Get(makross.HeaderContentType))
+	assert.True(t, strings.HasPrefix(res.Body.String(), `<?xml version="1.0" encoding="UTF-8"?>`))
+}
+
+func TestSitemapPriority(t *testing.T) {
+	set := newURLSet([]SitemapURL{{Priority: 0.25}, {Priority: 1.5}, {Priority: -1}, {Priority: 1}})
+	assert.Equal(t, "0.25", set.URLs[0].Priority)
+	assert.Equal(t, "1", set.URLs[1].Priority)
+	assert.Equal(t, "", set.URLs[2].Priority)
+	assert.Equal(t, "1", set.URLs[3].Priority)
+}
+
+func TestSitemapIndex(t *testing.T) {
+	s := &Sitemap{}
+	for i := 0; i < MaxSitemapURLs+1; i++ {
+		s.Add(SitemapURL{Loc: "http://www.example.com/" + strconv.Itoa(i), LastMod: date("2004-10-01T18:23:17Z")})
+	}
+	s.URLs[MaxSitemapURLs].LastMod = date("2005-01-01T00:00:00Z")
+	assert.Equal(t, 2, s.Pages())
+	assert.Len(t, s.Page(1), MaxSitemapURLs)
+	assert.Len(t, s.Page(2), 1)
+	assert.Nil(t, s.Page(3))
+
+	assert.Equal(t, ErrSitemapPageURL, s.Encode(&bytes.Buffer{}))
+	s.PageURL = func(page int) string {
+		return "http://www.example.com/sitemap" + strconv.Itoa(page) + ".xml"
+	}
+	assertGolden(t, "sitemapindex.xml", func(b *bytes.Buffer) error {
+		return s.Encode(b)
+	})
+
+	m := makross.New()
+	req := httptest.NewRequest(makross.GET, "/sitemap2.xml", nil)
+	res := httptest.NewRecorder()
+	assert.Nil(t, s.WritePageTo(m.NewContext(req, res), 2))
+	assert.Contains(t, res.Body.String(), "<loc>http://www.example.com/50000</loc>")
+	assert.NotContains(t, res.Body.String(), "<loc>http://www.example.com/49999</loc>")
+	assert.Equal(t, makross.ErrNotFound, s.WritePageTo(m.NewContext(req, httptest.NewRecorder()), 3))
+}
+
+// The sample of https://www.rssboard.org/files/sample-rss-2.xml
+func TestRSS(t *testing.T) {
+	r := &RSS{
+		Title:          "Liftoff News",
+		Link:           "http://liftoff.msfc.nasa.gov/",
+		Description:    "Liftoff to Space Exploration.",
+		Language:       "en-us",
+		PubDate:        date("2003-06-10T04:00:00Z"),
+		LastBuildDate:  date("2003-06-10T09:41:01Z"),
+		ManagingEditor: "editor@example.com",
+		WebMaster:      "webmaster@example.com",
+	}
+	r.Add(
+		RSSItem{
+			Title:       "Star City",
+			Link:        "http://liftoff.msfc.nasa.gov/news/2003/news-starcity.asp",
+			Description: `How do Americans get ready to work with Russians aboard the International Space Station? They take a crash course in culture, language and protocol at Russia's <a href="http://howe.iki.rssi.ru/GCTC/gctc_e.htm">Star City</a>.`,
+			GUID:        "http://liftoff.msfc.nasa.gov/2003/06/03.html#item573",
+			PubDate:     date("2003-06-03T09:39:21Z"),
+		},
+		RSSItem{
+			Description: "Sky watchers in Europe, Asia, and parts of Alaska and Canada will experience a partial eclipse of the Sun on Saturday, May 31st.",
+			GUID:        "http://liftoff.msfc.nasa.gov/2003/05/30.html#item572",
+			PubDate:     date("2003-05-30T11:06:42Z"),
+		},
+	)
+	assertGolden(t, "rss.xml", func(b *bytes.Buffer) error {
+		return r.Encode(b)
+	})
+
+	m := makross.New()
+	res := httptest.NewRecorder()
+	assert.Nil(t, r.WriteTo(m.NewContext(httptest.NewRequest(makross.GET, "/rss", nil), res)))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, MIMEApplicationRSSCharsetUTF8, res.Header().Get(makross.HeaderContentType))
+}
+
+// The example of https://tools.ietf.org/html/rfc4287#section-1.1
+func TestAtom(t *testing.T) {
+	a := &Atom{
+		ID:      "urn:uuid:60a76c80-d399-11d9-b93C-0003939e0af6",
+		Title:   "Example Feed",
+		Link:    "http://example.org/",
+		Updated: date("2003-12-13T18:30:02Z"),
+		Author:  "John Doe",
+	}
+	a.Add(AtomEntry{
+		ID:      "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+		Title:   "Atom-Powered Robots Run Amok",
+		Link:    "http://example.org/2003/12/13/atom03",
+		Updated: date("2003-12-13T18:30:02Z"),
+		Summary: "Some text.",
+	})
+	assertGolden(t, "atom.xml", func(b *bytes.Buffer) error {
+		return a.Encode(b)
+	})
+
+	m := makross.New()
+	res := httptest.NewRecorder()
+	assert.Nil(t, a.WriteTo(m.NewContext(httptest.NewRequest(makross.GET, "/atom", nil), res)))
+	assert.Equal(t, MIMEApplicationAtomCharsetUTF8, res.Header().Get(makross.HeaderContentType))
+}
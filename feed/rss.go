@@ -0,0 +1,106 @@
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+type (
+	// RSS is an RSS 2.0 channel.
+	// See: https://www.rssboard.org/rss-specification
+	RSS struct {
+		Title          string
+		Link           string
+		Description    string
+		Language       string    // optional
+		PubDate        time.Time // optional
+		LastBuildDate  time.Time // optional
+		ManagingEditor string    // optional
+		WebMaster      string    // optional
+		Items          []RSSItem
+	}
+
+	// RSSItem is an item of an RSS channel.
+	RSSItem struct {
+		Title       string
+		Link        string
+		Description string
+		Author      string    // optional, email address of the author
+		GUID        string    // optional, a permalink to the item
+		PubDate     time.Time // optional
+	}
+
+	rss struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		Channel rssChannel `xml:"channel"`
+	}
+
+	rssChannel struct {
+		Title          string    `xml:"title"`
+		Link           string    `xml:"link"`
+		Description    string    `xml:"description"`
+		Language       string    `xml:"language,omitempty"`
+		PubDate        string    `xml:"pubDate,omitempty"`
+		LastBuildDate  string    `xml:"lastBuildDate,omitempty"`
+		ManagingEditor string    `xml:"managingEditor,omitempty"`
+		WebMaster      string    `xml:"webMaster,omitempty"`
+		Items          []rssItem `xml:"item"`
+	}
+
+	rssItem struct {
+		Title       string `xml:"title,omitempty"`
+		Link        string `xml:"link,omitempty"`
+		Description string `xml:"description,omitempty"`
+		Author      string `xml:"author,omitempty"`
+		GUID        string `xml:"guid,omitempty"`
+		PubDate     string `xml:"pubDate,omitempty"`
+	}
+)
+
+// Add adds items to the channel.
+func (r *RSS) Add(items ...RSSItem) {
+	r.Items = append(r.Items, items...)
+}
+
+// WriteTo writes the channel to the response.
+func (r *RSS) WriteTo(c *makross.Context) error {
+	return write(c, MIMEApplicationRSSCharsetUTF8, r.document())
+}
+
+// Encode writes the channel to w.
+func (r *RSS) Encode(w io.Writer) error {
+	return encode(w, r.document())
+}
+
+// document returns the XML document of the channel. The dates are formatted as RFC 822 dates with a 4-digit year.
+func (r *RSS) document() *rss {
+	doc := &rss{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:          r.Title,
+			Link:           r.Link,
+			Description:    r.Description,
+			Language:       r.Language,
+			PubDate:        formatTime(r.PubDate, time.RFC1123Z),
+			LastBuildDate:  formatTime(r.LastBuildDate, time.RFC1123Z),
+			ManagingEditor: r.ManagingEditor,
+			WebMaster:      r.WebMaster,
+			Items:          make([]rssItem, len(r.Items)),
+		},
+	}
+	for i, item := range r.Items {
+		doc.Channel.Items[i] = rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			Author:      item.Author,
+			GUID:        item.GUID,
+			PubDate:     formatTime(item.PubDate, time.RFC1123Z),
+		}
+	}
+	return doc
+}
@@ -0,0 +1,184 @@
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+// MaxSitemapURLs is the maximum number of URLs of a sitemap file, as defined by the sitemaps protocol.
+const MaxSitemapURLs = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// ErrSitemapPageURL is returned when a sitemap must be split into pages but has no PageURL.
+var ErrSitemapPageURL = errors.New("feed: the sitemap has more than MaxSitemapURLs URLs and no PageURL")
+
+// Change frequencies of a SitemapURL.
+const (
+	Always  = "always"
+	Hourly  = "hourly"
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Yearly  = "yearly"
+	Never   = "never"
+)
+
+type (
+	// SitemapURL is an entry of a sitemap.
+	// See: https://www.sitemaps.org/protocol.html
+	SitemapURL struct {
+		Loc        string
+		LastMod    time.Time // optional
+		ChangeFreq string    // optional, one of Always, Hourly, Daily, Weekly, Monthly, Yearly or Never
+		Priority   float64   // optional, between 0.0 and 1.0, omitted if 0 or less, clamped to 1.0
+	}
+
+	// Sitemap is a list of URLs. A sitemap of more than MaxSitemapURLs URLs is split into pages,
+	// which are listed by a sitemap index.
+	Sitemap struct {
+		URLs []SitemapURL
+
+		// PageURL returns the URL of the given page of the sitemap, starting at 1, for the sitemap index.
+		// It is required if the sitemap has more than MaxSitemapURLs URLs.
+		PageURL func(page int) string
+	}
+
+	urlset struct {
+		XMLName xml.Name     `xml:"urlset"`
+		Xmlns   string       `xml:"xmlns,attr"`
+		URLs    []sitemapURL `xml:"url"`
+	}
+
+	sitemapURL struct {
+		Loc        string `xml:"loc"`
+		LastMod    string `xml:"lastmod,omitempty"`
+		ChangeFreq string `xml:"changefreq,omitempty"`
+		Priority   string `xml:"priority,omitempty"`
+	}
+
+	sitemapIndex struct {
+		XMLName  xml.Name      `xml:"sitemapindex"`
+		Xmlns    string        `xml:"xmlns,attr"`
+		Sitemaps []sitemapPage `xml:"sitemap"`
+	}
+
+	sitemapPage struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod,omitempty"`
+	}
+)
+
+// Add adds URLs to the sitemap.
+func (s *Sitemap) Add(urls ...SitemapURL) {
+	s.URLs = append(s.URLs, urls...)
+}
+
+// Pages returns the number of pages of the sitemap.
+func (s *Sitemap) Pages() int {
+	if len(s.URLs) == 0 {
+		return 1
+	}
+	return (len(s.URLs) + MaxSitemapURLs - 1) / MaxSitemapURLs
+}
+
+// Page returns the URLs of the given page of the sitemap, starting at 1.
+// Nil is returned if the page doesn't exist.
+func (s *Sitemap) Page(page int) []SitemapURL {
+	if page < 1 || page > s.Pages() {
+		return nil
+	}
+	start := (page - 1) * MaxSitemapURLs
+	end := start + MaxSitemapURLs
+	if end > len(s.URLs) {
+		end = len(s.URLs)
+	}
+	return s.URLs[start:end]
+}
+
+// WriteTo writes the sitemap to the response, or the sitemap index listing its pages
+// if it has more than MaxSitemapURLs URLs. Serve the pages with WritePageTo.
+func (s *Sitemap) WriteTo(c *makross.Context) error {
+	v, err := s.root()
+	if err != nil {
+		return err
+	}
+	return write(c, makross.MIMEApplicationXMLCharsetUTF8, v)
+}
+
+// WritePageTo writes the given page of the sitemap to the response, starting at 1.
+// It returns makross.ErrNotFound if the page doesn't exist.
+func (s *Sitemap) WritePageTo(c *makross.Context, page int) error {
+	if page < 1 || page > s.Pages() {
+		return makross.ErrNotFound
+	}
+	return write(c, makross.MIMEApplicationXMLCharsetUTF8, newURLSet(s.Page(page)))
+}
+
+// Encode writes the sitemap, or its sitemap index, to w.
+func (s *Sitemap) Encode(w io.Writer) error {
+	v, err := s.root()
+	if err != nil {
+		return err
+	}
+	return encode(w, v)
+}
+
+// root returns the document served at the sitemap URL.
+func (s *Sitemap) root() (interface{}, error) {
+	pages := s.Pages()
+	if pages == 1 {
+		return newURLSet(s.URLs), nil
+	}
+	if s.PageURL == nil {
+		return nil, ErrSitemapPageURL
+	}
+	index := &sitemapIndex{Xmlns: sitemapNamespace}
+	for page := 1; page <= pages; page++ {
+		var lastMod time.Time
+		for _, u := range s.Page(page) {
+			if u.LastMod.After(lastMod) {
+				lastMod = u.LastMod
+			}
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapPage{
+			Loc:     s.PageURL(page),
+			LastMod: formatW3CDatetime(lastMod),
+		})
+	}
+	return index, nil
+}
+
+func newURLSet(urls []SitemapURL) *urlset {
+	set := &urlset{Xmlns: sitemapNamespace, URLs: make([]sitemapURL, len(urls))}
+	for i, u := range urls {
+		set.URLs[i] = sitemapURL{
+			Loc:        u.Loc,
+			LastMod:    formatW3CDatetime(u.LastMod),
+			ChangeFreq: u.ChangeFreq,
+		}
+		if p := u.Priority; p > 0 {
+			if p > 1 {
+				p = 1
+			}
+			set.URLs[i].Priority = strconv.FormatFloat(p, 'f', -1, 64)
+		}
+	}
+	return set
+}
+
+// formatW3CDatetime formats t as a W3C Datetime, as a date alone if it's midnight UTC.
+func formatW3CDatetime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if t.Equal(t.UTC().Truncate(24 * time.Hour)) {
+		return t.UTC().Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
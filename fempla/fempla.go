@@ -165,7 +165,7 @@ func (r *Renderer) Render(w io.Writer, name string, ctx *makross.Context) error
 		return err
 	}
 
-	if b := []byte(template.ExecuteString(ctx.GetStore())); r.Filter {
+	if b := []byte(template.ExecuteString(ctx.TemplateData())); r.Filter {
 		_, err = fmt.Fprintf(w, "%s", ctx.DoFilterHook(fmt.Sprintf("%s_template", name), func() []byte {
 			return b
 		}))
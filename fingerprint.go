@@ -0,0 +1,96 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/netip"
+)
+
+// FingerprintOptions are the components of a request fingerprint, see Fingerprint.
+type FingerprintOptions uint
+
+// The components of a request fingerprint.
+const (
+	// FingerprintIP is the network of the client IP, see RealIP: the /24 of an IPv4 address, the /48 of an IPv6 one.
+	FingerprintIP FingerprintOptions = 1 << iota
+	// FingerprintUserAgent is the User-Agent header.
+	FingerprintUserAgent
+	// FingerprintAcceptLanguage is the Accept-Language header.
+	FingerprintAcceptLanguage
+	// FingerprintTLS is the negotiated TLS version, cipher suite, application protocol and server name.
+	FingerprintTLS
+
+	// DefaultFingerprint are the components of the fingerprint computed by the fingerprint middleware by default.
+	DefaultFingerprint = FingerprintIP | FingerprintUserAgent | FingerprintAcceptLanguage
+)
+
+// FingerprintKey is the key of the context data holding the fingerprint computed by the fingerprint middleware.
+const FingerprintKey = "fingerprint"
+
+// fingerprint is the fingerprint of a request, cached by Fingerprint.
+type fingerprint struct {
+	opts  FingerprintOptions
+	value string
+}
+
+// Fingerprint returns the fingerprint of the request, a hex digest of the components of the options,
+// e.g. to count the requests of a client across IP addresses of the same network for abuse detection:
+//
+//	fp := c.Fingerprint(makross.FingerprintIP | makross.FingerprintUserAgent)
+//
+// The fingerprint is a heuristic: the clients sharing a network and a browser share fingerprints, and
+// a client changes its fingerprint by changing any component. It must not be used for authentication.
+// The missing components, e.g. the User-Agent header or the TLS state of a plain HTTP request, are
+// hashed as empty. The TLS component is coarser than a JA3 hash, which net/http doesn't expose the
+// ClientHello for.
+//
+// The fingerprint is computed once per request and options.
+func (c *Context) Fingerprint(opts FingerprintOptions) string {
+	if c.fp.opts == opts && c.fp.value != "" {
+		return c.fp.value
+	}
+	h := sha256.New()
+	var buf [18]byte
+	// the components are delimited by their bit, so that moving a value between them changes the digest
+	write := func(component FingerprintOptions, b []byte) {
+		var n [5]byte
+		n[0] = byte(component)
+		binary.BigEndian.PutUint32(n[1:], uint32(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+	}
+	if opts&FingerprintIP != 0 {
+		var prefix []byte
+		if addr, err := netip.ParseAddr(c.RealIP()); err == nil {
+			bits := 48
+			if addr = addr.Unmap(); addr.Is4() {
+				bits = 24
+			}
+			p, _ := addr.Prefix(bits)
+			a := p.Addr().As16()
+			prefix = append(buf[:0], a[:]...)
+		}
+		write(FingerprintIP, prefix)
+	}
+	if opts&FingerprintUserAgent != 0 {
+		write(FingerprintUserAgent, []byte(c.Request.UserAgent()))
+	}
+	if opts&FingerprintAcceptLanguage != 0 {
+		write(FingerprintAcceptLanguage, []byte(c.Request.Header.Get(HeaderAcceptLanguage)))
+	}
+	if opts&FingerprintTLS != 0 {
+		var state []byte
+		if cs := c.Request.TLS; cs != nil {
+			state = binary.BigEndian.AppendUint16(buf[:0], cs.Version)
+			state = binary.BigEndian.AppendUint16(state, cs.CipherSuite)
+			state = append(append(append(state, cs.NegotiatedProtocol...), 0), cs.ServerName...)
+		}
+		write(FingerprintTLS, state)
+	}
+	var sum [sha256.Size]byte
+	c.fp = fingerprint{opts: opts, value: hex.EncodeToString(h.Sum(sum[:0])[:16])}
+	return c.fp.value
+}
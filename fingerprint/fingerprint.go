@@ -0,0 +1,68 @@
+package fingerprint
+
+import (
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// FingerprintConfig defines the config for Fingerprint middleware.
+	FingerprintConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Components are the components of the fingerprint, see `Context#Fingerprint()`.
+		// Optional. Default value makross.DefaultFingerprint.
+		Components makross.FingerprintOptions `json:"components"`
+	}
+)
+
+var (
+	// DefaultFingerprintConfig is the default Fingerprint middleware config.
+	DefaultFingerprintConfig = FingerprintConfig{
+		Skipper:    skipper.DefaultSkipper,
+		Components: makross.DefaultFingerprint,
+	}
+)
+
+// Fingerprint returns a Fingerprint middleware.
+//
+// Fingerprint middleware computes the fingerprint of each request, see `Context#Fingerprint()`,
+// stores it in the context under makross.FingerprintKey, so that it can be logged by the logger
+// middleware with the ${store:fingerprint} tag, and adds it to the fields of the request logger:
+//
+//	m.Use(fingerprint.Fingerprint())
+//	m.Post("/signup", func(c *makross.Context) error {
+//		if abuse.Suspicious(c.Get(makross.FingerprintKey).(string)) {
+//			return makross.NewTooManyRequestsError(time.Minute)
+//		}
+//		...
+//	})
+//
+// The fingerprint is a heuristic for abuse detection, not an identity.
+func Fingerprint() makross.Handler {
+	return FingerprintWithConfig(DefaultFingerprintConfig)
+}
+
+// FingerprintWithConfig returns a Fingerprint middleware with config.
+// See: `Fingerprint()`.
+func FingerprintWithConfig(config FingerprintConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultFingerprintConfig.Skipper
+	}
+	if config.Components == 0 {
+		config.Components = DefaultFingerprintConfig.Components
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		fp := c.Fingerprint(config.Components)
+		c.Set(makross.FingerprintKey, fp)
+		c.SetLogger(c.Logger().With(map[string]interface{}{makross.FingerprintKey: fp}))
+		return c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package fingerprint
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	buf := new(bytes.Buffer)
+	m := makross.New()
+	m.Use(logger.LoggerWithConfig(logger.LoggerConfig{
+		Format: "${store:fingerprint}\n",
+		Output: buf,
+	}), Fingerprint())
+	m.Get("/", func(c *makross.Context) error {
+		fp, _ := c.Get(makross.FingerprintKey).(string)
+		assert.Equal(t, c.Fingerprint(makross.DefaultFingerprint), fp)
+		return c.String(fp)
+	})
+	m.Get("/ua", FingerprintWithConfig(FingerprintConfig{Components: makross.FingerprintUserAgent}), func(c *makross.Context) error {
+		return c.String(c.Get(makross.FingerprintKey).(string))
+	})
+
+	serve := func(path, ip string) string {
+		req := httptest.NewRequest(makross.GET, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("User-Agent", "curl/8.0")
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res.Body.String()
+	}
+
+	fp := serve("/", "198.51.100.1")
+	assert.Len(t, fp, 32)
+	assert.Equal(t, fp+"\n", buf.String())
+	assert.Equal(t, fp, serve("/", "198.51.100.2"))
+	assert.NotEqual(t, fp, serve("/", "198.51.101.1"))
+
+	// the route level middleware computes its own fingerprint
+	ua := serve("/ua", "198.51.100.1")
+	assert.NotEqual(t, fp, ua)
+	assert.Equal(t, ua, serve("/ua", "192.0.2.1"))
+	assert.True(t, strings.HasSuffix(buf.String(), ua+"\n"))
+}
@@ -0,0 +1,99 @@
+package makross
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextFingerprint(t *testing.T) {
+	newRequest := func(ip, ua, lang string) *http.Request {
+		req := httptest.NewRequest(GET, "/", nil)
+		req.RemoteAddr = net.JoinHostPort(ip, "1234")
+		if ua != "" {
+			req.Header.Set("User-Agent", ua)
+		}
+		if lang != "" {
+			req.Header.Set(HeaderAcceptLanguage, lang)
+		}
+		return req
+	}
+	m := New()
+	compute := func(req *http.Request, opts FingerprintOptions) string {
+		return m.NewContext(req, httptest.NewRecorder()).Fingerprint(opts)
+	}
+	const ua = "Mozilla/5.0 (X11; Linux x86_64)"
+
+	base := compute(newRequest("203.0.113.7", ua, "fr-FR"), DefaultFingerprint)
+	assert.Len(t, base, 32)
+	// stable across identical requests, and within the same network
+	assert.Equal(t, base, compute(newRequest("203.0.113.7", ua, "fr-FR"), DefaultFingerprint))
+	assert.Equal(t, base, compute(newRequest("203.0.113.200", ua, "fr-FR"), DefaultFingerprint))
+	assert.Equal(t, base, compute(newRequest("::ffff:203.0.113.9", ua, "fr-FR"), DefaultFingerprint))
+	// sensitive to each component
+	assert.NotEqual(t, base, compute(newRequest("203.0.114.7", ua, "fr-FR"), DefaultFingerprint))
+	assert.NotEqual(t, base, compute(newRequest("203.0.113.7", ua+" Firefox", "fr-FR"), DefaultFingerprint))
+	assert.NotEqual(t, base, compute(newRequest("203.0.113.7", ua, "en-US"), DefaultFingerprint))
+	assert.NotEqual(t, base, compute(newRequest("203.0.113.7", ua, "fr-FR"), FingerprintIP|FingerprintUserAgent))
+	// the components are delimited
+	assert.NotEqual(t,
+		compute(newRequest("203.0.113.7", "ab", ""), FingerprintUserAgent|FingerprintAcceptLanguage),
+		compute(newRequest("203.0.113.7", "a", "b"), FingerprintUserAgent|FingerprintAcceptLanguage))
+	assert.NotEqual(t,
+		compute(newRequest("203.0.113.7", "fr", ""), FingerprintUserAgent|FingerprintAcceptLanguage),
+		compute(newRequest("203.0.113.7", "", "fr"), FingerprintUserAgent|FingerprintAcceptLanguage))
+
+	// IPv6 addresses are grouped by /48
+	v6 := compute(newRequest("2001:db8:1::1", ua, ""), DefaultFingerprint)
+	assert.Equal(t, v6, compute(newRequest("2001:db8:1:ffff::2", ua, ""), DefaultFingerprint))
+	assert.NotEqual(t, v6, compute(newRequest("2001:db8:2::1", ua, ""), DefaultFingerprint))
+
+	// missing components are tolerated
+	req := httptest.NewRequest(GET, "/", nil)
+	req.RemoteAddr = "garbage"
+	assert.Len(t, compute(req, DefaultFingerprint|FingerprintTLS), 32)
+
+	// the TLS state
+	plain := compute(newRequest("203.0.113.7", ua, ""), FingerprintTLS|FingerprintUserAgent)
+	req = newRequest("203.0.113.7", ua, "")
+	req.TLS = &tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_128_GCM_SHA256, NegotiatedProtocol: "h2"}
+	secure := compute(req, FingerprintTLS|FingerprintUserAgent)
+	assert.NotEqual(t, plain, secure)
+	req.TLS.CipherSuite = tls.TLS_CHACHA20_POLY1305_SHA256
+	assert.NotEqual(t, secure, compute(req, FingerprintTLS|FingerprintUserAgent))
+
+	// cached per request and options
+	c := m.NewContext(newRequest("203.0.113.7", ua, "fr-FR"), httptest.NewRecorder())
+	assert.Equal(t, base, c.Fingerprint(DefaultFingerprint))
+	if !raceEnabled {
+		assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+			c.Fingerprint(DefaultFingerprint)
+		}))
+		// a single pass, the digest string being the only allocation besides the client IP
+		assert.True(t, testing.AllocsPerRun(100, func() {
+			c.fp = fingerprint{}
+			c.Fingerprint(DefaultFingerprint)
+		}) <= 2)
+	}
+	c.Request.Header.Set(HeaderAcceptLanguage, "en-US")
+	assert.Equal(t, base, c.Fingerprint(DefaultFingerprint))
+	assert.NotEqual(t, base, c.Fingerprint(FingerprintIP))
+	c.Reset(httptest.NewRecorder(), newRequest("203.0.113.7", ua, "en-US"))
+	assert.NotEqual(t, base, c.Fingerprint(DefaultFingerprint))
+}
+
+func BenchmarkContextFingerprint(b *testing.B) {
+	req := httptest.NewRequest(GET, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64)")
+	req.Header.Set(HeaderAcceptLanguage, "fr-FR,fr;q=0.9")
+	c := New().NewContext(req, httptest.NewRecorder())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.fp = fingerprint{}
+		c.Fingerprint(DefaultFingerprint)
+	}
+}
@@ -0,0 +1,262 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ForwardedElement is a single element of the Forwarded header.
+// See: https://tools.ietf.org/html/rfc7239
+type ForwardedElement struct {
+	For   string
+	By    string
+	Host  string
+	Proto string
+}
+
+// ParseForwarded parses the Forwarded header fields of the given header into their elements,
+// in the order they were added by the proxies. Quoted values are unquoted, parameter names are
+// case-insensitive and unknown parameters are ignored.
+func ParseForwarded(h http.Header) []ForwardedElement {
+	values := h[HeaderForwarded]
+	if len(values) == 0 {
+		return nil
+	}
+
+	var (
+		elements []ForwardedElement
+		e        ForwardedElement
+		pair     []byte
+		quoted   bool
+		escaped  bool
+	)
+	set := func() {
+		p := string(pair)
+		pair = pair[:0]
+		i := strings.IndexByte(p, '=')
+		if i < 0 {
+			return
+		}
+		value := strings.TrimSpace(p[i+1:])
+		switch strings.ToLower(strings.TrimSpace(p[:i])) {
+		case "for":
+			e.For = value
+		case "by":
+			e.By = value
+		case "host":
+			e.Host = value
+		case "proto":
+			e.Proto = strings.ToLower(value)
+		}
+	}
+	flush := func() {
+		set()
+		if e != (ForwardedElement{}) {
+			elements = append(elements, e)
+		}
+		e = ForwardedElement{}
+	}
+
+	for _, s := range values {
+		for i := 0; i < len(s); i++ {
+			ch := s[i]
+			switch {
+			case escaped:
+				pair = append(pair, ch)
+				escaped = false
+			case quoted && ch == '\\':
+				escaped = true
+			case ch == '"':
+				quoted = !quoted
+			case quoted:
+				pair = append(pair, ch)
+			case ch == ';':
+				set()
+			case ch == ',':
+				flush()
+			case ch != ' ' && ch != '\t':
+				pair = append(pair, ch)
+			}
+		}
+		flush()
+		quoted, escaped = false, false
+	}
+	return elements
+}
+
+// ForIP returns the IP address of the For node, without port and IPv6 brackets.
+// An empty string is returned if the node is "unknown" or an obfuscated identifier.
+func (e ForwardedElement) ForIP() string {
+	return forwardedNodeIP(e.For)
+}
+
+// forwardedNodeIP extracts the IP address from a Forwarded node identifier.
+func forwardedNodeIP(node string) string {
+	if node == "" || node[0] == '_' || strings.EqualFold(node, "unknown") {
+		return ""
+	}
+	if node[0] == '[' {
+		if i := strings.IndexByte(node, ']'); i > 0 {
+			return node[1:i]
+		}
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+// forwarded returns the first element of the Forwarded header of the current request,
+// which describes the client side of the request.
+func (c *Context) forwarded() (ForwardedElement, bool) {
+	if elements := ParseForwarded(c.Request.Header); len(elements) > 0 {
+		return elements[0], true
+	}
+	return ForwardedElement{}, false
+}
+
+// SetTrustedProxies sets the proxies whose Forwarded, X-Forwarded-* and X-Real-IP headers are honored
+// when deriving the client IP, scheme and host of a request, see `Context#RealIP()`, as IP addresses or
+// CIDR ranges, e.g. "10.0.0.0/8". The headers of the requests from the other addresses are then ignored,
+// as any client can send them. Until it's called, the headers of all the requests are honored, which lets
+// the clients reaching the server directly spoof their IP address. It panics if an address is invalid.
+func (m *Makross) SetTrustedProxies(proxies ...string) {
+	prefixes := make([]netip.Prefix, len(proxies))
+	for i, p := range proxies {
+		var err error
+		if strings.Contains(p, "/") {
+			prefixes[i], err = netip.ParsePrefix(p)
+		} else {
+			var addr netip.Addr
+			if addr, err = netip.ParseAddr(p); err == nil {
+				addr = addr.Unmap()
+				prefixes[i] = netip.PrefixFrom(addr, addr.BitLen())
+			}
+		}
+		if err != nil {
+			panic("makross: invalid trusted proxy " + p + ": " + err.Error())
+		}
+		prefixes[i] = prefixes[i].Masked()
+	}
+	m.trustedProxies = prefixes
+}
+
+// trusted reports whether the IP address is the one of a trusted proxy.
+func (m *Makross) trusted(ip string) bool {
+	if m == nil || len(m.trustedProxies) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range m.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client of the request. The Forwarded header is consulted
+// first, followed by X-Forwarded-For and X-Real-IP, unless LegacyForwardedFirst is set, and the remote
+// address of the request is the fallback. Once trusted proxies are set, see SetTrustedProxies, the
+// forwarding headers are only honored when the request comes from one of them, and the client is the
+// address closest to the server which isn't the one of a trusted proxy.
+func (m *Makross) ClientIP(req *http.Request) string {
+	if m == nil || len(m.trustedProxies) == 0 {
+		return m.forwardedClientIP(req)
+	}
+	remote := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !m.trusted(remote) {
+		return remote
+	}
+	var fwd, xff []string
+	for _, e := range ParseForwarded(req.Header) {
+		fwd = append(fwd, e.ForIP())
+	}
+	for _, v := range req.Header.Values(HeaderXForwardedFor) {
+		for _, ip := range strings.Split(v, ",") {
+			xff = append(xff, strings.TrimSpace(ip))
+		}
+	}
+	var xri []string
+	if ip := strings.TrimSpace(req.Header.Get(HeaderXRealIP)); ip != "" {
+		xri = []string{ip}
+	}
+	sources := [][]string{fwd, xff, xri}
+	if m.LegacyForwardedFirst {
+		sources = [][]string{xff, xri, fwd}
+	}
+	for _, ips := range sources {
+		if len(ips) == 0 {
+			continue
+		}
+		// the proxies append the address they got the request from
+		ip := ips[0]
+		for i := len(ips) - 1; i >= 0; i-- {
+			if !m.trusted(ips[i]) {
+				ip = ips[i]
+				break
+			}
+		}
+		if ip == "" {
+			// an unknown or obfuscated node
+			return remote
+		}
+		return ip
+	}
+	return remote
+}
+
+// forwardedClientIP returns the client IP of the request from its forwarding headers, whoever sent them,
+// as long as no trusted proxy is set.
+func (m *Makross) forwardedClientIP(req *http.Request) string {
+	var fip string
+	if elements := ParseForwarded(req.Header); len(elements) > 0 {
+		fip = elements[0].ForIP()
+	}
+	if len(fip) > 0 && (m == nil || !m.LegacyForwardedFirst) {
+		return fip
+	}
+
+	ra := req.RemoteAddr
+	if ip := req.Header.Get(HeaderXForwardedFor); len(ip) > 0 {
+		ra = ip
+	} else if ip := req.Header.Get(HeaderXRealIP); len(ip) > 0 {
+		ra = ip
+	} else if len(fip) > 0 {
+		ra = fip
+	} else {
+		ra, _, _ = net.SplitHostPort(ra)
+	}
+	return ra
+}
+
+// trustsForwarded reports whether the forwarding headers of the request are honored: no trusted proxy
+// is set, or the request comes from one of them.
+func (c *Context) trustsForwarded() bool {
+	if c.makross == nil || len(c.makross.trustedProxies) == 0 {
+		return true
+	}
+	remote := c.Request.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	return c.makross.trusted(remote)
+}
+
+// preferForwarded reports whether the Forwarded header takes precedence over the legacy
+// X-Forwarded-* and X-Real-IP headers.
+func (c *Context) preferForwarded() bool {
+	return c.makross == nil || !c.makross.LegacyForwardedFirst
+}
@@ -0,0 +1,126 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForwarded(t *testing.T) {
+	tests := []struct {
+		header   []string
+		elements []ForwardedElement
+		ip       string
+	}{
+		// examples from RFC 7239
+		{[]string{`for="_gazonk"`}, []ForwardedElement{{For: "_gazonk"}}, ""},
+		{[]string{`For="[2001:db8:cafe::17]:4711"`}, []ForwardedElement{{For: "[2001:db8:cafe::17]:4711"}}, "2001:db8:cafe::17"},
+		{[]string{`for=192.0.2.60;proto=http;by=203.0.113.43`}, []ForwardedElement{{For: "192.0.2.60", Proto: "http", By: "203.0.113.43"}}, "192.0.2.60"},
+		{[]string{`for=192.0.2.43, for=198.51.100.17`}, []ForwardedElement{{For: "192.0.2.43"}, {For: "198.51.100.17"}}, "192.0.2.43"},
+		{[]string{`for=192.0.2.43`, `for=198.51.100.17;by=203.0.113.60;proto=http;host=example.com`},
+			[]ForwardedElement{{For: "192.0.2.43"}, {For: "198.51.100.17", By: "203.0.113.60", Proto: "http", Host: "example.com"}}, "192.0.2.43"},
+		{[]string{`for=unknown, for=192.0.2.43`}, []ForwardedElement{{For: "unknown"}, {For: "192.0.2.43"}}, ""},
+		// quoted separators, escapes and ports
+		{[]string{`for="192.0.2.43:47011";host="a,b;c"`}, []ForwardedElement{{For: "192.0.2.43:47011", Host: "a,b;c"}}, "192.0.2.43"},
+		{[]string{`for="\"x\""; PROTO=HTTPS`}, []ForwardedElement{{For: `"x"`, Proto: "https"}}, `"x"`},
+		{[]string{`for=[2001:db8::1]`}, []ForwardedElement{{For: "[2001:db8::1]"}}, "2001:db8::1"},
+		{nil, nil, ""},
+	}
+	for _, test := range tests {
+		h := http.Header{}
+		for _, v := range test.header {
+			h.Add(HeaderForwarded, v)
+		}
+		elements := ParseForwarded(h)
+		assert.Equal(t, test.elements, elements, "%v", test.header)
+		if len(elements) > 0 {
+			assert.Equal(t, test.ip, elements[0].ForIP(), "%v", test.header)
+		}
+	}
+}
+
+func TestContextForwarded(t *testing.T) {
+	m := New()
+	req, _ := http.NewRequest("GET", "http://example.org/users", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set(HeaderForwarded, `for="[2001:db8:cafe::17]:4711";proto=https;host=example.com`)
+	req.Header.Set(HeaderXForwardedFor, "192.0.2.1")
+	req.Header.Set(HeaderXForwardedProto, "http")
+	req.Header.Set(HeaderXForwardedHost, "legacy.example.com")
+	c := m.NewContext(req, nil)
+
+	// without trusted proxies, the headers of any peer are honored
+	assert.Equal(t, "2001:db8:cafe::17", c.RealIP())
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "example.com", c.Host())
+
+	// the headers of an untrusted peer are ignored
+	m.SetTrustedProxies("192.0.2.0/24")
+	assert.Equal(t, "10.0.0.1", c.RealIP())
+	assert.Equal(t, "http", c.Scheme())
+	assert.Equal(t, "example.org", c.Host())
+
+	m.SetTrustedProxies("10.0.0.0/8")
+	assert.Equal(t, "2001:db8:cafe::17", c.RealIP())
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "example.com", c.Host())
+
+	m.LegacyForwardedFirst = true
+	assert.Equal(t, "192.0.2.1", c.RealIP())
+	assert.Equal(t, "http", c.Scheme())
+	assert.Equal(t, "legacy.example.com", c.Host())
+
+	req.Header.Del(HeaderXForwardedFor)
+	req.Header.Del(HeaderXForwardedProto)
+	req.Header.Del(HeaderXForwardedHost)
+	assert.Equal(t, "2001:db8:cafe::17", c.RealIP())
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "example.com", c.Host())
+
+	req.Header.Del(HeaderForwarded)
+	assert.Equal(t, "10.0.0.1", c.RealIP())
+	assert.Equal(t, "http", c.Scheme())
+	assert.Equal(t, "example.org", c.Host())
+}
+
+func TestMakrossClientIP(t *testing.T) {
+	m := New()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set(HeaderXForwardedFor, "203.0.113.9")
+	assert.Equal(t, "203.0.113.9", m.ClientIP(req))
+	var nilMakross *Makross
+	assert.Equal(t, "203.0.113.9", nilMakross.ClientIP(req))
+
+	// the headers of an untrusted peer are ignored
+	m.SetTrustedProxies("192.0.2.1")
+	req.Header.Set(HeaderXForwardedFor, "203.0.113.9, 198.51.100.7, 10.0.0.2")
+	assert.Equal(t, "10.0.0.1", m.ClientIP(req))
+
+	// the client is the address closest to the server which isn't a trusted proxy
+	m.SetTrustedProxies("10.0.0.0/8", "::1")
+	assert.Equal(t, "198.51.100.7", m.ClientIP(req))
+	m.SetTrustedProxies("10.0.0.0/8", "198.51.100.7")
+	assert.Equal(t, "203.0.113.9", m.ClientIP(req))
+	req.Header.Set(HeaderXForwardedFor, "10.0.0.3")
+	assert.Equal(t, "10.0.0.3", m.ClientIP(req))
+
+	req.Header.Del(HeaderXForwardedFor)
+	req.Header.Set(HeaderXRealIP, "203.0.113.9")
+	assert.Equal(t, "203.0.113.9", m.ClientIP(req))
+	req.Header.Set(HeaderForwarded, "for=unknown")
+	assert.Equal(t, "10.0.0.1", m.ClientIP(req))
+
+	m.SetTrustedProxies("::1")
+	req.RemoteAddr = "[::1]:1234"
+	req.Header.Set(HeaderForwarded, `for="[2001:db8:cafe::17]:4711"`)
+	assert.Equal(t, "2001:db8:cafe::17", m.ClientIP(req))
+	req.RemoteAddr = "192.0.2.1:1234"
+	assert.Equal(t, "192.0.2.1", m.ClientIP(req))
+
+	assert.Panics(t, func() { m.SetTrustedProxies("10.0.0.0/33") })
+	assert.Panics(t, func() { m.SetTrustedProxies("proxy") })
+}
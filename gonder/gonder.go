@@ -58,10 +58,15 @@ func Renderor(opt ...Option) *Renderer {
 	return r
 }
 
-func (r *Renderer) buildTemplatesCache(name string) (t *template.Template, err error) {
+// parseFile parses the named template file with the template functions registered on Makross.
+func (r *Renderer) parseFile(name string, m *makross.Makross) (*template.Template, error) {
+	return template.New(filepath.Base(name)).Funcs(m.TemplateFuncs()).ParseFiles(filepath.Join(r.Directory, name))
+}
+
+func (r *Renderer) buildTemplatesCache(name string, m *makross.Makross) (t *template.Template, err error) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	t, err = template.ParseFiles(filepath.Join(r.Directory, name))
+	t, err = r.parseFile(name, m)
 	if err != nil {
 		return
 	}
@@ -69,16 +74,16 @@ func (r *Renderer) buildTemplatesCache(name string) (t *template.Template, err e
 	return
 }
 
-func (r *Renderer) getTemplate(name string) (t *template.Template, err error) {
+func (r *Renderer) getTemplate(name string, m *makross.Makross) (t *template.Template, err error) {
 	name = name + ".html"
 	if r.Reload {
-		return template.ParseFiles(filepath.Join(r.Directory, name))
+		return r.parseFile(name, m)
 	}
 	r.lock.RLock()
 	var okay bool
 	if t, okay = r.templates[name]; !okay {
 		r.lock.RUnlock()
-		t, err = r.buildTemplatesCache(name)
+		t, err = r.buildTemplatesCache(name, m)
 	} else {
 		r.lock.RUnlock()
 	}
@@ -87,25 +92,27 @@ func (r *Renderer) getTemplate(name string) (t *template.Template, err error) {
 
 // Render 渲染
 func (r *Renderer) Render(w io.Writer, name string, c *makross.Context) (err error) {
-	template, err := r.getTemplate(name)
+	template, err := r.getTemplate(name, c.Makross())
 	if err != nil {
 		return err
 	}
 	template.Delims(r.DelimLeft, r.DelimRight)
 
+	if !r.Filter {
+		// nothing to filter, execute the template directly into the writer, which may stream it
+		return template.Execute(w, c.TemplateData())
+	}
+
 	var buffer bytes.Buffer
-	err = template.Execute(&buffer, c.GetStore())
+	err = template.Execute(&buffer, c.TemplateData())
 	if err != nil {
 		return err
 	}
 
-	if b := buffer.Bytes(); r.Filter {
-		_, err = fmt.Fprintf(w, "%s", c.DoFilterHook(fmt.Sprintf("%s_template", name), func() []byte {
-			return b
-		}))
-	} else {
-		_, err = fmt.Fprintf(w, "%s", b)
-	}
+	b := buffer.Bytes()
+	_, err = fmt.Fprintf(w, "%s", c.DoFilterHook(fmt.Sprintf("%s_template", name), func() []byte {
+		return b
+	}))
 	return err
 
 }
@@ -1,10 +1,16 @@
 package gonder_test
 
 import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/insionng/makross"
 	"github.com/insionng/makross/gonder"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRender(t *testing.T) {
@@ -18,3 +24,27 @@ func TestRender(t *testing.T) {
 		}
 	}())
 }
+
+func TestRenderTemplateFuncs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "hello.html"), []byte(`{{upper .name}} {{asset "app.js"}} {{.Ctx.Request.URL.Path}}`), 0644)
+
+	e := makross.New()
+	e.AddTemplateFunc("upper", strings.ToUpper)
+	e.AddTemplateFunc("asset", func(name string) string {
+		return "/static/" + name
+	})
+	e.SetRenderer(gonder.Renderor(gonder.Option{Directory: dir}))
+	e.Get("/", func(self *makross.Context) error {
+		self.Set("name", "makross")
+		return self.Render("hello")
+	})
+
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest(makross.GET, "/", nil))
+	assert.Equal(t, "MAKROSS /static/app.js /", res.Body.String())
+}
@@ -9,6 +9,7 @@ type RouteGroup struct {
 	prefix   string
 	makross  *Makross
 	handlers []Handler
+	meta     map[string]interface{}
 }
 
 // newRouteGroup creates a new RouteGroup with the given path prefix, makross, and handlers.
@@ -99,7 +100,23 @@ func (rg *RouteGroup) Group(prefix string, handlers ...Handler) *RouteGroup {
 		handlers = make([]Handler, len(rg.handlers))
 		copy(handlers, rg.handlers)
 	}
-	return newRouteGroup(rg.prefix+prefix, rg.makross, handlers)
+	g := newRouteGroup(rg.prefix+prefix, rg.makross, handlers)
+	for k, v := range rg.meta {
+		g.Meta(k, v)
+	}
+	return g
+}
+
+// Meta associates a named piece of metadata with the routes added afterwards to the group and
+// to its subgroups, e.g. `api.Meta("priority", shed.High)`. The metadata set on a route overrides it.
+// It panics if the value is invalid, see RegisterMetaValidator.
+func (rg *RouteGroup) Meta(key string, value interface{}) *RouteGroup {
+	validateMeta(key, value)
+	if rg.meta == nil {
+		rg.meta = make(map[string]interface{})
+	}
+	rg.meta[key] = value
+	return rg
 }
 
 // Use registers one or multiple handlers to the current route group.
@@ -116,12 +133,19 @@ func (rg *RouteGroup) add(method, path string, handlers []Handler) *Route {
 
 // newRoute creates a new Route with the given route path and route group.
 func (rg *RouteGroup) newRoute(method, path string) *Route {
-	return &Route{
+	r := &Route{
 		group:    rg,
 		method:   method,
 		path:     path,
 		template: buildURLTemplate(rg.prefix + path),
 	}
+	if len(rg.meta) > 0 {
+		r.meta = make(map[string]interface{}, len(rg.meta))
+		for k, v := range rg.meta {
+			r.meta[k] = v
+		}
+	}
+	return r
 }
 
 // combineHandlers merges two lists of handlers into a new list.
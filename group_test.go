@@ -89,3 +89,20 @@ func TestRouteGroupUse(t *testing.T) {
 	group2.Use(newHandler("3", &buf))
 	assert.Equal(t, 3, len(group2.handlers), "len(group2.handlers) =")
 }
+
+func TestRouteGroupMeta(t *testing.T) {
+	group := newRouteGroup("/admin", New(), nil)
+	group.Meta("priority", 1)
+	r1 := group.Get("/users")
+	r2 := group.Get("/users/<id>").Meta("priority", 2)
+	sub := group.Group("/reports")
+	group.Meta("priority", 3)
+	r3 := sub.Get("/daily")
+	r4 := group.To("GET,POST", "/logs")
+	assert.Equal(t, 1, r1.GetMeta("priority"))
+	assert.Equal(t, 2, r2.GetMeta("priority"))
+	assert.Equal(t, 1, r3.GetMeta("priority"))
+	for _, r := range r4.routes {
+		assert.Equal(t, 3, r.GetMeta("priority"))
+	}
+}
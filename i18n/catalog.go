@@ -0,0 +1,118 @@
+// Copyright 2017 Insion Ng
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package i18n
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/insionng/makross"
+	"golang.org/x/text/language"
+)
+
+// CatalogConfig is the config of CatalogHandlerWithConfig.
+type CatalogConfig struct {
+	// FS holds the catalogs.
+	FS fs.FS
+
+	// Pattern is the path of the catalogs in FS, with "<lang>" standing for the language,
+	// e.g. "locales/<lang>.json".
+	Pattern string
+
+	// Default is the language of the requests matching none of the catalogs, whose catalog must exist.
+	// Default value "en".
+	Default string
+}
+
+// catalog is a catalog file, loaded at startup.
+type catalog struct {
+	lang string
+	body []byte
+	etag string
+}
+
+// CatalogHandler returns a handler serving the JSON catalog of the language best matching the
+// Accept-Language header of the request, among the files of the pattern, e.g. "locales/<lang>.json",
+// with "en" as default language. See CatalogHandlerWithConfig.
+func CatalogHandler(fsys fs.FS, pattern string) makross.Handler {
+	return CatalogHandlerWithConfig(CatalogConfig{FS: fsys, Pattern: pattern})
+}
+
+// CatalogHandlerWithConfig returns a handler serving the catalog of the language best matching the
+// Accept-Language header of the request. A regional language falls back to its base language, e.g.
+// "pt-BR" is served "pt" if there is no "pt-BR" catalog, the languages with q=0 are excluded, and
+// the requests matching no catalog are served the Default one. The catalog is sent with its
+// Content-Language, "Vary: Accept-Language" and an ETag, to which If-None-Match is compared.
+//
+// The catalogs are loaded once. It panics if none is found or the catalog of Default is missing.
+func CatalogHandlerWithConfig(config CatalogConfig) makross.Handler {
+	if config.Default == "" {
+		config.Default = "en"
+	}
+	i := strings.Index(config.Pattern, "<lang>")
+	if config.FS == nil || i < 0 {
+		panic("i18n: catalog FS and pattern with <lang> are required")
+	}
+	prefix, suffix := config.Pattern[:i], config.Pattern[i+len("<lang>"):]
+	names, err := fs.Glob(config.FS, prefix+"*"+suffix)
+	if err != nil {
+		panic(fmt.Errorf("i18n: catalog pattern %q: %v", config.Pattern, err))
+	}
+
+	// the default catalog is the first one, the one the matcher falls back to
+	catalogs := []*catalog{nil}
+	for _, name := range names {
+		lang := strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix)
+		if lang == "" || strings.Contains(lang, "/") {
+			continue
+		}
+		body, err := fs.ReadFile(config.FS, name)
+		if err != nil {
+			panic(fmt.Errorf("i18n: catalog %s: %v", name, err))
+		}
+		sum := sha256.Sum256(body)
+		c := &catalog{lang: lang, body: body, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+		if lang == config.Default {
+			catalogs[0] = c
+		} else {
+			catalogs = append(catalogs, c)
+		}
+	}
+	if catalogs[0] == nil {
+		panic(fmt.Errorf("i18n: catalog of the default language %q not found for %q", config.Default, config.Pattern))
+	}
+	tags := make([]language.Tag, len(catalogs))
+	for i, c := range catalogs {
+		tags[i] = language.Raw.Make(c.lang)
+	}
+	matcher := language.NewMatcher(tags)
+
+	return func(ctx *makross.Context) error {
+		c := catalogs[matchIndex(matcher, ctx.Request.Header.Get("Accept-Language"))]
+		header := ctx.Response.Header()
+		header.Add(makross.HeaderVary, "Accept-Language")
+		header.Set("Content-Language", c.lang)
+		header.Set(makross.HeaderContentType, makross.MIMEApplicationJSONCharsetUTF8)
+		header.Set(makross.HeaderETag, c.etag)
+		http.ServeContent(ctx.Response, ctx.Request, "", time.Time{}, bytes.NewReader(c.body))
+		return ctx.Abort()
+	}
+}
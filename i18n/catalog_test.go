@@ -0,0 +1,78 @@
+// Copyright 2017 Insion Ng
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package i18n
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/en.json":    {Data: []byte(`{"hello": "Hello"}`)},
+		"locales/pt.json":    {Data: []byte(`{"hello": "Olá"}`)},
+		"locales/de-CH.json": {Data: []byte(`{"hello": "Grüezi"}`)},
+		"locales/README.md":  {Data: []byte(`catalogs`)},
+	}
+	m := makross.New()
+	m.Get("/locales", CatalogHandler(fsys, "locales/<lang>.json"))
+	serve := func(accept, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/locales", nil)
+		req.Header.Set("Accept-Language", accept)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	// the catalog of the best language
+	res := serve("pt-BR, en;q=0.5", "")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, `{"hello": "Olá"}`, res.Body.String())
+	assert.Equal(t, "pt", res.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language", res.Header().Get("Vary"))
+	assert.Equal(t, makross.MIMEApplicationJSONCharsetUTF8, res.Header().Get("Content-Type"))
+	assert.Equal(t, "de-CH", serve("de-CH", "").Header().Get("Content-Language"))
+	assert.Equal(t, "en", serve("en-GB", "").Header().Get("Content-Language"))
+
+	// the languages with q=0 are excluded
+	assert.Equal(t, "en", serve("pt;q=0, en;q=0.1", "").Header().Get("Content-Language"))
+
+	// the default language
+	for _, accept := range []string{"", "fr", "ja;q=0.9, ko", "not a language ;;q=x"} {
+		res := serve(accept, "")
+		assert.Equal(t, 200, res.Code, accept)
+		assert.Equal(t, `{"hello": "Hello"}`, res.Body.String(), accept)
+		assert.Equal(t, "en", res.Header().Get("Content-Language"), accept)
+	}
+
+	// the ETag
+	etag := serve("pt", "").Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, 304, serve("pt", etag).Code)
+	assert.Equal(t, 200, serve("en", etag).Code)
+
+	// the catalog of the default language is missing
+	assert.Panics(t, func() {
+		CatalogHandlerWithConfig(CatalogConfig{FS: fsys, Pattern: "locales/<lang>.json", Default: "fr"})
+	})
+	assert.Panics(t, func() { CatalogHandler(fsys, "locales/en.json") })
+}
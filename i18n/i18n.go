@@ -25,6 +25,7 @@ import (
 	"github.com/insionng/makross"
 	"github.com/insionng/makross/libraries/com"
 	"github.com/insionng/makross/libraries/i18n"
+	"github.com/insionng/makross/locale"
 	"golang.org/x/text/language"
 )
 
@@ -35,10 +36,15 @@ func Version() string {
 }
 
 // initLocales initializes language type list and Accept-Language header matcher.
-func initLocales(opt Options) language.Matcher {
-	tags := make([]language.Tag, len(opt.Langs))
+// The languages of the matcher are returned, the default one first.
+func initLocales(opt Options) (language.Matcher, []string) {
+	langs := make([]string, 0, len(opt.Langs))
 	for i, lang := range opt.Langs {
-		tags[i] = language.Raw.Make(lang)
+		if lang == opt.DefaultLang {
+			langs = append([]string{lang}, langs...)
+		} else {
+			langs = append(langs, lang)
+		}
 		fname := fmt.Sprintf(opt.Format, lang)
 		// Append custom locale file.
 		custom := []interface{}{}
@@ -59,7 +65,11 @@ func initLocales(opt Options) language.Matcher {
 			panic(fmt.Errorf("fail to set message file(%s): %v", lang, err))
 		}
 	}
-	return language.NewMatcher(tags)
+	tags := make([]language.Tag, len(langs))
+	for i, lang := range langs {
+		tags[i] = language.Raw.Make(lang)
+	}
+	return language.NewMatcher(tags), langs
 }
 
 // A Localer describles the information of localization.
@@ -86,7 +96,8 @@ type Options struct {
 	Langs []string
 	// Human friendly names corresponding to Langs list.
 	Names []string
-	// Default language locale, leave empty to remain unset.
+	// Default language locale, leave empty to remain unset. It is also the language of the requests
+	// whose Accept-Language matches none of Langs, the first of Langs by default.
 	DefaultLang string
 	// Locale file naming style. Default is "locale_%s.ini".
 	Format string
@@ -157,13 +168,17 @@ type LangType struct {
 // Otherwise it may not recognize browser input.
 func I18n(options ...Options) makross.Handler {
 	opt := prepareOptions(options)
-	m := initLocales(opt)
+	m, matchLangs := initLocales(opt)
 	return func(ctx *makross.Context) error {
 		isNeedRedir := false
 		hasCookie := false
 
-		// 1. Check URL arguments.
-		lang := ctx.Query(opt.Parameter)
+		// 1. Check the locale prefix of the path, see the locale middleware, then URL arguments.
+		lang, _ := ctx.Get(locale.Key).(string)
+		if len(lang) == 0 {
+			lang = ctx.Query(opt.Parameter)
+			isNeedRedir = len(lang) > 0
+		}
 
 		// 2. Get language information from cookies.
 		if len(lang) == 0 {
@@ -172,8 +187,6 @@ func I18n(options ...Options) makross.Handler {
 				lang = cookie.Value
 				hasCookie = true
 			}
-		} else {
-			isNeedRedir = true
 		}
 
 		// Check again in case someone modify by purpose.
@@ -183,12 +196,10 @@ func I18n(options ...Options) makross.Handler {
 			hasCookie = false
 		}
 
-		// 3. Get language information from 'Accept-Language'.
-		// The first element in the list is chosen to be the default language automatically.
+		// 3. Get language information from 'Accept-Language', see Match.
+		// The default language, or the first element in the list, is chosen when none matches.
 		if len(lang) == 0 {
-			tags, _, _ := language.ParseAcceptLanguage(string(ctx.Request.Header.Get("Accept-Language")))
-			tag, _, _ := m.Match(tags...)
-			lang = tag.String()
+			lang = matchLangs[matchIndex(m, ctx.Request.Header.Get("Accept-Language"))]
 			isNeedRedir = false
 		}
 
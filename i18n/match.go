@@ -0,0 +1,63 @@
+// Copyright 2017 Insion Ng
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package i18n
+
+import (
+	"github.com/insionng/makross/locale"
+	"golang.org/x/text/language"
+)
+
+// Match returns the supported language best matching the Accept-Language header, e.g. "en" for
+// "en-GB" if there is no "en-GB" among the supported languages. The languages of the header are
+// tried by decreasing quality, those with q=0 being excluded, and a regional language falls back to
+// its base language, or to another region of the language if the base language isn't supported.
+// The first supported language is the default, returned when the header is missing, invalid or
+// matches none of them. It returns language.Und if there is no supported language.
+//
+// Match builds a matcher for each call: the middlewares matching every request build theirs once.
+func Match(acceptLanguage string, supported []language.Tag) language.Tag {
+	if len(supported) == 0 {
+		return language.Und
+	}
+	return supported[matchIndex(language.NewMatcher(supported), acceptLanguage)]
+}
+
+// matchIndex returns the index of the language of the matcher best matching the Accept-Language
+// header, 0 for the default language if there is no acceptable match.
+func matchIndex(matcher language.Matcher, acceptLanguage string) int {
+	// the languages of the header are parsed as the locale middleware does, by decreasing quality,
+	// the refused ones left out
+	var tags []language.Tag
+	for _, lang := range locale.ParseAcceptLanguage(acceptLanguage) {
+		if tag, err := language.Parse(lang.Tag); err == nil && lang.Tag != "*" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return 0
+	}
+	// the matcher weighs the languages of the header together, and may prefer an exact match of a
+	// lower quality language to the regional fallback of a higher quality one, e.g. "en" to "fr" for
+	// "fr-CA, en;q=0.9", so that the languages are first matched one by one
+	for _, tag := range tags {
+		if _, i, confidence := matcher.Match(tag); confidence >= language.High {
+			return i
+		}
+	}
+	if _, i, confidence := matcher.Match(tags...); confidence != language.No {
+		return i
+	}
+	return 0
+}
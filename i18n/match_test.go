@@ -0,0 +1,90 @@
+// Copyright 2017 Insion Ng
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package i18n
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/libraries/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestMatch(t *testing.T) {
+	supported := []language.Tag{language.English, language.French, language.MustParse("pt-BR"), language.German}
+	tests := []struct {
+		accept, lang string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		// by quality, whatever the order
+		{"de;q=0.5, fr;q=0.8", "fr"},
+		{"de, fr;q=0.8", "de"},
+		{"ja, de;q=0.1", "de"},
+		// regional fallbacks
+		{"en-GB", "en"},
+		{"fr-CA, en;q=0.9", "fr"},
+		{"pt-PT", "pt-BR"},
+		{"de-CH", "de"},
+		// the refused languages are excluded
+		{"fr;q=0, ja", "en"},
+		{"de;q=0", "en"},
+		// no acceptable match
+		{"ja, ko;q=0.5", "en"},
+		{"*", "en"},
+		{"!!invalid", "en"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.lang, Match(test.accept, supported).String(), test.accept)
+	}
+	assert.Equal(t, language.Und, Match("en", nil))
+}
+
+func TestI18nAcceptLanguage(t *testing.T) {
+	m := makross.New()
+	m.Use(I18n(Options{
+		Files: map[string][]byte{
+			"locale_en-US.ini": []byte(""),
+			"locale_fr-FR.ini": []byte(""),
+			"locale_de-DE.ini": []byte(""),
+		},
+		Langs:       []string{"en-US", "fr-FR", "de-DE"},
+		Names:       []string{"English", "Français", "Deutsch"},
+		DefaultLang: "fr-FR",
+	}))
+	m.Get("/", func(c *makross.Context) error {
+		return c.String(c.Localer.Language())
+	})
+	defer i18n.SetDefaultLang("")
+
+	tests := []struct {
+		accept, lang string
+	}{
+		{"en-GB", "en-US"},
+		{"de-AT;q=0.9, en;q=0.5", "de-DE"},
+		{"en;q=0.5, de;q=0", "en-US"},
+		{"ja", "fr-FR"},
+		{"", "fr-FR"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", test.accept)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, test.lang, res.Body.String(), test.accept)
+	}
+}
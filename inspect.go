@@ -0,0 +1,177 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// RouteInfo describes a route, for the route inspector and the tests asserting the routing.
+type RouteInfo struct {
+	Method   string                 `json:"method"`
+	Path     string                 `json:"path"`
+	Name     string                 `json:"name,omitempty"`
+	Handlers []string               `json:"handlers"` // the middlewares first, the handler last
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	Consumes []string               `json:"consumes,omitempty"`
+	Produces []string               `json:"produces,omitempty"`
+	Allow    []string               `json:"allow,omitempty"`    // set by Lookup, the methods matching the path
+	Examples []Example              `json:"examples,omitempty"` // declared with Route.Example, not listed in Meta
+}
+
+// Info returns the description of the route.
+func (r *Route) Info() RouteInfo {
+	info := RouteInfo{
+		Method: r.method,
+		Path:   r.Path(),
+		Name:   r.name,
+	}
+	for _, h := range r.handlers {
+		info.Handlers = append(info.Handlers, HandlerName(h))
+	}
+	if len(r.meta) > 0 {
+		info.Meta = make(map[string]interface{}, len(r.meta))
+		for k, v := range r.meta {
+			if k == MetaExamples {
+				// listed under Examples
+				continue
+			}
+			// keep the JSON output working with any metadata, such as functions
+			if _, err := json.Marshal(v); err != nil {
+				v = fmt.Sprintf("%T", v)
+			}
+			info.Meta[k] = v
+		}
+	}
+	info.Consumes, _ = r.meta[MetaConsumes].([]string)
+	info.Produces, _ = r.meta[MetaProduces].([]string)
+	examples, _ := r.meta[MetaExamples].([]Example)
+	for _, e := range examples {
+		// as the metadata, the bodies which can't be encoded are replaced with their type
+		if _, err := json.Marshal(e.Body); err != nil {
+			e.Body = fmt.Sprintf("%T", e.Body)
+		}
+		if _, err := json.Marshal(e.Response); err != nil {
+			e.Response = fmt.Sprintf("%T", e.Response)
+		}
+		info.Examples = append(info.Examples, e)
+	}
+	return info
+}
+
+// HandlerName returns the function name of the handler, e.g. "github.com/insionng/makross/logger.LoggerWithConfig.func1".
+func HandlerName(h Handler) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(h).Pointer()); f != nil {
+		return f.Name()
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+// Lookup returns the route the request with the method and path would be routed to, with the
+// values of the path parameters, without serving a request. The redirects, the pre handlers and
+// the drain mode aren't taken into account.
+//
+//	info, params, ok := m.Lookup("GET", "/users/42")
+//	// info.Name == "user", params["id"] == "42"
+func (m *Makross) Lookup(method, path string) (RouteInfo, map[string]string, bool) {
+	return m.lookup(method, path, nil)
+}
+
+// lookup is Lookup among the routes kept by the filter.
+func (m *Makross) lookup(method, path string, filter RouteFilter) (RouteInfo, map[string]string, bool) {
+	pvalues := make([]string, m.maxParams)
+	route, _, pnames := m.findRoute(method, path, pvalues)
+	if route == nil || !filter.keeps(route) {
+		return RouteInfo{}, nil, false
+	}
+	params := make(map[string]string, len(pnames))
+	for i, name := range pnames {
+		params[name] = pvalues[i]
+	}
+	info := route.Info()
+	info.Allow = m.allowedMethods(path, filter)
+	return info, params, true
+}
+
+// AllowedMethods returns the sorted methods of the routes matching the path.
+func (m *Makross) AllowedMethods(path string) []string {
+	return m.allowedMethods(path, nil)
+}
+
+// AllowedMethods returns the sorted methods of the routes matching the request path
+// which are served on the listener of the request, see ListenerSpec.Filter.
+func (c *Context) AllowedMethods() []string {
+	return c.makross.allowedMethods(c.Request.URL.Path, c.filter)
+}
+
+// Routes returns the routes served on the listener of the request, see ListenerSpec.Filter.
+func (c *Context) Routes() []*Route {
+	if c.filter == nil {
+		return c.makross.routes
+	}
+	routes := make([]*Route, 0, len(c.makross.routes))
+	for _, r := range c.makross.routes {
+		if c.filter(r) {
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+// allowedMethods is AllowedMethods among the routes kept by the filter.
+func (m *Makross) allowedMethods(path string, filter RouteFilter) []string {
+	methods := make([]string, 0, len(m.stores))
+	for method := range m.findAllowedMethods(path, filter) {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// MountRouteInspector serves the route table under the prefix, to debug the routing in production.
+// It should be protected by the given middlewares, such as an authentication:
+//
+//	m.MountRouteInspector("/_debug", bauth.BasicAuth(validator))
+//
+// GET prefix/routes returns the routes and GET prefix/match?method=GET&path=/users/42 returns the route
+// the request would be routed to, with the values of the path parameters, or "404 - Not Found".
+func (m *Makross) MountRouteInspector(prefix string, middleware ...Handler) {
+	g := m.Group(prefix, middleware...)
+	g.Get("/routes", func(c *Context) error {
+		infos := make([]RouteInfo, 0, len(m.routes))
+		for _, r := range m.routes {
+			if c.filter.keeps(r) {
+				infos = append(infos, r.Info())
+			}
+		}
+		sort.SliceStable(infos, func(i, j int) bool {
+			if infos[i].Path != infos[j].Path {
+				return infos[i].Path < infos[j].Path
+			}
+			return infos[i].Method < infos[j].Method
+		})
+		return c.JSON(infos)
+	})
+	g.Get("/match", func(c *Context) error {
+		method, path := c.Query("method", GET), c.Query("path")
+		if path == "" {
+			return NewHTTPError(StatusBadRequest, "path is required")
+		}
+		info, params, ok := m.lookup(method, path, c.filter)
+		if !ok {
+			return c.JSON(map[string]interface{}{
+				"method": method,
+				"path":   path,
+				"allow":  m.allowedMethods(path, c.filter),
+			}, StatusNotFound)
+		}
+		return c.JSON(map[string]interface{}{
+			"route":  info,
+			"params": params,
+		})
+	})
+}
@@ -0,0 +1,131 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func inspectTestMiddleware(c *Context) error {
+	return nil
+}
+
+func inspectTestHandler(c *Context) error {
+	return c.String("ok")
+}
+
+func newInspectTestMakross() *Makross {
+	m := New()
+	m.Use(inspectTestMiddleware)
+	m.Get("/users/<id:\\d+>", inspectTestHandler).Name("user").Meta("ratelimit", "5/m").Produces(MIMEApplicationJSON).
+		Example(Example{Name: "existing user", Params: map[string]string{"id": "7"}, Status: StatusOK, Response: "ok"})
+	m.Put("/users/<id:\\d+>", inspectTestHandler).Consumes(MIMEApplicationJSON).Meta("transform", func() {}).
+		Example(Example{Name: "stream", Body: make(chan int), Status: StatusOK})
+	m.Get("/files/*", inspectTestHandler)
+	return m
+}
+
+func TestLookup(t *testing.T) {
+	m := newInspectTestMakross()
+
+	info, params, ok := m.Lookup(GET, "/users/42")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"id": "42"}, params)
+	assert.Equal(t, GET, info.Method)
+	assert.Equal(t, "/users/<id:\\d+>", info.Path)
+	assert.Equal(t, "user", info.Name)
+	assert.Equal(t, "5/m", info.Meta["ratelimit"])
+	assert.Equal(t, []string{MIMEApplicationJSON}, info.Produces)
+	assert.Equal(t, []string{GET, PUT}, info.Allow)
+	if assert.Len(t, info.Handlers, 2) {
+		assert.True(t, strings.HasSuffix(info.Handlers[0], ".inspectTestMiddleware"), info.Handlers[0])
+		assert.True(t, strings.HasSuffix(info.Handlers[1], ".inspectTestHandler"), info.Handlers[1])
+	}
+
+	info, _, ok = m.Lookup(PUT, "/users/42")
+	assert.True(t, ok)
+	assert.Equal(t, []string{MIMEApplicationJSON}, info.Consumes)
+	assert.Equal(t, "func()", info.Meta["transform"])
+	if assert.Len(t, info.Examples, 1) {
+		assert.Equal(t, "chan int", info.Examples[0].Body)
+	}
+
+	_, params, ok = m.Lookup(GET, "/files/css/site.css")
+	assert.True(t, ok)
+	assert.Equal(t, "css/site.css", params[""])
+
+	_, _, ok = m.Lookup(GET, "/users/abc")
+	assert.False(t, ok)
+	_, _, ok = m.Lookup(DELETE, "/users/42")
+	assert.False(t, ok)
+}
+
+func TestMountRouteInspector(t *testing.T) {
+	m := newInspectTestMakross()
+	m.MountRouteInspector("/_debug", func(c *Context) error {
+		if c.Request.Header.Get(HeaderAuthorization) != "secret" {
+			return ErrUnauthorized
+		}
+		return nil
+	})
+	serve := func(uri string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(GET, uri, nil)
+		if auth {
+			req.Header.Set(HeaderAuthorization, "secret")
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	assert.Equal(t, StatusUnauthorized, serve("/_debug/routes", false).Code)
+
+	res := serve("/_debug/routes", true)
+	assert.Equal(t, StatusOK, res.Code)
+	var routes []RouteInfo
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &routes))
+	var paths []string
+	for _, r := range routes {
+		paths = append(paths, r.Method+" "+r.Path)
+	}
+	// the examples document the routes
+	var examples []Example
+	for _, r := range routes {
+		examples = append(examples, r.Examples...)
+		assert.Nil(t, r.Meta[MetaExamples])
+	}
+	if assert.Len(t, examples, 2) {
+		assert.Equal(t, Example{Name: "existing user", Params: map[string]string{"id": "7"}, Status: StatusOK, Response: "ok"}, examples[0])
+		// the bodies which can't be encoded are replaced with their type
+		assert.Equal(t, "chan int", examples[1].Body)
+	}
+	assert.Equal(t, []string{
+		"GET /_debug/match",
+		"GET /_debug/routes",
+		"GET /files/*",
+		"GET /users/<id:\\d+>",
+		"PUT /users/<id:\\d+>",
+	}, paths)
+
+	res = serve("/_debug/match?path=/users/7", true)
+	assert.Equal(t, StatusOK, res.Code)
+	var match struct {
+		Route  RouteInfo
+		Params map[string]string
+	}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &match))
+	assert.Equal(t, "user", match.Route.Name)
+	assert.Equal(t, "7", match.Params["id"])
+	assert.Len(t, match.Route.Examples, 1)
+
+	res = serve("/_debug/match?method=DELETE&path=/users/7", true)
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Contains(t, res.Body.String(), `"allow":["GET","PUT"]`)
+
+	assert.Equal(t, StatusBadRequest, serve("/_debug/match", true).Code)
+}
@@ -0,0 +1,183 @@
+package integrity
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// IntegrityConfig defines the config for Integrity middleware.
+	IntegrityConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Required rejects the requests without Digest nor Content-MD5 header.
+		// Optional. Default value false.
+		Required bool `json:"required"`
+
+		// Algorithms are the accepted digest algorithms, among "md5", "sha-256" and "sha-512".
+		// The digests of the other algorithms are ignored.
+		// Optional. Default value all of them.
+		Algorithms []string `json:"algorithms"`
+	}
+
+	// Digest is a verified digest of a request body.
+	Digest struct {
+		Algorithm string // lower case, e.g. "sha-256"
+		Value     string // base64 encoded, as sent by the client
+	}
+)
+
+const (
+	// HeaderDigest is the header of the digests of the request body, see RFC 3230.
+	HeaderDigest = "Digest"
+
+	// HeaderContentMD5 is the header of the MD5 digest of the request body, see RFC 1864.
+	HeaderContentMD5 = "Content-MD5"
+
+	// Key is the store key of the verified digests, e.g. for audit logging. See `Verified()`.
+	Key = "integrity.digests"
+)
+
+var (
+	// DefaultIntegrityConfig is the default Integrity middleware config.
+	DefaultIntegrityConfig = IntegrityConfig{
+		Skipper:    skipper.DefaultSkipper,
+		Algorithms: []string{"md5", "sha-256", "sha-512"},
+	}
+
+	// ErrDigestMissing is returned when a digest is required and the request has none.
+	ErrDigestMissing = makross.NewHTTPError(makross.StatusUnprocessableEntity, "digest_missing")
+
+	// ErrDigestInvalid is returned when the digest headers can't be parsed, or have no accepted algorithm.
+	ErrDigestInvalid = makross.NewHTTPError(makross.StatusUnprocessableEntity, "digest_invalid")
+
+	// ErrDigestMismatch is returned when a digest doesn't match the request body.
+	ErrDigestMismatch = makross.NewHTTPError(makross.StatusUnprocessableEntity, "digest_mismatch")
+
+	hashes = map[string]func() hash.Hash{
+		"md5":     md5.New,
+		"sha-256": sha256.New,
+		"sha-512": sha512.New,
+	}
+)
+
+// Integrity returns an Integrity middleware.
+//
+// Integrity middleware verifies the digests of the request body declared by the Digest header,
+// e.g. "Digest: sha-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=,md5=...", and the Content-MD5
+// header. The body is hashed as it is read into memory, and is replaced with the buffered one, so that
+// the handlers can still read and bind it. A request whose digest doesn't match its body is rejected with
+// "422 - Unprocessable Entity" and the "digest_mismatch" message. The requests without digest go through,
+// unless Required is set.
+//
+// It should be registered after BodyLimit, which bounds the body held in memory.
+func Integrity() makross.Handler {
+	return IntegrityWithConfig(DefaultIntegrityConfig)
+}
+
+// IntegrityWithConfig returns an Integrity middleware with config.
+// See: `Integrity()`.
+func IntegrityWithConfig(config IntegrityConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultIntegrityConfig.Skipper
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = DefaultIntegrityConfig.Algorithms
+	}
+	accepted := make(map[string]bool, len(config.Algorithms))
+	for _, alg := range config.Algorithms {
+		alg = strings.ToLower(alg)
+		if hashes[alg] == nil {
+			panic("integrity: unsupported algorithm " + alg)
+		}
+		accepted[alg] = true
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		req := c.Request
+		header := req.Header[HeaderDigest]
+		md5Header := req.Header.Get(HeaderContentMD5)
+		if len(header) == 0 && md5Header == "" {
+			if config.Required {
+				return ErrDigestMissing
+			}
+			return c.Next()
+		}
+
+		digests, err := parse(strings.Join(header, ","), accepted)
+		if err != nil {
+			return err
+		}
+		if md5Header != "" && accepted["md5"] {
+			digests = append(digests, Digest{Algorithm: "md5", Value: strings.TrimSpace(md5Header)})
+		}
+		if len(digests) == 0 {
+			return ErrDigestInvalid
+		}
+
+		hs := make([]hash.Hash, len(digests))
+		ws := make([]io.Writer, len(digests))
+		for i, d := range digests {
+			hs[i] = hashes[d.Algorithm]()
+			ws[i] = hs[i]
+		}
+		// the body can be read again by the handlers
+		if _, err := makross.ReadBody(req, io.MultiWriter(ws...)); err != nil {
+			return err
+		}
+
+		for i, d := range digests {
+			want, err := base64.StdEncoding.DecodeString(d.Value)
+			if err != nil {
+				return ErrDigestInvalid
+			}
+			if subtle.ConstantTimeCompare(hs[i].Sum(nil), want) != 1 {
+				return ErrDigestMismatch
+			}
+		}
+		c.Set(Key, digests)
+		return c.Next()
+	}
+}
+
+// Verified returns the digests of the request body verified by the Integrity middleware.
+func Verified(c *makross.Context) []Digest {
+	digests, _ := c.Get(Key).([]Digest)
+	return digests
+}
+
+// parse parses the digests of a Digest header, e.g. "sha-256=X48E...=,md5=HUXZ...==",
+// keeping the ones of the accepted algorithms.
+func parse(header string, accepted map[string]bool) ([]Digest, error) {
+	var digests []Digest
+	for _, s := range strings.Split(header, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		i := strings.IndexByte(s, '=')
+		if i <= 0 || i == len(s)-1 {
+			return nil, ErrDigestInvalid
+		}
+		alg := strings.ToLower(strings.TrimSpace(s[:i]))
+		if accepted[alg] {
+			digests = append(digests, Digest{Algorithm: alg, Value: strings.TrimSpace(s[i+1:])})
+		}
+	}
+	return digests, nil
+}
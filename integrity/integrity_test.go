@@ -0,0 +1,95 @@
+package integrity
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrity(t *testing.T) {
+	body := `{"amount": 42}`
+	sumMD5 := md5.Sum([]byte(body))
+	sum256 := sha256.Sum256([]byte(body))
+	sum512 := sha512.Sum512([]byte(body))
+	b64 := base64.StdEncoding.EncodeToString
+	wrong := b64(make([]byte, 32))
+
+	echo := func(c *makross.Context) error {
+		var data map[string]int
+		if err := c.Bind(&data); err != nil {
+			return err
+		}
+		var algs []string
+		for _, d := range Verified(c) {
+			algs = append(algs, d.Algorithm)
+		}
+		return c.String(strings.Join(algs, ",") + " " + strconv.Itoa(data["amount"]))
+	}
+	m := makross.New()
+	m.Post("/", Integrity(), echo)
+	m.Post("/required", IntegrityWithConfig(IntegrityConfig{Required: true, Algorithms: []string{"SHA-256"}}), echo)
+
+	tests := []struct {
+		path, digest, contentMD5 string
+		code                     int
+		body                     string
+	}{
+		{"/", "", "", makross.StatusOK, " 42"},
+		{"/", "sha-256=" + b64(sum256[:]), "", makross.StatusOK, "sha-256 42"},
+		{"/", "SHA-512=" + b64(sum512[:]) + ", md5=" + b64(sumMD5[:]), "", makross.StatusOK, "sha-512,md5 42"},
+		{"/", "", b64(sumMD5[:]), makross.StatusOK, "md5 42"},
+		{"/", "unixsum=30637, sha-256=" + b64(sum256[:]), "", makross.StatusOK, "sha-256 42"},
+		{"/", "sha-256=" + wrong, "", makross.StatusUnprocessableEntity, ""},
+		{"/", "sha-256=" + b64(sum256[:]) + ",md5=" + wrong, "", makross.StatusUnprocessableEntity, ""},
+		{"/", "", wrong, makross.StatusUnprocessableEntity, ""},
+		{"/", "sha-256=!!!", "", makross.StatusUnprocessableEntity, ""},
+		{"/", "sha-256", "", makross.StatusUnprocessableEntity, ""},
+		{"/", "unixsum=30637", "", makross.StatusUnprocessableEntity, ""},
+		{"/required", "", "", makross.StatusUnprocessableEntity, ""},
+		{"/required", "", b64(sumMD5[:]), makross.StatusUnprocessableEntity, ""},
+		{"/required", "sha-256=" + b64(sum256[:]), "", makross.StatusOK, "sha-256 42"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(makross.POST, test.path, strings.NewReader(body))
+		req.Header.Set(makross.HeaderContentType, makross.MIMEApplicationJSON)
+		if test.digest != "" {
+			req.Header.Set(HeaderDigest, test.digest)
+		}
+		if test.contentMD5 != "" {
+			req.Header.Set(HeaderContentMD5, test.contentMD5)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, test.code, res.Code, test.digest+test.contentMD5)
+		if test.code == makross.StatusOK {
+			assert.Equal(t, test.body, res.Body.String())
+		}
+	}
+
+	// the body can be read again
+	m = makross.New()
+	m.Post("/", Integrity(), func(c *makross.Context) error {
+		ioutil.ReadAll(c.Request.Body)
+		r, _ := c.Request.GetBody()
+		b, _ := ioutil.ReadAll(r)
+		return c.String(string(b))
+	})
+	req := httptest.NewRequest(makross.POST, "/", strings.NewReader(body))
+	req.Header.Set(HeaderDigest, "sha-256="+b64(sum256[:]))
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, body, res.Body.String())
+
+	assert.Panics(t, func() {
+		IntegrityWithConfig(IntegrityConfig{Algorithms: []string{"sha-1"}})
+	})
+}
@@ -0,0 +1,332 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fieldTree holds the selected fields by name, with their selected subfields, none selecting all of them.
+type fieldTree map[string]fieldTree
+
+// JSONFields sends the JSON of the data with only the selected fields, a sparse fieldset, e.g. with `?fields=id,name,author.name`:
+//
+//	return c.JSONFields(article, strings.Split(c.Query("fields"), ","))
+//
+// The fields are the JSON names of the fields of the objects, see the json tags of the structs, and a dotted path
+// selects a field of a nested object. The fields apply to each element of an array. All the fields are sent if none
+// is selected. The unknown fields are ignored, unless Makross.StrictJSONFields is set: the fields are then checked
+// against the type of the data, following the encoding/json rules, so that a field omitted because it is empty is
+// still known. The fields of the maps are the keys of their values, and the ones of interfaces, or of types with
+// their own MarshalJSON method, aren't checked.
+func (c *Context) JSONFields(data interface{}, fields []string, status ...int) error {
+	var code int
+	if len(status) > 0 {
+		code = status[0]
+	} else {
+		code = c.Response.status()
+	}
+	tree := newFieldTree(fields)
+	if len(tree) > 0 && c.makross.StrictJSONFields {
+		if err := checkFields(reflect.ValueOf(data), tree, ""); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if len(tree) > 0 {
+		var buf bytes.Buffer
+		if err = filterJSON(&buf, b, tree); err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+	return c.JSONBlob(b, code)
+}
+
+func newFieldTree(fields []string) fieldTree {
+	tree := fieldTree{}
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		t := tree
+		names := strings.Split(field, ".")
+		for i, name := range names {
+			sub, ok := t[name]
+			if ok && len(sub) == 0 {
+				// the whole field is already selected, e.g. "a" before "a.b"
+				break
+			}
+			if i == len(names)-1 {
+				// the whole field, e.g. "a" after "a.b"
+				t[name] = fieldTree{}
+				break
+			}
+			if !ok {
+				sub = fieldTree{}
+				t[name] = sub
+			}
+			t = sub
+		}
+	}
+	return tree
+}
+
+// filterJSON writes the JSON value with only the fields of the tree.
+func filterJSON(buf *bytes.Buffer, value json.RawMessage, tree fieldTree) error {
+	value = bytes.TrimSpace(value)
+	if len(tree) == 0 {
+		buf.Write(value)
+		return nil
+	}
+	switch value[0] {
+	case '[':
+		var elements []json.RawMessage
+		if err := json.Unmarshal(value, &elements); err != nil {
+			return err
+		}
+		buf.WriteByte('[')
+		for i, element := range elements {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := filterJSON(buf, element, tree); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case '{':
+		return filterObject(buf, value, tree)
+	}
+	buf.Write(value)
+	return nil
+}
+
+// filterObject writes the JSON object with only the fields of the tree, in their order in the object.
+func filterObject(buf *bytes.Buffer, object json.RawMessage, tree fieldTree) error {
+	dec := json.NewDecoder(bytes.NewReader(object))
+	if _, err := dec.Token(); err != nil { // {
+		return err
+	}
+	found := false
+	buf.WriteByte('{')
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return err
+		}
+		name, _ := t.(string)
+		sub, ok := tree[name]
+		if !ok {
+			continue
+		}
+		if found {
+			buf.WriteByte(',')
+		}
+		found = true
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err = filterJSON(buf, value, sub); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// jsonNames caches the JSON field indexes of the struct types, by name.
+	jsonNames sync.Map // map[reflect.Type]map[string][]int
+)
+
+// checkFields returns the "400 - Bad Request" HTTPError of the first field of the tree, in alphabetical
+// order, which the JSON of the value can't have, prefix being the path of the value.
+func checkFields(v reflect.Value, tree fieldTree, prefix string) error {
+	if len(tree) == 0 || !v.IsValid() {
+		return nil
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			if t.Kind() == reflect.Interface {
+				return nil
+			}
+			// the fields of the type are known all the same
+			return checkFields(reflect.Zero(t.Elem()), tree, prefix)
+		}
+		return checkFields(v.Elem(), tree, prefix)
+	case reflect.Struct:
+		fields := jsonFieldsOf(t)
+		for _, name := range sortedNames(tree) {
+			index, ok := fields[name]
+			if !ok {
+				return unknownField(prefix + name)
+			}
+			f, err := v.FieldByIndexErr(index)
+			if err != nil {
+				// through a nil embedded pointer
+				f = reflect.Zero(t.FieldByIndex(index).Type)
+			}
+			if err = checkFields(f, tree[name], prefix+name+"."); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			// the keys aren't known
+			return nil
+		}
+		for _, name := range sortedNames(tree) {
+			value := v.MapIndex(reflect.ValueOf(name).Convert(t.Key()))
+			if !value.IsValid() {
+				return unknownField(prefix + name)
+			}
+			if err := checkFields(value, tree[name], prefix+name+"."); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoded as a string
+			break
+		}
+		if v.Len() == 0 {
+			return checkFields(reflect.Zero(t.Elem()), tree, prefix)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := checkFields(v.Index(i), tree, prefix); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return unknownField(prefix + sortedNames(tree)[0])
+}
+
+// jsonFieldsOf returns the indexes of the fields of the struct type encoded by encoding/json, by JSON name,
+// the fields of the embedded structs included.
+func jsonFieldsOf(t reflect.Type) map[string][]int {
+	if fields, ok := jsonNames.Load(t); ok {
+		return fields.(map[string][]int)
+	}
+	type field struct {
+		index  []int
+		tagged bool
+	}
+	// the fields by name and depth, the shallower ones hiding the deeper ones as in encoding/json
+	byName := map[string][]field{}
+	current := []field{{}}
+	visited := map[reflect.Type]bool{}
+	for len(current) > 0 {
+		var next []field
+		found := map[string][]field{}
+		for _, parent := range current {
+			st := t
+			if len(parent.index) > 0 {
+				st = t.FieldByIndex(parent.index).Type
+				if st.Kind() == reflect.Ptr {
+					st = st.Elem()
+				}
+			}
+			if visited[st] {
+				continue
+			}
+			visited[st] = true
+			for i := 0; i < st.NumField(); i++ {
+				sf := st.Field(i)
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, _, _ := strings.Cut(tag, ",")
+				index := append(append([]int(nil), parent.index...), i)
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, field{index: index})
+					continue
+				}
+				if name == "" {
+					name = sf.Name
+				}
+				found[name] = append(found[name], field{index: index, tagged: tag != "" && !strings.HasPrefix(tag, ",")})
+			}
+		}
+		for name, fs := range found {
+			if _, hidden := byName[name]; !hidden {
+				byName[name] = fs
+			}
+		}
+		current = next
+	}
+	fields := make(map[string][]int, len(byName))
+	for name, fs := range byName {
+		if len(fs) == 1 {
+			fields[name] = fs[0].index
+			continue
+		}
+		// the conflicting fields are dropped, unless a single one is tagged
+		var tagged []field
+		for _, f := range fs {
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		if len(tagged) == 1 {
+			fields[name] = tagged[0].index
+		}
+	}
+	jsonNames.Store(t, fields)
+	return fields
+}
+
+func sortedNames(tree fieldTree) []string {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func unknownField(name string) error {
+	return NewHTTPError(StatusBadRequest, fmt.Sprintf("unknown field %q", name))
+}
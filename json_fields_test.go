@@ -0,0 +1,129 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fieldsAuthor struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Bio  string `json:"bio"`
+}
+
+type fieldsArticle struct {
+	ID     int                    `json:"id"`
+	Title  string                 `json:"title"`
+	Body   string                 `json:"body,omitempty"`
+	Author *fieldsAuthor          `json:"author"`
+	Tags   []string               `json:"tags"`
+	Extra  map[string]interface{} `json:"extra"`
+	secret string
+}
+
+type fieldsEmbedding struct {
+	fieldsAuthor
+	*fieldsArticle
+	Note   string
+	Tagged fieldsAuthor
+	ID     int `json:"-"`
+}
+
+func TestContextJSONFields(t *testing.T) {
+	article := fieldsArticle{
+		ID:     1,
+		Title:  "Hello",
+		Body:   "...",
+		Author: &fieldsAuthor{ID: 7, Name: "Ann", Bio: "..."},
+		Tags:   []string{"go"},
+		Extra:  map[string]interface{}{"views": 10, "likes": 2},
+	}
+	send := func(strict bool, data interface{}, fields ...string) (*httptest.ResponseRecorder, error) {
+		c, res := testNewContext()
+		c.makross.StrictJSONFields = strict
+		return res, c.JSONFields(data, fields)
+	}
+
+	tests := []struct {
+		fields []string
+		body   string
+	}{
+		{nil, `{"id":1,"title":"Hello","body":"...","author":{"id":7,"name":"Ann","bio":"..."},"tags":["go"],"extra":{"likes":2,"views":10}}`},
+		{[]string{""}, `{"id":1,"title":"Hello","body":"...","author":{"id":7,"name":"Ann","bio":"..."},"tags":["go"],"extra":{"likes":2,"views":10}}`},
+		{[]string{"title", "id"}, `{"id":1,"title":"Hello"}`},
+		{[]string{"id", "author.name"}, `{"id":1,"author":{"name":"Ann"}}`},
+		// a selected field keeps all its subfields
+		{[]string{"author", "author.name"}, `{"author":{"id":7,"name":"Ann","bio":"..."}}`},
+		{[]string{"author.name", "author"}, `{"author":{"id":7,"name":"Ann","bio":"..."}}`},
+		{[]string{"author.name", "author.nope.x", "author.nope"}, `{"author":{"name":"Ann"}}`},
+		{[]string{"author"}, `{"author":{"id":7,"name":"Ann","bio":"..."}}`},
+		{[]string{" tags ", "extra.views"}, `{"tags":["go"],"extra":{"views":10}}`},
+		{[]string{"id", "nope", "author.nope"}, `{"id":1,"author":{}}`},
+	}
+	for _, test := range tests {
+		res, err := send(false, article, test.fields...)
+		if assert.Nil(t, err, test.fields) {
+			assert.Equal(t, test.body, res.Body.String(), test.fields)
+			assert.Equal(t, MIMEApplicationJSONCharsetUTF8, res.Header().Get(HeaderContentType))
+		}
+	}
+
+	// the fields apply to the elements of arrays, maps work as structs
+	res, err := send(true, []interface{}{
+		article,
+		fieldsArticle{ID: 2, Title: "Nobody"},
+		map[string]interface{}{"id": 3, "author": map[string]string{"name": "Bob"}},
+	}, "id", "author.name")
+	if assert.Nil(t, err) {
+		assert.Equal(t, `[{"id":1,"author":{"name":"Ann"}},{"id":2,"author":null},{"author":{"name":"Bob"},"id":3}]`, res.Body.String())
+	}
+
+	// strict mode
+	strictTests := []struct {
+		data    interface{}
+		fields  []string
+		message string
+	}{
+		{article, []string{"id", "nope"}, `unknown field "nope"`},
+		{map[string]interface{}{"author": map[string]string{"name": "Ann"}}, []string{"author.bio"}, `unknown field "author.bio"`},
+		{article, []string{"title.size"}, `unknown field "title.size"`},
+		{[]fieldsArticle{}, []string{"author.nope"}, `unknown field "author.nope"`},
+		{fieldsEmbedding{}, []string{"secret"}, `unknown field "secret"`},
+		{fieldsEmbedding{}, []string{"id"}, `unknown field "id"`},
+	}
+	for _, test := range strictTests {
+		_, err := send(true, test.data, test.fields...)
+		if assert.IsType(t, &HTTPError{}, err, test.message) {
+			assert.Equal(t, StatusBadRequest, err.(*HTTPError).Status)
+			assert.Equal(t, test.message, err.(*HTTPError).Message)
+		}
+	}
+
+	// the fields are known from the types, as encoded by encoding/json, even when omitted
+	known := []struct {
+		data   interface{}
+		fields []string
+		body   string
+	}{
+		{fieldsArticle{ID: 1}, []string{"body"}, `{}`},
+		{&fieldsArticle{ID: 1}, []string{"id", "author.bio", "extra.views"}, `{"id":1,"author":null,"extra":null}`},
+		{[]*fieldsArticle{}, []string{"author.name"}, `[]`},
+		{fieldsEmbedding{Note: "x", fieldsAuthor: fieldsAuthor{Name: "Ann"}}, []string{"name", "Note"}, `{"name":"Ann","Note":"x"}`},
+		{fieldsEmbedding{}, []string{"Tagged", "title"}, `{"Tagged":{"id":0,"name":"","bio":""}}`},
+	}
+	for _, test := range known {
+		res, err := send(true, test.data, test.fields...)
+		if assert.Nil(t, err, fmt.Sprint(test.fields)) {
+			assert.Equal(t, test.body, res.Body.String(), fmt.Sprint(test.fields))
+		}
+	}
+
+	c, res := testNewContext()
+	assert.Nil(t, c.JSONFields(article, []string{"id"}, StatusCreated))
+	assert.Equal(t, StatusCreated, res.Code)
+}
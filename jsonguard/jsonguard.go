@@ -0,0 +1,116 @@
+package jsonguard
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// JSONGuardConfig defines the config for JSONGuard middleware.
+	JSONGuardConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// AllowArray accepts the bodies which are arrays as well as objects, e.g. for the batch endpoints.
+		// Optional. Default value false.
+		AllowArray bool `json:"allow_array"`
+	}
+
+	guardedBody struct {
+		io.Reader
+		io.Closer
+	}
+)
+
+var (
+	// DefaultJSONGuardConfig is the default JSONGuard middleware config.
+	DefaultJSONGuardConfig = JSONGuardConfig{
+		Skipper: skipper.DefaultSkipper,
+	}
+
+	// ErrNotObject is returned when a JSON body isn't an object.
+	ErrNotObject = makross.NewHTTPError(makross.StatusBadRequest, "JSON body must be an object")
+
+	// ErrNotObjectOrArray is returned when a JSON body is neither an object nor an array.
+	ErrNotObjectOrArray = makross.NewHTTPError(makross.StatusBadRequest, "JSON body must be an object or an array")
+)
+
+// JSONGuard returns a JSONGuard middleware.
+//
+// JSONGuard middleware rejects the JSON request bodies which aren't objects, such as top-level
+// arrays, strings or numbers, with "400 - Bad Request". Only the first non-whitespace byte is read,
+// and the body is left intact for the handler. The requests with another content type and those
+// without body go through.
+func JSONGuard() makross.Handler {
+	return JSONGuardWithConfig(DefaultJSONGuardConfig)
+}
+
+// JSONGuardWithConfig returns a JSONGuard middleware with config.
+// See: `JSONGuard()`.
+func JSONGuardWithConfig(config JSONGuardConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultJSONGuardConfig.Skipper
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		req := c.Request
+		if req.Body == nil || !isJSON(req.Header.Get(makross.HeaderContentType)) {
+			return c.Next()
+		}
+
+		first, peeked, err := peek(req.Body)
+		// restore the body, including what has been read
+		req.Body = &guardedBody{io.MultiReader(bytes.NewReader(peeked), req.Body), req.Body}
+		if err != nil {
+			return err
+		}
+		switch {
+		case first == 0, first == '{':
+		case first == '[' && config.AllowArray:
+		case config.AllowArray:
+			return ErrNotObjectOrArray
+		default:
+			return ErrNotObject
+		}
+		return c.Next()
+	}
+}
+
+// peek reads the body up to its first non-whitespace byte, which is 0 for an empty body,
+// and returns the bytes read.
+func peek(r io.Reader) (byte, []byte, error) {
+	var peeked []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+				return b, append(peeked, buf[:n]...), nil
+			}
+		}
+		peeked = append(peeked, buf[:n]...)
+		if err == io.EOF {
+			return 0, peeked, nil
+		}
+		if err != nil {
+			return 0, peeked, err
+		}
+	}
+}
+
+// isJSON reports whether the content type is application/json or a JSON based type,
+// such as application/merge-patch+json.
+func isJSON(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (t == makross.MIMEApplicationJSON || strings.HasSuffix(t, "+json"))
+}
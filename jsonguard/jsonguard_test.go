@@ -0,0 +1,60 @@
+package jsonguard
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONGuard(t *testing.T) {
+	echo := func(c *makross.Context) error {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		return c.String(string(body))
+	}
+	m := makross.New()
+	m.Post("/", JSONGuard(), echo)
+	m.Post("/batch", JSONGuardWithConfig(JSONGuardConfig{AllowArray: true}), echo)
+
+	tests := []struct {
+		path, contentType, body string
+		code                    int
+	}{
+		{"/", makross.MIMEApplicationJSON, `{}`, makross.StatusOK},
+		{"/", makross.MIMEApplicationJSONCharsetUTF8, " \n\t{\"name\": \"makross\"}", makross.StatusOK},
+		{"/", "application/merge-patch+json", `{"name": null}`, makross.StatusOK},
+		{"/", makross.MIMEApplicationJSON, `[]`, makross.StatusBadRequest},
+		{"/", makross.MIMEApplicationJSON, `"x"`, makross.StatusBadRequest},
+		{"/", makross.MIMEApplicationJSON, ` 42`, makross.StatusBadRequest},
+		{"/", makross.MIMEApplicationJSON, ``, makross.StatusOK},
+		{"/", makross.MIMETextPlain, `[]`, makross.StatusOK},
+		{"/batch", makross.MIMEApplicationJSON, `[{}, {}]`, makross.StatusOK},
+		{"/batch", makross.MIMEApplicationJSON, `{}`, makross.StatusOK},
+		{"/batch", makross.MIMEApplicationJSON, `"x"`, makross.StatusBadRequest},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(makross.POST, test.path, strings.NewReader(test.body))
+		req.Header.Set(makross.HeaderContentType, test.contentType)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, test.code, res.Code, test.body)
+		if test.code == makross.StatusOK {
+			// the handler reads the whole body
+			assert.Equal(t, test.body, res.Body.String())
+		}
+	}
+
+	// a body longer than the peeked chunk
+	body := strings.Repeat(" ", 100) + `{"a": 1}`
+	req := httptest.NewRequest(makross.POST, "/", strings.NewReader(body))
+	req.Header.Set(makross.HeaderContentType, makross.MIMEApplicationJSON)
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, body, res.Body.String())
+}
@@ -0,0 +1,199 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// link is a link set with SetLink, formatted.
+type link struct {
+	rel      string
+	hreflang string
+	value    string
+}
+
+// SetLink adds a link to the Link header of the response, as specified by RFC 8288, e.g. for the pagination:
+//
+//	c.SetLink("next", "/users?page=3")
+//	c.SetLink("prev", "/users?page=1", "title", "Page précédente")
+//	// Link: </users?page=3>; rel="next", </users?page=1>; rel="prev"; title*=UTF-8''Page%20pr%C3%A9c%C3%A9dente
+//
+// The params are the pairs of names and values of the target attributes, such as "title", "type" or
+// "hreflang". The values are quoted, or encoded as specified by RFC 8187 if they aren't ASCII, in which
+// case the name gets a "*". The value of a name ending with "*" may start with its language followed
+// by a quote, e.g. "title*", "de'nächstes Kapitel". The characters of the URL which can't appear in a
+// URI reference are percent-encoded, and the braces of the URI templates are kept.
+//
+// The links are written when the header is written, in one Link header value, after the ones added
+// to the header otherwise. Setting a link of a relation type already set replaces it, unless their
+// "hreflang" differ, e.g. for the alternate versions of a page in several languages.
+func (c *Context) SetLink(rel, url string, params ...string) {
+	value := formatLink(rel, url, params)
+	hreflang := ""
+	for i := 0; i+1 < len(params); i += 2 {
+		if params[i] == "hreflang" {
+			hreflang = params[i+1]
+		}
+	}
+	for i := range c.links {
+		if c.links[i].rel == rel && c.links[i].hreflang == hreflang {
+			c.links[i].value = value
+			return
+		}
+	}
+	if len(c.links) == 0 {
+		c.Response.Before(c.writeLinks)
+	}
+	c.links = append(c.links, link{rel: rel, hreflang: hreflang, value: value})
+}
+
+// WriteLinkHeaders sets the pagination links of the list of total items whose page of limit
+// items starting at offset is sent, see Paginate, with SetLink: "first", "prev" unless it is the
+// first page, and, if the total is known, "next" unless it is the last page and "last". A negative
+// total is unknown. The links are the URL of the request with the page, or the offset if the
+// request has one, of the other pages:
+//
+//	c.WriteLinkHeaders(20, 20, 45) // GET /items?page=2
+//	// first: /items?page=1, prev: /items?page=1, next: /items?page=3, last: /items?page=3
+func (c *Context) WriteLinkHeaders(limit, offset, total int) {
+	if limit < 1 || offset < 0 {
+		return
+	}
+	query := c.Request.URL.Query()
+	_, byOffset := query["offset"]
+	pageURL := func(offset int) string {
+		if byOffset {
+			query.Set("offset", strconv.Itoa(offset))
+		} else {
+			query.Set("page", strconv.Itoa(offset/limit+1))
+		}
+		return c.Request.URL.Path + "?" + query.Encode()
+	}
+	c.SetLink("first", pageURL(0))
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		c.SetLink("prev", pageURL(prev))
+	}
+	if total < 0 {
+		return
+	}
+	if offset+limit < total {
+		c.SetLink("next", pageURL(offset+limit))
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / limit * limit
+	}
+	c.SetLink("last", pageURL(last))
+}
+
+// writeLinks adds the links set with SetLink to the Link header.
+func (c *Context) writeLinks() {
+	if len(c.links) == 0 {
+		return
+	}
+	values := make([]string, len(c.links))
+	for i, l := range c.links {
+		values[i] = l.value
+	}
+	c.Response.Header().Add(HeaderLink, strings.Join(values, ", "))
+}
+
+// formatLink returns the link-value of the link, e.g. `</users?page=3>; rel="next"`.
+func formatLink(rel, url string, params []string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	writeLinkURL(&b, url)
+	b.WriteString(`>; rel=`)
+	writeQuoted(&b, rel)
+	for i := 0; i+1 < len(params); i += 2 {
+		name, value := params[i], params[i+1]
+		b.WriteString("; ")
+		if strings.HasSuffix(name, "*") || !isASCII(value) {
+			b.WriteString(strings.TrimSuffix(name, "*"))
+			b.WriteString("*=")
+			writeExtValue(&b, value, strings.HasSuffix(name, "*"))
+			continue
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		writeQuoted(&b, value)
+	}
+	return b.String()
+}
+
+// writeLinkURL writes the URL, percent-encoding the characters which can't appear in a URI reference,
+// but the braces of the URI templates.
+func writeLinkURL(b *strings.Builder, url string) {
+	for i := 0; i < len(url); i++ {
+		ch := url[i]
+		if ch <= ' ' || ch >= utf8.RuneSelf || strings.IndexByte(`<>"\^`+"`|", ch) >= 0 {
+			writePercent(b, ch)
+			continue
+		}
+		b.WriteByte(ch)
+	}
+}
+
+// writeQuoted writes the value as a quoted-string.
+func writeQuoted(b *strings.Builder, value string) {
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	b.WriteByte('"')
+}
+
+// writeExtValue writes the value as an ext-value of RFC 8187, e.g. "UTF-8'de'n%C3%A4chstes%20Kapitel",
+// with the language before the first quote of the value if withLanguage.
+func writeExtValue(b *strings.Builder, value string, withLanguage bool) {
+	language := ""
+	if i := strings.IndexByte(value, '\''); withLanguage && i >= 0 {
+		language, value = value[:i], value[i+1:]
+	}
+	b.WriteString("UTF-8'")
+	b.WriteString(language)
+	b.WriteByte('\'')
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if isAttrChar(ch) {
+			b.WriteByte(ch)
+		} else {
+			writePercent(b, ch)
+		}
+	}
+}
+
+// isAttrChar reports whether the byte is an attr-char of RFC 8187, which isn't percent-encoded.
+func isAttrChar(ch byte) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", ch) >= 0
+}
+
+func writePercent(b *strings.Builder, ch byte) {
+	const hex = "0123456789ABCDEF"
+	b.WriteByte('%')
+	b.WriteByte(hex[ch>>4])
+	b.WriteByte(hex[ch&15])
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLink(t *testing.T) {
+	// the examples of RFC 8288, section 3.5
+	tests := []struct {
+		rel, url string
+		params   []string
+		link     string
+	}{
+		{"previous", "http://example.com/TheBook/chapter2", []string{"title", "previous chapter"},
+			`<http://example.com/TheBook/chapter2>; rel="previous"; title="previous chapter"`},
+		{"http://example.net/foo", "/", nil,
+			`</>; rel="http://example.net/foo"`},
+		{"copyright", "/terms", []string{"anchor", "#foo"},
+			`</terms>; rel="copyright"; anchor="#foo"`},
+		{"previous", "/TheBook/chapter2", []string{"title*", "de'letztes Kapitel"},
+			`</TheBook/chapter2>; rel="previous"; title*=UTF-8'de'letztes%20Kapitel`},
+		{"next", "/TheBook/chapter4", []string{"title*", "de'nächstes Kapitel"},
+			`</TheBook/chapter4>; rel="next"; title*=UTF-8'de'n%C3%A4chstes%20Kapitel`},
+		{"start http://example.net/relation/other", "http://example.org/", nil,
+			`<http://example.org/>; rel="start http://example.net/relation/other"`},
+
+		// quoting and escaping
+		{"next", "/users?page=3", []string{"title", `the "next" page \o/`},
+			`</users?page=3>; rel="next"; title="the \"next\" page \\o/"`},
+		{"prev", "/users?page=1", []string{"title", "Page précédente", "type", "text/html"},
+			`</users?page=1>; rel="prev"; title*=UTF-8''Page%20pr%C3%A9c%C3%A9dente; type="text/html"`},
+		{"search", "/search{?q,page}", nil,
+			`</search{?q,page}>; rel="search"`},
+		{"alternate", "/café/a b<c>", []string{"hreflang", "fr"},
+			`</caf%C3%A9/a%20b%3Cc%3E>; rel="alternate"; hreflang="fr"`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.link, formatLink(test.rel, test.url, test.params))
+	}
+}
+
+func TestContextSetLink(t *testing.T) {
+	m := New()
+	m.Get("/users", func(c *Context) error {
+		c.Response.Header().Add(HeaderLink, `</docs>; rel="help"`)
+		c.SetLink("next", "/users?page=2")
+		c.SetLink("prev", "/users?page=0")
+		c.SetLink("next", "/users?page=3", "title", "next page")
+		return c.String("users")
+	})
+	m.Get("/none", func(c *Context) error {
+		return c.String("none")
+	})
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/users", nil))
+	assert.Equal(t, []string{
+		`</docs>; rel="help"`,
+		`</users?page=3>; rel="next"; title="next page", </users?page=0>; rel="prev"`,
+	}, res.Header()[HeaderLink])
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/none", nil))
+	assert.Nil(t, res.Header()[HeaderLink])
+}
+
+func TestContextWriteLinkHeaders(t *testing.T) {
+	tests := []struct {
+		uri                  string
+		limit, offset, total int
+		links                string
+	}{
+		{"/items", 20, 0, 45,
+			`</items?page=1>; rel="first", </items?page=2>; rel="next", </items?page=3>; rel="last"`},
+		{"/items?page=2", 20, 20, 45,
+			`</items?page=1>; rel="first", </items?page=1>; rel="prev", </items?page=3>; rel="next", </items?page=3>; rel="last"`},
+		{"/items?page=3&sort=name", 20, 40, 45,
+			`</items?page=1&sort=name>; rel="first", </items?page=2&sort=name>; rel="prev", </items?page=3&sort=name>; rel="last"`},
+		{"/items?offset=15&limit=10", 10, 15, 40,
+			`</items?limit=10&offset=0>; rel="first", </items?limit=10&offset=5>; rel="prev", </items?limit=10&offset=25>; rel="next", </items?limit=10&offset=30>; rel="last"`},
+		{"/items?offset=5", 10, 5, -1,
+			`</items?offset=0>; rel="first", </items?offset=0>; rel="prev"`},
+		{"/items", 20, 0, 0,
+			`</items?page=1>; rel="first", </items?page=1>; rel="last"`},
+	}
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		c := New().NewContext(httptest.NewRequest(GET, test.uri, nil), res)
+		c.WriteLinkHeaders(test.limit, test.offset, test.total)
+		c.String("items")
+		assert.Equal(t, test.links, res.Header().Get(HeaderLink), test.uri)
+	}
+}
+
+func TestContextSetLinkHreflang(t *testing.T) {
+	res := httptest.NewRecorder()
+	c := New().NewContext(httptest.NewRequest(GET, "/", nil), res)
+	c.SetLink("alternate", "/en/", "hreflang", "en")
+	c.SetLink("alternate", "/de/", "hreflang", "de")
+	c.SetLink("alternate", "/en-us/", "hreflang", "en")
+	c.String("home")
+	assert.Equal(t, `</en-us/>; rel="alternate"; hreflang="en", </de/>; rel="alternate"; hreflang="de"`, res.Header().Get(HeaderLink))
+}
@@ -4,6 +4,7 @@ package makross
 
 import (
 	"log"
+	"net"
 	"os"
 	"runtime"
 	"strconv"
@@ -20,7 +21,13 @@ func (m *Makross) Listen(args ...interface{}) {
 	m.DoActionHook("MakrossListen")
 	m.Server.Addr = addr
 
-	log.Fatal(m.Server.ListenAndServe())
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Fatal(m.serveWarm(func() error {
+		return m.Server.Serve(l)
+	}, m.Server.Close))
 }
 
 func (m *Makross) ListenTLS(certFile, keyFile string, args ...interface{}) {
@@ -33,7 +40,13 @@ func (m *Makross) ListenTLS(certFile, keyFile string, args ...interface{}) {
 	m.DoActionHook("MakrossListenTLS")
 	m.Server.Addr = addr
 
-	log.Fatal(m.Server.ListenAndServeTLS(certFile, keyFile))
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Fatal(m.serveWarm(func() error {
+		return m.Server.ServeTLS(l, certFile, keyFile)
+	}, m.Server.Close))
 }
 
 func GetAddress(args ...interface{}) string {
@@ -0,0 +1,178 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+)
+
+type (
+	// ListenerSpec describes one of the listeners of StartMultiple.
+	ListenerSpec struct {
+		// Addr is the address to listen on, e.g. ":8080".
+		Addr string
+
+		// Listener is listened on instead of Addr, if set.
+		Listener net.Listener
+
+		// TLSConfig serves HTTPS on the listener, with its certificates.
+		// Optional.
+		TLSConfig *tls.Config
+
+		// Filter restricts the routes served on the listener, the other ones respond "404 - Not Found".
+		// Optional. Default all the routes.
+		Filter RouteFilter
+	}
+
+	// RouteFilter reports whether a route is served, see ListenerSpec.Filter.
+	RouteFilter func(*Route) bool
+)
+
+// RoutesTagged returns a RouteFilter keeping the routes with the tag, see Route.Tag.
+func RoutesTagged(tag interface{}) RouteFilter {
+	return func(r *Route) bool {
+		return hasTag(r, tag)
+	}
+}
+
+// RoutesNotTagged returns a RouteFilter keeping the routes without the tag, see Route.Tag.
+func RoutesNotTagged(tag interface{}) RouteFilter {
+	return func(r *Route) bool {
+		return !hasTag(r, tag)
+	}
+}
+
+// keeps reports whether the route is served, all the routes being served without filter.
+func (f RouteFilter) keeps(r *Route) bool {
+	return f == nil || f(r)
+}
+
+func hasTag(r *Route, tag interface{}) bool {
+	for _, t := range r.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// StartMultiple serves the application on several listeners at once, e.g. a public port and an internal
+// admin port with extra routes:
+//
+//	m.Get("/admin/stats", stats).Tag("admin")
+//	err := m.StartMultiple([]makross.ListenerSpec{
+//		{Addr: ":8080", Filter: makross.RoutesNotTagged("admin")},
+//		{Addr: "127.0.0.1:9090"},
+//	})
+//
+// The listeners share the routes, the middlewares and the limits of Makross.Server, see Configure.
+// StartMultiple returns the first error of the listeners, after closing the other ones, or
+// http.ErrServerClosed after Shutdown or Close, which stop all the listeners. The Warmup functions
+// run once the listeners are bound, and their error is returned after closing the listeners.
+func (m *Makross) StartMultiple(specs []ListenerSpec) error {
+	listeners := make([]net.Listener, 0, len(specs))
+	for _, spec := range specs {
+		l := spec.Listener
+		if l == nil {
+			var err error
+			if l, err = net.Listen("tcp", spec.Addr); err != nil {
+				for _, l := range listeners {
+					l.Close()
+				}
+				return err
+			}
+		}
+		listeners = append(listeners, l)
+	}
+
+	servers := make([]*http.Server, len(specs))
+	for i, spec := range specs {
+		servers[i] = m.newListenerServer(spec)
+	}
+	m.serversMu.Lock()
+	m.servers = append(m.servers, servers...)
+	m.serversMu.Unlock()
+	defer m.removeListenerServers(servers)
+
+	m.DoActionHook("MakrossListen")
+	closeAll := func() error {
+		for _, s := range servers {
+			s.Close()
+		}
+		return nil
+	}
+	return m.serveWarm(func() error {
+		errs := make(chan error, len(servers))
+		var wg sync.WaitGroup
+		for i, s := range servers {
+			wg.Add(1)
+			go func(s *http.Server, l net.Listener) {
+				defer wg.Done()
+				if s.TLSConfig != nil {
+					errs <- s.ServeTLS(l, "", "")
+				} else {
+					errs <- s.Serve(l)
+				}
+			}(s, listeners[i])
+		}
+
+		err := <-errs
+		if err != http.ErrServerClosed {
+			closeAll()
+		}
+		wg.Wait()
+		return err
+	}, closeAll)
+}
+
+// newListenerServer returns the server of the listener, with the limits of Makross.Server.
+func (m *Makross) newListenerServer(spec ListenerSpec) *http.Server {
+	s := &http.Server{
+		Addr:              spec.Addr,
+		Handler:           m,
+		TLSConfig:         spec.TLSConfig,
+		ReadHeaderTimeout: m.Server.ReadHeaderTimeout,
+		ReadTimeout:       m.Server.ReadTimeout,
+		WriteTimeout:      m.Server.WriteTimeout,
+		IdleTimeout:       m.Server.IdleTimeout,
+		MaxHeaderBytes:    m.Server.MaxHeaderBytes,
+		ConnState:         m.Server.ConnState,
+		ErrorLog:          m.Server.ErrorLog,
+	}
+	s.SetKeepAlivesEnabled(!m.keepAlivesOff.Load())
+	if filter := spec.Filter; filter != nil {
+		s.Handler = http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			m.serve(res, req, filter)
+		})
+	}
+	return s
+}
+
+// listenerServers returns the servers of the running StartMultiple calls.
+func (m *Makross) listenerServers() []*http.Server {
+	m.serversMu.Lock()
+	defer m.serversMu.Unlock()
+	return append([]*http.Server(nil), m.servers...)
+}
+
+func (m *Makross) removeListenerServers(servers []*http.Server) {
+	m.serversMu.Lock()
+	defer m.serversMu.Unlock()
+	kept := m.servers[:0]
+	for _, s := range m.servers {
+		keep := true
+		for _, removed := range servers {
+			if s == removed {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, s)
+		}
+	}
+	m.servers = kept
+}
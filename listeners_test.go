@@ -0,0 +1,178 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartMultiple(t *testing.T) {
+	m := New()
+	m.Get("/", func(c *Context) error {
+		return c.String("home")
+	})
+	m.Get("/admin/stats", func(c *Context) error {
+		return c.String("stats")
+	}).Tag("admin")
+
+	public, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- m.StartMultiple([]ListenerSpec{
+			{Listener: public, Filter: RoutesNotTagged("admin")},
+			{Listener: admin},
+		})
+	}()
+
+	get := func(l net.Listener, path string) (int, string) {
+		res, err := http.Get("http://" + l.Addr().String() + path)
+		if !assert.Nil(t, err) {
+			return 0, ""
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+	status, body := get(public, "/")
+	assert.Equal(t, StatusOK, status)
+	assert.Equal(t, "home", body)
+	status, _ = get(public, "/admin/stats")
+	assert.Equal(t, StatusNotFound, status)
+	status, body = get(admin, "/admin/stats")
+	assert.Equal(t, StatusOK, status)
+	assert.Equal(t, "stats", body)
+	status, _ = get(admin, "/")
+	assert.Equal(t, StatusOK, status)
+
+	assert.Nil(t, m.Shutdown(1))
+	select {
+	case err := <-done:
+		assert.Equal(t, http.ErrServerClosed, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartMultiple didn't return after Shutdown")
+	}
+	_, err = http.Get("http://" + public.Addr().String())
+	assert.NotNil(t, err)
+}
+
+type failingListener struct {
+	net.Listener
+}
+
+var errAccept = errors.New("accept failed")
+
+func (failingListener) Accept() (net.Conn, error) {
+	return nil, errAccept
+}
+
+func TestStartMultipleKeepAlives(t *testing.T) {
+	m := New()
+	m.Configure(ServerConfig{DisableKeepAlives: true})
+	m.Get("/", func(c *Context) error {
+		return c.String("ok")
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go m.StartMultiple([]ListenerSpec{{Listener: l}})
+	defer m.Close()
+
+	res, err := http.Get("http://" + l.Addr().String() + "/")
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.True(t, res.Close, "the listener should close the connection")
+	}
+}
+
+func TestStartMultipleError(t *testing.T) {
+	m := New()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer l.Close()
+	failing, _ := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, errAccept, m.StartMultiple([]ListenerSpec{{Listener: l}, {Listener: failingListener{failing}}}))
+	// the other listener is closed
+	_, err = l.Accept()
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, m.StartMultiple([]ListenerSpec{{Addr: "127.0.0.1:-1"}}))
+}
+
+func TestRouteFilters(t *testing.T) {
+	m := New()
+	r1 := m.Get("/admin").Tag("admin")
+	r2 := m.Get("/")
+	assert.True(t, RoutesTagged("admin")(r1))
+	assert.False(t, RoutesTagged("admin")(r2))
+	assert.False(t, RoutesNotTagged("admin")(r1))
+	assert.True(t, RoutesNotTagged("admin")(r2))
+}
+
+func TestRouteFilterHidesRoutes(t *testing.T) {
+	m := New()
+	m.Debug, m.RouteSuggestions = true, true
+	m.Get("/admin/stats", func(c *Context) error {
+		return c.String("stats")
+	}).Tag("admin")
+	m.Get("/admin/stat", func(c *Context) error {
+		return c.String("stat")
+	})
+	m.MountRouteInspector("/_debug")
+	filter := RoutesNotTagged("admin")
+	serve := func(method, path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if accept != "" {
+			req.Header.Set(HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+		m.serve(res, req, filter)
+		return res
+	}
+
+	// the methods of the hidden routes aren't allowed
+	for _, method := range []string{OPTIONS, POST} {
+		res := serve(method, "/admin/stats", "")
+		assert.Equal(t, StatusNotFound, res.Code, method)
+		assert.Equal(t, "", res.Header().Get("Allow"), method)
+	}
+	// nor suggested
+	res := serve(GET, "/admin/statz", MIMEApplicationJSON)
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Contains(t, res.Body.String(), `"/admin/stat"`)
+	assert.NotContains(t, res.Body.String(), `"/admin/stats"`)
+	// nor inspected
+	res = serve(GET, "/_debug/routes", "")
+	assert.NotContains(t, res.Body.String(), `"/admin/stats"`)
+	assert.Contains(t, res.Body.String(), `"/admin/stat"`)
+	res = serve(GET, "/_debug/match?path=/admin/stats", "")
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Equal(t, `{"allow":[],"method":"GET","path":"/admin/stats"}`, res.Body.String())
+
+	// the configured not found handlers respond to the hidden routes
+	m.NotFound(func(c *Context) error {
+		return c.String("custom", StatusNotFound)
+	})
+	res = serve(GET, "/admin/stats", "")
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Equal(t, "custom", res.Body.String())
+	res = serve(GET, "/admin/stat", "")
+	assert.Equal(t, "stat", res.Body.String())
+}
@@ -0,0 +1,310 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// LocaleConfig defines the config for Locale middleware.
+	LocaleConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Locales are the locales served under a path prefix, e.g. []string{"en", "de"}.
+		// Required.
+		Locales []string `json:"locales"`
+
+		// Default is the locale of the requests whose Accept-Language matches none of the Locales.
+		// Optional. Default value the first of the Locales.
+		Default string `json:"default"`
+
+		// Redirect redirects the GET and HEAD requests without locale prefix to the path
+		// prefixed with the locale matching their Accept-Language, e.g. /pricing to /de/pricing.
+		// The other requests are served with that locale.
+		// Optional. Default value false, true for `Locale()`.
+		Redirect bool `json:"redirect"`
+
+		// Code is the status code of the redirects.
+		// Optional. Default value 302.
+		Code int `json:"code"`
+
+		// Param is the name of the route parameter holding the locale, see `Context#Param()`.
+		// Optional. Default value "locale".
+		Param string `json:"param"`
+	}
+
+	// Alternate is the URL of a page in a locale.
+	Alternate struct {
+		Locale string
+		URL    string
+	}
+)
+
+const (
+	// Key is the store key of the locale of the request, also read by the i18n middleware.
+	Key = "locale"
+
+	// localesKey is the store key of the locales of the middleware, for the URL helpers.
+	localesKey = "locale.locales"
+)
+
+var (
+	// DefaultLocaleConfig is the default Locale middleware config.
+	DefaultLocaleConfig = LocaleConfig{
+		Skipper:  skipper.DefaultSkipper,
+		Redirect: true,
+		Code:     makross.StatusFound,
+		Param:    "locale",
+	}
+)
+
+// Locale returns a root level (before router) middleware which serves the routes under a locale
+// prefix, e.g. /en/pricing and /de/pricing are routed to the /pricing route, and redirects the
+// requests without locale prefix to the locale matching their Accept-Language.
+// The locale is stored under Key and is the value of the "locale" route parameter.
+//
+// Usage `makross#Pre(Locale("en", "de"))`
+func Locale(locales ...string) makross.Handler {
+	c := DefaultLocaleConfig
+	c.Locales = locales
+	return LocaleWithConfig(c)
+}
+
+// LocaleWithConfig returns a Locale middleware with config.
+// See `Locale()`.
+func LocaleWithConfig(config LocaleConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultLocaleConfig.Skipper
+	}
+	if len(config.Locales) == 0 {
+		panic("locale: locales are required")
+	}
+	if config.Default == "" {
+		config.Default = config.Locales[0]
+	}
+	if config.Code == 0 {
+		config.Code = DefaultLocaleConfig.Code
+	}
+	if config.Param == "" {
+		config.Param = DefaultLocaleConfig.Param
+	}
+	locales := append([]string(nil), config.Locales...)
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		u := c.Request.URL
+		loc, rest := split(u.Path, locales)
+		if loc == "" {
+			loc = Negotiate(c.Request.Header.Get(makross.HeaderAcceptLanguage), locales, config.Default)
+			c.Response.Header().Add(makross.HeaderVary, makross.HeaderAcceptLanguage)
+			if config.Redirect && (c.Request.Method == makross.GET || c.Request.Method == makross.HEAD) {
+				target := "/" + loc + u.EscapedPath()
+				if u.RawQuery != "" {
+					target += "?" + u.RawQuery
+				}
+				return c.Redirect(target, config.Code)
+			}
+		} else {
+			u.Path = rest
+			if u.RawPath != "" {
+				_, u.RawPath = split(u.RawPath, []string{loc})
+			}
+		}
+
+		c.Set(Key, loc)
+		c.Set(localesKey, locales)
+		c.SetParam(config.Param, loc)
+		return c.Next()
+	}
+}
+
+// From returns the locale of the request, "" if the Locale middleware didn't run.
+func From(c *makross.Context) string {
+	loc, _ := c.Get(Key).(string)
+	return loc
+}
+
+// URL creates the URL of the named route, like `Context#URL()`, prefixed with the locale,
+// e.g. URL(c, "de", "pricing") returns "/de/pricing". It returns "" if the route is unknown.
+func URL(c *makross.Context, locale, route string, pairs ...interface{}) string {
+	r := c.Makross().Route(route)
+	if r == nil {
+		return ""
+	}
+	return "/" + locale + r.URL(pairs...)
+}
+
+// Alternates returns the absolute URLs of the named route in all the locales of the Locale middleware,
+// e.g. for the sitemaps. It returns nil if the route is unknown or the middleware didn't run.
+func Alternates(c *makross.Context, route string, pairs ...interface{}) []Alternate {
+	locales, _ := c.Get(localesKey).([]string)
+	r := c.Makross().Route(route)
+	if r == nil || len(locales) == 0 {
+		return nil
+	}
+	path := r.URL(pairs...)
+	base := c.Scheme() + "://" + c.Host()
+	alternates := make([]Alternate, len(locales))
+	for i, loc := range locales {
+		alternates[i] = Alternate{Locale: loc, URL: base + "/" + loc + path}
+	}
+	return alternates
+}
+
+// SetLinks sets the links of the page of the named route with Context.SetLink: the canonical URL
+// in the locale of the request and the alternate URLs in all the locales, with their hreflang.
+func SetLinks(c *makross.Context, route string, pairs ...interface{}) {
+	loc := From(c)
+	for _, a := range Alternates(c, route, pairs...) {
+		if a.Locale == loc {
+			c.SetLink("canonical", a.URL)
+		}
+		c.SetLink("alternate", a.URL, "hreflang", a.Locale)
+	}
+}
+
+// Language is a language range of the Accept-Language header with its quality.
+type Language struct {
+	Tag string  // e.g. "de-AT", or "*"
+	Q   float64 // from 0.001 to 1
+}
+
+// ParseAcceptLanguage returns the language ranges accepted by the Accept-Language header, as
+// specified by RFC 9110, by decreasing quality, those of equal quality in the order of the header.
+// The ranges refused with q=0 are left out, as are the malformed ones, e.g. "de;q=2" or "de;q=0.5x",
+// the others being kept. The i18n package matches the same ranges.
+func ParseAcceptLanguage(header string) []Language {
+	var langs []Language
+	for _, s := range strings.Split(header, ",") {
+		if lang, ok := parseLanguage(s); ok && lang.Q > 0 {
+			langs = append(langs, lang)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].Q > langs[j].Q
+	})
+	return langs
+}
+
+// Negotiate returns the locale best matching the Accept-Language header, or the fallback.
+// A language matches a locale exactly, e.g. "de-AT" matches "de-AT", or by its primary subtag,
+// e.g. "de-AT" matches "de". An exact match takes precedence over a primary subtag match.
+func Negotiate(acceptLanguage string, locales []string, fallback string) string {
+	langs := ParseAcceptLanguage(acceptLanguage)
+	best, bestQ := fallback, 0.0
+	for _, loc := range locales {
+		q, exact := 0.0, false
+		for _, lang := range langs {
+			switch {
+			case strings.EqualFold(lang.Tag, loc):
+				if !exact {
+					q, exact = lang.Q, true
+				}
+			case exact:
+			case strings.EqualFold(primary(lang.Tag), loc) && lang.Q > q:
+				q = lang.Q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = loc, q
+		}
+	}
+	return best
+}
+
+// parseLanguage parses a language range of the Accept-Language header, e.g. "de-AT;q=0.8".
+func parseLanguage(s string) (Language, bool) {
+	params := strings.Split(s, ";")
+	lang := Language{Tag: strings.TrimSpace(params[0]), Q: 1}
+	if !isLanguageRange(lang.Tag) {
+		return Language{}, false
+	}
+	for _, p := range params[1:] {
+		name, value, _ := strings.Cut(p, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "q") {
+			// no other parameter is defined
+			return Language{}, false
+		}
+		q, ok := parseQValue(strings.TrimSpace(value))
+		if !ok {
+			return Language{}, false
+		}
+		lang.Q = q
+	}
+	return lang, true
+}
+
+// isLanguageRange reports whether s is a language range: "*", or 1 to 8 letters followed by subtags
+// of 1 to 8 letters or digits, separated by hyphens, e.g. "zh-Hant-TW".
+func isLanguageRange(s string) bool {
+	if s == "*" {
+		return true
+	}
+	for i, sub := range strings.Split(s, "-") {
+		if len(sub) == 0 || len(sub) > 8 {
+			return false
+		}
+		for _, ch := range sub {
+			alpha := ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+			if !alpha && (i == 0 || ch < '0' || ch > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseQValue parses a qvalue: "0" or "1", optionally followed by up to three decimals, at most 1.
+func parseQValue(s string) (float64, bool) {
+	if s == "" || s[0] != '0' && s[0] != '1' {
+		return 0, false
+	}
+	if len(s) > 1 && (s[1] != '.' || len(s) > 5) {
+		return 0, false
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+	}
+	q, err := strconv.ParseFloat(s, 64)
+	if err != nil || q > 1 {
+		return 0, false
+	}
+	return q, true
+}
+
+// primary returns the primary subtag of the language tag, e.g. "de" for "de-AT".
+func primary(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// split returns the locale prefixing the path, as a whole path segment, and the rest of the path,
+// or "" if the path has no locale prefix.
+func split(path string, locales []string) (string, string) {
+	for _, loc := range locales {
+		if !strings.HasPrefix(path, "/"+loc) {
+			continue
+		}
+		rest := path[len(loc)+1:]
+		if rest == "" {
+			return loc, "/"
+		}
+		if rest[0] == '/' {
+			return loc, rest
+		}
+	}
+	return "", ""
+}
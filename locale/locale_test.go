@@ -0,0 +1,129 @@
+package locale
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMakross(pre makross.Handler) *makross.Makross {
+	m := makross.New()
+	m.Pre(pre)
+	m.Get("/pricing", func(c *makross.Context) error {
+		return c.String(c.Param("locale").String() + " " + From(c) + " " + c.Request.URL.Path)
+	}).Name("pricing")
+	m.Post("/pricing", func(c *makross.Context) error {
+		return c.String(From(c))
+	})
+	m.Get("/users/<id>", func(c *makross.Context) error {
+		SetLinks(c, "user", "id", c.Param("id").String())
+		return c.String(URL(c, "en", "user", "id", c.Param("id").String()))
+	}).Name("user")
+	return m
+}
+
+func TestLocale(t *testing.T) {
+	m := newMakross(Locale("en", "de"))
+	tests := []struct {
+		method, path, accept string
+		code                 int
+		body, location       string
+	}{
+		{makross.GET, "/en/pricing", "", makross.StatusOK, "en en /pricing", ""},
+		{makross.GET, "/de/pricing", "en", makross.StatusOK, "de de /pricing", ""},
+		{makross.GET, "/pricing", "de-AT,en;q=0.8", makross.StatusFound, "", "/de/pricing"},
+		{makross.GET, "/pricing?x=1", "fr,en;q=0.5", makross.StatusFound, "", "/en/pricing?x=1"},
+		{makross.GET, "/pricing", "fr", makross.StatusFound, "", "/en/pricing"},
+		{makross.GET, "/", "de", makross.StatusFound, "", "/de/"},
+		{makross.GET, "/dev/pricing", "de", makross.StatusFound, "", "/de/dev/pricing"},
+		{makross.GET, "/fr/pricing", "", makross.StatusFound, "", "/en/fr/pricing"},
+		{makross.POST, "/pricing", "de", makross.StatusOK, "de", ""},
+		{makross.POST, "/en/pricing", "de", makross.StatusOK, "en", ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		req.Header.Set(makross.HeaderAcceptLanguage, test.accept)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, test.code, res.Code, test.path)
+		if test.code == makross.StatusOK {
+			assert.Equal(t, test.body, res.Body.String(), test.path)
+		}
+		assert.Equal(t, test.location, res.Header().Get(makross.HeaderLocation), test.path)
+	}
+
+	// without redirect, the bare paths are served with the negotiated locale
+	m = newMakross(LocaleWithConfig(LocaleConfig{Locales: []string{"en", "de"}}))
+	req := httptest.NewRequest(makross.GET, "/pricing", nil)
+	req.Header.Set(makross.HeaderAcceptLanguage, "de")
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, "de de /pricing", res.Body.String())
+	assert.Equal(t, makross.HeaderAcceptLanguage, res.Header().Get(makross.HeaderVary))
+
+	assert.Panics(t, func() { Locale() })
+}
+
+func TestLocaleURLs(t *testing.T) {
+	m := newMakross(Locale("en", "de"))
+	req := httptest.NewRequest(makross.GET, "http://example.com/de/users/42", nil)
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, "/en/users/42", res.Body.String())
+	assert.Equal(t, []string{
+		`<http://example.com/en/users/42>; rel="alternate"; hreflang="en", ` +
+			`<http://example.com/de/users/42>; rel="canonical", ` +
+			`<http://example.com/de/users/42>; rel="alternate"; hreflang="de"`,
+	}, res.Header()[makross.HeaderLink])
+
+	// the helpers need the middleware and a known route
+	c := m.NewContext(httptest.NewRequest(makross.GET, "/", nil), httptest.NewRecorder())
+	assert.Nil(t, Alternates(c, "user", "id", 1))
+	assert.Equal(t, "", URL(c, "en", "unknown"))
+	assert.Equal(t, "/de/pricing", URL(c, "de", "pricing"))
+}
+
+func TestNegotiate(t *testing.T) {
+	locales := []string{"en", "de", "pt-BR"}
+	tests := []struct {
+		accept, locale string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-CH;q=0.9, en;q=0.5", "de"},
+		{"pt-br", "pt-BR"},
+		{"pt", "en"},
+		{"en;q=0.4, de-AT;q=0.5, en-US;q=0.9", "de"},
+		{"de;q=0, fr", "en"},
+		{"de;q=x", "en"},
+		{"de;q=0.5x, en;q=0.1", "en"},
+		{"fr, de;Q=0.2", "de"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.locale, Negotiate(test.accept, locales, "en"), test.accept)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		langs  []Language
+	}{
+		{"", nil},
+		{"de", []Language{{"de", 1}}},
+		{"fr;q=0.5, de-AT , en;q=0.8", []Language{{"de-AT", 1}, {"en", 0.8}, {"fr", 0.5}}},
+		{"en;q=0.5, de;q=0.5", []Language{{"en", 0.5}, {"de", 0.5}}},
+		{"zh-Hant-TW;q=1.000, *;q=0.1", []Language{{"zh-Hant-TW", 1}, {"*", 0.1}}},
+		{"de ; q = 0.3", []Language{{"de", 0.3}}},
+		{"de;q=1., fr;q=0.", []Language{{"de", 1}}},
+		// refused or malformed
+		{"de;q=0, fr;q=0.000", nil},
+		{"de;q=2, fr;q=1.001, it;q=0.5x, es;q=0.1234, pt;q=-1, ja;q=", nil},
+		{"de;level=1, en_US, 12, toolonglanguage, -de", nil},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.langs, ParseAcceptLanguage(test.header), test.header)
+	}
+}
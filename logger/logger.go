@@ -2,7 +2,9 @@ package logger
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -29,20 +31,25 @@ type (
 		// - time_rfc3339_nano
 		// - id (Request ID)
 		// - remote_ip
-		// - uri
+		// - uri (The request target as received, even if the pre handlers rewrote it)
 		// - host
 		// - method
 		// - path
+		// - raw_path (The path as received, still escaped, see makross.Context.RawPath)
+		// - raw_query (The query string as received, see makross.Context.RawQuery)
 		// - referer
 		// - user_agent
 		// - status
 		// - latency (In nanoseconds)
 		// - latency_human (Human readable)
-		// - bytes_in (Bytes received)
+		// - bytes_in (Request body bytes read)
 		// - bytes_out (Bytes sent)
+		// - error (The error returned by the handlers, e.g. "slow client", see makross.SlowClientError)
+		// - server_timing (The timings of the sections measured with makross.Context.StartTimer)
 		// - header:<NAME>
 		// - query:<NAME>
 		// - form:<NAME>
+		// - store:<NAME> (Context data, e.g. store:enrich.country for a field of a map)
 		//
 		// Example "${remote_ip} ${status}"
 		//
@@ -53,9 +60,41 @@ type (
 		// Optional. Default value os.Stdout.
 		Output io.Writer
 
-		template *fasttemplate.Template
-		colorer  *color.Color
-		pool     *sync.Pool
+		// ServerTiming sends the timings of the sections measured with makross.Context.StartTimer
+		// in the Server-Timing header of the responses. Only the sections stopped before the header
+		// is written are sent.
+		// Optional. Default value false.
+		ServerTiming bool `json:"server_timing"`
+
+		// LogStart writes a line in the StartFormat when a request starts, in addition to the line
+		// written when it completes, so that the long-running requests, such as the streams, show
+		// in the logs while in flight. The id tag is the one of the request header, or of the
+		// response header if set by a middleware registered before the Logger.
+		// Optional. Default value false.
+		LogStart bool `json:"log_start"`
+
+		// StartFormat is the format of the line written when a request starts, with the tags of Format.
+		// The tags of the response, such as status and bytes_out, aren't known yet and shouldn't be used.
+		// Optional. Default value DefaultLoggerConfig.StartFormat.
+		StartFormat string `json:"start_format"`
+
+		template      *fasttemplate.Template
+		startTemplate *fasttemplate.Template
+		colorer       *color.Color
+		pool          *sync.Pool
+	}
+
+	// bodyCounter counts the bytes read from a request body.
+	bodyCounter struct {
+		io.ReadCloser
+		n int64
+	}
+
+	// record is the state of a request, written by the tags.
+	record struct {
+		start, stop time.Time
+		body        *bodyCounter
+		err         error
 	}
 )
 
@@ -67,6 +106,8 @@ var (
 			`"method":"${method}","uri":"${uri}","status":${status}, "latency":${latency},` +
 			`"latency_human":"${latency_human}","bytes_in":${bytes_in},` +
 			`"bytes_out":${bytes_out}}` + "\n",
+		StartFormat: `{"time":"${time_rfc3339_nano}","id":"${id}","remote_ip":"${remote_ip}","host":"${host}",` +
+			`"method":"${method}","uri":"${uri}","event":"start"}` + "\n",
 		Output:  os.Stdout,
 		colorer: color.New(),
 	}
@@ -90,8 +131,14 @@ func LoggerWithConfig(config LoggerConfig) makross.Handler {
 	if config.Output == nil {
 		config.Output = DefaultLoggerConfig.Output
 	}
+	if config.StartFormat == "" {
+		config.StartFormat = DefaultLoggerConfig.StartFormat
+	}
 
 	config.template = fasttemplate.New(config.Format, "${", "}")
+	if config.LogStart {
+		config.startTemplate = fasttemplate.New(config.StartFormat, "${", "}")
+	}
 	config.colorer = color.New()
 	config.colorer.SetOutput(config.Output)
 	config.pool = &sync.Pool{
@@ -107,98 +154,159 @@ func LoggerWithConfig(config LoggerConfig) makross.Handler {
 
 		req := c.Request
 		res := c.Response
-		start := time.Now()
+		body := &bodyCounter{}
+		if req.Body != nil && req.Body != http.NoBody {
+			// the body may be wrapped again by the next handlers, e.g. by blimit
+			body.ReadCloser = req.Body
+			req.Body = body
+		}
+		if config.ServerTiming {
+			c.Response.Before(func() {
+				if timing := c.ServerTiming(); timing != "" {
+					res.Header().Set(makross.HeaderServerTiming, timing)
+				}
+			})
+		}
+		r := &record{start: time.Now(), body: body}
+		if config.startTemplate != nil {
+			r.stop = r.start
+			// the request is served all the same
+			if err := config.write(config.startTemplate, c, r); err != nil {
+				c.Makross().Logger().Errorf("logger: writing the start line: %v", err)
+			}
+		}
 		if err = c.Next(); err != nil {
 			c.HandleError(err)
 		}
-		stop := time.Now()
-		buf := config.pool.Get().(*bytes.Buffer)
-		buf.Reset()
-		defer config.pool.Put(buf)
-
-		if _, err = config.template.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
-			switch tag {
-			case "time_unix":
-				return buf.WriteString(strconv.FormatInt(time.Now().Unix(), 10))
-			case "time_unix_nano":
-				return buf.WriteString(strconv.FormatInt(time.Now().UnixNano(), 10))
-			case "time_rfc3339":
-				return buf.WriteString(time.Now().Format(time.RFC3339))
-			case "time_rfc3339_nano":
-				return buf.WriteString(time.Now().Format(time.RFC3339Nano))
-			case "id":
-				id := req.Header.Get(makross.HeaderXRequestID)
-				if id == "" {
-					id = res.Header().Get(makross.HeaderXRequestID)
-				}
-				if id == "" {
-					id = "0"
-				}
-				return buf.WriteString(id)
-			case "remote_ip":
-				return buf.WriteString(c.RealIP())
-			case "host":
-				return buf.WriteString(req.Host)
-			case "uri":
-				return buf.WriteString(req.RequestURI)
-			case "method":
-				return buf.WriteString(req.Method)
-			case "path":
-				p := req.URL.Path
-				if p == "" {
-					p = "/"
-				}
-				return buf.WriteString(p)
-			case "referer":
-				return buf.WriteString(req.Referer())
-			case "user_agent":
-				return buf.WriteString(req.UserAgent())
-			case "status":
-				n := res.Status
-				s := config.colorer.Green(n)
-				switch {
-				case n >= 500:
-					s = config.colorer.Red(n)
-				case n >= 400:
-					s = config.colorer.Yellow(n)
-				case n >= 300:
-					s = config.colorer.Cyan(n)
-				}
-				return buf.WriteString(s)
-			case "latency":
-				l := stop.Sub(start)
-				return buf.WriteString(strconv.FormatInt(int64(l), 10))
-			case "latency_human":
-				return buf.WriteString(stop.Sub(start).String())
-			case "bytes_in":
-				cl := req.Header.Get(makross.HeaderContentLength)
-				if cl == "" {
-					cl = "0"
+		r.stop = time.Now()
+		r.err = err
+		return config.write(config.template, c, r)
+	}
+}
+
+// write writes the line of the template for the request to the output.
+func (config *LoggerConfig) write(t *fasttemplate.Template, c *makross.Context, r *record) (err error) {
+	req := c.Request
+	res := c.Response
+	buf := config.pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer config.pool.Put(buf)
+
+	if _, err = t.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+		switch tag {
+		case "time_unix":
+			return buf.WriteString(strconv.FormatInt(time.Now().Unix(), 10))
+		case "time_unix_nano":
+			return buf.WriteString(strconv.FormatInt(time.Now().UnixNano(), 10))
+		case "time_rfc3339":
+			return buf.WriteString(time.Now().Format(time.RFC3339))
+		case "time_rfc3339_nano":
+			return buf.WriteString(time.Now().Format(time.RFC3339Nano))
+		case "id":
+			id := req.Header.Get(makross.HeaderXRequestID)
+			if id == "" {
+				id = res.Header().Get(makross.HeaderXRequestID)
+			}
+			if id == "" {
+				id = "0"
+			}
+			return buf.WriteString(id)
+		case "remote_ip":
+			return buf.WriteString(c.RealIP())
+		case "host":
+			return buf.WriteString(req.Host)
+		case "uri":
+			if q := c.RawQuery(); q != "" {
+				return buf.WriteString(c.RawPath() + "?" + q)
+			}
+			return buf.WriteString(c.RawPath())
+		case "method":
+			return buf.WriteString(req.Method)
+		case "path":
+			p := req.URL.Path
+			if p == "" {
+				p = "/"
+			}
+			return buf.WriteString(p)
+		case "raw_path":
+			return buf.WriteString(c.RawPath())
+		case "raw_query":
+			return buf.WriteString(c.RawQuery())
+		case "referer":
+			return buf.WriteString(req.Referer())
+		case "user_agent":
+			return buf.WriteString(req.UserAgent())
+		case "status":
+			n := res.Status
+			s := config.colorer.Green(n)
+			switch {
+			case n >= 500:
+				s = config.colorer.Red(n)
+			case n >= 400:
+				s = config.colorer.Yellow(n)
+			case n >= 300:
+				s = config.colorer.Cyan(n)
+			}
+			return buf.WriteString(s)
+		case "latency":
+			l := r.stop.Sub(r.start)
+			return buf.WriteString(strconv.FormatInt(int64(l), 10))
+		case "latency_human":
+			return buf.WriteString(r.stop.Sub(r.start).String())
+		case "bytes_in":
+			return buf.WriteString(strconv.FormatInt(r.body.n, 10))
+		case "bytes_out":
+			return buf.WriteString(strconv.FormatInt(res.Size, 10))
+		case "error":
+			if r.err != nil {
+				return buf.WriteString(r.err.Error())
+			}
+		case "server_timing":
+			return buf.WriteString(c.ServerTiming())
+		default:
+			switch {
+			case strings.HasPrefix(tag, "header:"):
+				return buf.Write([]byte(c.Request.Header.Get(tag[7:])))
+			case strings.HasPrefix(tag, "query:"):
+				return buf.Write([]byte(c.Query(tag[6:])))
+			case strings.HasPrefix(tag, "form:"):
+				return buf.Write([]byte(c.Form(tag[5:])))
+			case strings.HasPrefix(tag, "store:"):
+				if v := storeValue(c, tag[6:]); v != nil {
+					return buf.WriteString(fmt.Sprint(v))
 				}
-				return buf.WriteString(cl)
-			case "bytes_out":
-				return buf.WriteString(strconv.FormatInt(res.Size, 10))
-			default:
-				switch {
-				case strings.HasPrefix(tag, "header:"):
-					return buf.Write([]byte(c.Request.Header.Get(tag[7:])))
-				case strings.HasPrefix(tag, "query:"):
-					return buf.Write([]byte(c.Query(tag[6:])))
-				case strings.HasPrefix(tag, "form:"):
-					return buf.Write([]byte(c.Form(tag[5:])))
-				case strings.HasPrefix(tag, "cookie:"):
-					cookie, err := c.GetCookie(tag[7:])
-					if err == nil {
-						return buf.Write([]byte(cookie.Value))
-					}
+			case strings.HasPrefix(tag, "cookie:"):
+				cookie, err := c.GetCookie(tag[7:])
+				if err == nil {
+					return buf.Write([]byte(cookie.Value))
 				}
 			}
-			return 0, nil
-		}); err != nil {
-			return
 		}
-
-		_, err = config.Output.Write(buf.Bytes())
+		return 0, nil
+	}); err != nil {
 		return
 	}
+
+	_, err = config.Output.Write(buf.Bytes())
+	return
+}
+
+// storeValue returns the value of the context data at the dotted path, e.g. "enrich.country".
+func storeValue(c *makross.Context, path string) interface{} {
+	keys := strings.Split(path, ".")
+	v := c.Get(keys[0])
+	for _, key := range keys[1:] {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func (b *bodyCounter) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
 }
@@ -3,12 +3,18 @@ package logger
 import (
 	"bytes"
 	"errors"
+	"io"
+	"io/ioutil"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/insionng/makross"
+	"github.com/insionng/makross/blimit"
+	"github.com/insionng/makross/slash"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -54,6 +60,7 @@ func TestLogger(t *testing.T) {
 
 func TestLoggerIPAddress(t *testing.T) {
 	e := makross.New()
+	e.SetTrustedProxies("192.0.2.1")
 
 	req := httptest.NewRequest(makross.GET, "/", nil)
 	rec := httptest.NewRecorder()
@@ -86,6 +93,7 @@ func TestLoggerTemplate(t *testing.T) {
 	buf := new(bytes.Buffer)
 
 	e := makross.New()
+	e.SetTrustedProxies("192.0.2.1")
 
 	e.Use(LoggerWithConfig(LoggerConfig{
 		Format: `{"time":"${time_rfc3339_nano}","id":"${id}","remote_ip":"${remote_ip}","host":"${host}","user_agent":"${user_agent}",` +
@@ -139,3 +147,158 @@ func TestLoggerTemplate(t *testing.T) {
 		assert.True(t, strings.Contains(buf.String(), token) == present, "Case: "+token)
 	}
 }
+
+func TestLoggerRawTarget(t *testing.T) {
+	buf := new(bytes.Buffer)
+	m := makross.New()
+	m.Pre(slash.AddTrailingSlash())
+	m.Use(LoggerWithConfig(LoggerConfig{
+		Format: "${uri} ${raw_path} ${raw_query} ${path}\n",
+		Output: buf,
+	}))
+	m.Get("/files/<name>/", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+
+	// the target is logged as received, not as rewritten by the pre handlers
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(makross.GET, "/files/a%20b?q=a+b&x=%7e", nil))
+	assert.Equal(t, "/files/a%20b?q=a+b&x=%7e /files/a%20b q=a+b&x=%7e /files/a b/\n", buf.String())
+}
+
+func TestLoggerBytesIn(t *testing.T) {
+	for _, order := range []string{"logger first", "blimit first"} {
+		buf := new(bytes.Buffer)
+		e := makross.New()
+		logger := LoggerWithConfig(LoggerConfig{Format: "${bytes_in}", Output: buf})
+		limit := blimit.BodyLimit("1K")
+		if order == "logger first" {
+			e.Use(logger, limit)
+		} else {
+			e.Use(limit, logger)
+		}
+		e.Post("/upload", func(c *makross.Context) error {
+			b, err := ioutil.ReadAll(c.Request.Body)
+			if err != nil {
+				return err
+			}
+			return c.String(strconv.Itoa(len(b)))
+		})
+		e.Post("/ignore", func(c *makross.Context) error {
+			return c.String("ignored")
+		})
+
+		req := httptest.NewRequest(makross.POST, "/upload", strings.NewReader("hello world"))
+		// the actual body size is logged, not the announced one
+		req.Header.Set(makross.HeaderContentLength, "100")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, "11", buf.String(), order)
+
+		buf.Reset()
+		req = httptest.NewRequest(makross.POST, "/ignore", strings.NewReader("hello world"))
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, "0", buf.String(), order)
+
+		buf.Reset()
+		req = httptest.NewRequest(makross.POST, "/upload", nil)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, "0", buf.String(), order)
+	}
+}
+
+func TestLoggerError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := makross.New()
+	e.Use(LoggerWithConfig(LoggerConfig{Format: "${status} ${error}\n", Output: buf}))
+	e.Get("/slow", func(c *makross.Context) error {
+		c.String("partial")
+		return &makross.SlowClientError{Err: errors.New("i/o timeout")}
+	})
+	e.Get("/ok", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(makross.GET, "/slow", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(makross.GET, "/ok", nil))
+	assert.Equal(t, "200 slow client\n200 \n", buf.String())
+}
+
+func TestLoggerServerTiming(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := makross.New()
+	e.Use(LoggerWithConfig(LoggerConfig{Format: "${server_timing}\n", Output: buf, ServerTiming: true}))
+	e.Get("/", func(c *makross.Context) error {
+		c.StartTimer("db")()
+		c.StartTimer("render")()
+		return c.String("ok")
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(makross.GET, "/", nil))
+	header := rec.Header().Get(makross.HeaderServerTiming)
+	assert.Regexp(t, `^db;dur=[0-9.]+, render;dur=[0-9.]+$`, header)
+	assert.Equal(t, header+"\n", buf.String())
+}
+
+func TestLoggerLogStart(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := makross.New()
+	e.Use(LoggerWithConfig(LoggerConfig{
+		Format:      "end ${id} ${method} ${path} ${status} ${bytes_out} ${latency_human}\n",
+		StartFormat: "start ${id} ${method} ${path}\n",
+		LogStart:    true,
+		Output:      buf,
+	}))
+	e.Get("/stream", func(c *makross.Context) error {
+		// the start line is written while the request is in flight
+		assert.Equal(t, "start 42 GET /stream\n", buf.String())
+		time.Sleep(10 * time.Millisecond)
+		return c.String("chunk")
+	})
+	req := httptest.NewRequest(makross.GET, "/stream", nil)
+	req.Header.Set(makross.HeaderXRequestID, "42")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "start 42 GET /stream", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "end 42 GET /stream 200 5 "), lines[1])
+		latency, err := time.ParseDuration(strings.TrimPrefix(lines[1], "end 42 GET /stream 200 5 "))
+		assert.Nil(t, err)
+		assert.True(t, latency >= 10*time.Millisecond, latency)
+	}
+
+	// the default start format
+	buf.Reset()
+	e = makross.New()
+	e.Use(LoggerWithConfig(LoggerConfig{Format: "end\n", LogStart: true, Output: buf}))
+	e.Get("/", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(makross.GET, "/", nil))
+	assert.Regexp(t, `^\{"time":".+","id":"0",.*"method":"GET","uri":"/","event":"start"\}\nend\n$`, buf.String())
+
+	// a failed start line is reported, and the request served all the same
+	buf.Reset()
+	logs := new(bytes.Buffer)
+	e = makross.New()
+	e.SetLogger(makross.NewLogger(logs))
+	e.Use(LoggerWithConfig(LoggerConfig{Format: "end\n", StartFormat: "start\n", LogStart: true, Output: &startFailingWriter{buf}}))
+	e.Get("/", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(makross.GET, "/", nil))
+	assert.Equal(t, "ok", rec.Body.String())
+	assert.Equal(t, "end\n", buf.String())
+	assert.Contains(t, logs.String(), "logger: writing the start line: disk full")
+}
+
+// startFailingWriter fails to write the start lines.
+type startFailingWriter struct {
+	io.Writer
+}
+
+func (w *startFailingWriter) Write(b []byte) (int, error) {
+	if bytes.HasPrefix(b, []byte("start")) {
+		return 0, errors.New("disk full")
+	}
+	return w.Writer.Write(b)
+}
@@ -0,0 +1,105 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Logger is the logging abstraction of Makross, see Makross.SetLogger and Context.Logger.
+//
+// With derives a logger adding the fields to all its lines, e.g. the request ID added by the requestid
+// middleware to the logger of the request. An adapter of another logging library must return a new Logger
+// from With and leave the receiver as is, since it's shared by the loggers derived for all the requests.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	With(fields map[string]interface{}) Logger
+}
+
+// stdLogger is the default Logger, writing the lines with the standard log package
+// and the fields as sorted key=value pairs.
+type stdLogger struct {
+	l      *log.Logger
+	fields map[string]interface{}
+	suffix string // the formatted fields
+}
+
+// NewLogger returns the default Logger, writing to out lines such as:
+//
+//	[Makross] 2009/11/10 23:00:00 INFO user signed in request_id=6ba7b810 user=42
+func NewLogger(out io.Writer) Logger {
+	return &stdLogger{l: log.New(out, "[Makross] ", log.LstdFlags)}
+}
+
+var defaultLogger = NewLogger(os.Stderr)
+
+func (l *stdLogger) Debugf(format string, args ...interface{}) { l.output("DEBUG", format, args) }
+func (l *stdLogger) Infof(format string, args ...interface{})  { l.output("INFO", format, args) }
+func (l *stdLogger) Warnf(format string, args ...interface{})  { l.output("WARN", format, args) }
+func (l *stdLogger) Errorf(format string, args ...interface{}) { l.output("ERROR", format, args) }
+
+func (l *stdLogger) output(level, format string, args []interface{}) {
+	l.l.Output(3, level+" "+fmt.Sprintf(format, args...)+l.suffix)
+}
+
+func (l *stdLogger) With(fields map[string]interface{}) Logger {
+	merged := make(map[string]interface{}, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		v := fmt.Sprint(merged[k])
+		if v == "" || strings.ContainsAny(v, " =\"\t\n") {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(" " + k + "=" + v)
+	}
+	return &stdLogger{l: l.l, fields: merged, suffix: b.String()}
+}
+
+// SetLogger sets the logger of the application, returned by Logger. NewLogger(os.Stderr) is the default one.
+func (m *Makross) SetLogger(l Logger) {
+	m.logger = l
+}
+
+// Logger returns the logger of the application, see SetLogger.
+func (m *Makross) Logger() Logger {
+	if m.logger == nil {
+		return defaultLogger
+	}
+	return m.logger
+}
+
+// Logger returns the logger of the request, the one of the application unless a middleware derived
+// one for the request with SetLogger, e.g. adding the request ID to its lines.
+func (c *Context) Logger() Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return c.makross.Logger()
+}
+
+// SetLogger sets the logger of the request, usually derived from the current one with its With method:
+//
+//	c.SetLogger(c.Logger().With(map[string]interface{}{"user": user.ID}))
+func (c *Context) SetLogger(l Logger) {
+	c.logger = l
+}
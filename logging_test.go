@@ -0,0 +1,46 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf)
+	l.Infof("hello %s", "world")
+	assert.Regexp(t, `^\[Makross\] \d{4}/\d\d/\d\d \d\d:\d\d:\d\d INFO hello world\n$`, buf.String())
+
+	buf.Reset()
+	l2 := l.With(map[string]interface{}{"user": 42, "name": "Ann Lee"})
+	l3 := l2.With(map[string]interface{}{"user": 7, "empty": ""})
+	l2.Warnf("first")
+	l3.Errorf("second")
+	l.Debugf("third")
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, string(lines[0]), `WARN first name="Ann Lee" user=42`)
+		assert.Contains(t, string(lines[1]), `ERROR second empty="" name="Ann Lee" user=7`)
+		assert.Regexp(t, `DEBUG third$`, string(lines[2]))
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	m := New()
+	assert.Equal(t, defaultLogger, m.Logger())
+	m.SetLogger(NewLogger(&buf))
+	c := m.NewContext(httptest.NewRequest(GET, "/", nil), httptest.NewRecorder())
+	assert.Equal(t, m.Logger(), c.Logger())
+	c.SetLogger(c.Logger().With(map[string]interface{}{"request_id": "abc"}))
+	c.Logger().Infof("handled")
+	assert.Contains(t, buf.String(), "INFO handled request_id=abc")
+
+	c.Reset(httptest.NewRecorder(), httptest.NewRequest(GET, "/", nil))
+	assert.Equal(t, m.Logger(), c.Logger())
+}
@@ -3,13 +3,19 @@
 package makross
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"net/netip"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/insionng/makross/libraries/ini.v1"
@@ -25,7 +31,8 @@ type (
 		pool        sync.Pool
 		routes      []*Route
 		namedRoutes map[string]*Route
-		stores      map[string]routeStore
+		stores      map[string]RouteMatcher
+		newMatcher  func() RouteMatcher    // see SetRouteMatcher
 		data        map[string]interface{} // data items managed by Key , Value
 
 		QueuesMap  *sync.Map //map[string]*prior.PriorityQueue
@@ -35,15 +42,102 @@ type (
 		notFound         []Handler
 		notFoundHandlers []Handler
 		binder           Binder
+		validator        Validator
 		renderer         Renderer
+		templateFuncs    template.FuncMap
+		sanitizer        Sanitizer
+		streamsMu        sync.Mutex
+		streams          map[*streamConn]struct{}
+		reportMu         sync.Mutex
+		reporters        []ErrorReporter
+		reports          chan *ErrorReport
+		reportsDone      chan struct{}
+		responseFns      []ResponseFunc
+		middlewaresMu    sync.Mutex
+		middlewares      atomic.Value // []Handler
+		handlerTimeout   time.Duration
+		keepAlivesOff    atomic.Bool
+		redirects        *Redirector
+		pre              []Handler
+		draining         int32
+		warming          int32
+		warmups          []func(context.Context) error
+		deferMu          sync.Mutex
+		deferQueue       chan deferredCall // see OnSuccess
+		deferPending     int               // the number of queued and running deferred functions
+		deferIdle        chan struct{}     // closed when deferPending drops to 0
+		deferCtx         context.Context
+		deferCancel      context.CancelFunc
+		deferClosed      bool
+		multipartTemp    int64  // see MultipartTempBytes
+		multipartTempDir string // see SetMultipartTempDir
+		emptyStatus      int
+		renderCache      *RenderCache
+		logger           Logger
+		headersSet       http.Header // see SetResponseHeaders
+		headersRemoved   []string
+		serversMu        sync.Mutex
+		servers          []*http.Server
 		Server           *http.Server
-	}
 
-	// routeStore stores route paths and the corresponding handlers.
-	routeStore interface {
-		Add(key string, data interface{}) int
-		Get(key string, pvalues []string) (data interface{}, pnames []string)
-		String() string
+		// LegacyForwardedFirst makes the X-Forwarded-* and X-Real-IP headers take precedence over
+		// the standard Forwarded header when deriving the client IP, scheme and host.
+		LegacyForwardedFirst bool
+		trustedProxies       []netip.Prefix // the proxies whose forwarding headers are honored, see SetTrustedProxies
+
+		// StrictJSONFields makes Context.JSONFields refuse the unknown fields with "400 - Bad Request".
+		StrictJSONFields bool
+
+		// Debug makes errors more visible during development,
+		// e.g. the errors of Context.RenderStream are appended to the page.
+		Debug bool
+
+		// TenantFunc resolves the tenant of a request for the OnResponse accounting functions.
+		TenantFunc func(*Context) string
+
+		// RouteSuggestions makes the 404 responses to the requests without matching route list the routes
+		// of the request method with the nearest paths, with their names and descriptions, to the clients
+		// accepting HTML or JSON, e.g. "did you mean GET /users/<id>?". It only applies in Debug mode,
+		// as it discloses the routes.
+		RouteSuggestions bool
+
+		// DrainExcludePaths are the paths served while draining, such as the health and metrics endpoints.
+		// A path also excludes the paths below it. See SetDraining.
+		DrainExcludePaths []string
+
+		// DrainRetryAfter is the Retry-After of the responses to the requests refused while draining.
+		DrainRetryAfter time.Duration
+
+		// WarmupTimeout bounds the time the Warmup functions take, see Warmup. Zero means no timeout.
+		WarmupTimeout time.Duration
+
+		// WarmupConcurrent runs the Warmup functions concurrently, instead of one after the other.
+		WarmupConcurrent bool
+
+		// DeferredWorkers is the number of goroutines running the functions registered with
+		// Context.OnSuccess and Context.OnFailure. Default 4.
+		DeferredWorkers int
+
+		// DeferredQueueSize is the number of deferred functions which can wait for the workers,
+		// the functions being dropped when the queue is full. Default 256.
+		DeferredQueueSize int
+
+		// MultipartMemory is the size of the files of a multipart form held in memory, the larger
+		// files being written to temporary files, see SetMultipartTempDir. Default 32 MB.
+		MultipartMemory int64
+
+		// DataSizeHint is the initial capacity of the map of the data items of a context, created on
+		// the first Set. The map is kept for the next request of the pooled context. Default 8.
+		DataSizeHint int
+
+		// ErrorTemplate is the template of the HTML error pages rendered by HandleError, with the
+		// HTTPError under the ErrorDataKey key of the context data. Without it, or without renderer,
+		// the errors are sent as plain text to the requests preferring HTML.
+		ErrorTemplate string
+
+		// ErrorFormatFunc returns the media type of the error responses sent by HandleError:
+		// MIMETextHTML, MIMEApplicationJSON or MIMETextPlain. Default NegotiateErrorFormat.
+		ErrorFormatFunc func(*Context) string
 	}
 
 	// Renderer is the interface that wraps the Render function.
@@ -82,6 +176,9 @@ var (
 	// FlashNow applies to current request.
 	FlashNow bool
 
+	// ShutdownStreamMessage is sent to the open SSE streams when Shutdown closes them.
+	ShutdownStreamMessage = "server shutting down"
+
 	// Configuration convention object.
 	cfg *ini.File
 )
@@ -105,6 +202,7 @@ const (
 	MIMETextPlainCharsetUTF8             = MIMETextPlain + "; " + charsetUTF8
 	MIMEMultipartForm                    = "multipart/form-data"
 	MIMEOctetStream                      = "application/octet-stream"
+	MIMETextEventStream                  = "text/event-stream"
 )
 
 const (
@@ -115,21 +213,32 @@ const (
 const (
 	HeaderAccept              = "Accept"
 	HeaderAcceptEncoding      = "Accept-Encoding"
+	HeaderAcceptLanguage      = "Accept-Language"
+	HeaderAcceptRanges        = "Accept-Ranges"
 	HeaderAllow               = "Allow"
 	HeaderAuthorization       = "Authorization"
+	HeaderCacheControl        = "Cache-Control"
 	HeaderContentDisposition  = "Content-Disposition"
 	HeaderContentEncoding     = "Content-Encoding"
 	HeaderContentLength       = "Content-Length"
+	HeaderContentRange        = "Content-Range"
 	HeaderContentType         = "Content-Type"
 	HeaderCookie              = "Cookie"
 	HeaderSetCookie           = "Set-Cookie"
 	HeaderIfModifiedSince     = "If-Modified-Since"
 	HeaderLastModified        = "Last-Modified"
+	HeaderLink                = "Link"
+	HeaderIfNoneMatch         = "If-None-Match"
+	HeaderETag                = "ETag"
+	HeaderExpect              = "Expect"
 	HeaderLocation            = "Location"
+	HeaderRange               = "Range"
 	HeaderUpgrade             = "Upgrade"
 	HeaderVary                = "Vary"
 	HeaderWWWAuthenticate     = "WWW-Authenticate"
+	HeaderForwarded           = "Forwarded"
 	HeaderXForwardedFor       = "X-Forwarded-For"
+	HeaderXForwardedHost      = "X-Forwarded-Host"
 	HeaderXForwardedProto     = "X-Forwarded-Proto"
 	HeaderXForwardedProtocol  = "X-Forwarded-Protocol"
 	HeaderXForwardedSsl       = "X-Forwarded-Ssl"
@@ -137,7 +246,13 @@ const (
 	HeaderXHTTPMethodOverride = "X-HTTP-Method-Override"
 	HeaderXRealIP             = "X-Real-IP"
 	HeaderXRequestID          = "X-Request-ID"
+	HeaderXRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderXRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRetryAfter          = "Retry-After"
+	HeaderXQuotaRemaining     = "X-Quota-Remaining"
+	HeaderXQuotaReset         = "X-Quota-Reset"
 	HeaderServer              = "Server"
+	HeaderServerTiming        = "Server-Timing"
 	HeaderOrigin              = "Origin"
 
 	// Access control
@@ -306,14 +421,20 @@ func New() (m *Makross) {
 	m = &Makross{
 		Server:      new(http.Server),
 		namedRoutes: make(map[string]*Route),
-		stores:      make(map[string]routeStore),
+		stores:      make(map[string]RouteMatcher),
 		QueuesMap:   new(sync.Map),
 		FiltersMap:  new(sync.Map),
 	}
 	m.Server.Handler = m
+	m.Configure(DefaultServerConfig)
 	m.RouteGroup = *newRouteGroup("", m, make([]Handler, 0))
 	m.NotFound(MethodNotAllowedHandler, NotFoundHandler)
 	m.SetBinder(&DefaultBinder{})
+	m.AddTemplateFunc("sanitize", m.sanitize)
+	m.DrainExcludePaths = []string{"/health", "/healthz", "/ready", "/readyz", "/metrics"}
+	m.DrainRetryAfter = 30 * time.Second
+	m.DataSizeHint = 8
+	m.MultipartMemory = defaultMemory
 	m.pool.New = func() interface{} {
 		return m.NewContext(nil, nil)
 	}
@@ -326,9 +447,12 @@ func (m *Makross) NewContext(r *http.Request, w http.ResponseWriter, handlers ..
 		Request:  r,
 		Response: NewResponse(w, m),
 		makross:  m,
-		pvalues:  make([]string, m.maxParams),
 		handlers: handlers,
 	}
+	c.pvalues = c.pbuf[:]
+	if m.maxParams > len(c.pbuf) {
+		c.pvalues = make([]string, m.maxParams)
+	}
 	c.Reset(w, r)
 	return c
 }
@@ -351,13 +475,58 @@ func (m *Makross) ReleaseContext(c *Context) {
 // ServeHTTP handles the HTTP request.
 // It is required by http.Handler
 func (m *Makross) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	m.serve(res, req, nil)
+}
+
+// serve handles the HTTP request, routing it only to the routes kept by the filter, if any.
+func (m *Makross) serve(res http.ResponseWriter, req *http.Request, filter RouteFilter) {
 	c := m.AcquireContext()
 	c.Reset(res, req)
-	c.Response.Header().Set("Server", "Makross")
-	c.handlers, c.pnames = m.find(req.Method, req.URL.Path, c.pvalues)
+	// even if a handler panics
+	panicked := true
+	defer func() { m.releaseRequest(c, panicked) }()
+	c.filter = filter
+	if m.headersSet != nil || m.headersRemoved != nil {
+		c.Response.Before(func() {
+			m.applyResponseHeaders(c.Response.Header())
+		})
+	}
+	c.Response.Header().Set(HeaderServer, "Makross")
+	if len(m.pre) > 0 {
+		c.handlers = m.pre
+	} else {
+		c.handlers = c.withMiddlewares(m.route(c))
+	}
 	if err := c.Next(); err != nil {
 		m.HandleError(c, err)
 	}
+	if m.emptyStatus != 0 && !c.Response.Committed {
+		m.writeEmpty(c)
+	}
+	if !c.Response.Committed && (m.headersSet != nil || m.headersRemoved != nil) {
+		// net/http sends the header of the empty response
+		m.applyResponseHeaders(c.Response.Header())
+	}
+	c.Response.finish()
+	if len(c.deferred) > 0 {
+		m.runDeferred(c, false)
+	}
+	panicked = false
+}
+
+// releaseRequest accounts the response, removes the temporary files of the request and releases its context.
+// The response of a request whose handler panicked is accounted as a 500, unless it was already sent.
+func (m *Makross) releaseRequest(c *Context, panicked bool) {
+	if len(m.responseFns) > 0 {
+		if panicked && !c.Response.Committed {
+			c.Response.Status = StatusInternalServerError
+		}
+		m.accountResponse(c)
+	}
+	if panicked && len(c.deferred) > 0 {
+		m.runDeferred(c, true)
+	}
+	c.removeMultipartFiles()
 	m.ReleaseContext(c)
 }
 
@@ -370,15 +539,40 @@ func (m *Makross) Shutdown(times ...int64) error {
 		n = 3
 	}
 	// shut down gracefully, but wait no longer than n seconds before halting
-	ctx, _ := context.WithTimeout(context.Background(), n*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), n*time.Second)
+	defer cancel()
+	m.CloseStreams(ShutdownStreamMessage)
 	m.DoActionHook("MakrossShutdown")
-	return m.Server.Shutdown(ctx)
+	err := m.Server.Shutdown(ctx)
+	for _, s := range m.listenerServers() {
+		if e := s.Shutdown(ctx); err == nil {
+			err = e
+		}
+	}
+	if e := m.shutdownDeferred(ctx); err == nil {
+		err = e
+	}
+	if e := m.stopErrorReporters(ctx); err == nil {
+		err = e
+	}
+	return err
 }
 
 // Close 立即关闭HTTP服务
 func (m *Makross) Close() error {
 	m.DoActionHook("MakrossClose")
-	return m.Server.Close()
+	err := m.Server.Close()
+	for _, s := range m.listenerServers() {
+		if e := s.Close(); err == nil {
+			err = e
+		}
+	}
+	// cancel the deferred functions without waiting for them
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.shutdownDeferred(ctx)
+	m.stopErrorReporters(ctx)
+	return err
 }
 
 // Route returns the named route.
@@ -413,6 +607,86 @@ func (m *Makross) Binder() Binder {
 	return m.binder
 }
 
+// SetValidator registers a validator. It's invoked by `Context#Validate()`.
+func (m *Makross) SetValidator(v Validator) {
+	m.validator = v
+}
+
+// Validator returns the validator instance.
+func (m *Makross) Validator() Validator {
+	return m.validator
+}
+
+// SetResponseHeaders sets the headers of all the responses, e.g. `"X-Frame-Options": "DENY"`, just before
+// their header is written, replacing the headers set with a previous call.
+func (m *Makross) SetResponseHeaders(headers map[string]string) {
+	h := make(http.Header, len(headers))
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	m.headersSet = h
+}
+
+// RemoveResponseHeaders removes the headers from all the responses, e.g. "Server" or "X-Powered-By",
+// just before their header is written, replacing the headers removed with a previous call.
+func (m *Makross) RemoveResponseHeaders(names []string) {
+	m.headersRemoved = append([]string{}, names...)
+}
+
+// applyResponseHeaders sets and removes the headers of SetResponseHeaders and RemoveResponseHeaders.
+func (m *Makross) applyResponseHeaders(header http.Header) {
+	for k, v := range m.headersSet {
+		if k == HeaderSetCookie {
+			// the cookies of the handlers are kept
+			header[k] = append(header[k], v...)
+			continue
+		}
+		header[k] = append([]string(nil), v...)
+	}
+	for _, name := range m.headersRemoved {
+		header.Del(name)
+	}
+}
+
+// SetEmptyStatus sets the status code sent when the handlers complete without writing the response,
+// 204 No Content by default, instead of the empty 200 net/http would send. A status set with
+// `Context#SetStatus()` is sent instead, and the responses written empty, e.g. with c.NoContent(200),
+// are left alone. A zero status disables it.
+func (m *Makross) SetEmptyStatus(status ...int) {
+	if len(status) > 0 {
+		m.emptyStatus = status[0]
+	} else {
+		m.emptyStatus = StatusNoContent
+	}
+}
+
+// writeEmpty writes the header of the response the handlers left unwritten.
+func (m *Makross) writeEmpty(c *Context) {
+	if c.Response.statusSet {
+		c.Response.WriteHeader(c.Response.Status)
+	} else {
+		c.Response.WriteHeader(m.emptyStatus)
+	}
+}
+
+// AddTemplateFunc registers a function which the built-in renderer makes available to every template,
+// such as a date formatter or an asset URL helper. It should be called before the templates are rendered.
+func (m *Makross) AddTemplateFunc(name string, fn interface{}) {
+	if m.templateFuncs == nil {
+		m.templateFuncs = make(template.FuncMap)
+	}
+	m.templateFuncs[name] = fn
+}
+
+// TemplateFuncs returns a copy of the functions registered with AddTemplateFunc.
+func (m *Makross) TemplateFuncs() template.FuncMap {
+	funcs := make(template.FuncMap, len(m.templateFuncs))
+	for name, fn := range m.templateFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
 func (m *Makross) Pull(key string) interface{} {
 	return m.data[key]
 }
@@ -463,6 +737,15 @@ func (m *Makross) File(path, file string) {
 
 // NotFound specifies the handlers that should be invoked when the makross cannot find any route matching a request.
 // Note that the handlers registered via Use will be invoked first in this case.
+// The handlers have access to the full Context, so they can respond differently based on the attempted
+// path returned by Context.Path() or on the Accept header, for example with Context.Negotiate():
+//
+//	m.NotFound(func(c *makross.Context) error {
+//		if c.Negotiate(makross.MIMETextHTML, makross.MIMEApplicationJSON) == makross.MIMEApplicationJSON {
+//			return c.JSON(map[string]string{"error": "not found"}, makross.StatusNotFound)
+//		}
+//		return c.Render("404", makross.StatusNotFound)
+//	})
 func (r *Makross) NotFound(handlers ...Handler) {
 	r.notFound = handlers
 	r.notFoundHandlers = combineHandlers(r.handlers, r.notFound)
@@ -487,21 +770,119 @@ func (m *Makross) NewHTTPError(status int, message ...interface{}) *HTTPError {
 }
 
 // HandleError is the error handler for handling any unhandled errors.
+// Errors with a status of 500 or above are passed to the reporters registered by OnError.
+// The binding errors, see BindError, are sent with a "400 - Bad Request" status, and listed under
+// "errors" in the JSON responses, with their field, source, value, expected type and message.
+// The Retry-After of an HTTPError, see NewTooManyRequestsError and NewServiceUnavailableError, is sent
+// in the Retry-After header, and as "retry_after", in seconds, in the JSON responses.
 func (m *Makross) HandleError(c *Context, err interface{}) {
 
 	status := StatusInternalServerError
 	msg := StatusText(status)
+	var bindErrors []*BindError
+	var retryAfter int
 	if httpError, okay := err.(*HTTPError); okay {
 		status = httpError.Status
 		msg = httpError.Message
+		bindErrors = BindErrorsOf(httpError.Err)
+		if v := httpError.RetryAfterHeader(); v != "" {
+			c.Response.Header().Set(HeaderRetryAfter, v)
+			retryAfter = httpError.retryAfterSeconds()
+		}
 	} else if iError, okay := err.(error); okay {
 		msg = iError.Error()
+		// the binding errors of the DataReaders
+		if bindErrors = BindErrorsOf(iError); len(bindErrors) > 0 {
+			status = StatusBadRequest
+		}
+	}
+	if status >= StatusInternalServerError {
+		if e, okay := err.(error); okay {
+			m.ReportError(c, e, nil)
+		} else {
+			m.ReportError(c, fmt.Errorf("%v", err), nil)
+		}
 	}
 	if c.Request != nil && c.Request.Method == HEAD {
 		c.NoContent(status)
-	} else {
-		c.String(msg, status)
+		return
+	}
+	format := NegotiateErrorFormat
+	if m.ErrorFormatFunc != nil {
+		format = m.ErrorFormatFunc
 	}
+	switch format(c) {
+	case MIMETextHTML:
+		if m.renderError(c, &HTTPError{Status: status, Message: msg}) {
+			return
+		}
+	case MIMEApplicationJSON:
+		body := map[string]interface{}{"status": status, "message": msg}
+		if len(bindErrors) > 0 {
+			body["errors"] = bindErrorsBody(bindErrors)
+		}
+		if retryAfter > 0 {
+			body["retry_after"] = retryAfter
+		}
+		if rid := c.Response.Header().Get(HeaderXRequestID); rid != "" {
+			// to be quoted to the support
+			body["request_id"] = rid
+		}
+		c.JSON(body, status)
+		return
+	}
+	c.String(msg, status)
+}
+
+// bindErrorsBody returns the JSON error list of the binding errors.
+func bindErrorsBody(errs []*BindError) []map[string]string {
+	body := make([]map[string]string, len(errs))
+	for i, e := range errs {
+		body[i] = map[string]string{
+			"field":   e.Field,
+			"source":  e.Source,
+			"value":   e.Value,
+			"message": e.Message(),
+		}
+		if e.Type != nil {
+			body[i]["expected"] = e.Type.String()
+		}
+		if e.Offset > 0 {
+			body[i]["offset"] = strconv.FormatInt(e.Offset, 10)
+		}
+	}
+	return body
+}
+
+// ErrorDataKey is the key of the context data holding the HTTPError rendered with the ErrorTemplate.
+const ErrorDataKey = "error"
+
+// NegotiateErrorFormat is the default Makross.ErrorFormatFunc. It uses the Accept header of the request,
+// JSON being preferred on equal quality. JSON and HTML are only chosen when the Accept header lists them,
+// so that a wildcard such as "*/*", like the lack of Accept header, gets plain text.
+func NegotiateErrorFormat(c *Context) string {
+	if c.Request == nil || len(c.Request.Header[HeaderAccept]) == 0 {
+		return MIMETextPlain
+	}
+	if format := c.Accepts(MIMEApplicationJSON, MIMETextHTML, MIMETextPlain); format != "" && c.acceptsExplicitly(format) {
+		return format
+	}
+	return MIMETextPlain
+}
+
+// renderError renders the error page, if there is an error template, and reports whether it did.
+func (m *Makross) renderError(c *Context, he *HTTPError) bool {
+	if m.ErrorTemplate == "" || m.renderer == nil || c.Response.Committed {
+		return false
+	}
+	c.Set(ErrorDataKey, he)
+	buf := new(bytes.Buffer)
+	if err := m.renderer.Render(buf, m.ErrorTemplate, c); err != nil {
+		c.Logger().Errorf("rendering the error page: %v", err)
+		return false
+	}
+	c.Blob(MIMETextHTMLCharsetUTF8, buf.Bytes(), he.Status)
+	return true
 }
 
 func (r *Makross) addRoute(route *Route, handlers []Handler) {
@@ -511,7 +892,11 @@ func (r *Makross) addRoute(route *Route, handlers []Handler) {
 
 	store := r.stores[route.method]
 	if store == nil {
-		store = newStore()
+		if r.newMatcher != nil {
+			store = r.newMatcher()
+		} else {
+			store = newAutoMatcher()
+		}
 		r.stores[route.method] = store
 	}
 
@@ -520,27 +905,37 @@ func (r *Makross) addRoute(route *Route, handlers []Handler) {
 		path = path[:len(path)-1] + "<:.*>"
 	}
 
-	if n := store.Add(path, handlers); n > r.maxParams {
+	route.handlers = handlers
+	if n := store.Add(path, route); n > r.maxParams {
 		r.maxParams = n
 	}
 }
 
 func (m *Makross) find(method, path string, pvalues []string) (handlers []Handler, pnames []string) {
-	var hs interface{}
+	_, handlers, pnames = m.findRoute(method, path, pvalues)
+	return
+}
+
+// findRoute returns the route matching the given method and path together with its handlers.
+// The route is nil if there is no match, in which case the not found handlers are returned.
+func (m *Makross) findRoute(method, path string, pvalues []string) (route *Route, handlers []Handler, pnames []string) {
+	var data interface{}
 	if store := m.stores[method]; store != nil {
-		hs, pnames = store.Get(path, pvalues)
+		data, pnames = store.Get(path, pvalues)
 	}
-	if hs != nil {
-		return hs.([]Handler), pnames
+	if data != nil {
+		route = data.(*Route)
+		return route, route.handlers, pnames
 	}
-	return m.notFoundHandlers, pnames
+	return nil, m.notFoundHandlers, pnames
 }
 
-func (r *Makross) findAllowedMethods(path string) map[string]bool {
+// findAllowedMethods returns the methods of the routes matching the path and kept by the filter.
+func (r *Makross) findAllowedMethods(path string, filter RouteFilter) map[string]bool {
 	methods := make(map[string]bool)
 	pvalues := make([]string, r.maxParams)
 	for m, store := range r.stores {
-		if handlers, _ := store.Get(path, pvalues); handlers != nil {
+		if data, _ := store.Get(path, pvalues); data != nil && filter.keeps(data.(*Route)) {
 			methods[m] = true
 		}
 	}
@@ -548,7 +943,11 @@ func (r *Makross) findAllowedMethods(path string) map[string]bool {
 }
 
 // NotFoundHandler returns a 404 HTTP error indicating a request has no matching route.
-func NotFoundHandler(*Context) error {
+// In Debug mode, with RouteSuggestions, it lists the routes with the nearest paths instead, see SuggestRoutes.
+func NotFoundHandler(c *Context) error {
+	if m := c.makross; m != nil && m.Debug && m.RouteSuggestions && c.route == nil {
+		return m.notFoundSuggestions(c)
+	}
 	return NewHTTPError(StatusNotFound)
 }
 
@@ -556,7 +955,7 @@ func NotFoundHandler(*Context) error {
 // In this case, the handler will respond with an Allow HTTP header listing the allowed HTTP methods.
 // Otherwise, the handler will do nothing and let the next handler (usually a NotFoundHandler) to handle the problem.
 func MethodNotAllowedHandler(c *Context) error {
-	methods := c.Makross().findAllowedMethods(c.Request.URL.Path)
+	methods := c.Makross().findAllowedMethods(c.Request.URL.Path, c.filter)
 	if len(methods) == 0 {
 		return nil
 	}
@@ -4,8 +4,11 @@ package makross
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -54,6 +57,95 @@ func TestRouterNotFound(t *testing.T) {
 	assert.Equal(t, StatusNotFound, res.Code, "HTTP status code")
 }
 
+func TestRouterNotFoundNegotiation(t *testing.T) {
+	m := New()
+	m.NotFound(func(c *Context) error {
+		if c.Negotiate(MIMETextHTML, MIMEApplicationJSON) == MIMEApplicationJSON {
+			return c.JSON(map[string]string{"path": c.Path()}, StatusNotFound)
+		}
+		return c.Blob(MIMETextHTMLCharsetUTF8, []byte("<h1>"+c.Path()+"</h1>"), StatusNotFound)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/users", nil)
+	req.Header.Set(HeaderAccept, "application/json")
+	m.ServeHTTP(res, req)
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Equal(t, MIMEApplicationJSONCharsetUTF8, res.Header().Get(HeaderContentType))
+	assert.Equal(t, `{"path":"/api/users"}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users", nil)
+	req.Header.Set(HeaderAccept, "text/html,application/xhtml+xml,*/*;q=0.8")
+	m.ServeHTTP(res, req)
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Equal(t, "<h1>/users</h1>", res.Body.String())
+}
+
+func TestContextNegotiate(t *testing.T) {
+	m := New()
+	req, _ := http.NewRequest("GET", "/", nil)
+	c := m.NewContext(req, nil)
+	assert.Equal(t, "", c.Negotiate())
+	assert.Equal(t, MIMETextHTML, c.Negotiate(MIMETextHTML, MIMEApplicationJSON))
+
+	tests := []struct{ accept, expected string }{
+		{"application/json", MIMEApplicationJSON},
+		{"text/html;q=0.5, application/json;q=0.9", MIMEApplicationJSON},
+		{"application/*", MIMEApplicationJSON},
+		{"*/*", MIMETextHTML},
+		{"text/*;q=0.1, */*;q=0.2", MIMEApplicationJSON},
+		{"image/png", MIMETextHTML},
+		{"application/json;q=0", MIMETextHTML},
+	}
+	for _, test := range tests {
+		req.Header.Set(HeaderAccept, test.accept)
+		assert.Equal(t, test.expected, c.Negotiate(MIMETextHTML, MIMEApplicationJSON), test.accept)
+	}
+}
+
+func TestContextAccepts(t *testing.T) {
+	m := New()
+	req, _ := http.NewRequest("GET", "/", nil)
+	c := m.NewContext(req, nil)
+	assert.Equal(t, "", c.Accepts())
+	assert.Equal(t, "text/csv", c.Accepts("text/csv", MIMEApplicationJSON))
+
+	tests := []struct{ accept, expected string }{
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+		{"text/*", "text/csv"},
+		{"TEXT/CSV", "text/csv"},
+		{"text/csv;header=present", "text/csv;header=present"},
+		{"text/csv;header=absent, application/json;q=0.1", MIMEApplicationJSON},
+		{"text/csv;q=0.5;ext=1, application/json", MIMEApplicationJSON},
+	}
+	for _, test := range tests {
+		req.Header.Set(HeaderAccept, test.accept)
+		assert.Equal(t, test.expected, c.Accepts("text/csv", "text/csv;header=present", MIMEApplicationJSON), test.accept)
+	}
+}
+
+func TestMatchMediaType(t *testing.T) {
+	tests := []struct {
+		pattern, mediaType string
+		expected           bool
+	}{
+		{"application/json", "application/json", true},
+		{"application/json", "Application/JSON; charset=utf-8", true},
+		{"application/json", "application/jsonp", false},
+		{"application/*", "application/xml", true},
+		{"*/*", "image/png", true},
+		{"text/plain; charset=utf-8", "text/plain;charset=UTF-8", true},
+		{"text/plain; charset=utf-8", "text/plain", false},
+		{"text/plain; charset=\"utf-8\"", "text/plain; charset=utf-8", true},
+		{"", "text/plain", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, MatchMediaType(test.pattern, test.mediaType), test.pattern+" "+test.mediaType)
+	}
+}
+
 func TestRouterUse(t *testing.T) {
 	m := New()
 	assert.Equal(t, 2, len(m.notFoundHandlers))
@@ -100,6 +192,78 @@ func TestRouterHandleError(t *testing.T) {
 	assert.Equal(t, StatusNotFound, res.Code)
 }
 
+// errorRenderer renders the error pages, and fails for the other templates.
+type errorRenderer struct{}
+
+func (r *errorRenderer) Render(w io.Writer, name string, c *Context) error {
+	if name != "error" {
+		return errors.New("unknown template " + name)
+	}
+	he := c.Get(ErrorDataKey).(*HTTPError)
+	_, err := fmt.Fprintf(w, "<h1>%d %s</h1>", he.Status, he.Message)
+	return err
+}
+
+func TestHandleErrorNegotiation(t *testing.T) {
+	m := New()
+	m.SetRenderer(&errorRenderer{})
+	m.Get("/users/<id>", func(c *Context) error {
+		return NewHTTPError(StatusNotFound, "user not found")
+	})
+	request := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(GET, "/users/1", nil)
+		if accept != "" {
+			req.Header.Set(HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	tests := []struct {
+		accept, contentType, body string
+	}{
+		{"", MIMETextPlainCharsetUTF8, "user not found"},
+		{"text/plain", MIMETextPlainCharsetUTF8, "user not found"},
+		{"application/json", MIMEApplicationJSONCharsetUTF8, `{"message":"user not found","status":404}`},
+		{"*/*", MIMETextPlainCharsetUTF8, "user not found"},
+		{"application/*", MIMETextPlainCharsetUTF8, "user not found"},
+		{"text/plain;q=0.5, application/json", MIMEApplicationJSONCharsetUTF8, `{"message":"user not found","status":404}`},
+		{"application/json, text/plain, */*", MIMEApplicationJSONCharsetUTF8, `{"message":"user not found","status":404}`},
+		{"image/png", MIMETextPlainCharsetUTF8, "user not found"},
+		// without error template, HTML falls back to plain text
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", MIMETextPlainCharsetUTF8, "user not found"},
+	}
+	check := func() {
+		for _, test := range tests {
+			res := request(test.accept)
+			assert.Equal(t, StatusNotFound, res.Code, test.accept)
+			assert.Equal(t, test.contentType, res.Header().Get(HeaderContentType), test.accept)
+			assert.Equal(t, test.body, res.Body.String(), test.accept)
+		}
+	}
+	check()
+
+	m.ErrorTemplate = "error"
+	tests[len(tests)-1].contentType = MIMETextHTMLCharsetUTF8
+	tests[len(tests)-1].body = "<h1>404 user not found</h1>"
+	tests = append(tests, struct{ accept, contentType, body string }{"text/html", MIMETextHTMLCharsetUTF8, "<h1>404 user not found</h1>"})
+	check()
+
+	// a failing error template falls back to plain text
+	m.ErrorTemplate = "missing"
+	res := request("text/html")
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Equal(t, "user not found", res.Body.String())
+
+	// the decision is overridable
+	m.ErrorFormatFunc = func(c *Context) string {
+		return MIMEApplicationJSON
+	}
+	res = request("text/plain")
+	assert.Equal(t, `{"message":"user not found","status":404}`, res.Body.String())
+}
+
 func TestHTTPHandler(t *testing.T) {
 	res := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/users/", nil)
@@ -119,3 +283,178 @@ func TestHTTPHandler(t *testing.T) {
 	assert.Nil(t, h2(c))
 	assert.Equal(t, StatusNotFound, res.Code)
 }
+
+func TestTemplateFuncs(t *testing.T) {
+	m := New()
+	// built-in functions
+	assert.Len(t, m.TemplateFuncs(), 1)
+	assert.NotNil(t, m.TemplateFuncs()["sanitize"])
+	m.AddTemplateFunc("upper", strings.ToUpper)
+	funcs := m.TemplateFuncs()
+	assert.Len(t, funcs, 2)
+	assert.NotNil(t, funcs["upper"])
+
+	// the returned map is a copy
+	funcs["lower"] = strings.ToLower
+	assert.Len(t, m.TemplateFuncs(), 2)
+}
+
+func TestEmptyStatus(t *testing.T) {
+	m := New()
+	m.Get("/noop", func(c *Context) error {
+		return nil
+	})
+	m.Get("/empty", func(c *Context) error {
+		return c.NoContent(StatusOK)
+	})
+	m.Get("/accepted", func(c *Context) error {
+		c.SetStatus(StatusAccepted)
+		return nil
+	})
+	m.Get("/error", func(c *Context) error {
+		return NewHTTPError(StatusConflict)
+	})
+	serve := func(path string) int {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, path, nil))
+		return res.Code
+	}
+
+	// disabled by default
+	assert.Equal(t, StatusOK, serve("/noop"))
+
+	m.SetEmptyStatus()
+	assert.Equal(t, StatusNoContent, serve("/noop"))
+	assert.Equal(t, StatusOK, serve("/empty"))
+	assert.Equal(t, StatusAccepted, serve("/accepted"))
+	assert.Equal(t, StatusConflict, serve("/error"))
+
+	m.SetEmptyStatus(StatusAccepted)
+	assert.Equal(t, StatusAccepted, serve("/noop"))
+	m.SetEmptyStatus(0)
+	assert.Equal(t, StatusOK, serve("/noop"))
+}
+
+func TestResponseHeaders(t *testing.T) {
+	m := New()
+	m.SetResponseHeaders(map[string]string{"x-frame-options": "DENY", "X-Version": "1.2"})
+	m.RemoveResponseHeaders([]string{"Server", "X-Powered-By"})
+	m.Get("/", func(c *Context) error {
+		c.Response.Header().Set("X-Powered-By", "Go")
+		c.Response.Header().Set("X-Version", "0.9")
+		return c.String("home")
+	})
+	m.Get("/empty", func(c *Context) error {
+		return nil
+	})
+
+	for _, path := range []string{"/", "/empty", "/missing"} {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, path, nil))
+		assert.Equal(t, "DENY", res.Header().Get("X-Frame-Options"), path)
+		assert.Equal(t, "1.2", res.Header().Get("X-Version"), path)
+		assert.Empty(t, res.Header().Get("Server"), path)
+		assert.Empty(t, res.Header().Get("X-Powered-By"), path)
+	}
+
+	m.SetResponseHeaders(nil)
+	m.RemoveResponseHeaders(nil)
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/", nil))
+	assert.Equal(t, "Makross", res.Header().Get("Server"))
+	assert.Equal(t, "0.9", res.Header().Get("X-Version"))
+	assert.Empty(t, res.Header().Get("X-Frame-Options"))
+}
+
+// nopResponseWriter is a response writer without allocations, for the allocation benchmarks.
+type nopResponseWriter struct {
+	header http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header         { return w.header }
+func (w *nopResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *nopResponseWriter) WriteHeader(int)             {}
+
+func newAllocsMakross() *Makross {
+	m := New()
+	m.Get("/ping", func(c *Context) error {
+		return nil
+	})
+	m.Get("/users/<id>/posts/<post>", func(c *Context) error {
+		if c.Param("id").String() == "" || c.Param("post").String() == "" {
+			return ErrNotFound
+		}
+		// a pointer, not to measure the boxing of the value
+		c.Set("makross", c.Makross())
+		return nil
+	})
+	return m
+}
+
+func BenchmarkServeHTTPStatic(b *testing.B) {
+	m := newAllocsMakross()
+	req, _ := http.NewRequest(GET, "/ping", nil)
+	w := &nopResponseWriter{header: http.Header{}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeHTTPParams(b *testing.B) {
+	m := newAllocsMakross()
+	req, _ := http.NewRequest(GET, "/users/1/posts/2", nil)
+	w := &nopResponseWriter{header: http.Header{}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.ServeHTTP(w, req)
+	}
+}
+
+func TestServeHTTPAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	m := newAllocsMakross()
+	w := &nopResponseWriter{header: http.Header{}}
+	for _, path := range []string{"/ping", "/users/1/posts/2"} {
+		req, _ := http.NewRequest(GET, path, nil)
+		allocs := testing.AllocsPerRun(100, func() {
+			m.ServeHTTP(w, req)
+		})
+		// the Server header, owned by each response
+		assert.Equal(t, 1.0, allocs, path)
+	}
+}
+
+func TestContextStorageReuse(t *testing.T) {
+	m := New()
+	m.Get("/<a>/<b>/<c>/<d>/<e>/<f>/<g>/<h>/<i>/<j>", func(c *Context) error {
+		assert.Nil(t, c.Get("previous"))
+		assert.Nil(t, c.GetStore())
+		c.Set("previous", true)
+		return c.String(c.Param("a").String() + c.Param("j").String())
+	})
+	for i := 0; i < 3; i++ {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, "/1/2/3/4/5/6/7/8/9/10", nil))
+		assert.Equal(t, "110", res.Body.String())
+	}
+
+	// the maps returned by GetStore aren't reused
+	c := m.NewContext(nil, nil)
+	c.Set("a", 1)
+	escaped := c.GetStore()
+	c.Reset(nil, nil)
+	c.Set("b", 2)
+	assert.Equal(t, map[string]interface{}{"a": 1}, escaped)
+
+	c = m.NewContext(nil, nil)
+	assert.Len(t, c.pvalues, 10)
+	c.Set("a", 1)
+	c.FiltersMap.Store("f", true)
+	c.Reset(nil, nil)
+	assert.Nil(t, c.GetStore())
+	_, ok := c.FiltersMap.Load("f")
+	assert.False(t, ok)
+}
@@ -0,0 +1,164 @@
+// Package makrosstest provides utilities for testing makross applications.
+package makrosstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+)
+
+// RunExamples executes the examples declared with Route.Example against the router, each in
+// a subtest named after the route and the example, and reports the unexpected responses:
+//
+//	m.Get("/users/<id>", getUser).Example(makross.Example{
+//		Name:     "existing user",
+//		Params:   map[string]string{"id": "42"},
+//		Status:   makross.StatusOK,
+//		Response: map[string]interface{}{"id": 42, "name": "Alice"},
+//	})
+//
+//	func TestExamples(t *testing.T) {
+//		makrosstest.RunExamples(t, newApp())
+//	}
+func RunExamples(t *testing.T, m *makross.Makross) {
+	for _, route := range m.Routes() {
+		examples, _ := route.GetMeta(makross.MetaExamples).([]makross.Example)
+		for i, example := range examples {
+			name := example.Name
+			if name == "" {
+				name = fmt.Sprintf("example %d", i+1)
+			}
+			route, example := route, example
+			t.Run(route.String()+" "+name, func(t *testing.T) {
+				for _, failure := range RunExample(m, route, example) {
+					t.Error(failure)
+				}
+			})
+		}
+	}
+}
+
+// RunExample executes the example of the route against the router and returns the differences
+// between the expected and the actual response, if any.
+func RunExample(m *makross.Makross, route *makross.Route, example makross.Example) []string {
+	names := make([]string, 0, len(example.Params))
+	for name := range example.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]interface{}, 0, 2*len(names))
+	for _, name := range names {
+		pairs = append(pairs, name, example.Params[name])
+	}
+	target := route.URL(pairs...)
+	if example.Query != "" {
+		target += "?" + example.Query
+	}
+
+	var body io.Reader
+	isJSON := false
+	switch b := example.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+	case []byte:
+		body = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return []string{fmt.Sprintf("invalid example body: %v", err)}
+		}
+		body, isJSON = bytes.NewReader(data), true
+	}
+	req := httptest.NewRequest(route.Method(), target, body)
+	if isJSON {
+		req.Header.Set(makross.HeaderContentType, makross.MIMEApplicationJSON)
+	}
+	for name, value := range example.Header {
+		req.Header.Set(name, value)
+	}
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+
+	var failures []string
+	if example.Status != 0 && res.Code != example.Status {
+		failures = append(failures, fmt.Sprintf("status: expected %d, got %d", example.Status, res.Code))
+	}
+	switch expected := example.Response.(type) {
+	case nil:
+	case string:
+		if !strings.Contains(res.Body.String(), expected) {
+			failures = append(failures, fmt.Sprintf("body: expected to contain %q, got %q", expected, res.Body.String()))
+		}
+	default:
+		var want, got interface{}
+		data, err := json.Marshal(expected)
+		if err == nil {
+			err = json.Unmarshal(data, &want)
+		}
+		if err != nil {
+			return append(failures, fmt.Sprintf("invalid example response: %v", err))
+		}
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			return append(failures, fmt.Sprintf("body: expected JSON, got %q", res.Body.String()))
+		}
+		failures = append(failures, diff("$", want, got)...)
+	}
+	return failures
+}
+
+// diff returns the differences between the expected subset and the actual JSON values.
+func diff(path string, want, got interface{}) []string {
+	switch want := want.(type) {
+	case map[string]interface{}:
+		obj, ok := got.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %s", path, encode(got))}
+		}
+		keys := make([]string, 0, len(want))
+		for key := range want {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var diffs []string
+		for _, key := range keys {
+			v, ok := obj[key]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s: expected %s, got nothing", path, key, encode(want[key])))
+				continue
+			}
+			diffs = append(diffs, diff(path+"."+key, want[key], v)...)
+		}
+		return diffs
+	case []interface{}:
+		arr, ok := got.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %s", path, encode(got))}
+		}
+		if len(arr) != len(want) {
+			return []string{fmt.Sprintf("%s: expected %d elements, got %d: %s", path, len(want), len(arr), encode(got))}
+		}
+		var diffs []string
+		for i := range want {
+			diffs = append(diffs, diff(fmt.Sprintf("%s[%d]", path, i), want[i], arr[i])...)
+		}
+		return diffs
+	}
+	if !reflect.DeepEqual(want, got) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, encode(want), encode(got))}
+	}
+	return nil
+}
+
+func encode(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,88 @@
+package makrosstest
+
+import (
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func newApp() *makross.Makross {
+	m := makross.New()
+	m.Get("/users/<id>", func(c *makross.Context) error {
+		if c.Param("id").String() != "42" {
+			return makross.NewHTTPError(makross.StatusNotFound, "unknown user")
+		}
+		return c.JSON(user{42, "Alice", []string{"admin"}})
+	}).Example(makross.Example{
+		Name:     "existing user",
+		Params:   map[string]string{"id": "42"},
+		Status:   makross.StatusOK,
+		Response: map[string]interface{}{"name": "Alice", "tags": []string{"admin"}},
+	}, makross.Example{
+		Name:     "unknown user",
+		Params:   map[string]string{"id": "7"},
+		Status:   makross.StatusNotFound,
+		Response: "unknown user",
+	})
+	m.Post("/users", func(c *makross.Context) error {
+		var u user
+		if err := c.Bind(&u); err != nil {
+			return err
+		}
+		u.ID = 43
+		return c.JSON(u, makross.StatusCreated)
+	}).Example(makross.Example{
+		Body:     user{Name: "Bob"},
+		Status:   makross.StatusCreated,
+		Response: user{ID: 43, Name: "Bob"},
+	})
+	return m
+}
+
+func TestRunExamples(t *testing.T) {
+	RunExamples(t, newApp())
+}
+
+func TestRunExampleFailures(t *testing.T) {
+	m := newApp()
+	route := m.Routes()[0]
+
+	failures := RunExample(m, route, makross.Example{
+		Params:   map[string]string{"id": "42"},
+		Query:    "expand=1",
+		Status:   makross.StatusCreated,
+		Response: map[string]interface{}{"id": 43, "name": "Alice", "email": "alice@example.com", "tags": []string{}},
+	})
+	assert.Equal(t, []string{
+		"status: expected 201, got 200",
+		`$.email: expected "alice@example.com", got nothing`,
+		"$.id: expected 43, got 42",
+		`$.tags: expected 0 elements, got 1: ["admin"]`,
+	}, failures)
+
+	failures = RunExample(m, route, makross.Example{
+		Params:   map[string]string{"id": "7"},
+		Response: "unknown group",
+	})
+	assert.Equal(t, []string{`body: expected to contain "unknown group", got "unknown user"`}, failures)
+
+	failures = RunExample(m, route, makross.Example{
+		Params:   map[string]string{"id": "42"},
+		Response: []string{"Alice"},
+	})
+	assert.Equal(t, []string{`$: expected an array, got {"id":42,"name":"Alice","tags":["admin"]}`}, failures)
+}
+
+func TestExamplesInRouteInfo(t *testing.T) {
+	info := newApp().Routes()[0].Info()
+	if assert.Len(t, info.Examples, 2) {
+		assert.Equal(t, "existing user", info.Examples[0].Name)
+	}
+}
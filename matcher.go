@@ -0,0 +1,126 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"sort"
+	"strings"
+)
+
+type (
+	// RouteMatcher stores the routes of a method by path, and finds the route matching the path of a request.
+	// The paths are the ones of the routes prefixed by their group, with the asterisk at the end replaced
+	// by the "<:.*>" parameter. See `Makross#SetRouteMatcher()`.
+	RouteMatcher interface {
+		// Add stores the data under the path, and returns the number of parameters of the path.
+		// The data of a path already added is kept.
+		Add(path string, data interface{}) int
+
+		// Get returns the data matching the path of a request, or nil, and the names of the parameters
+		// of the matching path, whose values are set in pvalues.
+		Get(path string, pvalues []string) (data interface{}, pnames []string)
+
+		// String describes the stored paths, for debugging.
+		String() string
+	}
+
+	// ExactMatcher is a RouteMatcher of static paths, matched with a map lookup without allocation,
+	// e.g. for the tens of thousands of generated routes of an API gateway. It panics when a path
+	// with parameters is added.
+	ExactMatcher struct {
+		data  map[string]interface{}
+		paths []string // in the order they were added
+	}
+
+	// autoMatcher is the default RouteMatcher: an ExactMatcher until a path with parameters is added,
+	// after which the routes are moved to the radix tree.
+	autoMatcher struct {
+		exact *ExactMatcher
+		tree  *store
+	}
+)
+
+// NewExactMatcher returns an ExactMatcher.
+func NewExactMatcher() RouteMatcher {
+	return &ExactMatcher{data: make(map[string]interface{})}
+}
+
+// NewTreeMatcher returns the RouteMatcher of the paths with parameters, a radix tree.
+// A parametric path is a string containing tokens in the format of "<name>", "<name:pattern>", or "<:pattern>".
+func NewTreeMatcher() RouteMatcher {
+	return newStore()
+}
+
+// Add implements `RouteMatcher#Add()`.
+func (m *ExactMatcher) Add(path string, data interface{}) int {
+	if !isStaticPath(path) {
+		panic("makross: the ExactMatcher can't match the parameters of " + path)
+	}
+	if _, ok := m.data[path]; !ok {
+		m.data[path] = data
+		m.paths = append(m.paths, path)
+	}
+	return 0
+}
+
+// Get implements `RouteMatcher#Get()`.
+func (m *ExactMatcher) Get(path string, pvalues []string) (data interface{}, pnames []string) {
+	return m.data[path], nil
+}
+
+// String implements `RouteMatcher#String()`, listing the paths in order.
+func (m *ExactMatcher) String() string {
+	paths := append([]string(nil), m.paths...)
+	sort.Strings(paths)
+	return strings.Join(paths, "\n")
+}
+
+func newAutoMatcher() *autoMatcher {
+	return &autoMatcher{exact: &ExactMatcher{data: make(map[string]interface{})}}
+}
+
+func (m *autoMatcher) Add(path string, data interface{}) int {
+	if m.tree == nil {
+		if isStaticPath(path) {
+			return m.exact.Add(path, data)
+		}
+		m.tree = newStore()
+		for _, p := range m.exact.paths {
+			m.tree.Add(p, m.exact.data[p])
+		}
+		m.exact = nil
+	}
+	return m.tree.Add(path, data)
+}
+
+func (m *autoMatcher) Get(path string, pvalues []string) (data interface{}, pnames []string) {
+	if m.tree == nil {
+		return m.exact.Get(path, pvalues)
+	}
+	return m.tree.Get(path, pvalues)
+}
+
+func (m *autoMatcher) String() string {
+	if m.tree == nil {
+		return m.exact.String()
+	}
+	return m.tree.String()
+}
+
+// isStaticPath reports whether the path has no parameters.
+func isStaticPath(path string) bool {
+	return strings.IndexByte(path, '<') < 0
+}
+
+// SetRouteMatcher sets the function returning the RouteMatcher of the routes of each method,
+// e.g. NewExactMatcher for a router of static routes only, or a custom implementation.
+// It must be called before adding the routes.
+//
+// By default, the routes of a method are matched with a map lookup while they are all static,
+// and with the radix tree returned by NewTreeMatcher once a route has parameters.
+func (m *Makross) SetRouteMatcher(newMatcher func() RouteMatcher) {
+	if len(m.routes) > 0 {
+		panic("makross: SetRouteMatcher must be called before adding the routes")
+	}
+	m.newMatcher = newMatcher
+}
@@ -0,0 +1,179 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteMatchers(t *testing.T) {
+	paths := []struct {
+		key, value string
+	}{
+		{"/gopher/bumper.png", "1"},
+		{"/gopher/bumper192x108.png", "2"},
+		{"/gopher/doc.png", "3"},
+		{"/gopher/docpage.png", "4"},
+		{"/gopher/doc.png", "5"},
+		{"/gopher/doc", "6"},
+		{"/gopher/doc/", "7"},
+		{"", "8"},
+	}
+	tests := []struct {
+		key   string
+		value interface{}
+	}{
+		{"/gopher/bumper.png", "1"},
+		{"/gopher/bumper192x108.png", "2"},
+		{"/gopher/doc.png", "3"},
+		{"/gopher/docpage.png", "4"},
+		{"/gopher/doc", "6"},
+		{"/gopher/doc/", "7"},
+		{"", "8"},
+		{"/gopher/", nil},
+		{"/gopher/bumper", nil},
+		{"/gopher/doc.png/", nil},
+		{"/Gopher/doc", nil},
+	}
+	matchers := map[string]RouteMatcher{
+		"tree":  NewTreeMatcher(),
+		"exact": NewExactMatcher(),
+		"auto":  newAutoMatcher(),
+	}
+	for name, matcher := range matchers {
+		for _, path := range paths {
+			assert.Equal(t, 0, matcher.Add(path.key, path.value), name)
+		}
+		for _, test := range tests {
+			data, pnames := matcher.Get(test.key, nil)
+			assert.Equal(t, test.value, data, name+" Get("+test.key+")")
+			assert.Empty(t, pnames, name+" Get("+test.key+")")
+		}
+	}
+	assert.Equal(t, "\n/gopher/bumper.png\n/gopher/bumper192x108.png\n/gopher/doc\n/gopher/doc.png\n/gopher/doc/\n/gopher/docpage.png",
+		matchers["exact"].String())
+
+	assert.Panics(t, func() {
+		NewExactMatcher().Add("/users/<id>", "1")
+	})
+
+	// the auto matcher moves the routes to the tree when a parametric path is added
+	auto := matchers["auto"]
+	assert.Equal(t, 1, auto.Add("/users/<id>", "9"))
+	assert.Equal(t, 2, auto.Add("/users/<id>/<accnt:\\d+>", "10"))
+	assert.Equal(t, 0, auto.Add("/gopher/doc.png", "11"))
+	assert.Equal(t, 0, auto.Add("/status", "12"))
+	pvalues := make([]string, 2)
+	for _, test := range append(tests, []struct {
+		key   string
+		value interface{}
+	}{
+		{"/users/abc", "9"},
+		{"/users/abc/123", "10"},
+		{"/users/abc/xyz", nil},
+		{"/status", "12"},
+	}...) {
+		data, _ := auto.Get(test.key, pvalues)
+		assert.Equal(t, test.value, data, "auto Get("+test.key+")")
+	}
+	data, pnames := auto.Get("/users/abc/123", pvalues)
+	assert.Equal(t, "10", data)
+	assert.Equal(t, []string{"id", "accnt"}, pnames)
+	assert.Equal(t, []string{"abc", "123"}, pvalues)
+}
+
+func TestSetRouteMatcher(t *testing.T) {
+	m := New()
+	m.SetRouteMatcher(NewExactMatcher)
+	m.Get("/users", func(c *Context) error {
+		return c.String("users")
+	})
+	m.Any("/any", func(c *Context) error {
+		return c.String(c.Request.Method)
+	})
+	assert.Panics(t, func() {
+		m.Get("/users/<id>", NotFoundHandler)
+	})
+	assert.Panics(t, func() {
+		m.SetRouteMatcher(NewTreeMatcher)
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+		return res
+	}
+	res := serve(GET, "/users")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "users", res.Body.String())
+	assert.Equal(t, PUT, serve(PUT, "/any").Body.String())
+	assert.Equal(t, StatusNotFound, serve(GET, "/users/1").Code)
+	res = serve(POST, "/users")
+	assert.Equal(t, StatusMethodNotAllowed, res.Code)
+	assert.Contains(t, res.Header().Get(HeaderAllow), GET)
+
+	// the routes of the default matcher match whether they are all static or not
+	m = New()
+	m.Get("/users", func(c *Context) error {
+		return c.String("users")
+	})
+	m.Get("/users/<id>", func(c *Context) error {
+		return c.String("user " + c.Param("id").String())
+	})
+	assert.Equal(t, "users", serveTest(m, GET, "/users"))
+	assert.Equal(t, "user 7", serveTest(m, GET, "/users/7"))
+}
+
+func serveTest(m *Makross, method, path string) string {
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+	return res.Body.String()
+}
+
+func TestExactMatcherAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are counted under the race detector")
+	}
+	m := New()
+	m.SetRouteMatcher(NewExactMatcher)
+	for i := 0; i < 1000; i++ {
+		m.Get("/api/v1/resource"+strconv.Itoa(i), NotFoundHandler)
+	}
+	m.Get("/ping", func(c *Context) error {
+		return c.NoContent(StatusNoContent)
+	})
+	req := httptest.NewRequest(GET, "/ping", nil)
+	w := &nopResponseWriter{header: http.Header{}}
+	m.ServeHTTP(w, req)
+	// the Server header, owned by each response
+	assert.Equal(t, 1.0, testing.AllocsPerRun(100, func() {
+		m.ServeHTTP(w, req)
+	}))
+}
+
+func benchmarkMatcher(b *testing.B, matcher RouteMatcher) {
+	const n = 10000
+	for i := 0; i < n; i++ {
+		matcher.Add(fmt.Sprintf("/api/v1/service%d/resource%d", i%100, i), i)
+	}
+	key := fmt.Sprintf("/api/v1/service%d/resource%d", (n-1)%100, n-1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matcher.Get(key, nil)
+	}
+}
+
+func BenchmarkTreeMatcher(b *testing.B) {
+	benchmarkMatcher(b, NewTreeMatcher())
+}
+
+func BenchmarkExactMatcher(b *testing.B) {
+	benchmarkMatcher(b, NewExactMatcher())
+}
@@ -0,0 +1,102 @@
+package mediatype
+
+import (
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// MediaTypeConfig defines the config for MediaType middleware.
+	MediaTypeConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+	}
+)
+
+// ProducesKey is the context data key of the media type negotiated among the types declared with Route.Produces.
+const ProducesKey = "mediatype.produces"
+
+var (
+	// DefaultMediaTypeConfig is the default MediaType middleware config.
+	DefaultMediaTypeConfig = MediaTypeConfig{
+		Skipper: skipper.DefaultSkipper,
+	}
+)
+
+// MediaType returns a MediaType middleware.
+//
+// MediaType middleware enforces the media types declared on the matching route:
+//
+//	m.Use(mediatype.MediaType())
+//	m.Post("/reports", create).Consumes("application/json").Produces("application/json", "text/csv")
+//
+// A request with a body whose Content-Type doesn't match the types declared with Route.Consumes
+// is rejected with "415 - Unsupported Media Type" before the body is read. The response type
+// is negotiated among the types declared with Route.Produces according to the Accept header,
+// and is available to the handler with Produced. A request accepting none of them is rejected
+// with "406 - Not Acceptable" and the list of the supported types.
+func MediaType() makross.Handler {
+	return MediaTypeWithConfig(DefaultMediaTypeConfig)
+}
+
+// MediaTypeWithConfig returns a MediaType middleware with config.
+// See: `MediaType()`.
+func MediaTypeWithConfig(config MediaTypeConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultMediaTypeConfig.Skipper
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		route := c.Route()
+		if route == nil {
+			return c.Next()
+		}
+
+		req := c.Request
+		if consumes, _ := route.GetMeta(makross.MetaConsumes).([]string); len(consumes) > 0 && req.ContentLength != 0 {
+			if !matchAny(consumes, req.Header.Get(makross.HeaderContentType)) {
+				return makross.NewHTTPError(makross.StatusUnsupportedMediaType, supported(makross.StatusUnsupportedMediaType, consumes))
+			}
+		}
+
+		if produces, _ := route.GetMeta(makross.MetaProduces).([]string); len(produces) > 0 {
+			c.Response.Header().Add(makross.HeaderVary, makross.HeaderAccept)
+			produced := c.Accepts(produces...)
+			if produced == "" {
+				return makross.NewHTTPError(makross.StatusNotAcceptable, supported(makross.StatusNotAcceptable, produces))
+			}
+			c.Set(ProducesKey, produced)
+		}
+		return c.Next()
+	}
+}
+
+// Produced returns the media type negotiated by the MediaType middleware,
+// or an empty string if the route doesn't declare the types it produces.
+func Produced(c *makross.Context) string {
+	produced, _ := c.Get(ProducesKey).(string)
+	return produced
+}
+
+func matchAny(patterns []string, contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if makross.MatchMediaType(pattern, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+func supported(status int, mediaTypes []string) string {
+	return makross.StatusText(status) + ", supported media types: " + strings.Join(mediaTypes, ", ")
+}
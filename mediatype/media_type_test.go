@@ -0,0 +1,73 @@
+package mediatype
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaType(t *testing.T) {
+	m := makross.New()
+	m.Use(MediaType())
+	var read bool
+	m.Post("/reports", func(c *makross.Context) error {
+		read = true
+		return c.String(Produced(c))
+	}).Consumes(makross.MIMEApplicationJSON, "text/*").Produces(makross.MIMEApplicationJSON, "text/csv")
+	m.Get("/any", func(c *makross.Context) error {
+		return c.String("any" + Produced(c))
+	})
+
+	request := func(method, path, contentType, accept, body string) *httptest.ResponseRecorder {
+		var req *http.Request
+		if body == "" {
+			req = httptest.NewRequest(method, path, nil)
+		} else {
+			req = httptest.NewRequest(method, path, strings.NewReader(body))
+		}
+		if contentType != "" {
+			req.Header.Set(makross.HeaderContentType, contentType)
+		}
+		if accept != "" {
+			req.Header.Set(makross.HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	tests := []struct {
+		contentType, accept, body string
+		code                      int
+		response                  string
+	}{
+		{"application/json; charset=utf-8", "", "{}", http.StatusOK, makross.MIMEApplicationJSON},
+		{"text/plain", "text/csv", "a", http.StatusOK, "text/csv"},
+		{"", "text/csv", "", http.StatusOK, "text/csv"},
+		{"application/xml", "", "<a/>", http.StatusUnsupportedMediaType, "Unsupported Media Type, supported media types: application/json, text/*"},
+		{"", "", "{}", http.StatusUnsupportedMediaType, ""},
+		{"application/json", "image/png", "{}", http.StatusNotAcceptable, "Not Acceptable, supported media types: application/json, text/csv"},
+		{"application/json", "text/csv;q=0, application/*", "{}", http.StatusOK, makross.MIMEApplicationJSON},
+	}
+	for _, test := range tests {
+		read = false
+		res := request("POST", "/reports", test.contentType, test.accept, test.body)
+		assert.Equal(t, test.code, res.Code, test.contentType+" "+test.accept)
+		assert.Equal(t, test.code == http.StatusOK, read, test.contentType+" "+test.accept)
+		if test.response != "" {
+			assert.Equal(t, test.response, res.Body.String(), test.contentType+" "+test.accept)
+		}
+		if test.code != http.StatusUnsupportedMediaType {
+			assert.Equal(t, makross.HeaderAccept, res.Header().Get(makross.HeaderVary), test.contentType+" "+test.accept)
+		}
+	}
+
+	// routes without declarations aren't checked
+	res := request("GET", "/any", "application/xml", "image/png", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "any", res.Body.String())
+}
@@ -0,0 +1,117 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import "fmt"
+
+// Middlewares returns the middlewares which run before the handlers of every request,
+// including the requests without a matching route.
+//
+// Unlike the handlers registered with Use, which are bound to the routes when they are added,
+// these middlewares can be changed while serving with InsertMiddleware and RemoveMiddleware,
+// e.g. to load and unload plugins. The list is copied on write: a change takes effect on the next
+// request, while the requests in flight keep running the list they started with.
+func (m *Makross) Middlewares() []Handler {
+	m.middlewaresMu.Lock()
+	defer m.middlewaresMu.Unlock()
+	return append([]Handler(nil), m.loadMiddlewares()...)
+}
+
+// InsertMiddleware inserts a middleware at the given index of the Middlewares list.
+// An index equal to the length of the list appends the middleware. It panics if the index is out of range.
+// It is safe to call while serving.
+func (m *Makross) InsertMiddleware(index int, h Handler) {
+	m.middlewaresMu.Lock()
+	defer m.middlewaresMu.Unlock()
+	old := m.loadMiddlewares()
+	if index < 0 || index > len(old) {
+		panic(fmt.Sprintf("makross: middleware index %d out of range [0, %d]", index, len(old)))
+	}
+	mws := make([]Handler, 0, len(old)+1)
+	mws = append(mws, old[:index]...)
+	mws = append(mws, h)
+	mws = append(mws, old[index:]...)
+	m.middlewares.Store(mws)
+}
+
+// RemoveMiddleware removes the middleware at the given index of the Middlewares list.
+// It panics if the index is out of range. It is safe to call while serving.
+func (m *Makross) RemoveMiddleware(index int) {
+	m.middlewaresMu.Lock()
+	defer m.middlewaresMu.Unlock()
+	old := m.loadMiddlewares()
+	if index < 0 || index >= len(old) {
+		panic(fmt.Sprintf("makross: middleware index %d out of range [0, %d)", index, len(old)))
+	}
+	mws := make([]Handler, 0, len(old)-1)
+	mws = append(mws, old[:index]...)
+	mws = append(mws, old[index+1:]...)
+	m.middlewares.Store(mws)
+}
+
+// Pre registers handlers which run before the request is routed, so that they can change
+// the request path or method, e.g. to strip a path prefix, or the headers, e.g. to resolve
+// the tenant of a subdomain. The route is selected with the request as they leave it.
+// They run before the Middlewares and the handlers of the matching route. Pre should be called
+// before serving.
+//
+// As no route is matched yet, Route returns nil and Param returns "" in the pre handlers,
+// but the parameters they set with SetParam are kept after routing.
+func (m *Makross) Pre(handlers ...Handler) {
+	pre := m.pre
+	if len(pre) > 0 {
+		// drop the routing handler
+		pre = pre[:len(pre)-1]
+	}
+	pre = append(append([]Handler(nil), pre...), handlers...)
+	m.pre = append(pre, m.routePre)
+}
+
+// routePre is the last of the pre handlers: it routes the request and runs the handlers of the route.
+func (m *Makross) routePre(c *Context) error {
+	handlers := m.route(c)
+	c.chain = append(append(append(c.chain[:0], m.pre...), m.loadMiddlewares()...), handlers...)
+	c.handlers = c.chain
+	return c.Next()
+}
+
+// route returns the handlers of the request, which refuse it while draining
+// and redirect it if a redirect rule matches its path.
+func (m *Makross) route(c *Context) []Handler {
+	req := c.Request
+	c.routed = true
+	if (m.Draining() || m.WarmingUp()) && !m.drainExcluded(req.URL.Path) {
+		return drainHandlers
+	}
+	if h := m.redirects.handler(req); h != nil {
+		return []Handler{h}
+	}
+	var handlers []Handler
+	c.route, handlers, c.pnames = m.findRoute(req.Method, req.URL.Path, c.pvalues)
+	for i := 0; i+1 < len(c.preParams); i += 2 {
+		c.SetParam(c.preParams[i], c.preParams[i+1])
+	}
+	if c.route != nil && !c.filter.keeps(c.route) {
+		// the route isn't served by the listener of the request
+		c.route = nil
+		return m.notFoundHandlers
+	}
+	return handlers
+}
+
+// loadMiddlewares returns the current list of middlewares, which must not be modified.
+func (m *Makross) loadMiddlewares() []Handler {
+	mws, _ := m.middlewares.Load().([]Handler)
+	return mws
+}
+
+// withMiddlewares prepends the middlewares to the handlers of the request.
+// The chain is built in a buffer of the context, so that it doesn't allocate once the context is reused.
+func (c *Context) withMiddlewares(handlers []Handler) []Handler {
+	mws := c.makross.loadMiddlewares()
+	if len(mws) == 0 {
+		return handlers
+	}
+	c.chain = append(append(c.chain[:0], mws...), handlers...)
+	return c.chain
+}
@@ -0,0 +1,182 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertRemoveMiddleware(t *testing.T) {
+	m := New()
+	m.Get("/users", func(c *Context) error {
+		return c.String("users")
+	})
+	tag := func(s string) Handler {
+		return func(c *Context) error {
+			c.Response.Header().Add("X-Middleware", s)
+			return nil
+		}
+	}
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("/users")
+	assert.Equal(t, "users", res.Body.String())
+	assert.Empty(t, res.Header()["X-Middleware"])
+
+	m.InsertMiddleware(0, tag("b"))
+	m.InsertMiddleware(0, tag("a"))
+	m.InsertMiddleware(2, tag("c"))
+	assert.Len(t, m.Middlewares(), 3)
+	res = serve("/users")
+	assert.Equal(t, "users", res.Body.String())
+	assert.Equal(t, []string{"a", "b", "c"}, res.Header()["X-Middleware"])
+
+	// the middlewares also run for requests without a matching route
+	res = serve("/none")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, []string{"a", "b", "c"}, res.Header()["X-Middleware"])
+
+	m.RemoveMiddleware(1)
+	res = serve("/users")
+	assert.Equal(t, []string{"a", "c"}, res.Header()["X-Middleware"])
+
+	assert.Panics(t, func() { m.InsertMiddleware(3, tag("d")) })
+	assert.Panics(t, func() { m.RemoveMiddleware(2) })
+
+	m.RemoveMiddleware(0)
+	m.RemoveMiddleware(0)
+	res = serve("/users")
+	assert.Empty(t, res.Header()["X-Middleware"])
+}
+
+func TestMiddlewaresConcurrency(t *testing.T) {
+	m := New()
+	m.Get("/", func(c *Context) error {
+		return c.String("ok")
+	})
+	noop := func(c *Context) error { return nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				res := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/", nil)
+				m.ServeHTTP(res, req)
+				assert.Equal(t, "ok", res.Body.String())
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		m.InsertMiddleware(0, noop)
+		m.RemoveMiddleware(0)
+	}
+	wg.Wait()
+}
+
+func TestPre(t *testing.T) {
+	m := New()
+	tag := func(s string) Handler {
+		return func(c *Context) error {
+			c.Response.Header().Add("X-Handler", s)
+			return nil
+		}
+	}
+	m.Pre(tag("pre1"), func(c *Context) error {
+		if c.Request.URL.Path == "/forbidden" {
+			return NewHTTPError(http.StatusForbidden)
+		}
+		c.Request.URL.Path = strings.TrimSuffix(c.Request.URL.Path, ".json")
+		return c.Next()
+	})
+	m.Pre(tag("pre2"))
+	m.Use(tag("use"))
+	m.InsertMiddleware(0, tag("runtime"))
+	m.Get("/users", func(c *Context) error {
+		c.Response.Header().Add("X-Handler", "users")
+		return c.String(c.Route().String())
+	})
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, path, nil))
+		return res
+	}
+
+	// the pre handlers change the path before routing
+	for i := 0; i < 2; i++ {
+		res := serve("/users.json")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "GET /users", res.Body.String())
+		assert.Equal(t, []string{"pre1", "pre2", "runtime", "use", "users"}, res.Header()["X-Handler"])
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("/forbidden").Code)
+	res := serve("/unknown")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, []string{"pre1", "pre2", "runtime", "use"}, res.Header()["X-Handler"])
+}
+
+func TestPreSetParam(t *testing.T) {
+	m := New()
+	m.Pre(func(c *Context) error {
+		if strings.HasPrefix(c.Request.URL.Path, "/de/") {
+			c.Request.URL.Path = c.Request.URL.Path[3:]
+			c.SetParam("locale", "de")
+		}
+		return c.Next()
+	})
+	m.Get("/users/<id>", func(c *Context) error {
+		return c.String(c.Param("locale").String() + " " + c.Param("id").String())
+	})
+	for i := 0; i < 2; i++ {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, "/de/users/1", nil))
+		assert.Equal(t, "de 1", res.Body.String())
+
+		// the params of the previous request aren't kept
+		res = httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, "/users/2", nil))
+		assert.Equal(t, " 2", res.Body.String())
+	}
+}
+
+func TestPreRewrite(t *testing.T) {
+	m := New()
+	m.Pre(func(c *Context) error {
+		// resolve the tenant of the subdomain
+		if i := strings.Index(c.Request.Host, ".example.com"); i > 0 {
+			assert.Nil(t, c.Route())
+			assert.Equal(t, "", c.Param("tenant").String())
+			c.Request.URL.Path = "/tenants/" + c.Request.Host[:i] + c.Request.URL.Path
+			c.Request.Header.Set("X-Tenant", c.Request.Host[:i])
+		}
+		return c.Next()
+	})
+	m.Get("/dashboard", func(c *Context) error {
+		return c.String("public")
+	})
+	m.Get("/tenants/<tenant>/dashboard", func(c *Context) error {
+		return c.String(c.Param("tenant").String() + " " + c.Request.Header.Get("X-Tenant"))
+	})
+
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "http://acme.example.com/dashboard", nil))
+	assert.Equal(t, "acme acme", res.Body.String())
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "http://example.com/dashboard", nil))
+	assert.Equal(t, "public", res.Body.String())
+}
@@ -0,0 +1,214 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+)
+
+var (
+	// the offsets of the unexported fields of multipart.FileHeader holding its content, which
+	// mime/multipart gives no way to set, see readMultipartForm.
+	fileHeaderContent, fileHeaderContentOK = fileHeaderField("content", reflect.TypeOf([]byte(nil)))
+	fileHeaderTmpfile, fileHeaderTmpfileOK = fileHeaderField("tmpfile", reflect.TypeOf(""))
+)
+
+func fileHeaderField(name string, typ reflect.Type) (uintptr, bool) {
+	f, ok := reflect.TypeOf(multipart.FileHeader{}).FieldByName(name)
+	if !ok || f.Type != typ {
+		return 0, false
+	}
+	return f.Offset, true
+}
+
+// SetMultipartTempDir sets the directory of the temporary files holding the files of the multipart
+// forms too large to be held in memory, e.g. a volume rather than a small tmpfs. By default they are
+// created in os.TempDir(). The setting only applies to the forms parsed by this Makross, the other
+// temporary files of the process are left in their directory.
+//
+// The temporary files of a request are removed when the request ends, even if a handler panics,
+// and their size is counted by MultipartTempBytes while they exist.
+func (m *Makross) SetMultipartTempDir(dir string) error {
+	if !fileHeaderContentOK || !fileHeaderTmpfileOK {
+		return errors.New("makross: the multipart temporary directory is not supported by this version of Go")
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("makross: %s is not a directory", dir)
+	}
+	m.multipartTempDir = dir
+	return nil
+}
+
+// MultipartTempBytes returns the size of the temporary files currently holding the files of the
+// multipart forms parsed by the Context methods, such as FormFile or MultipartForm, for monitoring.
+func (m *Makross) MultipartTempBytes() int64 {
+	return atomic.LoadInt64(&m.multipartTemp)
+}
+
+// parseMultipartForm parses the multipart form of the request, counting the size of its temporary files.
+func (c *Context) parseMultipartForm() error {
+	parsed := c.Request.MultipartForm != nil
+	var err error
+	if dir := c.makross.multipartTempDir; dir != "" {
+		err = readMultipartForm(c.Request, c.makross.MultipartMemory, dir)
+	} else {
+		err = c.Request.ParseMultipartForm(c.makross.MultipartMemory)
+	}
+	if form := c.Request.MultipartForm; !parsed && form != nil {
+		for _, files := range form.File {
+			for _, fh := range files {
+				f, err := fh.Open()
+				if err != nil {
+					continue
+				}
+				if _, ok := f.(*os.File); ok {
+					// not held in memory
+					c.tempBytes += fh.Size
+				}
+				f.Close()
+			}
+		}
+		atomic.AddInt64(&c.makross.multipartTemp, c.tempBytes)
+	}
+	return err
+}
+
+// readMultipartForm parses the multipart form of the request as Request.ParseMultipartForm does,
+// but writes the files larger than maxMemory to temporary files in dir.
+func readMultipartForm(r *http.Request, maxMemory int64, dir string) error {
+	var parseFormErr error
+	if r.Form == nil {
+		parseFormErr = r.ParseForm()
+	}
+	if r.MultipartForm != nil {
+		return nil
+	}
+	ct := r.Header.Get(HeaderContentType)
+	if ct == "" {
+		return http.ErrNotMultipart
+	}
+	if r.Body == nil {
+		return errors.New("missing form body")
+	}
+	d, params, err := mime.ParseMediaType(ct)
+	if err != nil || d != MIMEMultipartForm {
+		return http.ErrNotMultipart
+	}
+	boundary, ok := params["boundary"]
+	if !ok {
+		return http.ErrMissingBoundary
+	}
+	form, err := readMultipartParts(multipart.NewReader(r.Body, boundary), maxMemory, dir)
+	if err != nil {
+		return err
+	}
+	if r.PostForm == nil {
+		r.PostForm = make(url.Values)
+	}
+	for k, v := range form.Value {
+		r.Form[k] = append(r.Form[k], v...)
+		r.PostForm[k] = append(r.PostForm[k], v...)
+	}
+	r.MultipartForm = form
+	return parseFormErr
+}
+
+// readMultipartParts reads the parts of a multipart form as multipart.Reader.ReadForm does, its
+// temporary files being created in dir. They are removed if the form cannot be read.
+func readMultipartParts(mr *multipart.Reader, maxMemory int64, dir string) (form *multipart.Form, err error) {
+	form = &multipart.Form{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*multipart.FileHeader),
+	}
+	defer func() {
+		if err != nil {
+			form.RemoveAll()
+		}
+	}()
+
+	// as mime/multipart, 10 MB are reserved for the values in addition to maxMemory
+	maxValueBytes := maxMemory + 10<<20
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return form, err
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		var b bytes.Buffer
+		filename := p.FileName()
+		if filename == "" {
+			n, err := io.CopyN(&b, p, maxValueBytes+1)
+			if err != nil && err != io.EOF {
+				return form, err
+			}
+			if maxValueBytes -= n; maxValueBytes < 0 {
+				return form, multipart.ErrMessageTooLarge
+			}
+			form.Value[name] = append(form.Value[name], b.String())
+			continue
+		}
+
+		fh := &multipart.FileHeader{
+			Filename: filename,
+			Header:   p.Header,
+		}
+		n, err := io.CopyN(&b, p, maxMemory+1)
+		if err != nil && err != io.EOF {
+			return form, err
+		}
+		if n <= maxMemory {
+			*(*[]byte)(unsafe.Add(unsafe.Pointer(fh), fileHeaderContent)) = b.Bytes()
+			fh.Size = n
+			maxMemory -= n
+			form.File[name] = append(form.File[name], fh)
+			continue
+		}
+
+		file, err := os.CreateTemp(dir, "multipart-")
+		if err != nil {
+			return form, err
+		}
+		// registered before being written, to be removed on failure
+		*(*string)(unsafe.Add(unsafe.Pointer(fh), fileHeaderTmpfile)) = file.Name()
+		form.File[name] = append(form.File[name], fh)
+		fh.Size, err = io.Copy(file, io.MultiReader(&b, p))
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return form, err
+		}
+	}
+}
+
+// removeMultipartFiles removes the temporary files of the multipart form of the request.
+func (c *Context) removeMultipartFiles() {
+	if c.Request != nil && c.Request.MultipartForm != nil {
+		c.Request.MultipartForm.RemoveAll()
+	}
+	if c.tempBytes != 0 {
+		atomic.AddInt64(&c.makross.multipartTemp, -c.tempBytes)
+		c.tempBytes = 0
+	}
+}
@@ -0,0 +1,113 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipartTempFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "makross-multipart")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	tmp := os.TempDir()
+
+	m := New()
+	m.MultipartMemory = 1024
+	assert.Nil(t, m.SetMultipartTempDir(dir))
+	assert.NotNil(t, m.SetMultipartTempDir(dir+"/missing"))
+	// the directory is set per instance, the one of the process is left as is
+	assert.Nil(t, New().SetMultipartTempDir("."))
+	assert.Equal(t, tmp, os.TempDir())
+
+	upload := func(c *Context) error {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, fh.Size, m.MultipartTempBytes())
+		files, _ := ioutil.ReadDir(dir)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "value", c.FormValue("name"))
+		f, err := fh.Open()
+		if assert.Nil(t, err) {
+			b, _ := ioutil.ReadAll(f)
+			f.Close()
+			assert.Len(t, b, 4096)
+		}
+		small, err := c.FormFile("small")
+		if assert.Nil(t, err) {
+			f, err := small.Open()
+			if assert.Nil(t, err) {
+				b, _ := ioutil.ReadAll(f)
+				f.Close()
+				assert.Equal(t, "small", string(b))
+			}
+		}
+		return nil
+	}
+	m.Post("/ok", upload, func(c *Context) error {
+		return c.String("ok")
+	})
+	m.Post("/error", upload, func(c *Context) error {
+		return errors.New("failed")
+	})
+	m.Post("/panic", upload, func(c *Context) error {
+		panic("failed")
+	})
+	// the form readers parse the form as the Context does
+	m.Post("/read", func(c *Context) error {
+		var form struct{ Name string }
+		if err := c.Read(&form); err != nil {
+			return err
+		}
+		assert.NotZero(t, m.MultipartTempBytes())
+		files, _ := ioutil.ReadDir(dir)
+		assert.Len(t, files, 1)
+		return nil
+	})
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("name", "value")
+	w, _ := mw.CreateFormFile("small", "small.txt")
+	w.Write([]byte("small"))
+	w, _ = mw.CreateFormFile("file", "large.bin")
+	w.Write(bytes.Repeat([]byte("x"), 4096))
+	mw.Close()
+	request := func(path string, body string) {
+		req := httptest.NewRequest(POST, path, strings.NewReader(body))
+		req.Header.Set(HeaderContentType, mw.FormDataContentType())
+		m.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	empty := func(name string) {
+		files, _ := ioutil.ReadDir(dir)
+		assert.Empty(t, files, name)
+		assert.Equal(t, int64(0), m.MultipartTempBytes(), name)
+	}
+
+	request("/ok", body.String())
+	empty("ok")
+	request("/read", body.String())
+	empty("read")
+	request("/error", body.String())
+	empty("error")
+	assert.Panics(t, func() {
+		request("/panic", body.String())
+	})
+	empty("panic")
+	// the client disconnects mid-upload
+	request("/ok", body.String()[:body.Len()/2])
+	empty("truncated")
+}
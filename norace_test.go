@@ -0,0 +1,5 @@
+//go:build !race
+
+package makross
+
+const raceEnabled = false
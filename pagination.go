@@ -0,0 +1,96 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+var (
+	errNotPositive = errors.New("must be positive")
+	errNegative    = errors.New("must not be negative")
+	errPageOffset  = errors.New("can't be used with page")
+	errTooFar      = errors.New("out of range")
+)
+
+// Paginate reads the pagination of a list from the query parameters: limit, the number of items,
+// and either page, counted from 1, or offset, the number of items skipped. The limit defaults
+// to defaultLimit and is clamped to maxLimit, and the offset defaults to 0:
+//
+//	limit, offset, err := c.Paginate(20, 100) // "?page=3&limit=50" gives 50, 100
+//	if err != nil {
+//		return err
+//	}
+//	items, err := store.List(limit, offset)
+//
+// An invalid value, such as "?page=0" or "?limit=x", or both a page and an offset, is reported by a *BindError,
+// wrapped in a "400 - Bad Request" HTTPError. It panics if defaultLimit isn't positive or maxLimit is below it.
+//
+// The "first" and "prev" links of the Link header are set, and the handler knowing the number of items
+// can add the "next" and "last" ones with WriteLinkHeaders:
+//
+//	c.WriteLinkHeaders(limit, offset, total)
+func (c *Context) Paginate(defaultLimit, maxLimit int) (limit, offset int, err error) {
+	if defaultLimit < 1 || maxLimit < defaultLimit {
+		panic("makross: invalid pagination limits")
+	}
+	if limit, offset, err = c.paginate(defaultLimit, maxLimit); err == nil {
+		c.WriteLinkHeaders(limit, offset, -1)
+	}
+	return
+}
+
+// paginate is Paginate without the links.
+func (c *Context) paginate(defaultLimit, maxLimit int) (limit, offset int, err error) {
+	query := c.Request.URL.Query()
+	limit = defaultLimit
+	if s, ok := query["limit"]; ok {
+		if limit, err = paginationParam("limit", s[0], 1, errNotPositive); err != nil {
+			return 0, 0, err
+		}
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+	}
+	page, hasPage := query["page"]
+	if s, ok := query["offset"]; ok {
+		if hasPage {
+			return 0, 0, paginationError("offset", s[0], errPageOffset)
+		}
+		if offset, err = paginationParam("offset", s[0], 0, errNegative); err != nil {
+			return 0, 0, err
+		}
+		return limit, offset, nil
+	}
+	if hasPage {
+		n, err := paginationParam("page", page[0], 1, errNotPositive)
+		if err != nil {
+			return 0, 0, err
+		}
+		if n-1 > math.MaxInt/limit {
+			return 0, 0, paginationError("page", page[0], errTooFar)
+		}
+		offset = (n - 1) * limit
+	}
+	return limit, offset, nil
+}
+
+// paginationParam parses the value of a pagination parameter, which must be at least min.
+func paginationParam(name, s string, min int, tooSmall error) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, paginationError(name, s, err)
+	}
+	if n < min {
+		return 0, paginationError(name, s, tooSmall)
+	}
+	return n, nil
+}
+
+func paginationError(name, value string, err error) error {
+	be := &BindError{Field: name, Source: BindSourceQuery, Value: value, Type: reflect.TypeOf(0), Err: err}
+	return badRequest(be.Error(), be)
+}
@@ -0,0 +1,94 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextPaginate(t *testing.T) {
+	tests := []struct {
+		query         string
+		limit, offset int
+		field         string // of the error
+	}{
+		{"", 20, 0, ""},
+		{"limit=50", 50, 0, ""},
+		{"limit=500", 100, 0, ""},
+		{"limit=100", 100, 0, ""},
+		{"page=1", 20, 0, ""},
+		{"page=3", 20, 40, ""},
+		{"page=3&limit=50", 50, 100, ""},
+		{"page=2&limit=1000", 100, 100, ""},
+		{"offset=0", 20, 0, ""},
+		{"offset=15&limit=5", 5, 15, ""},
+		{"limit=0", 0, 0, "limit"},
+		{"limit=-1", 0, 0, "limit"},
+		{"limit=x", 0, 0, "limit"},
+		{"limit=", 0, 0, "limit"},
+		{"page=0", 0, 0, "page"},
+		{"page=-2", 0, 0, "page"},
+		{"page=1.5", 0, 0, "page"},
+		{"page=9223372036854775807", 0, 0, "page"},
+		{"page=99999999999999999999", 0, 0, "page"},
+		{"offset=-1", 0, 0, "offset"},
+		{"offset=abc", 0, 0, "offset"},
+		{"page=2&offset=10", 0, 0, "offset"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(GET, "/items?"+test.query, nil)
+		c := New().NewContext(req, httptest.NewRecorder())
+		limit, offset, err := c.Paginate(20, 100)
+		assert.Equal(t, test.limit, limit, test.query)
+		assert.Equal(t, test.offset, offset, test.query)
+		if test.field == "" {
+			assert.Nil(t, err, test.query)
+			continue
+		}
+		if assert.IsType(t, &HTTPError{}, err, test.query) {
+			assert.Equal(t, StatusBadRequest, err.(*HTTPError).Status, test.query)
+		}
+		if be := FieldBindError(err, test.field); assert.NotNil(t, be, test.query) {
+			assert.Equal(t, BindSourceQuery, be.Source)
+		}
+	}
+
+	// the errors are sent as 400 responses
+	m := New()
+	m.Get("/items", func(c *Context) error {
+		if _, _, err := c.Paginate(10, 10); err != nil {
+			return err
+		}
+		return c.String("ok")
+	})
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(GET, "/items?page=0", nil)
+	req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+	m.ServeHTTP(res, req)
+	assert.Equal(t, StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), `"field":"page"`)
+	assert.Contains(t, res.Body.String(), `"message":"must be positive"`)
+
+	// the first and previous pages are linked
+	m.Get("/links", func(c *Context) error {
+		limit, offset, err := c.Paginate(10, 10)
+		if err != nil {
+			return err
+		}
+		c.WriteLinkHeaders(limit, offset, 25)
+		return c.String("ok")
+	})
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/items?page=2", nil))
+	assert.Equal(t, `</items?page=1>; rel="first", </items?page=1>; rel="prev"`, res.Header().Get(HeaderLink))
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/links?page=2", nil))
+	assert.Equal(t, `</links?page=1>; rel="first", </links?page=1>; rel="prev", </links?page=3>; rel="next", </links?page=3>; rel="last"`, res.Header().Get(HeaderLink))
+
+	c := New().NewContext(httptest.NewRequest(GET, "/", nil), httptest.NewRecorder())
+	assert.Panics(t, func() { c.Paginate(0, 10) })
+	assert.Panics(t, func() { c.Paginate(20, 10) })
+}
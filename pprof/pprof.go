@@ -0,0 +1,44 @@
+package pprof
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/insionng/makross"
+)
+
+// DefaultPrefix is the path prefix of the profiling endpoints of the net/http/pprof package.
+const DefaultPrefix = "/debug/pprof"
+
+// Mount registers the handlers of the net/http/pprof package on the router under the prefix,
+// "/debug/pprof" if empty: the index of the profiles, cmdline, profile, symbol, trace and
+// the named profiles, such as heap and goroutine. The middleware run after the ones registered
+// with Use, and before the handlers, e.g. to restrict the access to the profiles:
+//
+//	pprof.Mount(m, "/admin/pprof", bauth.BasicAuth(validate))
+//
+// The profiles are served under the prefix only, but note that importing this package,
+// like importing net/http/pprof, also registers them on http.DefaultServeMux.
+func Mount(m *makross.Makross, prefix string, middleware ...makross.Handler) *makross.RouteGroup {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	g := m.Group(prefix)
+	g.Use(middleware...)
+
+	index := prefix + "/"
+	g.Get("", func(c *makross.Context) error {
+		return c.Redirect(index, makross.StatusMovedPermanently)
+	})
+	g.Get("/", makross.WrapHandlerFunc(pprof.Index))
+	g.Get("/cmdline", makross.WrapHandlerFunc(pprof.Cmdline))
+	g.Get("/profile", makross.WrapHandlerFunc(pprof.Profile))
+	g.To("GET,POST", "/symbol", makross.WrapHandlerFunc(pprof.Symbol))
+	g.Get("/trace", makross.WrapHandlerFunc(pprof.Trace))
+	g.Get("/<name>", func(c *makross.Context) error {
+		pprof.Handler(c.Param("name").String()).ServeHTTP(c.Response, c.Request)
+		return nil
+	})
+	return g
+}
@@ -0,0 +1,65 @@
+package pprof
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/bauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMount(t *testing.T) {
+	m := makross.New()
+	var used []string
+	m.Use(func(c *makross.Context) error {
+		used = append(used, c.Request.URL.Path)
+		return c.Next()
+	})
+	Mount(m, "", bauth.BasicAuth(func(user, password string) bool {
+		return user == "admin" && password == "secret"
+	}))
+	serve := func(method, path string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	assert.Equal(t, makross.StatusUnauthorized, serve(makross.GET, "/debug/pprof/", false).Code)
+	assert.Equal(t, makross.StatusUnauthorized, serve(makross.GET, "/debug/pprof/heap", false).Code)
+
+	res := serve(makross.GET, "/debug/pprof/", true)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "goroutine")
+	assert.Equal(t, []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/"}, used)
+
+	res = serve(makross.GET, "/debug/pprof/goroutine?debug=1", true)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.True(t, strings.HasPrefix(res.Body.String(), "goroutine profile:"))
+
+	res = serve(makross.GET, "/debug/pprof/cmdline", true)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "pprof.test")
+
+	assert.Equal(t, makross.StatusOK, serve(makross.POST, "/debug/pprof/symbol", true).Code)
+	assert.Equal(t, makross.StatusNotFound, serve(makross.GET, "/debug/pprof/nothing", true).Code)
+
+	res = serve(makross.GET, "/debug/pprof", true)
+	assert.Equal(t, makross.StatusMovedPermanently, res.Code)
+	assert.Equal(t, "/debug/pprof/", res.Header().Get(makross.HeaderLocation))
+
+	// under a custom prefix
+	m = makross.New()
+	Mount(m, "/admin/pprof/")
+	res = serve(makross.GET, "/admin/pprof/", false)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "heap")
+	res = serve(makross.GET, "/admin/pprof/heap?debug=1", false)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.True(t, strings.HasPrefix(res.Body.String(), "heap profile:"))
+}
@@ -0,0 +1,50 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryService is a Service kept in memory, with a quota per calendar month (UTC).
+	// It is a reference implementation for a single instance, the usage is lost on restart.
+	MemoryService struct {
+		limit int
+		mu    sync.Mutex
+		usage map[string]*usage
+		now   func() time.Time
+	}
+
+	usage struct {
+		reset time.Time
+		used  int
+	}
+)
+
+// NewMemoryService returns a MemoryService allowing limit units per key and month.
+func NewMemoryService(limit int) *MemoryService {
+	return &MemoryService{
+		limit: limit,
+		usage: make(map[string]*usage),
+		now:   time.Now,
+	}
+}
+
+// Check implements Service.
+func (s *MemoryService) Check(ctx context.Context, key string, cost int) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC()
+	u, ok := s.usage[key]
+	if !ok || !now.Before(u.reset) {
+		u = &usage{reset: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)}
+		s.usage[key] = u
+	}
+	if u.used+cost > s.limit {
+		return Decision{Allowed: false, Remaining: s.limit - u.used, Reset: u.reset}, nil
+	}
+	u.used += cost
+	return Decision{Allowed: true, Remaining: s.limit - u.used, Reset: u.reset}, nil
+}
@@ -0,0 +1,153 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// Decision is the outcome of a quota check.
+	Decision struct {
+		Allowed   bool
+		Remaining int       // units left in the current window
+		Reset     time.Time // start of the next window
+	}
+
+	// Service keeps track of the quota usage.
+	Service interface {
+		// Check consumes cost units of the quota of key if enough units remain.
+		// A request which isn't allowed doesn't consume any unit.
+		Check(ctx context.Context, key string, cost int) (Decision, error)
+	}
+
+	// QuotaConfig defines the config for Quota middleware.
+	QuotaConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Service keeps track of the quota usage.
+		// Required.
+		Service Service
+
+		// KeyFunc returns the key of the quota of the request, e.g. the tenant.
+		// Optional. Default value Context.RealIP.
+		KeyFunc func(*makross.Context) string
+
+		// MetaKey is the route metadata key holding the cost of a request to the route, as an int.
+		// Another type panics when the metadata is set, see makross.RegisterMetaValidator.
+		// Optional. Default value "quota".
+		MetaKey string `json:"meta_key"`
+
+		// DefaultCost is the cost of a request to a route without a cost in its metadata.
+		// Optional. Default value 1.
+		DefaultCost int `json:"default_cost"`
+	}
+)
+
+var (
+	// DefaultQuotaConfig is the default Quota middleware config.
+	DefaultQuotaConfig = QuotaConfig{
+		Skipper: skipper.DefaultSkipper,
+		KeyFunc: func(c *makross.Context) string {
+			return c.RealIP()
+		},
+		MetaKey:     "quota",
+		DefaultCost: 1,
+	}
+)
+
+// Quota returns a Quota middleware.
+//
+// Quota middleware enforces long-term usage quotas, e.g. monthly API calls per tenant, while the
+// ratelimit middleware smooths the traffic over short windows. Both can be used together.
+// The cost of a request is read from the route metadata:
+//
+//	m.Use(quota.Quota(quota.NewMemoryService(10000)))
+//	m.Get("/search", search).Meta("quota", 5)
+//
+// The X-Quota-Remaining and X-Quota-Reset (Unix time) headers are added to the responses,
+// and requests exceeding the quota fail with a "429 - Too Many Requests" HTTPError, see
+// makross.NewTooManyRequestsError, whose Retry-After is the reset of the quota.
+func Quota(service Service) makross.Handler {
+	c := DefaultQuotaConfig
+	c.Service = service
+	return QuotaWithConfig(c)
+}
+
+// QuotaWithConfig returns a Quota middleware with config.
+// See: `Quota()`.
+func QuotaWithConfig(config QuotaConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultQuotaConfig.Skipper
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = DefaultQuotaConfig.KeyFunc
+	}
+	if config.MetaKey == "" {
+		config.MetaKey = DefaultQuotaConfig.MetaKey
+	}
+	if config.DefaultCost == 0 {
+		config.DefaultCost = DefaultQuotaConfig.DefaultCost
+	}
+	if config.Service == nil {
+		panic("quota: service is required")
+	}
+	makross.RegisterMetaValidator(config.MetaKey, func(v interface{}) error {
+		_, err := routeCost(v, config.MetaKey)
+		return err
+	})
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		cost := config.DefaultCost
+		if route := c.Route(); route != nil {
+			if v := route.GetMeta(config.MetaKey); v != nil {
+				n, err := routeCost(v, config.MetaKey)
+				if err != nil {
+					// set before the middleware was created
+					return err
+				}
+				cost = n
+			}
+		}
+
+		d, err := config.Service.Check(c.Kontext(), config.KeyFunc(c), cost)
+		if err != nil {
+			return err
+		}
+		header := c.Response.Header()
+		header.Set(makross.HeaderXQuotaRemaining, strconv.Itoa(d.Remaining))
+		header.Set(makross.HeaderXQuotaReset, strconv.FormatInt(d.Reset.Unix(), 10))
+		if !d.Allowed {
+			// the reset may be days away, hence an HTTP-date rather than a number of seconds
+			e := makross.NewTooManyRequestsError(0)
+			e.RetryAt = d.Reset
+			return e
+		}
+		return c.Next()
+	}
+}
+
+// routeCost returns the cost in the metadata of a route.
+func routeCost(v interface{}, key string) (int, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		if v < 0 {
+			return 0, fmt.Errorf("quota: negative %s metadata %d", key, v)
+		}
+		return v, nil
+	default:
+		return 0, fmt.Errorf("quota: invalid %s metadata of type %T", key, v)
+	}
+}
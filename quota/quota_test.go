@@ -0,0 +1,128 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryService(t *testing.T) {
+	now := time.Date(2017, time.December, 31, 23, 0, 0, 0, time.UTC)
+	s := NewMemoryService(10)
+	s.now = func() time.Time { return now }
+	reset := time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	d, err := s.Check(context.Background(), "acme", 6)
+	assert.Nil(t, err)
+	assert.Equal(t, Decision{Allowed: true, Remaining: 4, Reset: reset}, d)
+	d, _ = s.Check(context.Background(), "acme", 5)
+	assert.Equal(t, Decision{Allowed: false, Remaining: 4, Reset: reset}, d)
+	d, _ = s.Check(context.Background(), "acme", 4)
+	assert.Equal(t, Decision{Allowed: true, Remaining: 0, Reset: reset}, d)
+	d, _ = s.Check(context.Background(), "globex", 1)
+	assert.Equal(t, 9, d.Remaining)
+
+	// a new month starts afresh
+	now = reset
+	d, _ = s.Check(context.Background(), "acme", 1)
+	assert.Equal(t, Decision{Allowed: true, Remaining: 9, Reset: time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC)}, d)
+}
+
+func TestQuota(t *testing.T) {
+	s := NewMemoryService(6)
+	m := makross.New()
+	m.Use(
+		ratelimit.RateLimit("100/s"),
+		QuotaWithConfig(QuotaConfig{
+			Service: s,
+			KeyFunc: func(c *makross.Context) string {
+				return c.Request.Header.Get("X-Tenant")
+			},
+		}),
+	)
+	ok := func(c *makross.Context) error {
+		return c.String("ok")
+	}
+	m.Get("/search", ok).Meta("quota", 5)
+	m.Get("/read", ok)
+
+	request := func(path, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(makross.GET, path, nil)
+		req.Header.Set("X-Tenant", tenant)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := request("/search", "acme")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "1", res.Header().Get(makross.HeaderXQuotaRemaining))
+	now := time.Now().UTC()
+	reset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, strconv.FormatInt(reset.Unix(), 10), res.Header().Get(makross.HeaderXQuotaReset))
+	// both limiters add their headers
+	assert.Equal(t, "99", res.Header().Get(makross.HeaderXRateLimitRemaining))
+
+	res = request("/search", "acme")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, reset.Format(http.TimeFormat), res.Header().Get(makross.HeaderRetryAfter))
+	assert.Equal(t, "1", res.Header().Get(makross.HeaderXQuotaRemaining))
+	assert.Contains(t, res.Body.String(), makross.StatusText(makross.StatusTooManyRequests))
+
+	res = request("/read", "acme")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "0", res.Header().Get(makross.HeaderXQuotaRemaining))
+	assert.Equal(t, http.StatusTooManyRequests, request("/read", "acme").Code)
+
+	assert.Equal(t, http.StatusOK, request("/search", "globex").Code)
+}
+
+type failingService struct{}
+
+func (failingService) Check(ctx context.Context, key string, cost int) (Decision, error) {
+	return Decision{}, errors.New("quota service unavailable")
+}
+
+func TestQuotaServiceError(t *testing.T) {
+	m := makross.New()
+	m.Use(Quota(failingService{}))
+	m.Get("/", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+
+	assert.Panics(t, func() {
+		QuotaWithConfig(QuotaConfig{})
+	})
+}
+
+func TestQuotaInvalidCost(t *testing.T) {
+	m := makross.New()
+	m.Use(QuotaWithConfig(QuotaConfig{Service: NewMemoryService(10), MetaKey: "test.quota"}))
+	ok := func(c *makross.Context) error {
+		return c.String("ok")
+	}
+	// the invalid costs are reported when the routes are declared
+	assert.Panics(t, func() {
+		m.Get("/search", ok).Meta("test.quota", "5")
+	})
+	assert.Panics(t, func() {
+		m.Group("/api").Meta("test.quota", 2.5)
+	})
+	assert.Panics(t, func() {
+		m.Get("/negative", ok).Meta("test.quota", -1)
+	})
+	assert.NotPanics(t, func() {
+		m.Get("/free", ok).Meta("test.quota", 0)
+	})
+}
@@ -0,0 +1,124 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HTTPRange is a byte range of a Range request, starting at Start and Length bytes long.
+type HTTPRange struct {
+	Start  int64
+	Length int64
+}
+
+// ContentRange returns the Content-Range header of the range of a content of the size.
+func (r HTTPRange) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+// Range parses the Range header of the request against the size of the content, and returns
+// the satisfiable ranges, in the order of the header. It returns no ranges if the request isn't
+// a byte range request, so that the whole content is sent.
+//
+// ErrRangeNotSatisfiable is returned if the header is invalid or none of its ranges is satisfiable,
+// and the Content-Range header of the "416 - Requested Range Not Satisfiable" response is set:
+//
+//	ranges, err := c.Range(size)
+//	if err != nil {
+//		return err
+//	}
+//	if len(ranges) == 1 {
+//		return c.PartialContent("video/mp4", f, ranges[0], size)
+//	}
+func (c *Context) Range(size int64) ([]HTTPRange, error) {
+	ranges, err := parseRange(c.Request.Header.Get(HeaderRange), size)
+	if err != nil {
+		c.Response.Header().Set(HeaderContentRange, "bytes */"+strconv.FormatInt(size, 10))
+	}
+	return ranges, err
+}
+
+// PartialContent sends the range of the content of the size with a "206 - Partial Content" response.
+func (c *Context) PartialContent(contentType string, content io.ReadSeeker, r HTTPRange, size int64) error {
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
+	}
+	if _, err := content.Seek(r.Start, io.SeekStart); err != nil {
+		return err
+	}
+	header := c.Response.Header()
+	header.Set(HeaderContentType, contentType)
+	header.Set(HeaderAcceptRanges, "bytes")
+	header.Set(HeaderContentRange, r.ContentRange(size))
+	header.Set(HeaderContentLength, strconv.FormatInt(r.Length, 10))
+	c.Response.WriteHeader(StatusPartialContent)
+	var err error
+	if c.Request.Method != HEAD {
+		_, err = io.CopyN(c.Response, content, r.Length)
+	}
+	c.Abort()
+	return c.slowClient(err)
+}
+
+// parseRange parses a Range header, e.g. "bytes=0-499, -500", as specified by RFC 7233.
+func parseRange(s string, size int64) ([]HTTPRange, error) {
+	const prefix = "bytes="
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		// not a byte range request
+		return nil, nil
+	}
+	var ranges []HTTPRange
+	for _, spec := range strings.Split(s[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return nil, ErrRangeNotSatisfiable
+		}
+		first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+		var r HTTPRange
+		if first == "" {
+			// the suffix range, the last bytes of the content
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, ErrRangeNotSatisfiable
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = HTTPRange{Start: size - n, Length: n}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, ErrRangeNotSatisfiable
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, ErrRangeNotSatisfiable
+				}
+				if end >= size {
+					end = size - 1
+				}
+			}
+			if start >= size {
+				continue
+			}
+			r = HTTPRange{Start: start, Length: end - start + 1}
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, ErrRangeNotSatisfiable
+	}
+	return ranges, nil
+}
@@ -0,0 +1,92 @@
+package makross
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextRange(t *testing.T) {
+	tests := []struct {
+		header string
+		ranges []HTTPRange
+		err    bool
+	}{
+		{"", nil, false},
+		{"items=0-1", nil, false},
+		{"bytes=0-499", []HTTPRange{{0, 500}}, false},
+		{"bytes=500-", []HTTPRange{{500, 500}}, false},
+		{"bytes=-100", []HTTPRange{{900, 100}}, false},
+		{"bytes=-2000", []HTTPRange{{0, 1000}}, false},
+		{"bytes=900-2000", []HTTPRange{{900, 100}}, false},
+		{"bytes=0-0, -1", []HTTPRange{{0, 1}, {999, 1}}, false},
+		{"bytes=0-99, 1000-, 200-299", []HTTPRange{{0, 100}, {200, 100}}, false},
+		{"bytes=1000-1999", nil, true},
+		{"bytes=-0", nil, true},
+		{"bytes=5-1", nil, true},
+		{"bytes=x-1", nil, true},
+		{"bytes=1", nil, true},
+		{"bytes=", nil, true},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(GET, "/", nil)
+		if test.header != "" {
+			req.Header.Set(HeaderRange, test.header)
+		}
+		res := httptest.NewRecorder()
+		c := New().NewContext(req, res)
+		ranges, err := c.Range(1000)
+		assert.Equal(t, test.ranges, ranges, test.header)
+		if test.err {
+			assert.Equal(t, ErrRangeNotSatisfiable, err, test.header)
+			assert.Equal(t, "bytes */1000", res.Header().Get(HeaderContentRange), test.header)
+		} else {
+			assert.Nil(t, err, test.header)
+			assert.Equal(t, "", res.Header().Get(HeaderContentRange), test.header)
+		}
+	}
+}
+
+func TestContextPartialContent(t *testing.T) {
+	m := New()
+	content := "0123456789"
+	m.Get("/video", func(c *Context) error {
+		ranges, err := c.Range(int64(len(content)))
+		if err != nil {
+			return err
+		}
+		if len(ranges) != 1 {
+			return c.String(content)
+		}
+		return c.PartialContent("video/mp4", strings.NewReader(content), ranges[0], int64(len(content)))
+	})
+	serve := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(GET, "/video", nil)
+		req.Header.Set(HeaderRange, header)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("bytes=2-5")
+	assert.Equal(t, StatusPartialContent, res.Code)
+	assert.Equal(t, "2345", res.Body.String())
+	assert.Equal(t, "bytes 2-5/10", res.Header().Get(HeaderContentRange))
+	assert.Equal(t, "4", res.Header().Get(HeaderContentLength))
+	assert.Equal(t, "video/mp4", res.Header().Get(HeaderContentType))
+	assert.Equal(t, "bytes", res.Header().Get(HeaderAcceptRanges))
+
+	res = serve("bytes=-3")
+	assert.Equal(t, "789", res.Body.String())
+	assert.Equal(t, "bytes 7-9/10", res.Header().Get(HeaderContentRange))
+
+	res = serve("bytes=10-")
+	assert.Equal(t, StatusRequestedRangeNotSatisfiable, res.Code)
+	assert.Equal(t, "bytes */10", res.Header().Get(HeaderContentRange))
+
+	res = serve("")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, content, res.Body.String())
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Limit is the number of requests allowed per period of time.
+	Limit struct {
+		Rate int
+		Per  time.Duration
+	}
+
+	// Store keeps track of the requests made in each bucket.
+	Store interface {
+		// Take records a request in the bucket identified by key.
+		// It reports whether the request is within the limit, how many requests remain
+		// in the current window and how long until the window is reset.
+		Take(key string, limit Limit) (allowed bool, remaining int, reset time.Duration)
+	}
+
+	// MemoryStore is a fixed-window Store kept in memory.
+	MemoryStore struct {
+		mu      sync.Mutex
+		windows map[string]*window
+		now     func() time.Time
+	}
+
+	window struct {
+		start time.Time
+		count int
+	}
+)
+
+// ParseLimit parses a limit in the "N/s", "N/m" or "N/h" notation, e.g. "5/m" for 5 requests per minute.
+func ParseLimit(s string) (Limit, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q, expected N/s, N/m or N/h", s)
+	}
+	rate, err := strconv.Atoi(strings.TrimSpace(s[:i]))
+	if err != nil || rate <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid rate in limit %q", s)
+	}
+	var per time.Duration
+	switch strings.TrimSpace(s[i+1:]) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("ratelimit: invalid period in limit %q, expected s, m or h", s)
+	}
+	return Limit{Rate: rate, Per: per}, nil
+}
+
+// String returns the limit in the "N/s|m|h" notation.
+func (l Limit) String() string {
+	unit := l.Per.String()
+	switch l.Per {
+	case time.Second:
+		unit = "s"
+	case time.Minute:
+		unit = "m"
+	case time.Hour:
+		unit = "h"
+	}
+	return strconv.Itoa(l.Rate) + "/" + unit
+}
+
+// NewMemoryStore returns a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(key string, limit Limit) (bool, int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	w, ok := s.windows[key]
+	if !ok || now.Sub(w.start) >= limit.Per {
+		s.gc(now, limit.Per)
+		w = &window{start: now}
+		s.windows[key] = w
+	}
+	reset := w.start.Add(limit.Per).Sub(now)
+	if w.count >= limit.Rate {
+		return false, 0, reset
+	}
+	w.count++
+	return true, limit.Rate - w.count, reset
+}
+
+// gc removes the windows that expired a while ago, so that the store doesn't grow with every client.
+func (s *MemoryStore) gc(now time.Time, per time.Duration) {
+	if len(s.windows) < 1024 {
+		return
+	}
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= time.Hour && now.Sub(w.start) >= per {
+			delete(s.windows, key)
+		}
+	}
+}
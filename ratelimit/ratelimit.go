@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// RateLimitConfig defines the config for RateLimit middleware.
+	RateLimitConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Default is the limit of the routes without a limit in their metadata, e.g. "60/m".
+		// Optional. Routes without a limit are not throttled if it's empty.
+		Default string `json:"default"`
+
+		// MetaKey is the route metadata key holding the limit of a route.
+		// The metadata value is either a string in the "N/s|m|h" notation or a Limit.
+		// Another value panics when the metadata is set, see makross.RegisterMetaValidator.
+		// Optional. Default value "ratelimit".
+		MetaKey string `json:"meta_key"`
+
+		// KeyFunc returns the key identifying the client of the request.
+		// Optional. Default value Context.RealIP.
+		KeyFunc func(*makross.Context) string
+
+		// Store keeps track of the requests.
+		// Optional. Default value NewMemoryStore().
+		Store Store
+	}
+)
+
+var (
+	// DefaultRateLimitConfig is the default RateLimit middleware config.
+	DefaultRateLimitConfig = RateLimitConfig{
+		Skipper: skipper.DefaultSkipper,
+		MetaKey: "ratelimit",
+		KeyFunc: func(c *makross.Context) string {
+			return c.RealIP()
+		},
+	}
+)
+
+// RateLimit returns a RateLimit middleware.
+//
+// RateLimit middleware throttles the requests of each client per route. The limit of a route is read
+// from its metadata, falling back to the given default limit:
+//
+//	m.Use(ratelimit.RateLimit("60/m"))
+//	m.Post("/login", login).Meta("ratelimit", "5/m")
+//
+// Each route has its own bucket per client, identified by the route name (or its path if the route is unnamed).
+// Requests over the limit get a "429 - Too Many Requests" response.
+func RateLimit(defaultLimit string) makross.Handler {
+	c := DefaultRateLimitConfig
+	c.Default = defaultLimit
+	return RateLimitWithConfig(c)
+}
+
+// RateLimitFromEnv returns a RateLimit middleware with the default limit read from the environment
+// variable, e.g. `ratelimit.RateLimitFromEnv("RATE_LIMIT", "60/m")`. The fallback limit is used when the
+// variable is unset or empty, or when its value isn't a valid limit, which is logged.
+func RateLimitFromEnv(key, fallback string) makross.Handler {
+	limit := fallback
+	if v := os.Getenv(key); v != "" {
+		if _, err := ParseLimit(v); err != nil {
+			log.Printf("[ratelimit] %s: %v, using %s", key, err, fallback)
+		} else {
+			limit = v
+		}
+	}
+	return RateLimit(limit)
+}
+
+// RateLimitWithConfig returns a RateLimit middleware with config.
+// See: `RateLimit()`.
+func RateLimitWithConfig(config RateLimitConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultRateLimitConfig.Skipper
+	}
+	if config.MetaKey == "" {
+		config.MetaKey = DefaultRateLimitConfig.MetaKey
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = DefaultRateLimitConfig.KeyFunc
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	var defaultLimit *Limit
+	if config.Default != "" {
+		limit, err := ParseLimit(config.Default)
+		if err != nil {
+			panic(err)
+		}
+		defaultLimit = &limit
+	}
+	makross.RegisterMetaValidator(config.MetaKey, func(v interface{}) error {
+		_, err := metaLimit(v, config.MetaKey)
+		return err
+	})
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		route := c.Route()
+		limit, bucket := defaultLimit, ""
+		if route != nil {
+			l, err := metaLimit(route.GetMeta(config.MetaKey), config.MetaKey)
+			if err != nil {
+				return err
+			}
+			if l != nil {
+				limit = l
+			}
+			bucket = route.GetName()
+			if bucket == "" {
+				bucket = route.String()
+			}
+		}
+		if limit == nil {
+			return c.Next()
+		}
+
+		allowed, remaining, reset := config.Store.Take(bucket+"|"+config.KeyFunc(c), *limit)
+		header := c.Response.Header()
+		header.Set(makross.HeaderXRateLimitLimit, strconv.Itoa(limit.Rate))
+		header.Set(makross.HeaderXRateLimitRemaining, strconv.Itoa(remaining))
+		if !allowed {
+			return makross.NewTooManyRequestsError(reset)
+		}
+		return c.Next()
+	}
+}
+
+// metaLimit returns the limit in the route metadata value v, or nil if v is nil.
+func metaLimit(v interface{}, key string) (*Limit, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case Limit:
+		return &v, nil
+	case string:
+		limit, err := ParseLimit(v)
+		if err != nil {
+			return nil, err
+		}
+		return &limit, nil
+	default:
+		return nil, fmt.Errorf("ratelimit: invalid %s metadata of type %T", key, v)
+	}
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		limit Limit
+		ok    bool
+	}{
+		{"5/s", Limit{5, time.Second}, true},
+		{"60/m", Limit{60, time.Minute}, true},
+		{"1000/h", Limit{1000, time.Hour}, true},
+		{" 10 / m ", Limit{10, time.Minute}, true},
+		{"", Limit{}, false},
+		{"5", Limit{}, false},
+		{"0/s", Limit{}, false},
+		{"-1/s", Limit{}, false},
+		{"x/s", Limit{}, false},
+		{"5/d", Limit{}, false},
+		{"5/", Limit{}, false},
+	}
+	for _, test := range tests {
+		limit, err := ParseLimit(test.value)
+		if test.ok {
+			assert.Nil(t, err, test.value)
+			assert.Equal(t, test.limit, limit, test.value)
+		} else {
+			assert.NotNil(t, err, test.value)
+		}
+	}
+	assert.Equal(t, "60/m", Limit{60, time.Minute}.String())
+}
+
+func TestRateLimitInvalidDefault(t *testing.T) {
+	assert.Panics(t, func() {
+		RateLimit("5/week")
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{2, time.Minute}
+
+	allowed, remaining, _ := s.Take("a", limit)
+	assert.True(t, allowed)
+	assert.Equal(t, 1, remaining)
+	allowed, remaining, _ = s.Take("a", limit)
+	assert.True(t, allowed)
+	assert.Equal(t, 0, remaining)
+	allowed, _, reset := s.Take("a", limit)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, reset)
+
+	now = now.Add(time.Minute)
+	allowed, _, _ = s.Take("a", limit)
+	assert.True(t, allowed)
+}
+
+func TestRateLimit(t *testing.T) {
+	m := makross.New()
+	m.Use(RateLimit("3/m"))
+	ok := func(c *makross.Context) error {
+		return c.String("ok")
+	}
+	m.Post("/login", ok).Meta("ratelimit", "1/m").Name("login")
+	m.Get("/search", ok).Meta("ratelimit", Limit{2, time.Minute})
+	m.Get("/home", ok)
+	m.Get("/about", ok)
+	assert.Panics(t, func() {
+		m.Get("/broken", ok).Meta("ratelimit", "many")
+	})
+
+	request := func(method, path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	// route metadata takes precedence over the default
+	assert.Equal(t, http.StatusOK, request("POST", "/login", "1.1.1.1").Code)
+	res := request("POST", "/login", "1.1.1.1")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "1", res.Header().Get(makross.HeaderXRateLimitLimit))
+	assert.Equal(t, "0", res.Header().Get(makross.HeaderXRateLimitRemaining))
+	assert.Equal(t, "60", res.Header().Get(makross.HeaderRetryAfter))
+
+	// clients have their own buckets
+	assert.Equal(t, http.StatusOK, request("POST", "/login", "2.2.2.2").Code)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, request("GET", "/search", "1.1.1.1").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request("GET", "/search", "1.1.1.1").Code)
+
+	// routes without metadata use the default, each in their own bucket
+	for i := 0; i < 3; i++ {
+		res = request("GET", "/home", "1.1.1.1")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "3", res.Header().Get(makross.HeaderXRateLimitLimit))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request("GET", "/home", "1.1.1.1").Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/about", "1.1.1.1").Code)
+}
+
+func TestRateLimitWithoutDefault(t *testing.T) {
+	m := makross.New()
+	m.Use(RateLimitWithConfig(RateLimitConfig{}))
+	m.Get("/", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	for i := 0; i < 10; i++ {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "", res.Header().Get(makross.HeaderXRateLimitLimit))
+	}
+}
+
+func TestRateLimitFromEnv(t *testing.T) {
+	limit := func() string {
+		m := makross.New()
+		m.Use(RateLimitFromEnv("TEST_RATE_LIMIT", "3/m"))
+		m.Get("/", func(c *makross.Context) error {
+			return c.String("ok")
+		})
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+		return res.Header().Get(makross.HeaderXRateLimitLimit)
+	}
+
+	t.Setenv("TEST_RATE_LIMIT", "10/s")
+	assert.Equal(t, "10", limit())
+	t.Setenv("TEST_RATE_LIMIT", "")
+	assert.Equal(t, "3", limit())
+	t.Setenv("TEST_RATE_LIMIT", "10/week")
+	assert.Equal(t, "3", limit())
+}
@@ -3,9 +3,12 @@
 package makross
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"reflect"
 	"strconv"
@@ -47,30 +50,77 @@ var (
 // JSONDataReader reads the request body as JSON-formatted data.
 type JSONDataReader struct{}
 
+// Read returns a *BindError if the body is malformed or a value has not the type of its field.
 func (r *JSONDataReader) Read(req *http.Request, data interface{}) error {
-	return json.NewDecoder(req.Body).Decode(data)
+	return jsonBindError(json.NewDecoder(req.Body).Decode(data))
 }
 
 // XMLDataReader reads the request body as XML-formatted data.
 type XMLDataReader struct{}
 
+// Read returns a *BindError if the body is malformed or a value has not the type of its field.
 func (r *XMLDataReader) Read(req *http.Request, data interface{}) error {
-	return xml.NewDecoder(req.Body).Decode(data)
+	d := xml.NewDecoder(req.Body)
+	return xmlBindError(d, d.Decode(data))
 }
 
 // FormDataReader reads the query parameters and request body as form data.
 type FormDataReader struct{}
 
+// Read returns the *BindError of each value which can't be converted to its field, with
+// the BindSourceQuery source if the request has no form body, or BindSourceForm.
+// Called by `Context#Read()` or `Context#BindWith()`, it gets the multipart form parsed
+// by the Context, with the Makross.MultipartMemory limit and the temporary files accounted.
 func (r *FormDataReader) Read(req *http.Request, data interface{}) error {
 	// Do not check return result. Otherwise GET request will cause problem.
-	req.ParseMultipartForm(32 << 20)
-	return ReadFormData(req.Form, data)
+	req.ParseMultipartForm(defaultMemory)
+	source := BindSourceForm
+	if len(req.PostForm) == 0 && req.MultipartForm == nil {
+		source = BindSourceQuery
+	}
+	return readFormData(req.Form, data, source)
+}
+
+// ReadBody reads the whole body of the request, writing it to w as it is read unless w is nil,
+// and replaces it with SetBody, so that the handlers can read it again. The body is held in
+// memory: it should be bounded by the BodyLimit middleware.
+func ReadBody(req *http.Request, w io.Writer) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	var buf bytes.Buffer
+	var dst io.Writer = &buf
+	if w != nil {
+		dst = io.MultiWriter(&buf, w)
+	}
+	if _, err := io.Copy(dst, req.Body); err != nil {
+		return nil, err
+	}
+	SetBody(req, buf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// SetBody replaces the body of the request, and its Content-Length, with body,
+// which can be read again with GetBody.
+func SetBody(req *http.Request, body []byte) {
+	req.ContentLength = int64(len(body))
+	req.Header.Set(HeaderContentLength, strconv.Itoa(len(body)))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
 }
 
 const formTag = "form"
 
 // ReadFormData populates the data variable with the data from the given form values.
+// It returns the *BindError of each value which can't be converted to its field.
 func ReadFormData(form map[string][]string, data interface{}) error {
+	return readFormData(form, data, BindSourceForm)
+}
+
+func readFormData(form map[string][]string, data interface{}, source string) error {
 	rv := reflect.ValueOf(data)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return errors.New("data must be a pointer")
@@ -80,13 +130,14 @@ func ReadFormData(form map[string][]string, data interface{}) error {
 		return errors.New("data must be a pointer to a struct")
 	}
 
-	return readForm(form, "", rv)
+	return readForm(form, "", rv, source)
 }
 
-func readForm(form map[string][]string, prefix string, rv reflect.Value) error {
+func readForm(form map[string][]string, prefix string, rv reflect.Value, source string) error {
 	rv = indirect(rv)
 	rt := rv.Type()
 	n := rt.NumField()
+	var errs BindErrors
 	for i := 0; i < n; i++ {
 		field := rt.Field(i)
 		tag := field.Tag.Get(formTag)
@@ -110,8 +161,8 @@ func readForm(form map[string][]string, prefix string, rv reflect.Value) error {
 		}
 
 		if ft.Kind() != reflect.Struct {
-			if err := readFormField(form, name, rv.Field(i)); err != nil {
-				return err
+			if err := readFormField(form, name, rv.Field(i), source); err != nil {
+				errs = append(errs, err)
 			}
 			continue
 		}
@@ -119,32 +170,27 @@ func readForm(form map[string][]string, prefix string, rv reflect.Value) error {
 		if name == "" {
 			name = prefix
 		}
-		if err := readForm(form, name, rv.Field(i)); err != nil {
-			return err
+		if err := readForm(form, name, rv.Field(i), source); err != nil {
+			errs = appendBindError(errs, err)
 		}
 	}
-	return nil
+	return joinBindErrors(errs)
 }
 
-func readFormField(form map[string][]string, name string, rv reflect.Value) error {
+func readFormField(form map[string][]string, name string, rv reflect.Value, source string) error {
 	value, ok := form[name]
 	if !ok {
 		return nil
 	}
 	rv = indirect(rv)
 	if rv.Kind() != reflect.Slice {
-		return setFormFieldValue(rv, value[0])
-	}
-
-	n := len(value)
-	slice := reflect.MakeSlice(rv.Type(), n, n)
-	for i := 0; i < n; i++ {
-		if err := setFormFieldValue(slice.Index(i), value[i]); err != nil {
-			return err
+		if err := setFormFieldValue(rv, value[0]); err != nil {
+			return &BindError{Field: name, Source: source, Value: value[0], Type: rv.Type(), Err: err}
 		}
+		return nil
 	}
-	rv.Set(slice)
-	return nil
+
+	return setSlice(name, source, rv, value, setFormFieldValue)
 }
 
 func setFormFieldValue(rv reflect.Value, value string) error {
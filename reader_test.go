@@ -4,7 +4,15 @@ package makross
 
 import (
 	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -58,6 +66,36 @@ func TestReadForm(t *testing.T) {
 	assert.Equal(t, []int{100, 200, 300}, a.D)
 }
 
+func TestReadFormSlices(t *testing.T) {
+	var a struct {
+		IDs    []int     `form:"ids"`
+		Big    []int64   `form:"big"`
+		Prices []float64 `form:"prices"`
+		Flags  []bool    `form:"flags"`
+		Tags   []string  `form:"tags"`
+	}
+	values := map[string][]string{
+		"ids":    {"1", "2", "3"},
+		"big":    {"-9007199254740993"},
+		"prices": {"0.5", "10"},
+		"flags":  {"true", "false", ""},
+		"tags":   {"x", "y"},
+	}
+	assert.Nil(t, ReadFormData(values, &a))
+	assert.Equal(t, []int{1, 2, 3}, a.IDs)
+	assert.Equal(t, []int64{-9007199254740993}, a.Big)
+	assert.Equal(t, []float64{0.5, 10}, a.Prices)
+	assert.Equal(t, []bool{true, false, false}, a.Flags)
+	assert.Equal(t, []string{"x", "y"}, a.Tags)
+
+	err := ReadFormData(map[string][]string{"prices": {"1", "one", "2", "two"}}, &a)
+	if assert.IsType(t, &SliceConversionError{}, err) {
+		assert.Equal(t, `cannot convert prices to []float64: prices[1]="one": invalid syntax; prices[3]="two": invalid syntax`, err.Error())
+	}
+	// the field is left unchanged
+	assert.Equal(t, []float64{0.5, 10}, a.Prices)
+}
+
 func TestDefaultDataReader(t *testing.T) {
 	tests := []struct {
 		tag         string
@@ -87,3 +125,179 @@ func TestDefaultDataReader(t *testing.T) {
 		assert.Equal(t, expected, data, test.tag)
 	}
 }
+
+func TestReadBindErrors(t *testing.T) {
+	var a struct {
+		Age   int     `form:"age"`
+		Price float64 `form:"price"`
+		Inner struct {
+			Count int `form:"count"`
+		} `form:"inner"`
+	}
+	err := ReadFormData(map[string][]string{"age": {"x"}, "price": {"1"}, "inner.count": {"y"}}, &a)
+	errs := BindErrorsOf(err)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "age", errs[0].Field)
+		assert.Equal(t, BindSourceForm, errs[0].Source)
+		assert.Equal(t, "x", errs[0].Value)
+		assert.Equal(t, "inner.count", errs[1].Field)
+	}
+	assert.Equal(t, 1.0, a.Price)
+
+	req, _ := http.NewRequest("GET", "/?age=old", nil)
+	err = DefaultFormDataReader.Read(req, &a)
+	if be := FieldBindError(err, "age"); assert.NotNil(t, be) {
+		assert.Equal(t, BindSourceQuery, be.Source)
+	}
+
+	req, _ = http.NewRequest("POST", "/", bytes.NewBufferString(`{"age": "old"}`))
+	err = DataReaders[MIME_JSON].Read(req, &struct {
+		Age int `json:"age"`
+	}{})
+	if be := FieldBindError(err, "age"); assert.NotNil(t, be) {
+		assert.Equal(t, BindSourceJSON, be.Source)
+		assert.Equal(t, "string", be.Value)
+	}
+
+	// HandleError sends them as a 400 response
+	m := New()
+	m.Post("/", func(c *Context) error {
+		var data struct {
+			Age int `json:"age"`
+		}
+		return c.Read(&data)
+	})
+	req, _ = http.NewRequest("POST", "/", bytes.NewBufferString(`{"age": "old"}`))
+	req.Header.Set(HeaderContentType, MIME_JSON)
+	req.Header.Set(HeaderAccept, MIME_JSON)
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, StatusBadRequest, res.Code)
+	var body struct {
+		Errors []map[string]string `json:"errors"`
+	}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &body))
+	if assert.Len(t, body.Errors, 1) {
+		e := body.Errors[0]
+		assert.Equal(t, []string{"age", "json", "string", "int"}, []string{e["field"], e["source"], e["value"], e["expected"]})
+		assert.Contains(t, e["message"], "cannot unmarshal string")
+	}
+}
+
+func TestReadMalformedBody(t *testing.T) {
+	type person struct {
+		Age int `json:"age" xml:"age"`
+	}
+	read := func(mime, body string) *BindError {
+		req, _ := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+		err := DataReaders[mime].Read(req, new(person))
+		var be *BindError
+		if assert.True(t, errors.As(err, &be), body) {
+			return be
+		}
+		return nil
+	}
+
+	if be := read(MIME_JSON, `{"age": }`); be != nil {
+		assert.Equal(t, BindSourceJSON, be.Source)
+		assert.Equal(t, "", be.Field)
+		assert.Equal(t, int64(9), be.Offset)
+		assert.Equal(t, "cannot bind json body at offset 9: invalid character '}' looking for beginning of value", be.Error())
+	}
+	if be := read(MIME_JSON, `{"age": 1`); be != nil {
+		assert.Equal(t, io.ErrUnexpectedEOF, be.Err)
+	}
+	if be := read(MIME_XML, `<person><age>1</person>`); be != nil {
+		assert.Equal(t, BindSourceXML, be.Source)
+		assert.IsType(t, new(xml.SyntaxError), be.Err)
+		assert.NotZero(t, be.Offset)
+	}
+	if be := read(MIME_XML, `<person><age>old</age></person>`); be != nil {
+		assert.Equal(t, BindSourceXML, be.Source)
+		assert.Equal(t, "old", be.Value)
+		assert.Equal(t, "invalid syntax", be.Message())
+	}
+
+	// Bind wraps them in a 400 HTTPError
+	m := New()
+	for _, test := range []struct{ ctype, body, source string }{
+		{MIMEApplicationJSON, `{"age": }`, BindSourceJSON},
+		{MIMEApplicationXML, `<person><age>1</person>`, BindSourceXML},
+		{MIMEApplicationXML, `<person><age>old</age></person>`, BindSourceXML},
+	} {
+		req := httptest.NewRequest(POST, "/", bytes.NewBufferString(test.body))
+		req.Header.Set(HeaderContentType, test.ctype)
+		err := m.NewContext(req, httptest.NewRecorder()).Bind(new(person))
+		if he, ok := err.(*HTTPError); assert.True(t, ok, test.body) {
+			assert.Equal(t, StatusBadRequest, he.Status, test.body)
+		}
+		if errs := BindErrorsOf(err); assert.Len(t, errs, 1, test.body) {
+			assert.Equal(t, test.source, errs[0].Source, test.body)
+		}
+	}
+}
+
+func TestContextBindWith(t *testing.T) {
+	type event struct {
+		Type string `json:"type"`
+		ID   int    `json:"id"`
+	}
+	m := New()
+	m.Post("/hook", func(c *Context) error {
+		var e event
+		if err := c.BindWith(&e, &JSONDataReader{}); err != nil {
+			return err
+		}
+		return c.String(e.Type + " " + strconv.Itoa(e.ID))
+	})
+	serve := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/hook", bytes.NewBufferString(body))
+		req.Header.Set(HeaderContentType, MIMETextPlain)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(`{"type": "push", "id": 7}`)
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "push 7", res.Body.String())
+
+	// the errors of the reader are bad requests
+	res = serve(`{"type": "push", "id": "seven"}`)
+	assert.Equal(t, StatusBadRequest, res.Code)
+	res = serve(`{"type": `)
+	assert.Equal(t, StatusBadRequest, res.Code)
+
+	// Read doesn't decode the body of an unknown content type as JSON
+	req, _ := http.NewRequest("POST", "/", bytes.NewBufferString(`{"type": "push"}`))
+	req.Header.Set(HeaderContentType, MIMETextPlain)
+	var e event
+	assert.Nil(t, New().NewContext(req, httptest.NewRecorder()).Read(&e))
+	assert.Equal(t, "", e.Type)
+}
+
+func TestReadBody(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+	req.Header.Set(HeaderContentLength, "999")
+	var copied bytes.Buffer
+	body, err := ReadBody(req, &copied)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "hello", copied.String())
+	assert.Equal(t, int64(5), req.ContentLength)
+	assert.Equal(t, "5", req.Header.Get(HeaderContentLength))
+	for i := 0; i < 2; i++ {
+		b, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, "hello", string(b))
+		req.Body, _ = req.GetBody()
+	}
+
+	SetBody(req, []byte("bye"))
+	b, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, "bye", string(b))
+	assert.Equal(t, "3", req.Header.Get(HeaderContentLength))
+
+	body, err = ReadBody(httptest.NewRequest("GET", "/", nil), nil)
+	assert.Nil(t, err)
+	assert.Nil(t, body)
+}
@@ -0,0 +1,302 @@
+package recover
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+type (
+	// PanicCircuitConfig defines the config of a PanicCircuit.
+	PanicCircuitConfig struct {
+		// Threshold is the number of panics of a route within the Window disabling the route.
+		// Optional. Default value 5.
+		Threshold int `json:"threshold"`
+
+		// GlobalThreshold is the number of panics of all the routes within the Window disabling
+		// all the routes.
+		// Optional. Default value 0, no global budget.
+		GlobalThreshold int `json:"global_threshold"`
+
+		// Window is the sliding window over which the panics are counted.
+		// Optional. Default value 1 minute.
+		Window time.Duration `json:"window"`
+
+		// CoolDown is the time a route stays disabled before a request is let through as a probe:
+		// the route is enabled again if the probe doesn't panic, or disabled for another CoolDown.
+		// Optional. Default value 30 seconds.
+		CoolDown time.Duration `json:"cool_down"`
+
+		// LogInterval is the minimum interval between the logs of the requests refused by a disabled route.
+		// Optional. Default value 10 seconds.
+		LogInterval time.Duration `json:"log_interval"`
+	}
+
+	// PanicCircuit counts the panics recovered by the Recover middleware per route, and disables
+	// the routes panicking too often, e.g. after a bad deploy, so that they don't flood the logs
+	// and the error reporters. The requests to a disabled route get a "503 - Service Unavailable"
+	// response with a Retry-After header, without running the handlers.
+	//
+	//	circuit := recover.NewPanicCircuit(recover.PanicCircuitConfig{Threshold: 10, GlobalThreshold: 100})
+	//	m.Use(recover.RecoverWithConfig(recover.RecoverConfig{Circuit: circuit}))
+	//	m.Get("/admin/circuit", func(c *makross.Context) error {
+	//		return c.JSON(circuit.Disabled())
+	//	})
+	PanicCircuit struct {
+		config PanicCircuitConfig
+		open   int32 // the number of disabled circuits, for the lock-free path of the requests
+		mu     sync.Mutex
+		global circuitState
+		routes map[string]*circuitState
+		now    func() time.Time
+	}
+
+	// DisabledRoute is a route disabled by a PanicCircuit.
+	DisabledRoute struct {
+		Route  string    `json:"route"`  // e.g. "GET /users/<id>", or GlobalRoute
+		Panics int       `json:"panics"` // the panics within the window
+		Since  time.Time `json:"since"`
+		Until  time.Time `json:"until"` // the end of the cool-down, when a probe is let through
+	}
+
+	circuitState struct {
+		panics   []time.Time // within the window, oldest first
+		openedAt time.Time   // zero when enabled
+		probing  bool        // whether a probe is in flight
+		logged   time.Time   // the last log of the refused requests
+		refused  int         // the requests refused since the last log
+	}
+
+	// probe tells which circuits a request probes.
+	probe uint8
+)
+
+const (
+	// GlobalRoute is the Route of the DisabledRoute when all the routes are disabled by the GlobalThreshold.
+	GlobalRoute = "*"
+
+	probeGlobal probe = 1 << iota
+	probeRoute
+)
+
+var (
+	// DefaultPanicCircuitConfig is the default PanicCircuit config.
+	DefaultPanicCircuitConfig = PanicCircuitConfig{
+		Threshold:   5,
+		Window:      time.Minute,
+		CoolDown:    30 * time.Second,
+		LogInterval: 10 * time.Second,
+	}
+
+	// ErrRouteDisabled is returned for the requests to a route disabled by a PanicCircuit.
+	ErrRouteDisabled = &makross.HTTPError{Status: makross.StatusServiceUnavailable, Message: "temporarily disabled", Err: makross.ErrServiceUnavailable}
+)
+
+// NewPanicCircuit returns a PanicCircuit with config, to be set as the Circuit of the Recover middleware.
+func NewPanicCircuit(config PanicCircuitConfig) *PanicCircuit {
+	// Defaults
+	if config.Threshold == 0 {
+		config.Threshold = DefaultPanicCircuitConfig.Threshold
+	}
+	if config.Window == 0 {
+		config.Window = DefaultPanicCircuitConfig.Window
+	}
+	if config.CoolDown == 0 {
+		config.CoolDown = DefaultPanicCircuitConfig.CoolDown
+	}
+	if config.LogInterval == 0 {
+		config.LogInterval = DefaultPanicCircuitConfig.LogInterval
+	}
+	return &PanicCircuit{
+		config: config,
+		routes: make(map[string]*circuitState),
+		now:    time.Now,
+	}
+}
+
+// Disabled returns the disabled routes, ordered by route.
+func (p *PanicCircuit) Disabled() []DisabledRoute {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var disabled []DisabledRoute
+	add := func(route string, st *circuitState) {
+		if !st.openedAt.IsZero() {
+			disabled = append(disabled, DisabledRoute{
+				Route:  route,
+				Panics: len(st.panics),
+				Since:  st.openedAt,
+				Until:  st.openedAt.Add(p.config.CoolDown),
+			})
+		}
+	}
+	add(GlobalRoute, &p.global)
+	for route, st := range p.routes {
+		add(route, st)
+	}
+	sort.Slice(disabled, func(i, j int) bool {
+		return disabled[i].Route < disabled[j].Route
+	})
+	return disabled
+}
+
+// Reset enables the route, e.g. "GET /users/<id>" or GlobalRoute, and forgets its panics.
+// It reports whether the route was disabled.
+func (p *PanicCircuit) Reset(route string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := &p.global
+	if route != GlobalRoute {
+		if st = p.routes[route]; st == nil {
+			return false
+		}
+		delete(p.routes, route)
+	}
+	wasOpen := p.close(st)
+	st.panics = nil
+	return wasOpen
+}
+
+// ResetAll enables all the routes and forgets their panics.
+func (p *PanicCircuit) ResetAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.close(&p.global)
+	p.global.panics = nil
+	p.routes = make(map[string]*circuitState)
+	atomic.StoreInt32(&p.open, 0)
+}
+
+// enter returns ErrRouteDisabled if the route is disabled, unless the request is let through
+// as the probe of the route. The probe is reported with the result of the request, see done.
+func (p *PanicCircuit) enter(c *makross.Context, route string) (probe, error) {
+	if atomic.LoadInt32(&p.open) == 0 {
+		return 0, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	var pr probe
+	logger := c.Makross().Logger()
+	wait, ok := p.admit(logger, &p.global, GlobalRoute, now)
+	if ok && wait == 0 {
+		pr |= probeGlobal
+	}
+	if st := p.routes[route]; ok && st != nil {
+		if wait, ok = p.admit(logger, st, route, now); !ok && pr&probeGlobal != 0 {
+			// the route is still disabled, so not a probe of the global circuit after all
+			p.global.probing = false
+			pr = 0
+		} else if ok && wait == 0 {
+			pr |= probeRoute
+		}
+	}
+	if !ok {
+		he := makross.NewServiceUnavailableError(wait)
+		he.Message, he.Err = ErrRouteDisabled.Message, ErrRouteDisabled
+		return 0, he
+	}
+	return pr, nil
+}
+
+// admit reports whether a request may go through the circuit, and the time left before the probe
+// if it may not. A request let through as the probe of a disabled circuit gets a zero wait.
+func (p *PanicCircuit) admit(logger makross.Logger, st *circuitState, route string, now time.Time) (wait time.Duration, ok bool) {
+	if st.openedAt.IsZero() {
+		return -1, true
+	}
+	wait = st.openedAt.Add(p.config.CoolDown).Sub(now)
+	if wait <= 0 && !st.probing {
+		st.probing = true
+		return 0, true
+	}
+	if wait <= 0 {
+		// the probe is in flight
+		wait = time.Second
+	}
+	st.refused++
+	if now.Sub(st.logged) >= p.config.LogInterval {
+		logger.Warnf("recover: %s temporarily disabled, %d requests refused", route, st.refused)
+		st.logged = now
+		st.refused = 0
+	}
+	return wait, false
+}
+
+// done records the result of a request to the route, which panicked or not.
+func (p *PanicCircuit) done(logger makross.Logger, route string, pr probe, panicked bool) {
+	if !panicked && pr == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if !panicked {
+		if pr&probeGlobal != 0 && p.close(&p.global) {
+			p.global.panics = nil
+			logger.Infof("recover: %s enabled again", GlobalRoute)
+		}
+		if st := p.routes[route]; pr&probeRoute != 0 && st != nil && p.close(st) {
+			st.panics = nil
+			logger.Infof("recover: %s enabled again", route)
+		}
+		return
+	}
+
+	if p.config.GlobalThreshold > 0 {
+		p.record(logger, &p.global, GlobalRoute, now, p.config.GlobalThreshold, pr&probeGlobal != 0)
+	}
+	if route == "" {
+		return
+	}
+	st := p.routes[route]
+	if st == nil {
+		st = new(circuitState)
+		p.routes[route] = st
+	}
+	p.record(logger, st, route, now, p.config.Threshold, pr&probeRoute != 0)
+}
+
+// record counts the panic, and disables the circuit past the threshold, or if the panic is the one of its probe.
+func (p *PanicCircuit) record(logger makross.Logger, st *circuitState, route string, now time.Time, threshold int, probed bool) {
+	st.panics = append(st.panics, now)
+	i := 0
+	for i < len(st.panics) && now.Sub(st.panics[i]) >= p.config.Window {
+		i++
+	}
+	st.panics = st.panics[i:]
+
+	switch {
+	case probed:
+		st.openedAt = now
+		st.probing = false
+		logger.Errorf("recover: %s probe panicked, disabled for %v", route, p.config.CoolDown)
+	case st.openedAt.IsZero() && len(st.panics) >= threshold:
+		st.openedAt = now
+		st.logged = now
+		atomic.AddInt32(&p.open, 1)
+		logger.Errorf("recover: %s disabled for %v after %d panics in %v", route, p.config.CoolDown, len(st.panics), p.config.Window)
+	}
+}
+
+// close enables the circuit, and reports whether it was disabled.
+func (p *PanicCircuit) close(st *circuitState) bool {
+	if st.openedAt.IsZero() {
+		return false
+	}
+	st.openedAt = time.Time{}
+	st.probing = false
+	st.refused = 0
+	atomic.AddInt32(&p.open, -1)
+	return true
+}
+
+// routeKey returns the key of the route of the request, or "" if no route matched.
+func routeKey(c *makross.Context) string {
+	if route := c.Route(); route != nil {
+		return route.String()
+	}
+	return ""
+}
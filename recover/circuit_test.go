@@ -0,0 +1,139 @@
+package recover
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPanicCircuit(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	circuit := NewPanicCircuit(PanicCircuitConfig{Threshold: 3, Window: time.Minute, CoolDown: 30 * time.Second})
+	circuit.now = func() time.Time { return now }
+	m := makross.New()
+	logs := new(bytes.Buffer)
+	m.SetLogger(makross.NewLogger(logs))
+	m.Use(RecoverWithConfig(RecoverConfig{Circuit: circuit, DisablePrintStack: true}))
+	broken := true
+	calls := 0
+	m.Get("/boom", func(c *makross.Context) error {
+		calls++
+		if broken {
+			panic("bad deploy")
+		}
+		return c.String("fixed")
+	})
+	m.Get("/ok", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res
+	}
+
+	// the panics spread over more than the window don't disable the route
+	serve("/boom")
+	serve("/boom")
+	now = now.Add(time.Minute)
+	assert.Equal(t, makross.StatusInternalServerError, serve("/boom").Code)
+	assert.Empty(t, circuit.Disabled())
+
+	assert.Equal(t, makross.StatusInternalServerError, serve("/boom").Code)
+	assert.Equal(t, makross.StatusInternalServerError, serve("/boom").Code)
+	disabled := circuit.Disabled()
+	if assert.Len(t, disabled, 1) {
+		assert.Equal(t, DisabledRoute{Route: "GET /boom", Panics: 3, Since: now, Until: now.Add(30 * time.Second)}, disabled[0])
+	}
+	assert.Contains(t, logs.String(), "ERROR recover: GET /boom disabled for 30s after 3 panics in 1m0s")
+	calls = 0
+	res := serve("/boom")
+	assert.Equal(t, makross.StatusServiceUnavailable, res.Code)
+	assert.Equal(t, "30", res.Header().Get(makross.HeaderRetryAfter))
+	assert.Equal(t, "temporarily disabled", res.Body.String())
+	assert.Equal(t, 0, calls)
+	assert.True(t, errors.Is(ErrRouteDisabled, makross.ErrServiceUnavailable))
+	assert.Equal(t, makross.StatusOK, serve("/ok").Code)
+
+	// the probe panics, the route is disabled again
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, "20", serve("/boom").Header().Get(makross.HeaderRetryAfter))
+	now = now.Add(20 * time.Second)
+	assert.Equal(t, makross.StatusInternalServerError, serve("/boom").Code)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, makross.StatusServiceUnavailable, serve("/boom").Code)
+	if disabled = circuit.Disabled(); assert.Len(t, disabled, 1) {
+		assert.Equal(t, now, disabled[0].Since)
+	}
+
+	// the probe succeeds, the route is enabled again
+	broken = false
+	now = now.Add(30 * time.Second)
+	res = serve("/boom")
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, "fixed", res.Body.String())
+	assert.Empty(t, circuit.Disabled())
+	assert.Contains(t, logs.String(), "INFO recover: GET /boom enabled again")
+	assert.Equal(t, makross.StatusOK, serve("/boom").Code)
+
+	// manual reset
+	broken = true
+	for i := 0; i < 3; i++ {
+		serve("/boom")
+	}
+	assert.Equal(t, makross.StatusServiceUnavailable, serve("/boom").Code)
+	assert.True(t, circuit.Reset("GET /boom"))
+	assert.False(t, circuit.Reset("GET /boom"))
+	assert.Equal(t, makross.StatusInternalServerError, serve("/boom").Code)
+}
+
+func TestPanicCircuitGlobal(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	circuit := NewPanicCircuit(PanicCircuitConfig{Threshold: 10, GlobalThreshold: 3})
+	circuit.now = func() time.Time { return now }
+	m := makross.New()
+	m.SetLogger(makross.NewLogger(ioutil.Discard))
+	m.Use(RecoverWithConfig(RecoverConfig{Circuit: circuit, DisablePrintStack: true}))
+	for _, path := range []string{"/a", "/b", "/c"} {
+		m.Get(path, func(c *makross.Context) error {
+			panic("bad deploy")
+		})
+	}
+	m.Get("/ok", func(c *makross.Context) error {
+		return c.String("ok")
+	})
+	serve := func(path string) int {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res.Code
+	}
+
+	serve("/a")
+	serve("/b")
+	assert.Equal(t, makross.StatusOK, serve("/ok"))
+	serve("/c")
+	assert.Equal(t, makross.StatusServiceUnavailable, serve("/ok"))
+	assert.Equal(t, makross.StatusServiceUnavailable, serve("/missing"))
+	disabled := circuit.Disabled()
+	if assert.Len(t, disabled, 1) {
+		assert.Equal(t, GlobalRoute, disabled[0].Route)
+	}
+
+	// the probe succeeds
+	now = now.Add(DefaultPanicCircuitConfig.CoolDown)
+	assert.Equal(t, makross.StatusOK, serve("/ok"))
+	assert.Empty(t, circuit.Disabled())
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		serve(path)
+	}
+	assert.Equal(t, makross.StatusServiceUnavailable, serve("/ok"))
+	circuit.ResetAll()
+	assert.Equal(t, makross.StatusOK, serve("/ok"))
+}
@@ -27,6 +27,16 @@ type (
 		// DisablePrintStack disables printing stack trace.
 		// Optional. Default value as false.
 		DisablePrintStack bool `json:"disable_print_stack"`
+
+		// OnPanic is called with each recovered panic and the stack trace of its goroutine,
+		// e.g. to alert or to forward it to an error tracker, before the 500 response is written,
+		// so that it is called even if writing the response fails.
+		// Optional. Default value nil.
+		OnPanic func(c *makross.Context, err interface{}, stack []byte) `json:"-"`
+
+		// Circuit disables the routes panicking too often, see PanicCircuit.
+		// Optional. Default value nil.
+		Circuit *PanicCircuit `json:"-"`
 	}
 )
 
@@ -42,6 +52,7 @@ var (
 
 // Recover returns a middleware which recovers from panics anywhere in the chain
 // and handles the control to the centralized HTTPErrorHandler.
+// Recovered panics are passed to the error reporters registered with makross.Makross.OnError.
 func Recover() makross.Handler {
 	return RecoverWithConfig(DefaultRecoverConfig)
 }
@@ -62,8 +73,22 @@ func RecoverWithConfig(config RecoverConfig) makross.Handler {
 			return c.Next()
 		}
 
+		var route string
+		var pr probe
+		if config.Circuit != nil {
+			route = routeKey(c)
+			var err error
+			if pr, err = config.Circuit.enter(c, route); err != nil {
+				return err
+			}
+		}
+
 		defer func() {
-			if r := recover(); r != nil {
+			r := recover()
+			if config.Circuit != nil {
+				config.Circuit.done(c.Makross().Logger(), route, pr, r != nil)
+			}
+			if r != nil {
 				var err error
 				switch r := r.(type) {
 				case error:
@@ -76,6 +101,10 @@ func RecoverWithConfig(config RecoverConfig) makross.Handler {
 				if !config.DisablePrintStack {
 					log.Printf("[%s] %s %s\n", color.Red("PANIC RECOVER"), err, stack[:length])
 				}
+				if config.OnPanic != nil {
+					config.OnPanic(c, r, stack[:length])
+				}
+				c.ReportError(err, stack[:length])
 
 				c.Error(500, err.Error())
 
@@ -1,9 +1,15 @@
 package recover_test
 
 import (
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"os"
+	"testing"
+
 	"github.com/insionng/makross"
 	"github.com/insionng/makross/recover"
-	"testing"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRecover(t *testing.T) {
@@ -13,3 +19,38 @@ func TestRecover(t *testing.T) {
 	}))
 	go m.Listen(":8888")
 }
+
+func TestRecoverOnPanic(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var value interface{}
+	var stack []byte
+	var path string
+	m := makross.New()
+	m.Use(recover.RecoverWithConfig(recover.RecoverConfig{
+		DisablePrintStack: true,
+		OnPanic: func(c *makross.Context, err interface{}, s []byte) {
+			value, stack, path = err, s, c.Path()
+		},
+	}))
+	m.Get("/boom", func(c *makross.Context) error {
+		panic(42)
+	})
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/boom", nil))
+	assert.Equal(t, makross.StatusInternalServerError, res.Code)
+	assert.Equal(t, 42, value)
+	assert.Equal(t, "/boom", path)
+	assert.Contains(t, string(stack), "goroutine")
+
+	// even if the response can't be written
+	value = nil
+	m.Get("/committed", func(c *makross.Context) error {
+		c.String("partial")
+		panic("late")
+	})
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/committed", nil))
+	assert.Equal(t, "late", value)
+}
@@ -0,0 +1,232 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Redirector redirects the requests matching its rules before they are routed.
+	// The rules can be replaced with Update while the server is running.
+	Redirector struct {
+		// Code is the status code of the rules without their own.
+		// Default value 301.
+		Code int
+
+		// PreserveQuery appends the query string of the request to the targets.
+		// Default value false.
+		PreserveQuery bool
+
+		// MaxHops is the number of rules a redirect may go through. A target matching another rule
+		// is followed, so that the client is redirected once, and a chain longer than MaxHops is a loop.
+		// Default value 5.
+		MaxHops int
+
+		mu        sync.RWMutex
+		exact     map[string]redirectRule
+		wildcards []redirectRule // longest prefix first
+		fn        RedirectFunc
+	}
+
+	// RedirectFunc returns the redirect of a path, if any. A zero code stands for Redirector.Code.
+	RedirectFunc func(path string) (target string, code int, ok bool)
+
+	redirectRule struct {
+		from, to string // from is the prefix of the wildcard rules
+		code     int
+	}
+)
+
+// DefaultRedirectMaxHops is the default value of Redirector.MaxHops.
+const DefaultRedirectMaxHops = 5
+
+// ErrRedirectLoop is returned when the redirect rules lead to a loop.
+var ErrRedirectLoop = NewHTTPError(StatusLoopDetected, "redirect loop")
+
+// Redirects sets the redirect rules, mapping the paths to their targets, and returns the redirector
+// of the instance. A path ending with "*" matches any path with that prefix, and the rest of the path
+// replaces the "*" of the target. A target can start with the status code of the redirect:
+//
+//	m.Redirects(map[string]string{
+//		"/blog/old-slug": "/blog/new-slug",
+//		"/blog/2016/*":   "/archive/2016/*",
+//		"/sale":          "302 /promotions/summer",
+//		"/docs/*":        "308 https://docs.example.com/*",
+//	})
+//
+// It panics if the rules are invalid, see Redirector.Update.
+func (m *Makross) Redirects(rules map[string]string) *Redirector {
+	r := m.redirector()
+	if err := r.Update(rules); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// RedirectsFunc sets a function which is consulted for the paths the redirect rules don't match,
+// and returns the redirector of the instance.
+func (m *Makross) RedirectsFunc(fn RedirectFunc) *Redirector {
+	r := m.redirector()
+	r.mu.Lock()
+	r.fn = fn
+	r.mu.Unlock()
+	return r
+}
+
+func (m *Makross) redirector() *Redirector {
+	if m.redirects == nil {
+		m.redirects = &Redirector{}
+	}
+	return m.redirects
+}
+
+// Update replaces the redirect rules, see Makross.Redirects. It's safe for concurrent use.
+// The rules are left unchanged if any is invalid or leads to a loop.
+func (r *Redirector) Update(rules map[string]string) error {
+	exact := make(map[string]redirectRule)
+	var wildcards []redirectRule
+	for from, to := range rules {
+		rule := redirectRule{to: strings.TrimSpace(to)}
+		if i := strings.IndexByte(rule.to, ' '); i == 3 {
+			code, err := strconv.Atoi(rule.to[:i])
+			if err != nil || !isRedirectCode(code) {
+				return fmt.Errorf("makross: invalid status code in redirect %q: %q", from, to)
+			}
+			rule.code, rule.to = code, strings.TrimSpace(rule.to[i:])
+		}
+		if !strings.HasPrefix(from, "/") || rule.to == "" {
+			return fmt.Errorf("makross: invalid redirect %q: %q", from, to)
+		}
+		if strings.HasSuffix(from, "*") {
+			rule.from = strings.TrimSuffix(from, "*")
+			wildcards = append(wildcards, rule)
+		} else {
+			rule.from = from
+			exact[from] = rule
+		}
+	}
+	sort.Slice(wildcards, func(i, j int) bool {
+		return len(wildcards[i].from) > len(wildcards[j].from)
+	})
+
+	// check the loops with a copy, so that the current rules are served meanwhile
+	check := &Redirector{MaxHops: r.MaxHops, exact: exact, wildcards: wildcards}
+	for _, rule := range exact {
+		if _, _, _, err := check.resolve(rule.from); err != nil {
+			return fmt.Errorf("makross: redirect loop from %q", rule.from)
+		}
+	}
+	for _, rule := range wildcards {
+		if _, _, _, err := check.resolve(rule.from); err != nil {
+			return fmt.Errorf("makross: redirect loop from %q", rule.from+"*")
+		}
+	}
+
+	r.mu.Lock()
+	r.exact, r.wildcards = exact, wildcards
+	r.mu.Unlock()
+	return nil
+}
+
+// match returns the rule matching the path.
+func (r *Redirector) match(path string) (string, int, bool) {
+	if rule, ok := r.exact[path]; ok {
+		return rule.to, rule.code, true
+	}
+	for _, rule := range r.wildcards {
+		if strings.HasPrefix(path, rule.from) {
+			to := strings.Replace(rule.to, "*", path[len(rule.from):], 1)
+			if isLocalTarget(rule.to) {
+				// keep the target local, "//host" and "/\\host" are protocol-relative for the browsers
+				to = "/" + strings.TrimLeft(to, "/\\")
+			}
+			return to, rule.code, true
+		}
+	}
+	if r.fn != nil {
+		return r.fn(path)
+	}
+	return "", 0, false
+}
+
+// resolve follows the rules from the path and returns the final target, with the code of the first rule.
+func (r *Redirector) resolve(path string) (target string, code int, ok bool, err error) {
+	max := r.MaxHops
+	if max <= 0 {
+		max = DefaultRedirectMaxHops
+	}
+	for hops := 0; ; hops++ {
+		to, c, matched := r.match(path)
+		if !matched {
+			return
+		}
+		if hops == max {
+			return "", 0, false, ErrRedirectLoop
+		}
+		if !ok {
+			code, ok = c, true
+		}
+		target = to
+		// only the local targets can match other rules
+		if !isLocalTarget(to) {
+			return
+		}
+		path = to
+		if i := strings.IndexAny(path, "?#"); i >= 0 {
+			path = path[:i]
+		}
+	}
+}
+
+// handler returns the handler redirecting the request, or nil if no rule matches it.
+func (r *Redirector) handler(req *http.Request) Handler {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	target, code, ok, err := r.resolve(req.URL.Path)
+	r.mu.RUnlock()
+	if err != nil {
+		return func(c *Context) error {
+			return err
+		}
+	}
+	if !ok {
+		return nil
+	}
+	if code == 0 {
+		code = r.Code
+	}
+	if code == 0 {
+		code = StatusMovedPermanently
+	}
+	if r.PreserveQuery && req.URL.RawQuery != "" {
+		if strings.Contains(target, "?") {
+			target += "&" + req.URL.RawQuery
+		} else {
+			target += "?" + req.URL.RawQuery
+		}
+	}
+	return func(c *Context) error {
+		return c.Redirect(target, code)
+	}
+}
+
+// isLocalTarget reports whether the target is a path of the instance.
+func isLocalTarget(to string) bool {
+	return strings.HasPrefix(to, "/") && !strings.HasPrefix(to, "//") && !strings.HasPrefix(to, "/\\")
+}
+
+func isRedirectCode(code int) bool {
+	switch code {
+	case StatusMovedPermanently, StatusFound, StatusSeeOther, StatusTemporaryRedirect, StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirects(t *testing.T) {
+	m := New()
+	m.Get("/blog/new-slug", func(c *Context) error {
+		return c.String("new")
+	})
+	m.Get("/live", func(c *Context) error {
+		return c.String("live")
+	})
+	r := m.Redirects(map[string]string{
+		"/blog/old-slug":  "/blog/new-slug",
+		"/blog/older":     "/blog/old-slug",
+		"/blog/2016/*":    "/archive/2016/*",
+		"/blog/2016/top":  "/best",
+		"/news/*":         "/posts/*",
+		"/sale":           "302 /promotions/summer?ref=sale",
+		"/docs/*":         "308 https://docs.example.com/*",
+		"/products/cable": "307 /products/cables",
+		"/go/*":           "/*",
+	})
+
+	tests := []struct {
+		path, location string
+		code           int
+	}{
+		{"/blog/old-slug", "/blog/new-slug", StatusMovedPermanently},
+		// chains are followed, with the code of the first rule
+		{"/blog/older", "/blog/new-slug", StatusMovedPermanently},
+		{"/blog/2016/05/hello", "/archive/2016/05/hello", StatusMovedPermanently},
+		{"/blog/2016/top", "/best", StatusMovedPermanently},
+		{"/news/misc", "/posts/misc", StatusMovedPermanently},
+		{"/sale", "/promotions/summer?ref=sale", StatusFound},
+		{"/docs/guide/intro", "https://docs.example.com/guide/intro", StatusPermanentRedirect},
+		{"/products/cable", "/products/cables", StatusTemporaryRedirect},
+		{"/products/cable?color=red", "/products/cables", StatusTemporaryRedirect},
+		// the substituted targets stay local
+		{"/go//evil.example.com/x", "/evil.example.com/x", StatusMovedPermanently},
+		{"/go/%5C%5Cevil.example.com", "/evil.example.com", StatusMovedPermanently},
+		{"/live", "", StatusOK},
+	}
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, test.path, nil))
+		assert.Equal(t, test.code, res.Code, test.path)
+		assert.Equal(t, test.location, res.Header().Get(HeaderLocation), test.path)
+	}
+
+	r.PreserveQuery = true
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/products/cable?color=red", nil))
+	assert.Equal(t, "/products/cables?color=red", res.Header().Get(HeaderLocation))
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/sale?utm_source=mail", nil))
+	assert.Equal(t, "/promotions/summer?ref=sale&utm_source=mail", res.Header().Get(HeaderLocation))
+}
+
+func TestRedirectsUpdate(t *testing.T) {
+	m := New()
+	r := m.Redirects(map[string]string{"/a": "/b"})
+
+	invalid := []map[string]string{
+		{"/a": "/b", "/b": "/a"},
+		{"/a/*": "/a/b/*"},
+		{"/a": "/b", "/b": "/c", "/c": "/d", "/d": "/e", "/e": "/f", "/f": "/g"},
+		{"a": "/b"},
+		{"/a": ""},
+		{"/a": "200 /b"},
+		{"/a": "abc /b"},
+	}
+	for _, rules := range invalid {
+		assert.NotNil(t, r.Update(rules), "rules should be rejected")
+	}
+	assert.Panics(t, func() {
+		m.Redirects(invalid[0])
+	})
+	// the rules are unchanged after a failed update
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/a", nil))
+	assert.Equal(t, "/b", res.Header().Get(HeaderLocation))
+
+	assert.Nil(t, r.Update(map[string]string{"/a": "/c"}))
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/a", nil))
+	assert.Equal(t, "/c", res.Header().Get(HeaderLocation))
+
+	// hot reload while serving
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				res := httptest.NewRecorder()
+				m.ServeHTTP(res, httptest.NewRequest(GET, "/a", nil))
+				assert.Equal(t, StatusMovedPermanently, res.Code)
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		assert.Nil(t, r.Update(map[string]string{"/a": "/d"}))
+	}
+	wg.Wait()
+}
+
+func TestRedirectsFunc(t *testing.T) {
+	m := New()
+	m.Redirects(map[string]string{"/old": "/new"})
+	m.RedirectsFunc(func(path string) (string, int, bool) {
+		switch {
+		case strings.HasPrefix(path, "/u/"):
+			return "/users/" + path[3:], StatusFound, true
+		case path == "/loop":
+			return "/loop", 0, true
+		}
+		return "", 0, false
+	})
+	m.Get("/users/<id>", func(c *Context) error {
+		return c.String(c.Param("id").String())
+	})
+
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/u/42", nil))
+	assert.Equal(t, StatusFound, res.Code)
+	assert.Equal(t, "/users/42", res.Header().Get(HeaderLocation))
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/old", nil))
+	assert.Equal(t, "/new", res.Header().Get(HeaderLocation))
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/loop", nil))
+	assert.Equal(t, StatusLoopDetected, res.Code)
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/users/42", nil))
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "42", res.Body.String())
+}
@@ -0,0 +1,262 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// RenderCache caches the output of `Context#RenderCached()`, with its compressed variants,
+	// for the pages which are the same for all the users. The variant matching the Accept-Encoding
+	// of the request is served as is, without rendering nor compressing the page again.
+	RenderCache struct {
+		// TTL is how long a page is cached. Zero keeps the pages until they are purged.
+		TTL time.Duration
+
+		// Encoders compress the variants of the pages, in order of preference.
+		Encoders []ContentEncoder
+
+		mu      sync.RWMutex
+		entries map[string]map[string]*renderEntry // by cache key, then template name
+		now     func() time.Time
+	}
+
+	// ContentEncoder encodes the pages of the render cache for a Content-Encoding,
+	// e.g. a brotli encoder for "br":
+	//
+	//	rc := makross.NewRenderCache(time.Hour,
+	//		makross.EncoderFunc("br", func(b []byte) ([]byte, error) {
+	//			var buf bytes.Buffer
+	//			w := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	//			w.Write(b)
+	//			err := w.Close()
+	//			return buf.Bytes(), err
+	//		}),
+	//		makross.GzipEncoder(gzip.BestCompression),
+	//	)
+	ContentEncoder interface {
+		Encoding() string
+		Encode(b []byte) ([]byte, error)
+	}
+
+	// PassthroughWriter is implemented by the response writers which encode the response, such as
+	// the one of compress.Gzip, so that a body encoded already, e.g. by the render cache, is written as is.
+	PassthroughWriter interface {
+		Passthrough()
+	}
+
+	encoderFunc struct {
+		encoding string
+		fn       func([]byte) ([]byte, error)
+	}
+
+	renderEntry struct {
+		body     []byte
+		variants map[string][]byte // by encoding
+		expires  time.Time
+	}
+)
+
+// NewRenderCache returns a render cache keeping the pages for the TTL, with a gzip variant
+// if no encoder is given.
+func NewRenderCache(ttl time.Duration, encoders ...ContentEncoder) *RenderCache {
+	if len(encoders) == 0 {
+		encoders = []ContentEncoder{GzipEncoder(gzip.DefaultCompression)}
+	}
+	return &RenderCache{
+		TTL:      ttl,
+		Encoders: encoders,
+		entries:  make(map[string]map[string]*renderEntry),
+	}
+}
+
+// EncoderFunc returns a ContentEncoder for the Content-Encoding.
+func EncoderFunc(encoding string, fn func([]byte) ([]byte, error)) ContentEncoder {
+	return &encoderFunc{encoding, fn}
+}
+
+func (e *encoderFunc) Encoding() string {
+	return e.encoding
+}
+
+func (e *encoderFunc) Encode(b []byte) ([]byte, error) {
+	return e.fn(b)
+}
+
+// GzipEncoder returns a ContentEncoder compressing with gzip at the level.
+func GzipEncoder(level int) ContentEncoder {
+	return EncoderFunc("gzip", func(b []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w, err := gzip.NewWriterLevel(&buf, level)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(b); err != nil {
+			return nil, err
+		}
+		err = w.Close()
+		return buf.Bytes(), err
+	})
+}
+
+// Purge removes the pages cached under the key, for all the templates.
+func (rc *RenderCache) Purge(key string) {
+	rc.mu.Lock()
+	delete(rc.entries, key)
+	rc.mu.Unlock()
+}
+
+// PurgeAll removes all the cached pages.
+func (rc *RenderCache) PurgeAll() {
+	rc.mu.Lock()
+	rc.entries = make(map[string]map[string]*renderEntry)
+	rc.mu.Unlock()
+}
+
+func (rc *RenderCache) get(name, key string) *renderEntry {
+	rc.mu.RLock()
+	e := rc.entries[key][name]
+	rc.mu.RUnlock()
+	if e == nil || !e.expires.IsZero() && rc.clock().After(e.expires) {
+		return nil
+	}
+	return e
+}
+
+func (rc *RenderCache) clock() time.Time {
+	if rc.now == nil {
+		return time.Now()
+	}
+	return rc.now()
+}
+
+// add encodes the variants of the page and caches them.
+func (rc *RenderCache) add(name, key string, body []byte) (*renderEntry, error) {
+	e := &renderEntry{body: body, variants: make(map[string][]byte, len(rc.Encoders))}
+	for _, encoder := range rc.Encoders {
+		b, err := encoder.Encode(body)
+		if err != nil {
+			return nil, err
+		}
+		e.variants[encoder.Encoding()] = b
+	}
+	if rc.TTL > 0 {
+		e.expires = rc.clock().Add(rc.TTL)
+	}
+	rc.mu.Lock()
+	if rc.entries == nil {
+		rc.entries = make(map[string]map[string]*renderEntry)
+	}
+	if rc.entries[key] == nil {
+		rc.entries[key] = make(map[string]*renderEntry)
+	}
+	rc.entries[key][name] = e
+	rc.mu.Unlock()
+	return e, nil
+}
+
+// negotiate returns the encoding of the variant to serve for the Accept-Encoding header,
+// the first of the preferred ones, or "" for the uncompressed page.
+func (rc *RenderCache) negotiate(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, encoder := range rc.Encoders {
+		q, exact := 0.0, false
+		for _, accept := range strings.Split(acceptEncoding, ",") {
+			r, ok := parseMediaRange(accept)
+			switch {
+			case !ok:
+			case r.mediaType == encoder.Encoding():
+				q, exact = r.q, true
+			case r.mediaType == "*" && !exact:
+				q = r.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = encoder.Encoding(), q
+		}
+	}
+	return best
+}
+
+// SetRenderCache sets the cache of `Context#RenderCached()`.
+func (m *Makross) SetRenderCache(rc *RenderCache) {
+	m.renderCache = rc
+}
+
+// RenderCache returns the cache of `Context#RenderCached()`, nil if none is set.
+func (m *Makross) RenderCache() *RenderCache {
+	return m.renderCache
+}
+
+// RenderCached renders the named template like Render, but the page is cached under the name and the
+// key, e.g. the language of the page, with its compressed variants, see Makross.SetRenderCache.
+// The page must be the same for all the users. A compressed variant is sent with its Content-Encoding,
+// and the compress middlewares leave it as is. Without render cache, it is the same as Render.
+func (c *Context) RenderCached(name, key string, status ...int) error {
+	rc := c.makross.renderCache
+	if rc == nil {
+		return c.Render(name, status...)
+	}
+	var code int
+	if len(status) > 0 {
+		code = status[0]
+	} else {
+		code = c.Response.status()
+	}
+	if c.Response.Committed {
+		return ErrResponseAlreadyCommitted
+	}
+
+	e := rc.get(name, key)
+	if e == nil {
+		if c.makross.renderer == nil {
+			return ErrRendererNotRegistered
+		}
+		buf := new(bytes.Buffer)
+		if err := c.makross.renderer.Render(buf, name, c); err != nil {
+			return err
+		}
+		var err error
+		if e, err = rc.add(name, key, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	header := c.Response.Header()
+	addVary(header, HeaderAcceptEncoding)
+	header.Set(HeaderContentType, MIMETextHTMLCharsetUTF8)
+	body := e.body
+	if encoding := rc.negotiate(c.Request.Header.Get(HeaderAcceptEncoding)); encoding != "" {
+		body = e.variants[encoding]
+		header.Set(HeaderContentEncoding, encoding)
+		header.Set(HeaderContentLength, strconv.Itoa(len(body)))
+		for w := http.ResponseWriter(c.Response); w != nil; w = unwrapWriter(w) {
+			if p, ok := w.(PassthroughWriter); ok {
+				p.Passthrough()
+			}
+		}
+	}
+	c.Response.WriteHeader(code)
+	err := c.Write(body)
+	c.Abort()
+	return err
+}
+
+// addVary adds the header name to the Vary header, unless it is listed already.
+func addVary(header http.Header, name string) {
+	for _, v := range header[HeaderVary] {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return
+			}
+		}
+	}
+	header.Add(HeaderVary, name)
+}
@@ -0,0 +1,96 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRenderer renders the name of the template and the number of renders.
+type countingRenderer struct {
+	n int
+}
+
+func (r *countingRenderer) Render(w io.Writer, name string, c *Context) error {
+	r.n++
+	_, err := fmt.Fprintf(w, "<p>%v %v</p>", name, r.n)
+	return err
+}
+
+func TestRenderCached(t *testing.T) {
+	m := New()
+	renderer := &countingRenderer{}
+	m.SetRenderer(renderer)
+	reverse := EncoderFunc("rev", func(b []byte) ([]byte, error) {
+		r := make([]byte, len(b))
+		for i := range b {
+			r[len(b)-1-i] = b[i]
+		}
+		return r, nil
+	})
+	now := time.Now()
+	rc := NewRenderCache(time.Minute, reverse, GzipEncoder(gzip.BestCompression))
+	rc.now = func() time.Time { return now }
+	m.Get("/<lang>", func(c *Context) error {
+		return c.RenderCached("home", c.Param("lang").String())
+	})
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(GET, path, nil)
+		req.Header.Set(HeaderAcceptEncoding, acceptEncoding)
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	// without render cache, the page is rendered every time
+	assert.Equal(t, "<p>home 1</p>", request("/en", "gzip").Body.String())
+	assert.Equal(t, "<p>home 2</p>", request("/en", "gzip").Body.String())
+
+	m.SetRenderCache(rc)
+	res := request("/en", "gzip, deflate")
+	assert.Equal(t, StatusOK, res.Code)
+	assert.Equal(t, "gzip", res.Header().Get(HeaderContentEncoding))
+	assert.Equal(t, []string{HeaderAcceptEncoding}, res.Header()[HeaderVary])
+	assert.Equal(t, MIMETextHTMLCharsetUTF8, res.Header().Get(HeaderContentType))
+	assert.Equal(t, fmt.Sprint(res.Body.Len()), res.Header().Get(HeaderContentLength))
+	r, err := gzip.NewReader(res.Body)
+	if assert.Nil(t, err) {
+		body, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "<p>home 3</p>", string(body))
+	}
+
+	// the variants are served from the cache
+	res = request("/en", "")
+	assert.Equal(t, "", res.Header().Get(HeaderContentEncoding))
+	assert.Equal(t, "<p>home 3</p>", res.Body.String())
+	res = request("/en", "gzip;q=0.5, rev")
+	assert.Equal(t, "rev", res.Header().Get(HeaderContentEncoding))
+	assert.Equal(t, ">p/<3 emoh>p<", res.Body.String())
+	// the first encoder is preferred
+	assert.Equal(t, "rev", request("/en", "*").Header().Get(HeaderContentEncoding))
+	assert.Equal(t, "gzip", request("/en", "*, rev;q=0").Header().Get(HeaderContentEncoding))
+	assert.Equal(t, "", request("/en", "gzip;q=0").Header().Get(HeaderContentEncoding))
+	assert.Equal(t, 3, renderer.n)
+
+	// the keys are cached separately
+	assert.Equal(t, "<p>home 4</p>", request("/fr", "").Body.String())
+	assert.Equal(t, "<p>home 3</p>", request("/en", "").Body.String())
+
+	rc.Purge("en")
+	assert.Equal(t, "<p>home 5</p>", request("/en", "").Body.String())
+	assert.Equal(t, "<p>home 4</p>", request("/fr", "").Body.String())
+
+	now = now.Add(time.Minute + time.Second)
+	assert.Equal(t, "<p>home 6</p>", request("/fr", "").Body.String())
+
+	rc.PurgeAll()
+	assert.Equal(t, "<p>home 7</p>", request("/fr", "").Body.String())
+}
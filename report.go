@@ -0,0 +1,186 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type (
+	// ErrorReport is a snapshot of a failed request handed to the error reporters registered by OnError.
+	// It is safe to use after the response has been sent, as it doesn't reference the live Context.
+	ErrorReport struct {
+		Time      time.Time
+		Err       error
+		Stack     []byte // call stack of a recovered panic, nil for returned errors
+		Status    int
+		Method    string
+		URL       string      // request URL with any password redacted
+		Header    http.Header // request headers with the SensitiveHeaders filtered
+		Route     string      // name of the matching route, or its path if the route is unnamed
+		RequestID string
+		RealIP    string
+	}
+
+	// ErrorReporter pushes an ErrorReport to an error tracker.
+	ErrorReporter func(*ErrorReport)
+)
+
+var (
+	// SensitiveHeaders lists the request headers whose values are filtered out of an ErrorReport.
+	SensitiveHeaders = []string{
+		HeaderAuthorization,
+		HeaderCookie,
+		HeaderSetCookie,
+		HeaderXCSRFToken,
+		"Proxy-Authorization",
+		"X-Api-Key",
+	}
+
+	// ErrorReportQueueSize is the number of reports that can wait for the reporters.
+	// Reports are dropped when the queue is full so that a slow reporter can't add latency to requests.
+	ErrorReportQueueSize = 64
+)
+
+const filteredValue = "[FILTERED]"
+
+// OnError registers reporters that are notified of panics and of errors with a status of 500 or above.
+// Reporters run asynchronously on a bounded queue, one report at a time, in the order they were registered.
+// The queue is stopped by Shutdown, which waits for the queued reports, and by Close.
+func (m *Makross) OnError(reporters ...ErrorReporter) {
+	m.reportMu.Lock()
+	defer m.reportMu.Unlock()
+	m.reporters = append(m.reporters, reporters...)
+	if m.reports == nil {
+		m.reports = make(chan *ErrorReport, ErrorReportQueueSize)
+		m.reportsDone = make(chan struct{})
+		go m.runErrorReporters(m.reports, m.reportsDone)
+	}
+}
+
+// ReportError queues a report of the given error for the registered reporters.
+// The stack is the call stack of a recovered panic, if any. The error is reported at most once per request.
+func (m *Makross) ReportError(c *Context, err error, stack []byte) {
+	if c.errorReported {
+		return
+	}
+	c.errorReported = true
+
+	m.reportMu.Lock()
+	reporting := m.reports != nil
+	m.reportMu.Unlock()
+	if !reporting {
+		return
+	}
+	report := NewErrorReport(c, err, stack)
+
+	// the queue is only sent to under the lock, so that it can't be closed meanwhile
+	m.reportMu.Lock()
+	defer m.reportMu.Unlock()
+	select {
+	case m.reports <- report:
+	default:
+		// the queue is full or stopped, drop the report
+	}
+}
+
+func (m *Makross) runErrorReporters(reports <-chan *ErrorReport, done chan<- struct{}) {
+	defer close(done)
+	for report := range reports {
+		m.reportMu.Lock()
+		reporters := m.reporters
+		m.reportMu.Unlock()
+		for _, reporter := range reporters {
+			runErrorReporter(reporter, report)
+		}
+	}
+}
+
+// stopErrorReporters closes the report queue and waits for the queued reports to be handed to the reporters,
+// until the context is done. Reporters registered afterwards by OnError start a new queue.
+func (m *Makross) stopErrorReporters(ctx context.Context) error {
+	m.reportMu.Lock()
+	if m.reports == nil {
+		m.reportMu.Unlock()
+		return nil
+	}
+	// the goroutine stops once the queue is empty
+	close(m.reports)
+	m.reports = nil
+	done := m.reportsDone
+	m.reportMu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func runErrorReporter(reporter ErrorReporter, report *ErrorReport) {
+	defer func() {
+		recover()
+	}()
+	reporter(report)
+}
+
+// ReportError queues a report of the given error. See Makross.ReportError.
+func (c *Context) ReportError(err error, stack []byte) {
+	c.makross.ReportError(c, err, stack)
+}
+
+// NewErrorReport creates a sanitized snapshot of the current request for error reporting.
+func NewErrorReport(c *Context, err error, stack []byte) *ErrorReport {
+	report := &ErrorReport{
+		Time:   time.Now(),
+		Err:    err,
+		Stack:  stack,
+		Status: StatusInternalServerError,
+	}
+	if he, ok := err.(*HTTPError); ok {
+		report.Status = he.Status
+	}
+	report.Route = c.routeName()
+
+	req := c.Request
+	if req == nil {
+		return report
+	}
+	report.Method = req.Method
+	if req.URL != nil {
+		report.URL = req.URL.Redacted()
+	}
+	report.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		report.Header[k] = append([]string(nil), v...)
+	}
+	for _, k := range SensitiveHeaders {
+		if _, ok := report.Header[http.CanonicalHeaderKey(k)]; ok {
+			report.Header.Set(k, filteredValue)
+		}
+	}
+	report.RequestID = req.Header.Get(HeaderXRequestID)
+	if report.RequestID == "" && c.Response.Writer != nil {
+		report.RequestID = c.Response.Header().Get(HeaderXRequestID)
+	}
+	report.RealIP = c.RealIP()
+	return report
+}
+
+// LogErrorReporter returns an ErrorReporter that writes the reports with the given log function.
+// It also serves as an example of an adapter for an error tracking service.
+//
+//	m.OnError(makross.LogErrorReporter(log.Printf))
+func LogErrorReporter(logf func(format string, a ...interface{})) ErrorReporter {
+	return func(r *ErrorReport) {
+		msg := fmt.Sprintf("[%d] %s %s route=%q id=%q ip=%s: %v", r.Status, r.Method, r.URL, r.Route, r.RequestID, r.RealIP, r.Err)
+		if len(r.Stack) > 0 {
+			msg += "\n" + string(r.Stack)
+		}
+		logf("%s", msg)
+	}
+}
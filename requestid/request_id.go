@@ -1,6 +1,8 @@
 package requestid
 
 import (
+	"context"
+
 	"github.com/insionng/makross"
 	"github.com/insionng/makross/libraries/gommon/random"
 	"github.com/insionng/makross/skipper"
@@ -16,8 +18,14 @@ type (
 		// Optional. Default value random.String(32).
 		Generator func() string
 	}
+
+	// ContextKey is the type of the keys of the values stored in the standard context by the middleware.
+	ContextKey string
 )
 
+// RequestIDKey is the key of the request ID in the standard context returned by Context.Kontext.
+const RequestIDKey ContextKey = "request_id"
+
 var (
 	// DefaultRequestIDConfig is the default RequestID middleware config.
 	DefaultRequestIDConfig = RequestIDConfig{
@@ -27,6 +35,10 @@ var (
 )
 
 // RequestID returns a X-Request-ID middleware.
+//
+// The request ID is sent in the X-Request-ID header, stored in the standard context under RequestIDKey,
+// added to the lines of the request logger, see `Context#Logger()`, and to the JSON error responses
+// as the "request_id" field, so that the users can quote it to the support.
 func RequestID() makross.Handler {
 	return RequestIDWithConfig(DefaultRequestIDConfig)
 }
@@ -53,12 +65,20 @@ func RequestIDWithConfig(config RequestIDConfig) makross.Handler {
 			rid = config.Generator()
 		}
 		res.Header().Set(makross.HeaderXRequestID, rid)
+		c.WithValue(RequestIDKey, rid)
+		c.SetLogger(c.Logger().With(map[string]interface{}{string(RequestIDKey): rid}))
 
 		return c.Next()
 	}
 
 }
 
+// FromContext returns the request ID stored in the given standard context, or an empty string if there is none.
+func FromContext(ctx context.Context) string {
+	rid, _ := ctx.Value(RequestIDKey).(string)
+	return rid
+}
+
 func generator() string {
 	return random.String(32)
 }
@@ -1,6 +1,9 @@
 package requestid
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"net/http/httptest"
 	"testing"
 
@@ -28,3 +31,43 @@ func TestRequestID(t *testing.T) {
 	h(c)
 	assert.Equal(t, rec.Header().Get(makross.HeaderXRequestID), "customGenerator")
 }
+
+func TestRequestIDContext(t *testing.T) {
+	e := makross.New()
+	req := httptest.NewRequest(makross.GET, "/", nil)
+	req.Header.Set(makross.HeaderXRequestID, "abc")
+	rec := httptest.NewRecorder()
+
+	// a database call which only gets the standard context
+	var queried string
+	query := func(ctx context.Context) {
+		queried, _ = ctx.Value(RequestIDKey).(string)
+	}
+	c := e.NewContext(req, rec, RequestID(), func(c *makross.Context) error {
+		query(c.Kontext())
+		return c.String(FromContext(c.Kontext()))
+	})
+	c.Next()
+	assert.Equal(t, "abc", queried)
+	assert.Equal(t, "abc", rec.Body.String())
+}
+
+func TestRequestIDLogger(t *testing.T) {
+	var buf bytes.Buffer
+	m := makross.New()
+	m.SetLogger(makross.NewLogger(&buf))
+	m.Use(RequestID())
+	m.Get("/", func(c *makross.Context) error {
+		c.Logger().Infof("loading %s", "orders")
+		return errors.New("database unavailable")
+	})
+
+	req := httptest.NewRequest(makross.GET, "/", nil)
+	req.Header.Set(makross.HeaderXRequestID, "abc")
+	req.Header.Set(makross.HeaderAccept, makross.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Contains(t, buf.String(), "INFO loading orders request_id=abc\n")
+	assert.Equal(t, makross.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"status": 500, "message": "database unavailable", "request_id": "abc"}`, rec.Body.String())
+}
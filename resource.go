@@ -0,0 +1,79 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ResourceOptions configures the routes registered by RouteGroup.Resource.
+type ResourceOptions struct {
+	// Param is the name of the path parameter holding the ID of the resource. Default value "id".
+	Param string
+
+	// Middleware are run before all the actions of the resource.
+	Middleware []Handler
+
+	// ActionMiddleware are run before the named action, after Middleware, e.g. "create" or "delete".
+	ActionMiddleware map[string][]Handler
+}
+
+// resourceActions are the controller methods handled by Resource, in order of registration.
+var resourceActions = []struct {
+	method, action, verb string
+	member               bool
+}{
+	{"Index", "index", GET, false},
+	{"Create", "create", POST, false},
+	{"Show", "show", GET, true},
+	{"Update", "update", PUT, true},
+	{"Patch", "patch", PATCH, true},
+	{"Delete", "delete", DELETE, true},
+}
+
+// Resource registers the conventional REST routes of the controller methods with the Handler
+// signature, named after the resource and the action:
+//
+//	Index  GET    /users       users.index
+//	Create POST   /users       users.create
+//	Show   GET    /users/<id>  users.show
+//	Update PUT    /users/<id>  users.update
+//	Patch  PATCH  /users/<id>  users.patch
+//	Delete DELETE /users/<id>  users.delete
+//
+// The controller may implement any subset of these methods; its other methods are ignored.
+// Resource panics if one of these methods doesn't have the Handler signature.
+// It returns the registered routes, e.g. for `m.Resource("users", &Users{})`.
+func (rg *RouteGroup) Resource(name string, controller interface{}, options ...ResourceOptions) []*Route {
+	var opts ResourceOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Param == "" {
+		opts.Param = "id"
+	}
+
+	v := reflect.ValueOf(controller)
+	path := "/" + strings.Trim(name, "/")
+	routes := make([]*Route, 0, len(resourceActions))
+	for _, a := range resourceActions {
+		method := v.MethodByName(a.method)
+		if !method.IsValid() {
+			continue
+		}
+		h, ok := method.Interface().(func(*Context) error)
+		if !ok {
+			panic(fmt.Sprintf("makross: %T.%s must be a func(*makross.Context) error, got %s", controller, a.method, method.Type()))
+		}
+		handlers := combineHandlers(opts.Middleware, opts.ActionMiddleware[a.action])
+		handlers = append(handlers, h)
+		p := path
+		if a.member {
+			p += "/<" + opts.Param + ">"
+		}
+		routes = append(routes, rg.To(a.verb, p, handlers...).Name(name+"."+a.action))
+	}
+	return routes
+}
@@ -0,0 +1,75 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type articles struct{}
+
+func (articles) Index(c *Context) error  { return c.String("index") }
+func (articles) Show(c *Context) error   { return c.String("show " + c.Param("slug").String()) }
+func (articles) Delete(c *Context) error { return c.String("delete " + c.Param("slug").String()) }
+func (articles) Publish(c *Context) error {
+	return c.String("publish")
+}
+func (articles) Helper() string { return "" }
+
+type badController struct{}
+
+func (badController) Show(id int) string { return "" }
+
+func TestResource(t *testing.T) {
+	m := New()
+	var calls []string
+	api := m.Group("/api")
+	routes := api.Resource("articles", articles{}, ResourceOptions{
+		Param: "slug",
+		Middleware: []Handler{func(c *Context) error {
+			calls = append(calls, "all")
+			return c.Next()
+		}},
+		ActionMiddleware: map[string][]Handler{
+			"delete": {func(c *Context) error {
+				calls = append(calls, "delete")
+				return c.Next()
+			}},
+		},
+	})
+	if !assert.Len(t, routes, 3) {
+		return
+	}
+	assert.Equal(t, "GET /api/articles", routes[0].String())
+	assert.Equal(t, "GET /api/articles/<slug>", routes[1].String())
+	assert.Equal(t, "DELETE /api/articles/<slug>", routes[2].String())
+	assert.Len(t, m.Routes(), 3)
+	assert.Equal(t, "/api/articles/hello", m.Route("articles.show").URL("slug", "hello"))
+	assert.Equal(t, "/api/articles", m.Route("articles.index").URL())
+	assert.Nil(t, m.Route("articles.create"))
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+		return res
+	}
+	assert.Equal(t, "index", serve(GET, "/api/articles").Body.String())
+	assert.Equal(t, "show hello", serve(GET, "/api/articles/hello").Body.String())
+	assert.Equal(t, []string{"all", "all"}, calls)
+	calls = nil
+	assert.Equal(t, "delete hello", serve(DELETE, "/api/articles/hello").Body.String())
+	assert.Equal(t, []string{"all", "delete"}, calls)
+	assert.Equal(t, StatusMethodNotAllowed, serve(POST, "/api/articles").Code)
+	assert.Equal(t, StatusNotFound, serve(GET, "/api/articles/hello/publish").Code)
+
+	// the default path parameter
+	m.Resource("users", &articles{})
+	assert.Equal(t, "/users/42", m.Route("users.show").URL("id", 42))
+
+	assert.Panics(t, func() {
+		m.Resource("bad", badController{})
+	})
+}
@@ -2,7 +2,6 @@ package makross
 
 import (
 	"bufio"
-	"log"
 	"net"
 	"net/http"
 )
@@ -17,6 +16,11 @@ type (
 		Size      int64
 		Committed bool
 		makross   *Makross
+		beforeFns []func()
+		afterFns  []func()
+		statusSet bool // the status was set with SetStatus
+		hijacked  bool // the connection was taken over with Hijack
+		finished  bool // the After functions were called
 	}
 )
 
@@ -31,8 +35,29 @@ func NewResponse(w http.ResponseWriter, m *Makross) (r *Response) {
 // the "Trailer" header before the call to WriteHeader (see example)
 // To suppress implicit response headers, set their value to nil.
 // Example: https://golang.org/pkg/net/http/#example_ResponseWriter_trailers
+//
+// It is the header of the innermost writer, reached through the wrapping writers implementing
+// `Unwrap() http.ResponseWriter`, so that the headers set at any depth of wrapping, such as the
+// Set-Cookie headers of several middlewares, are all sent, whichever writer writes the header.
 func (r *Response) Header() http.Header {
-	return r.Writer.Header()
+	w := r.Writer
+	for next := unwrapWriter(w); next != nil; next = unwrapWriter(w) {
+		w = next
+	}
+	return w.Header()
+}
+
+// Before registers a function which is called just before the response header is written, with
+// the status being written in Status. It is the place to finalize response headers, such as ETag or timing headers.
+func (r *Response) Before(fn func()) {
+	r.beforeFns = append(r.beforeFns, fn)
+}
+
+// After registers a function which is called once the response is finished, after the handlers returned
+// and their error was handled, with the final Status and Size, e.g. to record the size of the responses.
+// It isn't called if the response wasn't committed, if the connection was hijacked, or if a handler panicked.
+func (r *Response) After(fn func()) {
+	r.afterFns = append(r.afterFns, fn)
 }
 
 // WriteHeader sends an HTTP response header with status code. If WriteHeader is
@@ -40,25 +65,72 @@ func (r *Response) Header() http.Header {
 // WriteHeader(http.StatusOK). Thus explicit calls to WriteHeader are mainly
 // used to send error codes.
 func (r *Response) WriteHeader(code int) {
+	if r.hijacked {
+		return
+	}
 	if r.Committed {
-		log.Println("[Makross] response already committed")
+		r.logger().Warnf("response already committed")
 		return
 	}
 	r.Status = code
+	for _, fn := range r.beforeFns {
+		fn()
+	}
 	r.Writer.WriteHeader(code)
 	r.Committed = true
 }
 
+// SetStatus sets the status code sent by the first write, or by the Context helpers called without status,
+// e.g. when a middleware decides the status and a generic writer writes the body later.
+func (r *Response) SetStatus(code int) {
+	if r.Committed {
+		r.logger().Warnf("response already committed")
+		return
+	}
+	r.Status = code
+	r.statusSet = true
+}
+
+// logger returns the logger of the application, or the default one if the response has none.
+func (r *Response) logger() Logger {
+	if r.makross == nil {
+		return defaultLogger
+	}
+	return r.makross.Logger()
+}
+
+// status returns the status code to send when the header is written implicitly.
+func (r *Response) status() int {
+	if r.Status == 0 {
+		return StatusOK
+	}
+	return r.Status
+}
+
 // Write writes the data to the connection as part of an HTTP reply.
 func (r *Response) Write(b []byte) (n int, err error) {
+	if r.hijacked {
+		return 0, http.ErrHijacked
+	}
 	if !r.Committed {
-		r.WriteHeader(StatusOK)
+		r.WriteHeader(r.status())
 	}
 	n, err = r.Writer.Write(b)
 	r.Size += int64(n)
 	return
 }
 
+// finish calls the After functions, once, if the response was committed.
+func (r *Response) finish() {
+	if r.finished || !r.Committed || r.hijacked {
+		return
+	}
+	r.finished = true
+	for _, fn := range r.afterFns {
+		fn()
+	}
+}
+
 // Flush implements the http.Flusher interface to allow an HTTP handler to flush
 // buffered data to the client.
 // See [http.Flusher](https://golang.org/pkg/net/http/#Flusher)
@@ -67,10 +139,53 @@ func (r *Response) Flush() {
 }
 
 // Hijack implements the http.Hijacker interface to allow an HTTP handler to
-// take over the connection.
+// take over the connection. The wrapped writers are unwrapped down to the writer of the server,
+// see Hijack. ErrHijackNotSupported is returned if it can't be hijacked, e.g. on HTTP/2.
+// Once hijacked, the response is committed: nothing more is written to the connection,
+// by the response or its Before and After functions, which belongs to the handler.
 // See [http.Hijacker](https://golang.org/pkg/net/http/#Hijacker)
 func (r *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	return r.Writer.(http.Hijacker).Hijack()
+	conn, rw, err := hijack(r.Writer)
+	if err == nil {
+		r.Committed = true
+		r.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped response writer.
+func (r *Response) Unwrap() http.ResponseWriter {
+	return r.Writer
+}
+
+// Hijack takes over the connection of the request, through the response writers wrapping the
+// writer of the server, such as the ones of the compress or sniff middlewares, which must implement
+// `Unwrap() http.ResponseWriter`. ErrHijackNotSupported is returned if the writer of the server
+// can't be hijacked, e.g. on HTTP/2.
+func Hijack(c *Context) (net.Conn, *bufio.ReadWriter, error) {
+	return c.Response.Hijack()
+}
+
+// hijack hijacks the innermost writer of the response writer.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	for next := unwrapWriter(w); next != nil; next = unwrapWriter(w) {
+		w = next
+	}
+	if h, ok := w.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, ErrHijackNotSupported
+}
+
+// unwrapWriter returns the writer wrapped by the response writer, if known.
+func unwrapWriter(w http.ResponseWriter) http.ResponseWriter {
+	switch w := w.(type) {
+	case *Response:
+		return w.Writer
+	case interface{ Unwrap() http.ResponseWriter }:
+		return w.Unwrap()
+	}
+	return nil
 }
 
 // CloseNotify implements the http.CloseNotifier interface to allow detecting
@@ -82,9 +197,21 @@ func (r *Response) CloseNotify() <-chan bool {
 	return r.Writer.(http.CloseNotifier).CloseNotify()
 }
 
+// flush flushes buffered data if the underlying writer supports it.
+func (r *Response) flush() {
+	if f, ok := r.Writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (r *Response) reset(w http.ResponseWriter) {
 	r.Writer = w
 	r.Size = 0
 	r.Status = StatusOK
 	r.Committed = false
+	r.beforeFns = nil
+	r.afterFns = nil
+	r.statusSet = false
+	r.hijacked = false
+	r.finished = false
 }
@@ -0,0 +1,267 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseBeforeAfter(t *testing.T) {
+	c, res := testNewContext()
+	var calls []string
+	c.OnBeforeResponse(func() {
+		calls = append(calls, "before")
+		assert.False(t, c.Response.Committed)
+		assert.False(t, res.Flushed)
+		c.Response.Header().Set("ETag", `"abc"`)
+	})
+	c.Response.After(func() {
+		calls = append(calls, "after")
+	})
+
+	assert.Nil(t, c.String("ok", StatusCreated))
+	assert.Equal(t, []string{"before"}, calls)
+	assert.Equal(t, StatusCreated, res.Code)
+	assert.Equal(t, `"abc"`, res.Header().Get("ETag"))
+	// the After functions are called once the response is finished
+	c.Response.Write([]byte("!"))
+	c.Response.finish()
+	c.Response.finish()
+	assert.Equal(t, []string{"before", "after"}, calls)
+
+	// the hooks don't survive a reset of the context
+	calls = nil
+	c.Reset(res, c.Request)
+	c.Response.WriteHeader(StatusOK)
+	assert.Nil(t, calls)
+
+	// writing the header twice is logged with the logger of the application
+	buf := new(bytes.Buffer)
+	c.Makross().SetLogger(NewLogger(buf))
+	c.Response.WriteHeader(StatusOK)
+	c.Response.SetStatus(StatusOK)
+	assert.Equal(t, 2, strings.Count(buf.String(), "WARN response already committed"))
+}
+
+// hidingWriter and embedWriter wrap response writers like the middlewares do, the first
+// one hiding the Hijacker of the writer.
+type (
+	hidingWriter struct {
+		w http.ResponseWriter
+	}
+
+	embedWriter struct {
+		http.ResponseWriter
+	}
+)
+
+func (w *hidingWriter) Header() http.Header         { return w.w.Header() }
+func (w *hidingWriter) Write(b []byte) (int, error) { return w.w.Write(b) }
+func (w *hidingWriter) WriteHeader(code int)        { w.w.WriteHeader(code) }
+func (w *hidingWriter) Unwrap() http.ResponseWriter { return w.w }
+func (w *embedWriter) Unwrap() http.ResponseWriter  { return w.ResponseWriter }
+
+func TestHijack(t *testing.T) {
+	m := New()
+	m.Use(func(c *Context) error {
+		c.Response.Writer = &embedWriter{c.Response.Writer}
+		return c.Next()
+	}, func(c *Context) error {
+		c.Response.Writer = &hidingWriter{NewResponse(c.Response.Writer, m)}
+		return c.Next()
+	})
+	m.Get("/", func(c *Context) error {
+		conn, rw, err := Hijack(c)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		return rw.Flush()
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if assert.Nil(t, err) {
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, "hijacked", string(body))
+	}
+
+	// the recorder can't be hijacked
+	c, _ := testNewContext()
+	c.Response.Writer = &hidingWriter{c.Response.Writer}
+	_, _, err = Hijack(c)
+	assert.Equal(t, ErrHijackNotSupported, err)
+	_, _, err = c.Response.Hijack()
+	assert.Equal(t, ErrHijackNotSupported, err)
+}
+
+func TestResponseAfterOnce(t *testing.T) {
+	m := New()
+	var sizes []int64
+	m.Get("/stream", func(c *Context) error {
+		c.Response.After(func() {
+			sizes = append(sizes, c.Response.Size)
+		})
+		for i := 0; i < 3; i++ {
+			c.Response.Write([]byte("chunk"))
+			c.Response.flush()
+		}
+		return nil
+	})
+	m.Get("/error", func(c *Context) error {
+		c.Response.After(func() {
+			sizes = append(sizes, int64(c.Response.Status))
+		})
+		return ErrNotFound
+	})
+	m.Get("/none", func(c *Context) error {
+		c.Response.After(func() {
+			sizes = append(sizes, -1)
+		})
+		return nil
+	})
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/stream", nil))
+	assert.Equal(t, []int64{15}, sizes)
+	// the error is handled before
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/error", nil))
+	assert.Equal(t, []int64{15, StatusNotFound}, sizes)
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/none", nil))
+	assert.Equal(t, []int64{15, StatusNotFound}, sizes)
+}
+
+func TestHijackCommits(t *testing.T) {
+	m := New()
+	m.SetEmptyStatus()
+	hooks := 0
+	m.Get("/", func(c *Context) error {
+		c.OnBeforeResponse(func() { hooks++ })
+		c.Response.After(func() { hooks++ })
+		conn, rw, err := c.Response.Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		assert.True(t, c.Response.Committed)
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		// nothing more is written to the connection
+		c.Response.WriteHeader(StatusOK)
+		_, err = c.Response.Write([]byte("more"))
+		assert.Equal(t, http.ErrHijacked, err)
+		return nil
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if assert.Nil(t, err) {
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, "hijacked", string(body))
+	}
+	assert.Equal(t, 0, hooks)
+}
+
+// copyingWriter holds a copy of the header, which it sends when the header is written, like some
+// wrapping writers do. bufferingWriter holds the response back until it is sent.
+type (
+	copyingWriter struct {
+		http.ResponseWriter
+		header http.Header
+	}
+
+	bufferingWriter struct {
+		http.ResponseWriter
+		code int
+		body []byte
+	}
+)
+
+func (w *copyingWriter) Header() http.Header { return w.header }
+func (w *copyingWriter) WriteHeader(code int) {
+	for k, v := range w.header {
+		w.ResponseWriter.Header()[k] = v
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+func (w *copyingWriter) Flush()                      { w.ResponseWriter.(http.Flusher).Flush() }
+func (w *copyingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *bufferingWriter) WriteHeader(code int) { w.code = code }
+func (w *bufferingWriter) Write(b []byte) (int, error) {
+	w.body = append(w.body, b...)
+	return len(b), nil
+}
+func (w *bufferingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+func (w *bufferingWriter) send() {
+	w.ResponseWriter.WriteHeader(w.code)
+	w.ResponseWriter.Write(w.body)
+}
+
+func TestResponseSetCookies(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		m := New()
+		m.SetResponseHeaders(map[string]string{HeaderSetCookie: "global=1"})
+		m.Use(func(c *Context) error {
+			// the writers wrapping the response at several depths
+			c.Response.Writer = &copyingWriter{c.Response.Writer, http.Header{}}
+			if streaming {
+				c.Response.Writer = &copyingWriter{c.Response.Writer, http.Header{}}
+				return c.Next()
+			}
+			w := &bufferingWriter{ResponseWriter: c.Response.Writer}
+			c.Response.Writer = &copyingWriter{w, http.Header{}}
+			err := c.Next()
+			w.send()
+			return err
+		}, func(c *Context) error {
+			c.SetCookie(&http.Cookie{Name: "pre", Value: "1"})
+			return c.Next()
+		}, func(c *Context) error {
+			if streaming {
+				// the header is sent by the handler
+				c.Response.Before(func() {
+					c.SetCookie(&http.Cookie{Name: "post", Value: "1"})
+				})
+				return c.Next()
+			}
+			err := c.Next()
+			c.SetCookie(&http.Cookie{Name: "post", Value: "1"})
+			return err
+		})
+		m.Get("/", func(c *Context) error {
+			c.SetCookie(&http.Cookie{Name: "handler", Value: "1"})
+			if err := c.String("ok"); err != nil {
+				return err
+			}
+			if streaming {
+				c.Response.Flush()
+			}
+			return nil
+		})
+
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(GET, "/", nil))
+		assert.Equal(t, "ok", res.Body.String())
+		var names []string
+		for _, cookie := range res.Result().Cookies() {
+			names = append(names, cookie.Name)
+		}
+		sort.Strings(names)
+		assert.Equal(t, []string{"global", "handler", "post", "pre"}, names, "streaming: %v", streaming)
+		assert.Equal(t, streaming, res.Flushed)
+	}
+}
@@ -6,15 +6,82 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 )
 
+// Route metadata keys of the media types declared with Route.Consumes and Route.Produces,
+// of the examples declared with Route.Example, and of the description set with Route.Describe.
+const (
+	MetaConsumes    = "consumes"
+	MetaProduces    = "produces"
+	MetaExamples    = "examples"
+	MetaDescription = "description"
+)
+
+var (
+	metaValidatorsMu sync.RWMutex
+	metaValidators   = map[string]func(interface{}) error{}
+)
+
+// RegisterMetaValidator registers the function validating the values of a route metadata key, which
+// replaces the one already registered for the key. Route.Meta and RouteGroup.Meta panic with its
+// error, so that an invalid value is reported when the routes are declared rather than when they are
+// requested. The middlewares reading the route metadata register one for their key when they are
+// created, e.g. the costs of the quota middleware, so they must be created before the routes are declared.
+func RegisterMetaValidator(key string, validate func(value interface{}) error) {
+	metaValidatorsMu.Lock()
+	metaValidators[key] = validate
+	metaValidatorsMu.Unlock()
+}
+
+// validateMeta panics if the value isn't valid for the metadata key, see RegisterMetaValidator.
+func validateMeta(key string, value interface{}) {
+	metaValidatorsMu.RLock()
+	validate := metaValidators[key]
+	metaValidatorsMu.RUnlock()
+	if validate == nil {
+		return
+	}
+	if err := validate(value); err != nil {
+		panic(err)
+	}
+}
+
+// Example is an example request to a route with its expected response. The examples document
+// the API and are executed by makrosstest.RunExamples.
+type Example struct {
+	// Name describes the example, e.g. "unknown user".
+	Name string `json:"name,omitempty"`
+
+	// Params are the values of the path parameters.
+	Params map[string]string `json:"params,omitempty"`
+
+	// Query is the query string, without the "?".
+	Query string `json:"query,omitempty"`
+
+	// Header are the request headers.
+	Header map[string]string `json:"header,omitempty"`
+
+	// Body is the request body, sent as is if it is a string or a []byte, as JSON otherwise.
+	Body interface{} `json:"body,omitempty"`
+
+	// Status is the expected status code.
+	Status int `json:"status"`
+
+	// Response is the expected response body: a string the body must contain, or a value whose
+	// JSON must be a subset of the body, e.g. the fields of an object which matter.
+	Response interface{} `json:"response,omitempty"`
+}
+
 // Route represents a URL path pattern that can be used to match requested URLs.
 type Route struct {
 	group          *RouteGroup
 	method, path   string
 	name, template string
 	tags           []interface{}
+	meta           map[string]interface{}
 	routes         []*Route
+	handlers       []Handler
 }
 
 // Name sets the name of the route.
@@ -22,6 +89,10 @@ type Route struct {
 func (r *Route) Name(name string) *Route {
 	r.name = name
 	r.group.makross.namedRoutes[name] = r
+	// this route may be a composite one (a path with multiple methods)
+	for _, route := range r.routes {
+		route.name = name
+	}
 	return r
 }
 
@@ -41,6 +112,69 @@ func (r *Route) Tag(value interface{}) *Route {
 	return r
 }
 
+// Meta associates a named piece of metadata with the route.
+// Middlewares may read it through Context.Route() to adapt their behavior to the route,
+// e.g. `m.Post("/login", login).Meta("ratelimit", "5/m")`. It panics if the value is invalid,
+// see RegisterMetaValidator.
+func (r *Route) Meta(key string, value interface{}) *Route {
+	validateMeta(key, value)
+	if len(r.routes) > 0 {
+		// this route is a composite one (a path with multiple methods)
+		for _, route := range r.routes {
+			route.Meta(key, value)
+		}
+		return r
+	}
+	if r.meta == nil {
+		r.meta = make(map[string]interface{})
+	}
+	r.meta[key] = value
+	return r
+}
+
+// Consumes declares the media types of the request bodies accepted by the route, e.g. "application/json".
+// The declared types are stored in the route metadata under MetaConsumes as a []string.
+func (r *Route) Consumes(mediaTypes ...string) *Route {
+	return r.Meta(MetaConsumes, mediaTypes)
+}
+
+// Produces declares the media types of the responses which the route can produce, in order of preference.
+// The declared types are stored in the route metadata under MetaProduces as a []string.
+func (r *Route) Produces(mediaTypes ...string) *Route {
+	return r.Meta(MetaProduces, mediaTypes)
+}
+
+// Example declares an example request to the route, see Example.
+// The examples are stored in the route metadata under MetaExamples as a []Example.
+func (r *Route) Example(examples ...Example) *Route {
+	if len(r.routes) > 0 {
+		// this route is a composite one (a path with multiple methods)
+		for _, route := range r.routes {
+			route.Example(examples...)
+		}
+		return r
+	}
+	existing, _ := r.meta[MetaExamples].([]Example)
+	return r.Meta(MetaExamples, append(existing, examples...))
+}
+
+// Describe sets the description of the route, e.g. "Returns the user of the ID", stored in the route
+// metadata under MetaDescription. It documents the route, e.g. in the route suggestions of the 404 responses
+// in debug mode, see Makross.RouteSuggestions.
+func (r *Route) Describe(description string) *Route {
+	return r.Meta(MetaDescription, description)
+}
+
+// GetMeta returns the named metadata associated with the route, or nil if there is none.
+func (r *Route) GetMeta(key string) interface{} {
+	return r.meta[key]
+}
+
+// GetName returns the name of the route, or an empty string if the route is unnamed.
+func (r *Route) GetName() string {
+	return r.name
+}
+
 // Method returns the HTTP method that this route is associated with.
 func (r *Route) Method() string {
 	return r.method
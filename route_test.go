@@ -4,6 +4,7 @@ package makross
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -20,7 +21,7 @@ func newMockStore() *mockStore {
 }
 
 func (s *mockStore) Add(key string, data interface{}) int {
-	for _, handler := range data.([]Handler) {
+	for _, handler := range data.(*Route).handlers {
 		handler(nil)
 	}
 	return s.store.Add(key, data)
@@ -96,6 +97,41 @@ func TestRouteAdd(t *testing.T) {
 	assert.Equal(t, "1.2.", buf.String(), "buf@3 =")
 }
 
+func TestRouteMeta(t *testing.T) {
+	m := New()
+	r := m.Get("/posts").Meta("ratelimit", "5/m").Name("posts")
+	assert.Equal(t, "5/m", r.GetMeta("ratelimit"))
+	assert.Nil(t, r.GetMeta("xyz"))
+	assert.Equal(t, "posts", r.GetName())
+
+	r = m.To("PUT,PATCH", "/comments").Meta("ratelimit", "1/s").Name("comments")
+	assert.Nil(t, r.GetMeta("ratelimit"))
+	for _, route := range r.routes {
+		assert.Equal(t, "1/s", route.GetMeta("ratelimit"))
+		assert.Equal(t, "comments", route.GetName())
+	}
+}
+
+func TestRegisterMetaValidator(t *testing.T) {
+	RegisterMetaValidator("test.positive", func(v interface{}) error {
+		if n, ok := v.(int); !ok || n <= 0 {
+			return errors.New("test.positive must be a positive int")
+		}
+		return nil
+	})
+	m := New()
+	assert.NotPanics(t, func() { m.Get("/ok").Meta("test.positive", 1) })
+	assert.Panics(t, func() { m.To("PUT,PATCH", "/comments").Meta("test.positive", "1") })
+	assert.Panics(t, func() { m.Group("/api").Meta("test.positive", 0) })
+}
+
+func TestRouteConsumesProduces(t *testing.T) {
+	m := New()
+	r := m.Post("/reports").Consumes(MIMEApplicationJSON).Produces(MIMEApplicationJSON, "text/csv")
+	assert.Equal(t, []string{MIMEApplicationJSON}, r.GetMeta(MetaConsumes))
+	assert.Equal(t, []string{MIMEApplicationJSON, "text/csv"}, r.GetMeta(MetaProduces))
+}
+
 func TestRouteTag(t *testing.T) {
 	makross := New()
 	makross.Get("/posts").Tag("posts")
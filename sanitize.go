@@ -0,0 +1,282 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"html"
+	"html/template"
+	"strings"
+)
+
+type (
+	// Sanitizer cleans untrusted HTML, such as user comments, so that it is safe to render.
+	// A bluemonday policy satisfies this interface.
+	Sanitizer interface {
+		Sanitize(s string) string
+	}
+
+	// AllowlistSanitizer is a minimal Sanitizer which keeps the allowed elements and attributes only.
+	// Scripts and other active content are removed with their content, and URLs are restricted
+	// to the allowed schemes, so event handlers and javascript: URLs never go through.
+	AllowlistSanitizer struct {
+		// Elements maps the allowed elements to their allowed attributes.
+		Elements map[string][]string
+
+		// URLSchemes are the schemes allowed in the href and src attributes.
+		// Relative URLs are always allowed.
+		URLSchemes []string
+	}
+)
+
+var (
+	// DefaultSanitizer is the sanitizer used when none is set with Makross.SetSanitizer.
+	// It allows basic formatting, lists, quotes, links and images.
+	DefaultSanitizer Sanitizer = &AllowlistSanitizer{
+		Elements: map[string][]string{
+			"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": nil, "br": nil, "code": nil,
+			"del": nil, "div": nil, "em": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+			"hr": nil, "i": nil, "img": {"src", "alt", "title", "width", "height"}, "ins": nil, "li": nil,
+			"ol": nil, "p": nil, "pre": nil, "q": nil, "s": nil, "small": nil, "span": nil, "strong": nil,
+			"sub": nil, "sup": nil, "u": nil, "ul": nil,
+		},
+		URLSchemes: []string{"http", "https", "mailto"},
+	}
+
+	// sanitizerDropContent lists the elements whose content is removed along with them.
+	sanitizerDropContent = map[string]bool{
+		"script": true, "style": true, "iframe": true, "object": true, "embed": true, "noscript": true,
+		"template": true, "textarea": true, "title": true, "svg": true, "math": true, "xmp": true,
+		"noembed": true, "noframes": true, "frameset": true, "applet": true,
+	}
+)
+
+// Sanitize implements Sanitizer.
+func (s *AllowlistSanitizer) Sanitize(in string) string {
+	var out strings.Builder
+	drop := "" // element whose content is being removed
+	for len(in) > 0 {
+		i := strings.IndexByte(in, '<')
+		if i < 0 {
+			i = len(in)
+		}
+		if drop == "" {
+			out.WriteString(html.EscapeString(html.UnescapeString(in[:i])))
+		}
+		in = in[i:]
+		if in == "" {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(in, "<!--"):
+			if end := strings.Index(in[4:], "-->"); end >= 0 {
+				in = in[4+end+3:]
+			} else {
+				in = ""
+			}
+			continue
+		case strings.HasPrefix(in, "<!"), strings.HasPrefix(in, "<?"):
+			if end := strings.IndexByte(in, '>'); end >= 0 {
+				in = in[end+1:]
+			} else {
+				in = ""
+			}
+			continue
+		}
+
+		t, rest, ok := parseTag(in)
+		if !ok {
+			// not a tag, a lone "<"
+			if drop == "" {
+				out.WriteString("&lt;")
+			}
+			in = in[1:]
+			continue
+		}
+		in = rest
+		if drop != "" {
+			if t.closing && t.name == drop {
+				drop = ""
+			}
+			continue
+		}
+		if sanitizerDropContent[t.name] {
+			if !t.closing && !t.selfClosing {
+				drop = t.name
+			}
+			continue
+		}
+		allowed, ok := s.Elements[t.name]
+		if !ok {
+			continue
+		}
+		if t.closing {
+			out.WriteString("</" + t.name + ">")
+			continue
+		}
+		out.WriteString("<" + t.name)
+		for _, a := range t.attrs {
+			if !containsString(allowed, a.name) {
+				continue
+			}
+			if (a.name == "href" || a.name == "src") && !s.allowURL(a.value) {
+				continue
+			}
+			out.WriteString(" " + a.name + `="` + html.EscapeString(a.value) + `"`)
+		}
+		if t.selfClosing {
+			out.WriteString(" /")
+		}
+		out.WriteString(">")
+	}
+	return out.String()
+}
+
+// allowURL reports whether the URL is relative or has one of the allowed schemes.
+func (s *AllowlistSanitizer) allowURL(u string) bool {
+	// browsers ignore whitespace and control characters, as in "java\tscript:"
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true
+	}
+	for _, scheme := range s.URLSchemes {
+		if strings.EqualFold(u[:i], scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+type (
+	htmlTag struct {
+		name        string
+		closing     bool
+		selfClosing bool
+		attrs       []htmlAttr
+	}
+
+	htmlAttr struct {
+		name, value string
+	}
+)
+
+// parseTag parses the tag at the start of s, returning the rest of s.
+// The tag and attribute names are lowercased and the attribute values unescaped.
+func parseTag(s string) (t htmlTag, rest string, ok bool) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && isTagNameByte(s[i]) {
+		i++
+	}
+	if i == start || !isASCIILetter(s[start]) {
+		return t, s, false
+	}
+	t.name = strings.ToLower(s[start:i])
+
+	for {
+		for i < len(s) && (isHTMLSpace(s[i]) || s[i] == '/') {
+			if s[i] == '/' {
+				t.selfClosing = true
+			}
+			i++
+		}
+		if i >= len(s) {
+			// unterminated tag, drop it
+			return htmlTag{}, "", true
+		}
+		if s[i] == '>' {
+			return t, s[i+1:], true
+		}
+		t.selfClosing = false
+
+		start = i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		a := htmlAttr{name: strings.ToLower(s[start:i])}
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return htmlTag{}, "", true
+				}
+				a.value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				a.value = s[start:i]
+			}
+			a.value = html.UnescapeString(a.value)
+		}
+		if a.name != "" {
+			t.attrs = append(t.attrs, a)
+		}
+	}
+}
+
+func isASCIILetter(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func isTagNameByte(b byte) bool {
+	return isASCIILetter(b) || '0' <= b && b <= '9' || b == '-'
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSanitizer sets the sanitizer used by `Context#SafeHTML()` and the "sanitize" template function.
+func (m *Makross) SetSanitizer(s Sanitizer) {
+	m.sanitizer = s
+}
+
+// Sanitizer returns the sanitizer of the instance, DefaultSanitizer if none is set.
+func (m *Makross) Sanitizer() Sanitizer {
+	if m.sanitizer == nil {
+		return DefaultSanitizer
+	}
+	return m.sanitizer
+}
+
+// sanitize is the "sanitize" template function.
+// It returns template.HTML so that html/template doesn't escape the result again.
+func (m *Makross) sanitize(s string) template.HTML {
+	return template.HTML(m.Sanitizer().Sanitize(s))
+}
+
+// SafeHTML sends an HTTP response with the untrusted HTML, such as a user comment,
+// cleaned by the sanitizer of the Makross instance.
+func (c *Context) SafeHTML(s string, status ...int) (err error) {
+	return c.Blob(MIMETextHTMLCharsetUTF8, []byte(c.makross.Sanitizer().Sanitize(s)), status...)
+}
@@ -0,0 +1,83 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{`<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{`a < b & c`, `a &lt; b &amp; c`},
+		{`<script>alert(1)</script>ok`, `ok`},
+		{`<SCRIPT SRC=//evil.example/x.js></SCRIPT>ok`, `ok`},
+		{`<scr<script>ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{`<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{`<img/src="x"/onerror="alert(1)">`, `<img src="x">`},
+		{`<p onclick="alert(1)" title="x">hi</p>`, `<p>hi</p>`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="&#106;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href=" javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`, `<a>x</a>`},
+		{`<a href="https://example.com/?a=1&amp;b=2" target="_blank">x</a>`, `<a href="https://example.com/?a=1&amp;b=2">x</a>`},
+		{`<a href="/about">x</a>`, `<a href="/about">x</a>`},
+		{`<a title='"><script>alert(1)</script>'>x</a>`, `<a title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">x</a>`},
+		{`<iframe src="https://evil.example"></iframe>ok`, `ok`},
+		{`<svg onload=alert(1)><circle/></svg>ok`, `ok`},
+		{`<style>body{display:none}</style>ok`, `ok`},
+		{`<!-- <script>alert(1)</script> -->ok`, `ok`},
+		{`<![CDATA[x]]>ok`, `ok`},
+		{`<div style="background:url(javascript:alert(1))">x</div>`, `<div>x</div>`},
+		{`<br/>`, `<br />`},
+		{`<img src="x" onerror="alert(1)`, ``},
+		{`&lt;script&gt;`, `&lt;script&gt;`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.out, DefaultSanitizer.Sanitize(test.in), test.in)
+	}
+}
+
+type upperSanitizer struct{}
+
+func (upperSanitizer) Sanitize(s string) string {
+	return strings.ToUpper(s)
+}
+
+func TestContextSafeHTML(t *testing.T) {
+	c, res := testNewContext()
+	assert.Nil(t, c.SafeHTML(`<b onmouseover="alert(1)">hi</b>`, StatusCreated))
+	assert.Equal(t, StatusCreated, res.Code)
+	assert.Equal(t, MIMETextHTMLCharsetUTF8, res.Header().Get(HeaderContentType))
+	assert.Equal(t, `<b>hi</b>`, res.Body.String())
+
+	// the policy is set per instance
+	c, res = testNewContext()
+	c.makross.SetSanitizer(upperSanitizer{})
+	assert.Nil(t, c.SafeHTML(`<b>hi</b>`))
+	assert.Equal(t, `<B>HI</B>`, res.Body.String())
+	assert.Equal(t, DefaultSanitizer, New().Sanitizer())
+}
+
+func TestSanitizeTemplateFunc(t *testing.T) {
+	m := New()
+	tmpl := template.Must(template.New("t").Funcs(m.TemplateFuncs()).Parse(`{{sanitize .}}`))
+	var buf bytes.Buffer
+	assert.Nil(t, tmpl.Execute(&buf, `<i>a</i><script>alert(1)</script>`))
+	assert.Equal(t, `<i>a</i>`, buf.String())
+
+	// the function follows the current sanitizer
+	m.SetSanitizer(upperSanitizer{})
+	buf.Reset()
+	assert.Nil(t, tmpl.Execute(&buf, `<i>a</i>`))
+	assert.Equal(t, `<I>A</I>`, buf.String())
+}
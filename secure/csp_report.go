@@ -0,0 +1,283 @@
+package secure
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/insionng/makross"
+)
+
+type (
+	// CSPReport is a Content-Security-Policy violation report, normalized from either
+	// the legacy report-uri format or the Reporting API format.
+	CSPReport struct {
+		DocumentURL        string `json:"document_url"`
+		Referrer           string `json:"referrer,omitempty"`
+		BlockedURL         string `json:"blocked_url"`
+		EffectiveDirective string `json:"effective_directive"`
+		OriginalPolicy     string `json:"original_policy"`
+		Disposition        string `json:"disposition,omitempty"`
+		SourceFile         string `json:"source_file,omitempty"`
+		Sample             string `json:"sample,omitempty"`
+		LineNumber         int    `json:"line_number,omitempty"`
+		ColumnNumber       int    `json:"column_number,omitempty"`
+		StatusCode         int    `json:"status_code,omitempty"`
+		UserAgent          string `json:"user_agent,omitempty"`
+	}
+
+	// CSPReportConfig defines the config for CSPReport handler.
+	CSPReportConfig struct {
+		// Sink receives the reports.
+		// Required.
+		Sink func(CSPReport)
+
+		// BodyLimit is the maximum size of a report request in bytes. Larger requests are dropped as malformed.
+		// Optional. Default value 64KB.
+		BodyLimit int64 `json:"body_limit"`
+
+		// Stats counts the reports.
+		// Optional. Default value DefaultCSPReportStats.
+		Stats *CSPReportStats
+	}
+
+	// CSPReportStats counts the reports received by a CSPReport handler.
+	CSPReportStats struct {
+		reports   uint64
+		malformed uint64
+	}
+
+	// CSPReportBuffer is a sink keeping the latest reports in memory, e.g. to show them on an admin page.
+	CSPReportBuffer struct {
+		mu      sync.Mutex
+		reports []CSPReport
+		next    int
+		full    bool
+	}
+
+	// legacyCSPReport is the format of the reports sent to the report-uri directive.
+	legacyCSPReport struct {
+		Report *struct {
+			DocumentURI        string `json:"document-uri"`
+			Referrer           string `json:"referrer"`
+			BlockedURI         string `json:"blocked-uri"`
+			ViolatedDirective  string `json:"violated-directive"`
+			EffectiveDirective string `json:"effective-directive"`
+			OriginalPolicy     string `json:"original-policy"`
+			Disposition        string `json:"disposition"`
+			SourceFile         string `json:"source-file"`
+			ScriptSample       string `json:"script-sample"`
+			LineNumber         int    `json:"line-number"`
+			ColumnNumber       int    `json:"column-number"`
+			StatusCode         int    `json:"status-code"`
+		} `json:"csp-report"`
+	}
+
+	// reportingAPIReport is the format of the reports sent to the report-to directive.
+	reportingAPIReport struct {
+		Type      string `json:"type"`
+		URL       string `json:"url"`
+		UserAgent string `json:"user_agent"`
+		Body      struct {
+			DocumentURL        string `json:"documentURL"`
+			Referrer           string `json:"referrer"`
+			BlockedURL         string `json:"blockedURL"`
+			EffectiveDirective string `json:"effectiveDirective"`
+			OriginalPolicy     string `json:"originalPolicy"`
+			Disposition        string `json:"disposition"`
+			SourceFile         string `json:"sourceFile"`
+			Sample             string `json:"sample"`
+			LineNumber         int    `json:"lineNumber"`
+			ColumnNumber       int    `json:"columnNumber"`
+			StatusCode         int    `json:"statusCode"`
+		} `json:"body"`
+	}
+)
+
+const (
+	// MIMEApplicationCSPReport is the content type of the legacy reports.
+	MIMEApplicationCSPReport = "application/csp-report"
+	// MIMEApplicationReportsJSON is the content type of the Reporting API reports.
+	MIMEApplicationReportsJSON = "application/reports+json"
+)
+
+var (
+	// DefaultCSPReportConfig is the default CSPReport handler config.
+	DefaultCSPReportConfig = CSPReportConfig{
+		BodyLimit: 64 << 10,
+		Stats:     DefaultCSPReportStats,
+	}
+
+	// DefaultCSPReportStats counts the reports received by the CSPReport handlers without their own Stats.
+	DefaultCSPReportStats = new(CSPReportStats)
+)
+
+// CSPReportHandler returns a handler collecting the Content-Security-Policy violation reports,
+// for the report-uri and report-to directives:
+//
+//	buf := secure.NewCSPReportBuffer(100)
+//	m.Post("/csp-report", secure.CSPReportHandler(buf.Add))
+//
+// It accepts the application/csp-report and application/reports+json formats and always responds
+// with "204 - No Content", so that the clients get no feedback. Malformed reports are dropped,
+// the reports are counted in DefaultCSPReportStats.
+func CSPReportHandler(sink func(CSPReport)) makross.Handler {
+	c := DefaultCSPReportConfig
+	c.Sink = sink
+	return CSPReportHandlerWithConfig(c)
+}
+
+// CSPReportHandlerWithConfig returns a CSPReport handler with config.
+// See: `CSPReportHandler()`.
+func CSPReportHandlerWithConfig(config CSPReportConfig) makross.Handler {
+	// Defaults
+	if config.BodyLimit == 0 {
+		config.BodyLimit = DefaultCSPReportConfig.BodyLimit
+	}
+	if config.Sink == nil {
+		panic("secure: csp report sink is required")
+	}
+	if config.Stats == nil {
+		config.Stats = DefaultCSPReportStats
+	}
+
+	return func(c *makross.Context) error {
+		reports, err := parseCSPReports(c, config.BodyLimit)
+		if err != nil {
+			atomic.AddUint64(&config.Stats.malformed, 1)
+		}
+		for _, r := range reports {
+			atomic.AddUint64(&config.Stats.reports, 1)
+			config.Sink(r)
+		}
+		return c.NoContent(makross.StatusNoContent)
+	}
+}
+
+// parseCSPReports reads the reports of the request body.
+func parseCSPReports(c *makross.Context, limit int64) ([]CSPReport, error) {
+	if c.Request.Body == nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, makross.ErrStatusRequestEntityTooLarge
+	}
+
+	ctype, _, _ := mime.ParseMediaType(c.Request.Header.Get(makross.HeaderContentType))
+	switch ctype {
+	case MIMEApplicationCSPReport, makross.MIMEApplicationJSON:
+		var r legacyCSPReport
+		if err := json.Unmarshal(body, &r); err != nil {
+			return nil, err
+		}
+		if r.Report == nil {
+			return nil, makross.ErrUnsupportedMediaType
+		}
+		l := r.Report
+		report := CSPReport{
+			DocumentURL:        l.DocumentURI,
+			Referrer:           l.Referrer,
+			BlockedURL:         l.BlockedURI,
+			EffectiveDirective: l.EffectiveDirective,
+			OriginalPolicy:     l.OriginalPolicy,
+			Disposition:        l.Disposition,
+			SourceFile:         l.SourceFile,
+			Sample:             l.ScriptSample,
+			LineNumber:         l.LineNumber,
+			ColumnNumber:       l.ColumnNumber,
+			StatusCode:         l.StatusCode,
+			UserAgent:          c.UserAgent(),
+		}
+		if report.EffectiveDirective == "" {
+			// older browsers only send the violated directive
+			report.EffectiveDirective = l.ViolatedDirective
+		}
+		return []CSPReport{report}, nil
+	case MIMEApplicationReportsJSON:
+		var rs []reportingAPIReport
+		if err := json.Unmarshal(body, &rs); err != nil {
+			return nil, err
+		}
+		var reports []CSPReport
+		for _, r := range rs {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			b := r.Body
+			report := CSPReport{
+				DocumentURL:        b.DocumentURL,
+				Referrer:           b.Referrer,
+				BlockedURL:         b.BlockedURL,
+				EffectiveDirective: b.EffectiveDirective,
+				OriginalPolicy:     b.OriginalPolicy,
+				Disposition:        b.Disposition,
+				SourceFile:         b.SourceFile,
+				Sample:             b.Sample,
+				LineNumber:         b.LineNumber,
+				ColumnNumber:       b.ColumnNumber,
+				StatusCode:         b.StatusCode,
+				UserAgent:          r.UserAgent,
+			}
+			if report.DocumentURL == "" {
+				report.DocumentURL = r.URL
+			}
+			reports = append(reports, report)
+		}
+		return reports, nil
+	}
+	return nil, makross.ErrUnsupportedMediaType
+}
+
+// Reports returns the number of reports received.
+func (s *CSPReportStats) Reports() uint64 {
+	return atomic.LoadUint64(&s.reports)
+}
+
+// Malformed returns the number of requests dropped because they are malformed, too large or of another content type.
+func (s *CSPReportStats) Malformed() uint64 {
+	return atomic.LoadUint64(&s.malformed)
+}
+
+// NewCSPReportBuffer returns a CSPReportBuffer keeping the size latest reports.
+func NewCSPReportBuffer(size int) *CSPReportBuffer {
+	return &CSPReportBuffer{reports: make([]CSPReport, size)}
+}
+
+// Add adds a report, dropping the oldest one if the buffer is full. It's the sink of the buffer.
+func (b *CSPReportBuffer) Add(r CSPReport) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.reports) == 0 {
+		return
+	}
+	b.reports[b.next] = r
+	b.next++
+	if b.next == len(b.reports) {
+		b.next, b.full = 0, true
+	}
+}
+
+// Reports returns a copy of the reports in the buffer, oldest first.
+func (b *CSPReportBuffer) Reports() []CSPReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]CSPReport(nil), b.reports[:b.next]...)
+	}
+	return append(append([]CSPReport(nil), b.reports[b.next:]...), b.reports[:b.next]...)
+}
+
+// CSPReportLogFunc returns a sink logging the reports with logf, e.g. log.Printf.
+func CSPReportLogFunc(logf func(format string, v ...interface{})) func(CSPReport) {
+	return func(r CSPReport) {
+		logf("[CSP] %s blocked %q on %s (%s:%d:%d)", r.EffectiveDirective, r.BlockedURL, r.DocumentURL,
+			r.SourceFile, r.LineNumber, r.ColumnNumber)
+	}
+}
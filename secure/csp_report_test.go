@@ -0,0 +1,127 @@
+package secure
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func postReport(t *testing.T, h makross.Handler, contentType string, body []byte) int {
+	req := httptest.NewRequest(makross.POST, "/csp-report", bytes.NewReader(body))
+	req.Header.Set(makross.HeaderContentType, contentType)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Legacy)")
+	res := httptest.NewRecorder()
+	m := makross.New()
+	c := m.NewContext(req, res, h)
+	assert.Nil(t, c.Next())
+	return res.Code
+}
+
+func fixture(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCSPReportHandler(t *testing.T) {
+	buf := NewCSPReportBuffer(10)
+	stats := new(CSPReportStats)
+	h := CSPReportHandlerWithConfig(CSPReportConfig{Sink: buf.Add, Stats: stats, BodyLimit: 4096})
+
+	assert.Equal(t, makross.StatusNoContent, postReport(t, h, MIMEApplicationCSPReport, fixture(t, "csp-report.json")))
+	assert.Equal(t, makross.StatusNoContent, postReport(t, h, MIMEApplicationReportsJSON, fixture(t, "reports.json")))
+
+	reports := buf.Reports()
+	assert.Equal(t, []CSPReport{
+		{
+			DocumentURL:        "https://example.com/signup.html",
+			BlockedURL:         "https://evil.example.com/css/style.css",
+			EffectiveDirective: "style-src",
+			OriginalPolicy:     "default-src 'none'; style-src cdn.example.com; report-uri /_/csp-reports",
+			Disposition:        "enforce",
+			SourceFile:         "https://example.com/signup.html",
+			LineNumber:         12,
+			ColumnNumber:       7,
+			StatusCode:         200,
+			UserAgent:          "Mozilla/5.0 (Legacy)",
+		},
+		{
+			DocumentURL:        "https://example.com/csp-report",
+			Referrer:           "https://www.google.com/",
+			BlockedURL:         "inline",
+			EffectiveDirective: "script-src-elem",
+			OriginalPolicy:     "default-src 'self'; report-to csp-endpoint-name",
+			Disposition:        "enforce",
+			SourceFile:         "https://example.com/csp-report",
+			Sample:             `console.log("lo")`,
+			LineNumber:         121,
+			ColumnNumber:       39,
+			StatusCode:         200,
+			UserAgent:          "Mozilla/5.0 (X11; Linux x86_64) Chrome/127.0.0.0 Safari/537.36",
+		},
+	}, reports)
+	assert.Equal(t, uint64(2), stats.Reports())
+	assert.Equal(t, uint64(0), stats.Malformed())
+
+	// malformed reports are counted and still get a 204
+	malformed := []struct {
+		contentType string
+		body        string
+	}{
+		{MIMEApplicationCSPReport, `{"csp-report": `},
+		{MIMEApplicationCSPReport, `{"other": {}}`},
+		{MIMEApplicationReportsJSON, `{"type": "csp-violation"}`},
+		{makross.MIMETextPlain, `hello`},
+		{MIMEApplicationCSPReport, `{"csp-report": {"document-uri": "` + strings.Repeat("x", 4096) + `"}}`},
+	}
+	for _, test := range malformed {
+		assert.Equal(t, makross.StatusNoContent, postReport(t, h, test.contentType, []byte(test.body)), test.body)
+	}
+	assert.Equal(t, uint64(len(malformed)), stats.Malformed())
+	assert.Len(t, buf.Reports(), 2)
+}
+
+func TestCSPReportHandlerDefaultStats(t *testing.T) {
+	reports, malformed := DefaultCSPReportStats.Reports(), DefaultCSPReportStats.Malformed()
+	h := CSPReportHandler(NewCSPReportBuffer(10).Add)
+	postReport(t, h, MIMEApplicationCSPReport, fixture(t, "csp-report.json"))
+	postReport(t, h, MIMEApplicationCSPReport, []byte("{"))
+	assert.Equal(t, reports+1, DefaultCSPReportStats.Reports())
+	assert.Equal(t, malformed+1, DefaultCSPReportStats.Malformed())
+}
+
+func TestCSPReportBuffer(t *testing.T) {
+	buf := NewCSPReportBuffer(3)
+	for i := 0; i < 5; i++ {
+		buf.Add(CSPReport{LineNumber: i})
+	}
+	reports := buf.Reports()
+	assert.Len(t, reports, 3)
+	for i, r := range reports {
+		assert.Equal(t, i+2, r.LineNumber)
+	}
+}
+
+func TestCSPReportLogFunc(t *testing.T) {
+	var logged string
+	sink := CSPReportLogFunc(func(format string, v ...interface{}) {
+		logged = fmt.Sprintf(format, v...)
+	})
+	sink(CSPReport{
+		DocumentURL:        "https://example.com/",
+		BlockedURL:         "inline",
+		EffectiveDirective: "script-src",
+		SourceFile:         "https://example.com/app.js",
+		LineNumber:         3,
+		ColumnNumber:       14,
+	})
+	assert.Equal(t, `[CSP] script-src blocked "inline" on https://example.com/ (https://example.com/app.js:3:14)`, logged)
+}
@@ -0,0 +1,172 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ServerConfig defines the limits of the HTTP server which protect it from slow clients and large headers.
+// The zero timeouts and header size are set to the values of DefaultServerConfig.
+type ServerConfig struct {
+	// Addr is the address to listen on for Start, see GetAddress.
+	Addr string
+
+	// ReadHeaderTimeout is the time allowed to read the request headers.
+	// It's the main protection against slowloris attacks.
+	// Default value 10s.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is the time allowed to read the entire request, including the body.
+	// It also bounds the uploads, which must be sent within it. A negative value means no timeout.
+	// Default value 60s.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the time allowed to write the response, counted from the end of the request headers.
+	// It also bounds the large downloads, which can be given more time with Context.SetWriteDeadline or
+	// the writedeadline middleware. The streams of Context.Stream and Context.SSE aren't bound by it, and
+	// neither are the hijacked connections, such as the WebSockets. A negative value means no timeout.
+	// Default value 60s.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the time a keep-alive connection waits for the next request.
+	// Default value 120s.
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes is the maximum size of the request headers, including the request line.
+	// Default value 1MB (http.DefaultMaxHeaderBytes).
+	MaxHeaderBytes int
+
+	// ConnState is called when a client connection changes state, see http.Server.ConnState.
+	// Optional.
+	ConnState func(net.Conn, http.ConnState)
+
+	// MaxHandlerTimeout caps the timeouts handlers set with Context.WithTimeout, e.g. to keep
+	// long-poll requests below the timeouts of the load balancers.
+	// Default value 0, no cap.
+	MaxHandlerTimeout time.Duration
+
+	// DisableKeepAlives makes the server close the connection after each response.
+	// Keep-alives can also be toggled at runtime with SetKeepAlivesEnabled, e.g. to drain an instance.
+	// Default value false.
+	DisableKeepAlives bool
+}
+
+// DefaultServerConfig is the default ServerConfig, applied to the server of a new Makross.
+var DefaultServerConfig = ServerConfig{
+	ReadHeaderTimeout: 10 * time.Second,
+	ReadTimeout:       60 * time.Second,
+	WriteTimeout:      60 * time.Second,
+	IdleTimeout:       120 * time.Second,
+	MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+}
+
+// ServerConfigFromEnv returns a ServerConfig with the address and the timeouts read from the environment
+// variables with the prefix, e.g. with the "APP_" prefix:
+//
+//	APP_ADDR=:8080
+//	APP_READ_HEADER_TIMEOUT=5s
+//	APP_READ_TIMEOUT=30s
+//	APP_WRITE_TIMEOUT=30s
+//	APP_IDLE_TIMEOUT=2m
+//	APP_HANDLER_TIMEOUT=25s
+//
+// The timeouts are durations such as "30s" or "1m30s". The unset variables, and the invalid ones, which
+// are logged with the default logger, are left to zero, so that Configure and Start use the defaults.
+// See Makross.ServerConfigFromEnv to log them with the logger of the application.
+func ServerConfigFromEnv(prefix string) ServerConfig {
+	return serverConfigFromEnv(prefix, defaultLogger)
+}
+
+// ServerConfigFromEnv is the ServerConfigFromEnv function logging the invalid variables with the
+// logger of the application, see SetLogger.
+func (m *Makross) ServerConfigFromEnv(prefix string) ServerConfig {
+	return serverConfigFromEnv(prefix, m.Logger())
+}
+
+func serverConfigFromEnv(prefix string, logger Logger) ServerConfig {
+	config := ServerConfig{Addr: os.Getenv(prefix + "ADDR")}
+	for name, d := range map[string]*time.Duration{
+		"READ_HEADER_TIMEOUT": &config.ReadHeaderTimeout,
+		"READ_TIMEOUT":        &config.ReadTimeout,
+		"WRITE_TIMEOUT":       &config.WriteTimeout,
+		"IDLE_TIMEOUT":        &config.IdleTimeout,
+		"HANDLER_TIMEOUT":     &config.MaxHandlerTimeout,
+	} {
+		key := prefix + name
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		if t, err := time.ParseDuration(v); err != nil || t < 0 {
+			logger.Warnf("invalid %s=%q, using the default", key, v)
+		} else {
+			*d = t
+		}
+	}
+	return config
+}
+
+// Configure applies the server config to the HTTP server, using the defaults for the zero timeouts
+// and MaxHeaderBytes.
+func (m *Makross) Configure(config ServerConfig) {
+	if config.ReadHeaderTimeout == 0 {
+		config.ReadHeaderTimeout = DefaultServerConfig.ReadHeaderTimeout
+	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = DefaultServerConfig.ReadTimeout
+	}
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = DefaultServerConfig.WriteTimeout
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = DefaultServerConfig.IdleTimeout
+	}
+	if config.MaxHeaderBytes == 0 {
+		config.MaxHeaderBytes = DefaultServerConfig.MaxHeaderBytes
+	}
+
+	s := m.Server
+	if config.Addr != "" {
+		s.Addr = config.Addr
+	}
+	s.ReadHeaderTimeout = config.ReadHeaderTimeout
+	s.ReadTimeout = config.ReadTimeout
+	s.WriteTimeout = config.WriteTimeout
+	s.IdleTimeout = config.IdleTimeout
+	s.MaxHeaderBytes = config.MaxHeaderBytes
+	s.ConnState = config.ConnState
+	m.SetKeepAlivesEnabled(!config.DisableKeepAlives)
+	m.handlerTimeout = config.MaxHandlerTimeout
+}
+
+// Start configures the HTTP server and listens on the configured address.
+// Unlike Listen, it returns the error instead of exiting, http.ErrServerClosed after Shutdown or Close.
+// The Warmup functions run once the address is bound, and their error is returned after closing the server.
+func (m *Makross) Start(config ServerConfig) error {
+	m.Configure(config)
+	if m.Server.Addr == "" {
+		m.Server.Addr = GetAddress()
+	}
+	l, err := net.Listen("tcp", m.Server.Addr)
+	if err != nil {
+		return err
+	}
+	m.DoActionHook("MakrossListen")
+	return m.serveWarm(func() error {
+		return m.Server.Serve(l)
+	}, m.Server.Close)
+}
+
+// SetKeepAlivesEnabled controls whether HTTP keep-alives are enabled. It can be called at runtime,
+// e.g. to have the clients reconnect elsewhere while draining an instance before Shutdown.
+func (m *Makross) SetKeepAlivesEnabled(v bool) {
+	m.keepAlivesOff.Store(!v)
+	m.Server.SetKeepAlivesEnabled(v)
+	for _, s := range m.listenerServers() {
+		s.SetKeepAlivesEnabled(v)
+	}
+}
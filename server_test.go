@@ -0,0 +1,185 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigure(t *testing.T) {
+	m := New()
+	assert.Equal(t, DefaultServerConfig.ReadHeaderTimeout, m.Server.ReadHeaderTimeout)
+	assert.Equal(t, 60*time.Second, m.Server.ReadTimeout)
+	assert.Equal(t, 60*time.Second, m.Server.WriteTimeout)
+	assert.Equal(t, DefaultServerConfig.IdleTimeout, m.Server.IdleTimeout)
+	assert.Equal(t, http.DefaultMaxHeaderBytes, m.Server.MaxHeaderBytes)
+
+	m.Configure(ServerConfig{Addr: ":8080", ReadTimeout: time.Second, WriteTimeout: -1, MaxHeaderBytes: 4096})
+	assert.Equal(t, ":8080", m.Server.Addr)
+	assert.Equal(t, time.Second, m.Server.ReadTimeout)
+	// a negative timeout disables it
+	assert.Equal(t, time.Duration(-1), m.Server.WriteTimeout)
+	assert.Equal(t, DefaultServerConfig.ReadHeaderTimeout, m.Server.ReadHeaderTimeout)
+	assert.Equal(t, 4096, m.Server.MaxHeaderBytes)
+}
+
+func TestSlowClient(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		states []http.ConnState
+	)
+	m := New()
+	m.Get("/", func(c *Context) error {
+		return c.String("ok")
+	})
+	m.Configure(ServerConfig{
+		ReadHeaderTimeout: 100 * time.Millisecond,
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			mu.Lock()
+			states = append(states, state)
+			mu.Unlock()
+		},
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go m.Server.Serve(ln)
+	defer m.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// send the headers one byte at a time, too slowly to finish before the deadline
+	start := time.Now()
+	for _, b := range []byte("GET / HTTP/1.1\r\nHost: localhost\r\n") {
+		if _, err := conn.Write([]byte{b}); err != nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	b, _ := ioutil.ReadAll(conn)
+	elapsed := time.Since(start)
+
+	assert.NotContains(t, string(b), "ok")
+	assert.True(t, elapsed >= 100*time.Millisecond, "the connection was dropped before the deadline")
+	assert.True(t, elapsed < time.Second+500*time.Millisecond, "the connection should be dropped at the deadline")
+	mu.Lock()
+	assert.Contains(t, states, http.StateClosed)
+	mu.Unlock()
+}
+
+// slowReader returns its chunks with a delay.
+type slowReader struct {
+	chunks []string
+	delay  time.Duration
+}
+
+func (r *slowReader) Read(b []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	n := copy(b, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestWriteTimeoutStreams(t *testing.T) {
+	m := New()
+	m.Get("/stream", func(c *Context) error {
+		return c.Stream(MIMETextPlain, &slowReader{chunks: []string{"a", "b", "c"}, delay: 100 * time.Millisecond})
+	})
+	m.Get("/slow", func(c *Context) error {
+		time.Sleep(300 * time.Millisecond)
+		return c.String("late")
+	})
+	m.Configure(ServerConfig{WriteTimeout: 150 * time.Millisecond})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go m.Server.Serve(ln)
+	defer m.Close()
+
+	// the streams outlive the WriteTimeout
+	res, err := http.Get("http://" + ln.Addr().String() + "/stream")
+	if assert.Nil(t, err) {
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, "abc", string(b))
+	}
+	// the other responses don't
+	res, err = http.Get("http://" + ln.Addr().String() + "/slow")
+	if err == nil {
+		b, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.NotEqual(t, "late", string(b))
+	}
+}
+
+func TestSetKeepAlivesEnabled(t *testing.T) {
+	m := New()
+	m.Get("/", func(c *Context) error {
+		return c.String("ok")
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go m.Server.Serve(ln)
+	defer m.Close()
+
+	m.SetKeepAlivesEnabled(false)
+	res, err := http.Get("http://" + ln.Addr().String() + "/")
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.True(t, res.Close, "the server should close the connection")
+	}
+}
+
+func TestServerConfigFromEnv(t *testing.T) {
+	t.Setenv("TEST_ADDR", ":9090")
+	t.Setenv("TEST_READ_TIMEOUT", "30s")
+	t.Setenv("TEST_HANDLER_TIMEOUT", "1m30s")
+	t.Setenv("TEST_IDLE_TIMEOUT", "2 minutes")
+	t.Setenv("TEST_WRITE_TIMEOUT", "-1s")
+	config := ServerConfigFromEnv("TEST_")
+	assert.Equal(t, ServerConfig{
+		Addr:              ":9090",
+		ReadTimeout:       30 * time.Second,
+		MaxHandlerTimeout: 90 * time.Second,
+	}, config)
+
+	m := New()
+	m.Configure(config)
+	assert.Equal(t, ":9090", m.Server.Addr)
+	assert.Equal(t, 30*time.Second, m.Server.ReadTimeout)
+	assert.Equal(t, DefaultServerConfig.ReadHeaderTimeout, m.Server.ReadHeaderTimeout)
+	assert.Equal(t, DefaultServerConfig.IdleTimeout, m.Server.IdleTimeout)
+	assert.Equal(t, DefaultServerConfig.WriteTimeout, m.Server.WriteTimeout)
+
+	assert.Equal(t, ServerConfig{}, ServerConfigFromEnv("UNSET_"))
+
+	// the invalid variables are logged with the logger of the application
+	buf := new(bytes.Buffer)
+	m.SetLogger(NewLogger(buf))
+	assert.Equal(t, config, m.ServerConfigFromEnv("TEST_"))
+	assert.Contains(t, buf.String(), `WARN invalid TEST_IDLE_TIMEOUT="2 minutes", using the default`)
+	assert.Contains(t, buf.String(), `WARN invalid TEST_WRITE_TIMEOUT="-1s", using the default`)
+}
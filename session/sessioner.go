@@ -45,7 +45,10 @@ type store struct {
 	*Manager
 }
 
-var _ Store = &store{}
+var (
+	_ Store      = &store{}
+	_ TypedStore = &store{}
+)
 
 type Options struct {
 	Provider string
@@ -0,0 +1,238 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+// The coercion rules of the typed getters are the same for all the stores, so that a value reads
+// the same whether it was kept in memory or went through gob or JSON:
+//
+//	GetString: string, []byte
+//	GetInt:    the integers fitting in an int, the floats without fraction such as the JSON
+//	           numbers, json.Number and the strings parsed by strconv.Atoi
+//	GetBool:   bool and the strings parsed by strconv.ParseBool
+//	GetTime:   time.Time, *time.Time and the RFC 3339 strings
+//
+// They return false, and the zero value, when the key is missing or its value can't be coerced.
+
+// TypedStore is implemented by the stores with typed getters, such as the one of Sessioner.
+// The functions GetString, GetInt, GetBool and GetTime read any store, using its typed getters
+// if it has them, and the coercion rules otherwise.
+type TypedStore interface {
+	// GetString returns the value of the key as a string.
+	GetString(key interface{}) (string, bool)
+	// GetInt returns the value of the key as an int.
+	GetInt(key interface{}) (int, bool)
+	// GetBool returns the value of the key as a bool.
+	GetBool(key interface{}) (bool, bool)
+	// GetTime returns the value of the key as a time.
+	GetTime(key interface{}) (time.Time, bool)
+}
+
+var (
+	// ErrNoSession is returned by Bind and Put when the request has no session, see Sessioner.
+	ErrNoSession = errors.New("session: no session in the context")
+
+	// ErrNotFound is returned by Bind when the session has no value under the key.
+	ErrNotFound = errors.New("session: key not found")
+)
+
+// GetString returns the value of the key as a string.
+func (s store) GetString(key interface{}) (string, bool) {
+	return toString(s.Get(key))
+}
+
+// GetString returns the value of the key of the store as a string, see TypedStore.
+func GetString(s makross.RawStore, key interface{}) (string, bool) {
+	if t, ok := s.(TypedStore); ok {
+		return t.GetString(key)
+	}
+	return toString(s.Get(key))
+}
+
+func toString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// GetInt returns the value of the key as an int.
+func (s store) GetInt(key interface{}) (int, bool) {
+	return toInt(s.Get(key))
+}
+
+// GetInt returns the value of the key of the store as an int, see TypedStore.
+func GetInt(s makross.RawStore, key interface{}) (int, bool) {
+	if t, ok := s.(TypedStore); ok {
+		return t.GetInt(key)
+	}
+	return toInt(s.Get(key))
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return intFromInt64(v)
+	case uint:
+		return intFromUint64(uint64(v))
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return intFromUint64(uint64(v))
+	case uint64:
+		return intFromUint64(v)
+	case float32:
+		return intFromFloat64(float64(v))
+	case float64:
+		return intFromFloat64(v)
+	case json.Number:
+		return intFromString(string(v))
+	case string:
+		return intFromString(v)
+	}
+	return 0, false
+}
+
+// GetBool returns the value of the key as a bool.
+func (s store) GetBool(key interface{}) (bool, bool) {
+	return toBool(s.Get(key))
+}
+
+// GetBool returns the value of the key of the store as a bool, see TypedStore.
+func GetBool(s makross.RawStore, key interface{}) (bool, bool) {
+	if t, ok := s.(TypedStore); ok {
+		return t.GetBool(key)
+	}
+	return toBool(s.Get(key))
+}
+
+func toBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, true
+		}
+	}
+	return false, false
+}
+
+// GetTime returns the value of the key as a time.
+func (s store) GetTime(key interface{}) (time.Time, bool) {
+	return toTime(s.Get(key))
+}
+
+// GetTime returns the value of the key of the store as a time, see TypedStore.
+func GetTime(s makross.RawStore, key interface{}) (time.Time, bool) {
+	if t, ok := s.(TypedStore); ok {
+		return t.GetTime(key)
+	}
+	return toTime(s.Get(key))
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func intFromInt64(v int64) (int, bool) {
+	if int64(int(v)) != v {
+		return 0, false
+	}
+	return int(v), true
+}
+
+func intFromUint64(v uint64) (int, bool) {
+	if v > math.MaxInt64 || uint64(int(v)) != v {
+		return 0, false
+	}
+	return int(v), true
+}
+
+func intFromFloat64(v float64) (int, bool) {
+	if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		return 0, false
+	}
+	return intFromInt64(int64(v))
+}
+
+func intFromString(v string) (int, bool) {
+	i, err := strconv.Atoi(v)
+	return i, err == nil
+}
+
+// Put stores the value, e.g. a struct, under the key of the session as JSON, so that it is read
+// back identically by Bind whatever the store, unlike the values set as they are, which the stores
+// encode differently and which must be registered with gob.
+func Put(c *makross.Context, key string, value interface{}) error {
+	s := GetStore(c)
+	if s == nil {
+		return ErrNoSession
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.Set(key, string(b))
+}
+
+// Bind decodes the value stored by Put under the key of the session into dest.
+// The values set as they are, e.g. a map, are converted through JSON.
+//
+//	var cart Cart
+//	if err := session.Bind(c, "cart", &cart); err != nil && err != session.ErrNotFound {
+//		return err
+//	}
+func Bind(c *makross.Context, key string, dest interface{}) error {
+	s := GetStore(c)
+	if s == nil {
+		return ErrNoSession
+	}
+	var b []byte
+	switch v := s.Get(key).(type) {
+	case nil:
+		return ErrNotFound
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(b, dest)
+}
@@ -0,0 +1,205 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+)
+
+// roundTrip stores the values in a new session of the provider, releases it and reads it back,
+// as the next request would.
+func roundTrip(t *testing.T, provider Provider, values map[string]interface{}) makross.RawStore {
+	m := makross.New()
+	sid := "0123456789abcdef"
+	rs, err := provider.Read(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range values {
+		rs.Set(k, v)
+	}
+	res := httptest.NewRecorder()
+	if err := rs.Release(m.NewContext(httptest.NewRequest("GET", "/", nil), res)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := provider.(*CookieProvider); ok {
+		// the cookie store keeps the session in the cookie
+		cookies := res.Result().Cookies()
+		if len(cookies) == 0 {
+			t.Fatal("no session cookie")
+		}
+		sid, _ = url.QueryUnescape(cookies[0].Value)
+	}
+	rs, err = provider.Read(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+func testProviders(t *testing.T) map[string]Provider {
+	if err := mempder.Init(3600, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := cookiepder.Init(3600, `{"securityKey":"makrosscookiehashkey","cookieName":"makrossSessionId"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := filepder.Init(3600, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Provider{"memory": mempder, "cookie": cookiepder, "file": filepder}
+}
+
+func TestTypedGetters(t *testing.T) {
+	now := time.Date(2017, 5, 1, 10, 30, 0, 0, time.UTC)
+	values := map[string]interface{}{
+		"int":      42,
+		"int64":    int64(42),
+		"uint8":    uint8(42),
+		"float":    float64(42), // as decoded from JSON
+		"fraction": 4.2,
+		"number":   json.Number("42"),
+		"intstr":   "42",
+		"str":      "makross",
+		"bytes":    []byte("makross"),
+		"bool":     true,
+		"boolstr":  "true",
+		"time":     now,
+		"timestr":  now.Format(time.RFC3339Nano),
+	}
+	tests := []struct {
+		key      string
+		getter   string
+		expected interface{}
+		ok       bool
+	}{
+		{"int", "int", 42, true},
+		{"int64", "int", 42, true},
+		{"uint8", "int", 42, true},
+		{"float", "int", 42, true},
+		{"fraction", "int", 0, false},
+		{"number", "int", 42, true},
+		{"intstr", "int", 42, true},
+		{"str", "int", 0, false},
+		{"missing", "int", 0, false},
+		{"str", "string", "makross", true},
+		{"bytes", "string", "makross", true},
+		{"int", "string", "", false},
+		{"bool", "bool", true, true},
+		{"boolstr", "bool", true, true},
+		{"int", "bool", false, false},
+		{"time", "time", now, true},
+		{"timestr", "time", now, true},
+		{"int", "time", time.Time{}, false},
+	}
+
+	for name, provider := range testProviders(t) {
+		s := store{RawStore: roundTrip(t, provider, values)}
+		for _, test := range tests {
+			var v interface{}
+			var ok bool
+			switch test.getter {
+			case "string":
+				v, ok = s.GetString(test.key)
+			case "int":
+				v, ok = s.GetInt(test.key)
+			case "bool":
+				v, ok = s.GetBool(test.key)
+			case "time":
+				var tm time.Time
+				tm, ok = s.GetTime(test.key)
+				if tm.Equal(now) {
+					tm = now
+				}
+				v = tm
+			}
+			if v != test.expected || ok != test.ok {
+				t.Errorf("%s: Get%s(%q) = %v, %v; expected %v, %v", name, test.getter, test.key, v, ok, test.expected, test.ok)
+			}
+
+			// the same through the functions, with a store without typed getters
+			switch test.getter {
+			case "string":
+				v, ok = GetString(s.RawStore, test.key)
+			case "int":
+				v, ok = GetInt(s.RawStore, test.key)
+			case "bool":
+				v, ok = GetBool(s.RawStore, test.key)
+			case "time":
+				var tm time.Time
+				tm, ok = GetTime(s.RawStore, test.key)
+				if tm.Equal(now) {
+					tm = now
+				}
+				v = tm
+			}
+			if v != test.expected || ok != test.ok {
+				t.Errorf("%s: session.Get%s(%q) = %v, %v; expected %v, %v", name, test.getter, test.key, v, ok, test.expected, test.ok)
+			}
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	type Cart struct {
+		Items   []string
+		Count   int
+		Total   float64
+		Updated time.Time
+		Meta    map[string]int
+	}
+	cart := Cart{
+		Items:   []string{"book", "pen"},
+		Count:   2,
+		Total:   12.5,
+		Updated: time.Date(2017, 5, 1, 10, 30, 0, 0, time.UTC),
+		Meta:    map[string]int{"coupon": 10},
+	}
+	m := makross.New()
+	contextWith := func(rs makross.RawStore) *makross.Context {
+		c := m.NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+		c.Set(CONTEXT_SESSION_KEY, store{RawStore: rs})
+		return c
+	}
+
+	for name, provider := range testProviders(t) {
+		rs, _ := provider.Read("fedcba9876543210")
+		if err := Put(contextWith(rs), "cart", cart); err != nil {
+			t.Fatal(name, err)
+		}
+		raw, _ := rs.Get("cart").(string)
+		c := contextWith(roundTrip(t, provider, map[string]interface{}{"cart": raw}))
+
+		var got Cart
+		if err := Bind(c, "cart", &got); err != nil {
+			t.Fatal(name, err)
+		}
+		if !reflect.DeepEqual(cart, got) {
+			t.Errorf("%s: got %+v, expected %+v", name, got, cart)
+		}
+		if err := Bind(c, "missing", &got); err != ErrNotFound {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+	}
+
+	// the values set as they are go through JSON
+	rs, _ := mempder.Read("0011223344556677")
+	rs.Set("meta", map[string]interface{}{"coupon": 10})
+	var meta map[string]int
+	if err := Bind(contextWith(rs), "meta", &meta); err != nil || meta["coupon"] != 10 {
+		t.Errorf("got %v, %v", meta, err)
+	}
+
+	c := m.NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+	if err := Bind(c, "cart", &cart); err != ErrNoSession {
+		t.Errorf("expected ErrNoSession, got %v", err)
+	}
+	if err := Put(c, "cart", cart); err != ErrNoSession {
+		t.Errorf("expected ErrNoSession, got %v", err)
+	}
+}
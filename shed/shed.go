@@ -0,0 +1,144 @@
+package shed
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+// Priority is the priority of a route, read from its metadata, see ShedConfig.MetaKey.
+type Priority int
+
+// The priorities of the routes. The routes without priority are Normal.
+const (
+	Low Priority = iota - 1
+	Normal
+	High
+)
+
+type (
+	// ShedConfig defines the config for Shed middleware.
+	ShedConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// MaxInFlight is the number of requests being served by the middleware over which the server is
+		// overloaded. Optional. Zero disables the check.
+		MaxInFlight int `json:"max_in_flight"`
+
+		// MaxGoroutines is the number of goroutines over which the server is overloaded.
+		// Optional. Zero disables the check.
+		MaxGoroutines int `json:"max_goroutines"`
+
+		// Overloaded reports whether the server is overloaded, e.g. from the CPU usage.
+		// Optional.
+		Overloaded func() bool
+
+		// MinPriority is the priority under which the routes are shed when the server is overloaded.
+		// Optional. Default value Normal, i.e. only the Low routes are shed.
+		MinPriority Priority `json:"min_priority"`
+
+		// MetaKey is the route metadata key holding the priority of a route, set on the route or its group:
+		// the metadata value is either a Priority, an int or one of "low", "normal" and "high".
+		// Optional. Default value "priority".
+		MetaKey string `json:"meta_key"`
+
+		// RetryAfter is sent in the Retry-After header of the shed requests.
+		// Optional. Zero doesn't send the header.
+		RetryAfter time.Duration `json:"retry_after"`
+	}
+)
+
+var (
+	// DefaultShedConfig is the default Shed middleware config.
+	DefaultShedConfig = ShedConfig{
+		Skipper:     skipper.DefaultSkipper,
+		MinPriority: Normal,
+		MetaKey:     "priority",
+	}
+)
+
+// Shed returns a Shed middleware.
+//
+// Shed middleware sheds the low priority routes with "503 - Service Unavailable" before running them,
+// when more than maxInFlight requests are being served, so that the high priority routes proceed:
+//
+//	m.Use(shed.Shed(512))
+//	m.Get("/recommendations", recommendations).Meta("priority", shed.Low)
+//	api := m.Group("/api").Meta("priority", shed.High)
+func Shed(maxInFlight int) makross.Handler {
+	c := DefaultShedConfig
+	c.MaxInFlight = maxInFlight
+	return ShedWithConfig(c)
+}
+
+// ShedWithConfig returns a Shed middleware with config.
+// See: `Shed()`.
+func ShedWithConfig(config ShedConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultShedConfig.Skipper
+	}
+	if config.MetaKey == "" {
+		config.MetaKey = DefaultShedConfig.MetaKey
+	}
+
+	var inFlight int64
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+
+		overloaded := config.MaxInFlight > 0 && n > int64(config.MaxInFlight) ||
+			config.MaxGoroutines > 0 && runtime.NumGoroutine() > config.MaxGoroutines ||
+			config.Overloaded != nil && config.Overloaded()
+		if !overloaded {
+			return c.Next()
+		}
+
+		priority := Normal
+		if route := c.Route(); route != nil {
+			p, err := routePriority(route, config.MetaKey)
+			if err != nil {
+				return err
+			}
+			priority = p
+		}
+		if priority >= config.MinPriority {
+			return c.Next()
+		}
+		return makross.NewServiceUnavailableError(config.RetryAfter)
+	}
+}
+
+// routePriority returns the priority in the metadata of the route, Normal if the route has none.
+func routePriority(route *makross.Route, key string) (Priority, error) {
+	switch v := route.GetMeta(key).(type) {
+	case nil:
+		return Normal, nil
+	case Priority:
+		return v, nil
+	case int:
+		return Priority(v), nil
+	case string:
+		switch strings.ToLower(v) {
+		case "low":
+			return Low, nil
+		case "normal":
+			return Normal, nil
+		case "high":
+			return High, nil
+		}
+		return Normal, fmt.Errorf("shed: invalid %s metadata %q", key, v)
+	default:
+		return Normal, fmt.Errorf("shed: invalid %s metadata of type %T", key, v)
+	}
+}
@@ -0,0 +1,96 @@
+package shed
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShed(t *testing.T) {
+	var overloaded bool
+	m := makross.New()
+	m.Use(ShedWithConfig(ShedConfig{
+		Overloaded: func() bool { return overloaded },
+		RetryAfter: 1500 * time.Millisecond,
+	}))
+	ok := func(c *makross.Context) error {
+		return c.String("ok")
+	}
+	m.Get("/low", ok).Meta("priority", Low)
+	m.Get("/normal", ok)
+	m.Get("/custom", ok).Meta("priority", "low")
+	m.Get("/invalid", ok).Meta("priority", 1.5)
+	api := m.Group("/api").Meta("priority", High)
+	api.Get("/orders", ok)
+	api.Get("/stats", ok).Meta("priority", Low)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res
+	}
+	for _, path := range []string{"/low", "/normal", "/custom", "/api/orders", "/api/stats"} {
+		assert.Equal(t, makross.StatusOK, serve(path).Code, path)
+	}
+
+	overloaded = true
+	for _, path := range []string{"/low", "/custom", "/api/stats"} {
+		res := serve(path)
+		assert.Equal(t, makross.StatusServiceUnavailable, res.Code, path)
+		assert.Equal(t, "2", res.Header().Get(makross.HeaderRetryAfter), path)
+	}
+	for _, path := range []string{"/normal", "/api/orders"} {
+		assert.Equal(t, makross.StatusOK, serve(path).Code, path)
+	}
+	assert.Equal(t, makross.StatusInternalServerError, serve("/invalid").Code)
+}
+
+func TestShedMaxInFlight(t *testing.T) {
+	m := makross.New()
+	m.Use(Shed(1))
+	started, release := make(chan struct{}), make(chan struct{})
+	m.Get("/slow", func(c *makross.Context) error {
+		close(started)
+		<-release
+		return c.String("slow")
+	}).Meta("priority", High)
+	m.Get("/report", func(c *makross.Context) error {
+		return c.String("report")
+	}).Meta("priority", Low)
+	m.Get("/checkout", func(c *makross.Context) error {
+		return c.String("checkout")
+	}).Meta("priority", High)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res
+	}
+
+	// a single request in flight doesn't overload the server
+	assert.Equal(t, makross.StatusOK, serve("/report").Code)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve("/slow")
+	}()
+	<-started
+
+	// with the slow request in flight, the low priority routes are shed
+	res := serve("/report")
+	assert.Equal(t, makross.StatusServiceUnavailable, res.Code)
+	assert.Empty(t, res.Header().Get(makross.HeaderRetryAfter))
+	res = serve("/checkout")
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, "checkout", res.Body.String())
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, makross.StatusOK, serve("/report").Code)
+}
@@ -0,0 +1,77 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// SetSignedCookie sets the cookie with its value signed with HMAC-SHA256, so that GetSignedCookie
+// can verify it wasn't changed by the client. The value isn't encrypted.
+//
+// The cookie is signed with keys[0], the newest key. The other keys are the older ones, which still
+// verify the cookies they signed, so that the keys can be rotated by prepending a new key and dropping
+// the oldest one once its cookies have expired. The cookie holds the ID of its key, derived from the key,
+// so that a rotation doesn't invalidate the cookies of the kept keys and only that key is checked.
+func (c *Context) SetSignedCookie(cookie *http.Cookie, keys [][]byte) error {
+	if len(keys) == 0 {
+		return errors.New("makross: no cookie signing key")
+	}
+	signed := *cookie
+	payload := base64.RawURLEncoding.EncodeToString([]byte(cookie.Value))
+	kid := keyID(keys[0])
+	signed.Value = payload + "." + kid + "." + signCookie(keys[0], cookie.Name, payload, kid)
+	c.SetCookie(&signed)
+	return nil
+}
+
+// GetSignedCookie returns the named cookie set by SetSignedCookie, with its original value, after
+// verifying its signature with the key it was signed with, which must be one of the keys.
+// ErrCookieNotFound is returned if the request has no such cookie, and ErrCookieSignature if the
+// signature doesn't match or the key is not one of the keys any longer.
+func (c *Context) GetSignedCookie(name string, keys [][]byte) (*http.Cookie, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return nil, ErrCookieNotFound
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return nil, ErrCookieSignature
+	}
+	payload, kid, sig := parts[0], parts[1], parts[2]
+	for _, key := range keys {
+		if keyID(key) != kid {
+			continue
+		}
+		if !hmac.Equal([]byte(sig), []byte(signCookie(key, name, payload, kid))) {
+			return nil, ErrCookieSignature
+		}
+		value, err := base64.RawURLEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, ErrCookieSignature
+		}
+		cookie.Value = string(value)
+		return cookie, nil
+	}
+	return nil, ErrCookieSignature
+}
+
+// keyID returns the ID of a cookie signing key, the first bytes of its SHA-256 hash.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// signCookie returns the signature of the payload of the named cookie, bound to its name
+// so that the value of a cookie can't be replayed as the one of another.
+func signCookie(key []byte, name, payload, kid string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + payload + "." + kid))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
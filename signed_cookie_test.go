@@ -0,0 +1,71 @@
+package makross
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedCookie(t *testing.T) {
+	m := New()
+	oldKey, newKey := []byte("old secret"), []byte("new secret")
+
+	// sign with the old key
+	res := httptest.NewRecorder()
+	c := m.NewContext(httptest.NewRequest(GET, "/", nil), res)
+	assert.Nil(t, c.SetSignedCookie(&http.Cookie{Name: "user", Value: "42; admin=0", Path: "/"}, [][]byte{oldKey}))
+	signed := res.Result().Cookies()[0]
+	assert.Equal(t, "/", signed.Path)
+	assert.NotContains(t, signed.Value, "42")
+
+	get := func(cookie *http.Cookie, keys ...[]byte) (*http.Cookie, error) {
+		req := httptest.NewRequest(GET, "/", nil)
+		req.AddCookie(cookie)
+		return m.NewContext(req, httptest.NewRecorder()).GetSignedCookie(cookie.Name, keys)
+	}
+	cookie, err := get(signed, oldKey)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "42; admin=0", cookie.Value)
+	}
+
+	// a new key is prepended: the cookie of the old key still verifies
+	cookie, err = get(signed, newKey, oldKey)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "42; admin=0", cookie.Value)
+	}
+
+	// and new cookies are signed with the new key
+	res = httptest.NewRecorder()
+	c = m.NewContext(httptest.NewRequest(GET, "/", nil), res)
+	c.SetSignedCookie(&http.Cookie{Name: "user", Value: "43"}, [][]byte{newKey, oldKey})
+	renewed := res.Result().Cookies()[0]
+	cookie, err = get(renewed, newKey)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "43", cookie.Value)
+	}
+	_, err = get(renewed, oldKey)
+	assert.Equal(t, ErrCookieSignature, err)
+
+	// the old key is dropped
+	_, err = get(signed, newKey)
+	assert.Equal(t, ErrCookieSignature, err)
+
+	// a changed value or name doesn't verify
+	tampered := *renewed
+	tampered.Value = "NDI" + tampered.Value[3:]
+	_, err = get(&tampered, newKey)
+	assert.Equal(t, ErrCookieSignature, err)
+	renamed := *renewed
+	renamed.Name = "admin"
+	_, err = get(&renamed, newKey)
+	assert.Equal(t, ErrCookieSignature, err)
+	_, err = get(&http.Cookie{Name: "user", Value: "42"}, newKey)
+	assert.Equal(t, ErrCookieSignature, err)
+
+	c = m.NewContext(httptest.NewRequest(GET, "/", nil), httptest.NewRecorder())
+	_, err = c.GetSignedCookie("user", [][]byte{newKey})
+	assert.Equal(t, ErrCookieNotFound, err)
+	assert.NotNil(t, c.SetSignedCookie(&http.Cookie{Name: "user"}, nil))
+}
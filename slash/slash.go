@@ -48,7 +48,7 @@ func AddTrailingSlashWithConfig(config TrailingSlashConfig) makross.Handler {
 		req := c.Request
 		url := req.URL
 		path := url.Path
-		qs := c.QueryString()
+		qs := url.RawQuery
 		if path != "/" && path[len(path)-1] != '/' {
 			path += "/"
 			uri := path
@@ -93,7 +93,7 @@ func RemoveTrailingSlashWithConfig(config TrailingSlashConfig) makross.Handler {
 		req := c.Request
 		url := req.URL
 		path := url.Path
-		qs := c.QueryString()
+		qs := url.RawQuery
 		l := len(path) - 1
 		if l >= 0 && path != "/" && path[l] == '/' {
 			path = path[:l]
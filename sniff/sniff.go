@@ -0,0 +1,134 @@
+package sniff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// SniffConfig defines the config for Sniff middleware.
+	SniffConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// ContentTypeNosniff is the value of the X-Content-Type-Options header.
+		// Optional. Default value "nosniff".
+		ContentTypeNosniff string `json:"content_type_nosniff"`
+	}
+
+	sniffResponseWriter struct {
+		http.ResponseWriter
+		code    int
+		pending bool
+	}
+)
+
+var (
+	// DefaultSniffConfig is the default Sniff middleware config.
+	DefaultSniffConfig = SniffConfig{
+		Skipper:            skipper.DefaultSkipper,
+		ContentTypeNosniff: "nosniff",
+	}
+)
+
+// Sniff returns a Sniff middleware.
+//
+// Sniff middleware sets the X-Content-Type-Options header, so that browsers trust the
+// Content-Type of the responses, and makes that Content-Type right for the responses
+// written without one, e.g. with Response.Write. The first chunk of such a response is
+// detected as JSON if it is a valid JSON object or array, otherwise the content type
+// is left to http.DetectContentType, which would serve JSON as text/plain.
+func Sniff() makross.Handler {
+	return SniffWithConfig(DefaultSniffConfig)
+}
+
+// SniffWithConfig returns a Sniff middleware with config.
+// See: `Sniff()`.
+func SniffWithConfig(config SniffConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultSniffConfig.Skipper
+	}
+	if config.ContentTypeNosniff == "" {
+		config.ContentTypeNosniff = DefaultSniffConfig.ContentTypeNosniff
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		res := c.Response
+		res.Header().Set(makross.HeaderXContentTypeOptions, config.ContentTypeNosniff)
+		rw := res.Writer
+		w := &sniffResponseWriter{ResponseWriter: rw}
+		res.Writer = w
+		defer func() {
+			// a response without body has nothing to sniff
+			w.writePendingHeader()
+			res.Writer = rw
+		}()
+		return c.Next()
+	}
+}
+
+// DetectContentType returns the content type of data, detecting JSON before
+// falling back to http.DetectContentType.
+func DetectContentType(data []byte) string {
+	if b := bytes.TrimLeft(data, " \t\r\n"); len(b) > 0 && (b[0] == '{' || b[0] == '[') && json.Valid(b) {
+		return makross.MIMEApplicationJSONCharsetUTF8
+	}
+	return http.DetectContentType(data)
+}
+
+// WriteHeader holds the header back until the first write if the content type is unknown yet.
+func (w *sniffResponseWriter) WriteHeader(code int) {
+	if w.Header().Get(makross.HeaderContentType) != "" {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+	w.pending = true
+}
+
+func (w *sniffResponseWriter) Write(b []byte) (int, error) {
+	if w.pending && len(b) > 0 {
+		w.Header().Set(makross.HeaderContentType, DetectContentType(b))
+	}
+	w.writePendingHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sniffResponseWriter) writePendingHeader() {
+	if w.pending {
+		w.pending = false
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+func (w *sniffResponseWriter) Flush() {
+	w.writePendingHeader()
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Unwrap returns the wrapped response writer.
+func (w *sniffResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *sniffResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, makross.ErrHijackNotSupported
+}
+
+func (w *sniffResponseWriter) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
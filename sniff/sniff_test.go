@@ -0,0 +1,70 @@
+package sniff
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		data        string
+		contentType string
+	}{
+		{`{"id": 1}`, makross.MIMEApplicationJSONCharsetUTF8},
+		{"\n  [1, 2, 3]", makross.MIMEApplicationJSONCharsetUTF8},
+		{`{"id": `, "text/plain; charset=utf-8"},
+		{"hello", "text/plain; charset=utf-8"},
+		{"<html><body>hi</body></html>", "text/html; charset=utf-8"},
+		{"", "text/plain; charset=utf-8"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.contentType, DetectContentType([]byte(test.data)), test.data)
+	}
+}
+
+func TestSniff(t *testing.T) {
+	m := makross.New()
+	m.Use(Sniff())
+	m.Get("/json", func(c *makross.Context) error {
+		_, err := c.Response.Write([]byte(`{"name":"makross"}`))
+		return err
+	})
+	m.Get("/status", func(c *makross.Context) error {
+		c.Response.WriteHeader(makross.StatusCreated)
+		_, err := c.Response.Write([]byte(`[1,2]`))
+		return err
+	})
+	m.Get("/text", func(c *makross.Context) error {
+		return c.String("{not json")
+	})
+	m.Get("/empty", func(c *makross.Context) error {
+		return c.NoContent(makross.StatusAccepted)
+	})
+
+	request := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, path, nil))
+		return res
+	}
+
+	res := request("/json")
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Equal(t, makross.MIMEApplicationJSONCharsetUTF8, res.Header().Get(makross.HeaderContentType))
+	assert.Equal(t, "nosniff", res.Header().Get(makross.HeaderXContentTypeOptions))
+	assert.Equal(t, `{"name":"makross"}`, res.Body.String())
+
+	res = request("/status")
+	assert.Equal(t, makross.StatusCreated, res.Code)
+	assert.Equal(t, makross.MIMEApplicationJSONCharsetUTF8, res.Header().Get(makross.HeaderContentType))
+
+	// explicit content types are kept
+	res = request("/text")
+	assert.Equal(t, makross.MIMETextPlainCharsetUTF8, res.Header().Get(makross.HeaderContentType))
+
+	res = request("/empty")
+	assert.Equal(t, makross.StatusAccepted, res.Code)
+	assert.Equal(t, "", res.Header().Get(makross.HeaderContentType))
+}
@@ -30,6 +30,22 @@ type (
 		// Optional. Default value false.
 		HTML5 bool `json:"html5"`
 
+		// HTML5ExcludePrefixes lists the path prefixes which are never forwarded to root in HTML5 mode,
+		// e.g. "/api/", so that the mistyped paths of an API get a 404 rather than the SPA index.
+		// Optional.
+		HTML5ExcludePrefixes []string `json:"html5_exclude_prefixes"`
+
+		// HTML5ExcludeExtensions lists the file extensions which are never forwarded to root in HTML5 mode,
+		// e.g. ".json" or ".map", even if HTML5AllowExtensions lists them.
+		// Optional.
+		HTML5ExcludeExtensions []string `json:"html5_exclude_extensions"`
+
+		// HTML5AllowExtensions lists the file extensions which are forwarded to root in HTML5 mode,
+		// e.g. ".html". The paths whose last segment has any other extension, such as a missing
+		// "/app.js", get a 404 rather than the SPA index, which browsers and caches would take for the asset.
+		// Optional.
+		HTML5AllowExtensions []string `json:"html5_allow_extensions"`
+
 		// Enable directory browsing.
 		// Optional. Default value false.
 		Browse bool `json:"browse"`
@@ -65,6 +81,24 @@ func StaticWithConfig(config StaticConfig) makross.Handler {
 	if config.Index == "" {
 		config.Index = DefaultStaticConfig.Index
 	}
+	excludedExts := make(map[string]bool, len(config.HTML5ExcludeExtensions))
+	for _, ext := range config.HTML5ExcludeExtensions {
+		excludedExts[strings.ToLower(ext)] = true
+	}
+	allowedExts := make(map[string]bool, len(config.HTML5AllowExtensions))
+	for _, ext := range config.HTML5AllowExtensions {
+		allowedExts[strings.ToLower(ext)] = true
+	}
+	// html5Fallback reports whether a not-found request path is forwarded to root in HTML5 mode.
+	html5Fallback := func(p string) bool {
+		for _, prefix := range config.HTML5ExcludePrefixes {
+			if strings.HasPrefix(p, prefix) {
+				return false
+			}
+		}
+		ext := strings.ToLower(path.Ext(p))
+		return ext == "" || allowedExts[ext] && !excludedExts[ext]
+	}
 
 	return func(c *makross.Context) error {
 		if config.Skipper(c) {
@@ -81,7 +115,7 @@ func StaticWithConfig(config StaticConfig) makross.Handler {
 		fi, err := os.Stat(name)
 		if err != nil {
 			if os.IsNotExist(err) {
-				if config.HTML5 && path.Ext(p) == "" {
+				if config.HTML5 && html5Fallback(c.Request.URL.Path) {
 					return c.ServeFile(filepath.Join(config.Root, config.Index))
 				}
 				return c.Next()
@@ -42,7 +42,7 @@ func TestStatic(t *testing.T) {
 	req = httptest.NewRequest(makross.GET, "/none", nil)
 	rec = httptest.NewRecorder()
 	c = e.NewContext(req, rec, makross.NotFoundHandler)
-	he := h(c).(makross.HTTPError)
+	he := h(c).(*makross.HTTPError)
 	assert.Equal(t, http.StatusNotFound, he.StatusCode())
 
 	// HTML5
@@ -70,3 +70,47 @@ func TestStatic(t *testing.T) {
 		assert.Contains(t, rec.Body.String(), "cert.pem")
 	}
 }
+
+func TestStaticHTML5Exclude(t *testing.T) {
+	e := makross.New()
+	e.Use(StaticWithConfig(StaticConfig{
+		Root:                   "../public",
+		HTML5:                  true,
+		HTML5ExcludePrefixes:   []string{"/api/", "/v1/"},
+		HTML5ExcludeExtensions: []string{".json", ".map"},
+		HTML5AllowExtensions:   []string{".html", ".json"},
+	}))
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/users/42", http.StatusOK},
+		{"/users/42?tab=orders", http.StatusOK},
+		{"/users/john.doe", http.StatusNotFound},
+		{"/about.HTML", http.StatusOK},
+		{"/app.js", http.StatusNotFound},
+		{"/img/logo.png?v=3", http.StatusNotFound},
+		{"/api/typo-ed-path", http.StatusNotFound},
+		{"/api/typo-ed-path?page=2", http.StatusNotFound},
+		{"/v1/users", http.StatusNotFound},
+		{"/config.json", http.StatusNotFound},
+		{"/js/app.JS.MAP?v=3", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(makross.GET, test.path, nil))
+		assert.Equal(t, test.code, rec.Code, test.path)
+		if test.code == http.StatusOK {
+			assert.Contains(t, rec.Body.String(), "Makross", test.path)
+		}
+	}
+
+	// without extensions configured, no path with an extension is forwarded
+	e = makross.New()
+	e.Use(StaticWithConfig(StaticConfig{Root: "../public", HTML5: true}))
+	for path, code := range map[string]int{"/users/42": http.StatusOK, "/users/john.doe": http.StatusNotFound, "/app.js": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(makross.GET, path, nil))
+		assert.Equal(t, code, rec.Code, path)
+	}
+}
@@ -0,0 +1,241 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	ktx "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type (
+	// SSEvent represents a single server-sent event written by Context.SSE().
+	// Data is written as is when it is a string or a byte slice, otherwise it is encoded as JSON.
+	SSEvent struct {
+		ID    string
+		Event string
+		Retry int
+		Data  interface{}
+	}
+
+	// streamConn is an active streaming response registered with the makross.
+	streamConn struct {
+		sync.Mutex
+		c      *Context
+		sse    bool
+		closed bool
+		ctx    ktx.Context // canceled when the stream ends or is closed by CloseStreams
+		cancel ktx.CancelCauseFunc
+	}
+
+	// streamWriter writes to a stream, flushing each write.
+	streamWriter struct {
+		s   *streamConn
+		err error // the error of the last write to the response
+	}
+)
+
+// RenderStreamFlushSize is the amount of output after which Context.RenderStream flushes the response.
+var RenderStreamFlushSize = 8 << 10
+
+// RenderDataKey is the context data key of the data passed to Context.RenderStream,
+// unless the data is a map[string]interface{}.
+const RenderDataKey = "Data"
+
+// flushWriter writes the response header with the first write, and flushes the response every size bytes.
+type flushWriter struct {
+	r       *Response
+	code    int
+	size    int
+	pending int
+}
+
+func (w *flushWriter) Write(b []byte) (int, error) {
+	if !w.r.Committed {
+		w.r.WriteHeader(w.code)
+	}
+	n, err := w.r.Write(b)
+	w.pending += n
+	if w.pending >= w.size {
+		w.r.flush()
+		w.pending = 0
+	}
+	return n, err
+}
+
+// trackStream registers the streaming response of the context so that it can be closed by CloseStreams.
+// The context's standard context is replaced with a child of its own, canceled with the stream, until
+// the stream ends. The returned function must be called when the stream ends: it restores the standard
+// context, which the handler may go on using.
+func (m *Makross) trackStream(c *Context, sse bool) (*streamConn, func()) {
+	parent := c.ktx
+	k, cancel := ktx.WithCancelCause(parent)
+	c.ktx = k
+	s := &streamConn{c: c, sse: sse, ctx: k, cancel: cancel}
+	if c.deadline.IsZero() {
+		// the streams outlive the WriteTimeout of the server, unless the handler set a deadline
+		c.SetWriteDeadline(time.Time{})
+	}
+
+	m.streamsMu.Lock()
+	if m.streams == nil {
+		m.streams = make(map[*streamConn]struct{})
+	}
+	m.streams[s] = struct{}{}
+	m.streamsMu.Unlock()
+
+	return s, func() {
+		m.streamsMu.Lock()
+		delete(m.streams, s)
+		m.streamsMu.Unlock()
+		s.Lock()
+		s.closed = true
+		s.Unlock()
+		cancel(nil)
+		if c.ktx == k {
+			c.ktx = parent
+		}
+	}
+}
+
+// CloseStreams ends all active streaming responses.
+// SSE streams receive graceMsg as a final comment before being closed. The handlers serving the
+// streams observe the closing as a cancellation of Context.Kontext() whose cause is ErrServerClosing.
+// CloseStreams is invoked automatically at the start of Shutdown.
+func (m *Makross) CloseStreams(graceMsg string) {
+	m.streamsMu.Lock()
+	streams := make([]*streamConn, 0, len(m.streams))
+	for s := range m.streams {
+		streams = append(streams, s)
+	}
+	m.streamsMu.Unlock()
+
+	for _, s := range streams {
+		s.Lock()
+		if !s.closed {
+			if s.sse && graceMsg != "" {
+				fmt.Fprintf(s.c.Response, ": %s\n\n", graceMsg)
+			}
+			s.c.Response.flush()
+			s.closed = true
+		}
+		s.Unlock()
+		s.cancel(ErrServerClosing)
+	}
+}
+
+// write writes b to the stream unless it has been closed already.
+func (s *streamConn) write(b []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return ErrServerClosing
+	}
+	if _, err := s.c.Response.Write(b); err != nil {
+		return err
+	}
+	s.c.Response.flush()
+	return nil
+}
+
+// SSE sends the events received from the given channel to the client as server-sent events.
+// It returns nil once the channel is closed. If the client goes away or the server is shutting down,
+// SSE returns the cause of the cancellation, which is ErrServerClosing for the latter.
+func (c *Context) SSE(events <-chan SSEvent) error {
+	s, untrack := c.makross.trackStream(c, true)
+	defer untrack()
+
+	h := c.Response.Header()
+	h.Set(HeaderContentType, MIMETextEventStream)
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	c.Response.WriteHeader(StatusOK)
+	c.Response.flush()
+
+	var done <-chan struct{}
+	if c.Request != nil {
+		done = c.Request.Context().Done()
+	}
+	for {
+		select {
+		case <-s.ctx.Done():
+			return ktx.Cause(s.ctx)
+		case <-done:
+			return c.Request.Context().Err()
+		case e, ok := <-events:
+			if !ok {
+				c.Abort()
+				return nil
+			}
+			b, err := e.encode()
+			if err != nil {
+				return err
+			}
+			if err = s.write(b); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// encode formats the event according to the text/event-stream format.
+func (e SSEvent) encode() ([]byte, error) {
+	var data []byte
+	switch d := e.Data.(type) {
+	case []byte:
+		data = d
+	case string:
+		data = []byte(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+
+	buf := make([]byte, 0, len(data)+64)
+	if e.ID != "" {
+		buf = append(buf, "id: "+e.ID+"\n"...)
+	}
+	if e.Event != "" {
+		buf = append(buf, "event: "+e.Event+"\n"...)
+	}
+	if e.Retry > 0 {
+		buf = append(buf, fmt.Sprintf("retry: %d\n", e.Retry)...)
+	}
+	start := 0
+	for i := 0; i <= len(data); i++ {
+		if i == len(data) || data[i] == '\n' {
+			buf = append(buf, "data: "...)
+			buf = append(buf, data[start:i]...)
+			buf = append(buf, '\n')
+			start = i + 1
+		}
+	}
+	return append(buf, '\n'), nil
+}
+
+// streamCopy copies r into the stream, flushing each chunk, and stops early when the stream is canceled.
+func (c *Context) streamCopy(s *streamConn, r io.Reader) error {
+	w := &streamWriter{s: s}
+	_, err := io.Copy(w, r)
+	if w.err != nil {
+		return c.slowClient(w.err)
+	}
+	return err
+}
+
+// Write writes b to the stream and flushes it, unless the stream is canceled.
+func (w *streamWriter) Write(b []byte) (int, error) {
+	if w.s.ctx.Err() != nil {
+		return 0, ktx.Cause(w.s.ctx)
+	}
+	if w.err = w.s.write(b); w.err != nil {
+		return 0, w.err
+	}
+	return len(b), nil
+}
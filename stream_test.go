@@ -0,0 +1,99 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEvent(t *testing.T) {
+	b, err := SSEvent{ID: "1", Event: "tick", Data: "a\nb"}.encode()
+	assert.Nil(t, err)
+	assert.Equal(t, "id: 1\nevent: tick\ndata: a\ndata: b\n\n", string(b))
+
+	b, err = SSEvent{Retry: 10, Data: map[string]int{"n": 1}}.encode()
+	assert.Nil(t, err)
+	assert.Equal(t, "retry: 10\ndata: {\"n\":1}\n\n", string(b))
+}
+
+func TestShutdownClosesStreams(t *testing.T) {
+	m := New()
+	result := make(chan error, 1)
+	m.Get("/events", func(c *Context) error {
+		events := make(chan SSEvent, 1)
+		events <- SSEvent{Data: "hello"}
+		err := c.SSE(events)
+		result <- err
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go m.Server.Serve(ln)
+
+	res, err := http.Get("http://" + ln.Addr().String() + "/events")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer res.Body.Close()
+	assert.Equal(t, MIMETextEventStream, res.Header.Get(HeaderContentType))
+	r := bufio.NewReader(res.Body)
+	line, _ := r.ReadString('\n')
+	assert.Equal(t, "data: hello\n", line)
+
+	start := time.Now()
+	assert.Nil(t, m.Shutdown(3))
+	assert.True(t, time.Since(start) < time.Second, "Shutdown should not wait for the stream")
+	assert.Equal(t, ErrServerClosing, <-result)
+
+	var rest strings.Builder
+	for {
+		l, err := r.ReadString('\n')
+		rest.WriteString(l)
+		if err != nil {
+			break
+		}
+	}
+	assert.Contains(t, rest.String(), ": "+ShutdownStreamMessage+"\n\n")
+}
+
+// writerToReader counts the calls to its WriteTo method.
+type writerToReader struct {
+	*strings.Reader
+	calls int
+}
+
+func (r *writerToReader) WriteTo(w io.Writer) (int64, error) {
+	r.calls++
+	return r.Reader.WriteTo(w)
+}
+
+func TestContextStream(t *testing.T) {
+	m := New()
+	body := &writerToReader{Reader: strings.NewReader("hello")}
+	m.Get("/", func(c *Context) error {
+		if err := c.Stream(MIMETextPlain, body); err != nil {
+			return err
+		}
+		// the standard context outlives the stream
+		assert.Nil(t, c.Kontext().Err())
+		return nil
+	})
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(GET, "/", nil))
+	assert.Equal(t, "hello", res.Body.String())
+	assert.True(t, res.Flushed)
+	// the fast path of the reader is used
+	assert.Equal(t, 1, body.calls)
+}
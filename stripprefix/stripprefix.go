@@ -0,0 +1,163 @@
+package stripprefix
+
+import (
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// StripPrefixConfig defines the config for StripPrefix middleware.
+	StripPrefixConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Prefix is removed from the request path, e.g. "/app".
+		// Required.
+		Prefix string `json:"prefix"`
+	}
+
+	// RewritePrefixConfig defines the config for RewritePrefix middleware.
+	RewritePrefixConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// From is the prefix to replace, e.g. "/v1".
+		// Required.
+		From string `json:"from"`
+
+		// To replaces From in the request path, e.g. "/api/v1".
+		// Optional. Default value "/".
+		To string `json:"to"`
+	}
+)
+
+// OriginalPathKey is the store key of the request path before it was changed, e.g. for logging.
+const OriginalPathKey = "original_path"
+
+var (
+	// DefaultStripPrefixConfig is the default StripPrefix middleware config.
+	DefaultStripPrefixConfig = StripPrefixConfig{
+		Skipper: skipper.DefaultSkipper,
+	}
+
+	// DefaultRewritePrefixConfig is the default RewritePrefix middleware config.
+	DefaultRewritePrefixConfig = RewritePrefixConfig{
+		Skipper: skipper.DefaultSkipper,
+		To:      "/",
+	}
+)
+
+// StripPrefix returns a root level (before router) middleware which removes the prefix
+// from the request path, e.g. for an application mounted under a path prefix by a load balancer.
+// The requests outside the prefix get a "404 - Not Found" response.
+//
+// Usage `makross#Pre(StripPrefix("/app"))`
+func StripPrefix(prefix string) makross.Handler {
+	c := DefaultStripPrefixConfig
+	c.Prefix = prefix
+	return StripPrefixWithConfig(c)
+}
+
+// StripPrefixWithConfig returns a StripPrefix middleware with config.
+// See `StripPrefix()`.
+func StripPrefixWithConfig(config StripPrefixConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultStripPrefixConfig.Skipper
+	}
+	prefix := strings.TrimSuffix(config.Prefix, "/")
+	if prefix == "" {
+		panic("stripprefix: prefix is required")
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		if !rewrite(c, prefix, "/") {
+			return makross.ErrNotFound
+		}
+		return c.Next()
+	}
+}
+
+// RewritePrefix returns a root level (before router) middleware which replaces the from prefix
+// of the request path with the to prefix. The requests outside the prefix are left unchanged.
+//
+// Usage `makross#Pre(RewritePrefix("/v1", "/api/v1"))`
+func RewritePrefix(from, to string) makross.Handler {
+	c := DefaultRewritePrefixConfig
+	c.From = from
+	c.To = to
+	return RewritePrefixWithConfig(c)
+}
+
+// RewritePrefixWithConfig returns a RewritePrefix middleware with config.
+// See `RewritePrefix()`.
+func RewritePrefixWithConfig(config RewritePrefixConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultRewritePrefixConfig.Skipper
+	}
+	if config.To == "" {
+		config.To = DefaultRewritePrefixConfig.To
+	}
+	from := strings.TrimSuffix(config.From, "/")
+	if from == "" {
+		panic("stripprefix: from prefix is required")
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		rewrite(c, from, config.To)
+		return c.Next()
+	}
+}
+
+// rewrite replaces the prefix of the request path, which has no trailing slash, with to.
+// It reports whether the path has the prefix, as a whole path segment.
+func rewrite(c *makross.Context, prefix, to string) bool {
+	u := c.Request.URL
+	rest, ok := trimPrefix(u.Path, prefix)
+	if !ok {
+		return false
+	}
+	if _, stored := c.Get(OriginalPathKey).(string); !stored {
+		c.Set(OriginalPathKey, u.Path)
+	}
+	u.Path = join(to, rest)
+	if u.RawPath != "" {
+		if rest, ok := trimPrefix(u.RawPath, prefix); ok {
+			u.RawPath = join(to, rest)
+		} else {
+			u.RawPath = ""
+		}
+	}
+	return true
+}
+
+// trimPrefix returns the path without the prefix, "" or starting with "/".
+func trimPrefix(path, prefix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	rest := path[len(prefix):]
+	if rest != "" && rest[0] != '/' {
+		return "", false
+	}
+	return rest, true
+}
+
+func join(prefix, rest string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if rest == "" {
+		rest = "/"
+	}
+	return prefix + rest
+}
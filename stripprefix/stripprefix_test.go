@@ -0,0 +1,87 @@
+package stripprefix
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMakross(pre makross.Handler) *makross.Makross {
+	m := makross.New()
+	m.Pre(pre)
+	handler := func(c *makross.Context) error {
+		original, _ := c.Get(OriginalPathKey).(string)
+		return c.String(c.Request.URL.Path + " " + original)
+	}
+	m.Get("/", handler)
+	m.Get("/users/<id>", handler)
+	m.Get("/api/v1/users", handler)
+	return m
+}
+
+func TestStripPrefix(t *testing.T) {
+	m := newMakross(StripPrefix("/app/"))
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/app/users/1", makross.StatusOK, "/users/1 /app/users/1"},
+		{"/app", makross.StatusOK, "/ /app"},
+		{"/app/", makross.StatusOK, "/ /app/"},
+		{"/app/users/1?x=1", makross.StatusOK, "/users/1 /app/users/1"},
+		{"/users/1", makross.StatusNotFound, ""},
+		{"/application/users/1", makross.StatusNotFound, ""},
+		{"/", makross.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, test.path, nil))
+		assert.Equal(t, test.code, res.Code, test.path)
+		if test.code == makross.StatusOK {
+			assert.Equal(t, test.body, res.Body.String(), test.path)
+		}
+	}
+
+	assert.Panics(t, func() {
+		StripPrefix("/")
+	})
+}
+
+func TestStripPrefixRawPath(t *testing.T) {
+	m := makross.New()
+	req := httptest.NewRequest(makross.GET, "/app/files/a%2Fb", nil)
+	c := m.NewContext(req, httptest.NewRecorder(), StripPrefix("/app"))
+	assert.Nil(t, c.Next())
+	assert.Equal(t, "/files/a/b", req.URL.Path)
+	assert.Equal(t, "/files/a%2Fb", req.URL.RawPath)
+}
+
+func TestRewritePrefix(t *testing.T) {
+	m := newMakross(RewritePrefix("/v1", "/api/v1"))
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/v1/users", makross.StatusOK, "/api/v1/users /v1/users"},
+		{"/users/1", makross.StatusOK, "/users/1 "},
+		{"/v10/users", makross.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest(makross.GET, test.path, nil))
+		assert.Equal(t, test.code, res.Code, test.path)
+		if test.code == makross.StatusOK {
+			assert.Equal(t, test.body, res.Body.String(), test.path)
+		}
+	}
+
+	// to the root
+	m = newMakross(RewritePrefix("/legacy", ""))
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/legacy/users/7", nil))
+	assert.Equal(t, "/users/7 /legacy/users/7", res.Body.String())
+}
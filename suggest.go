@@ -0,0 +1,149 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"html"
+	"sort"
+	"strings"
+)
+
+// RouteSuggestion is a route suggested for a request without matching route, see SuggestRoutes.
+type RouteSuggestion struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// maxRouteSuggestions is the number of routes suggested by the 404 responses.
+const maxRouteSuggestions = 3
+
+// SuggestRoutes returns up to n routes of the method whose paths are the nearest to the path, the nearest first,
+// by the Levenshtein distance between the path and the route paths, the parameters matching any path segment,
+// a missing one counting as its slash.
+// The routes too far from the path, by more than a third of its length, aren't suggested.
+func (m *Makross) SuggestRoutes(method, path string, n int) []RouteSuggestion {
+	return m.suggestRoutes(method, path, n, nil)
+}
+
+// suggestRoutes is SuggestRoutes among the routes kept by the filter.
+func (m *Makross) suggestRoutes(method, path string, n int, filter RouteFilter) []RouteSuggestion {
+	type candidate struct {
+		route    *Route
+		distance int
+	}
+	max := len(path) / 3
+	if max < 2 {
+		max = 2
+	}
+	var candidates []candidate
+	for _, route := range m.routes {
+		if route.method != method || !filter.keeps(route) {
+			continue
+		}
+		if d := Levenshtein(path, ProjectPath(route.Path(), path)); d <= max {
+			candidates = append(candidates, candidate{route, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	suggestions := make([]RouteSuggestion, len(candidates))
+	for i, c := range candidates {
+		description, _ := c.route.GetMeta(MetaDescription).(string)
+		suggestions[i] = RouteSuggestion{
+			Method:      c.route.method,
+			Path:        c.route.Path(),
+			Name:        c.route.name,
+			Description: description,
+		}
+	}
+	return suggestions
+}
+
+// notFoundSuggestions responds to a request without matching route with the suggested routes,
+// to the clients accepting HTML or JSON.
+func (m *Makross) notFoundSuggestions(c *Context) error {
+	format := NegotiateErrorFormat
+	if m.ErrorFormatFunc != nil {
+		format = m.ErrorFormatFunc
+	}
+	f := format(c)
+	if f != MIMETextHTML && f != MIMEApplicationJSON {
+		return NewHTTPError(StatusNotFound)
+	}
+	// the routes hidden from the listener of the request aren't suggested
+	suggestions := m.suggestRoutes(c.Request.Method, c.Request.URL.Path, maxRouteSuggestions, c.filter)
+	if len(suggestions) == 0 {
+		return NewHTTPError(StatusNotFound)
+	}
+	msg := StatusText(StatusNotFound)
+	if f == MIMEApplicationJSON {
+		return c.JSON(map[string]interface{}{
+			"status":      StatusNotFound,
+			"message":     msg,
+			"suggestions": suggestions,
+		}, StatusNotFound)
+	}
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><title>404 " + msg + "</title></head><body>\n")
+	b.WriteString("<h1>" + msg + "</h1>\n<p>Did you mean:</p>\n<ul>\n")
+	for _, s := range suggestions {
+		b.WriteString("<li><code>" + html.EscapeString(s.Method+" "+s.Path) + "</code>")
+		if s.Name != "" {
+			b.WriteString(" (" + html.EscapeString(s.Name) + ")")
+		}
+		if s.Description != "" {
+			b.WriteString(": " + html.EscapeString(s.Description))
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n</body></html>\n")
+	return c.Blob(MIMETextHTMLCharsetUTF8, []byte(b.String()), StatusNotFound)
+}
+
+// ProjectPath returns the route path with its parameters replaced by the segments of the path
+// at the same position, so that they match any value, or removed if the path has no such segment.
+// It's used to compare a request path with the route paths, e.g. with Levenshtein.
+func ProjectPath(route, path string) string {
+	if !strings.Contains(route, "<") {
+		return route
+	}
+	rs := strings.Split(route, "/")
+	ps := strings.Split(path, "/")
+	for i, s := range rs {
+		if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+			if i < len(ps) {
+				rs[i] = ps[i]
+			} else {
+				rs[i] = ""
+			}
+		}
+	}
+	return strings.Join(rs, "/")
+}
+
+// Levenshtein returns the edit distance between a and b, by bytes.
+func Levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
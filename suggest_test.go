@@ -0,0 +1,116 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"/users", "/user", 1},
+		{"/users", "/users", 0},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.distance, Levenshtein(test.a, test.b), test.a+" "+test.b)
+	}
+	assert.Equal(t, "/users/7/posts", ProjectPath("/users/<id>/posts", "/users/7/posts"))
+	assert.Equal(t, "/users/", ProjectPath("/users/<id>", "/users"))
+}
+
+func TestSuggestRoutes(t *testing.T) {
+	m := New()
+	m.Get("/users", NotFoundHandler)
+	m.Get("/users/<id>", NotFoundHandler).Name("user").Describe("Returns the user of the ID")
+	m.Get("/users/<id>/posts", NotFoundHandler)
+	m.Post("/user", NotFoundHandler)
+	m.Get("/orders", NotFoundHandler)
+	m.Get("/order/<id>", NotFoundHandler)
+
+	assert.Equal(t, []RouteSuggestion{
+		{GET, "/users", "", ""},
+		{GET, "/users/<id>", "user", "Returns the user of the ID"},
+	}, m.SuggestRoutes(GET, "/user", 3))
+	assert.Equal(t, []RouteSuggestion{
+		{GET, "/orders", "", ""},
+		{GET, "/order/<id>", "", ""},
+	}, m.SuggestRoutes(GET, "/order", 3))
+	assert.Equal(t, []RouteSuggestion{{GET, "/users/<id>/posts", "", ""}}, m.SuggestRoutes(GET, "/users/7/post", 3))
+	assert.Equal(t, []RouteSuggestion{
+		{GET, "/users/<id>", "user", "Returns the user of the ID"},
+	}, m.SuggestRoutes(GET, "/user/7", 1))
+	assert.Equal(t, []RouteSuggestion{{POST, "/user", "", ""}}, m.SuggestRoutes(POST, "/users", 3))
+	assert.Empty(t, m.SuggestRoutes(GET, "/products/catalog", 3))
+	assert.Empty(t, m.SuggestRoutes(PUT, "/users", 3))
+}
+
+func TestNotFoundSuggestions(t *testing.T) {
+	m := New()
+	m.RouteSuggestions = true
+	m.Debug = true
+	m.Get("/users/<id>", func(c *Context) error {
+		if c.Param("id").String() != "1" {
+			return NotFoundHandler(c)
+		}
+		return c.String("user")
+	}).Name("user").Describe(`Returns the <b>user</b> & "friends"`)
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(GET, path, nil)
+		if accept != "" {
+			req.Header.Set(HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("/user/1", MIMEApplicationJSON)
+	assert.Equal(t, StatusNotFound, res.Code)
+	var body struct {
+		Status      int               `json:"status"`
+		Suggestions []RouteSuggestion `json:"suggestions"`
+	}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.Equal(t, StatusNotFound, body.Status)
+	assert.Equal(t, []RouteSuggestion{{GET, "/users/<id>", "user", `Returns the <b>user</b> & "friends"`}}, body.Suggestions)
+
+	res = serve("/user/<script>alert(1)</script>", "text/html")
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.Equal(t, MIMETextHTMLCharsetUTF8, res.Header().Get(HeaderContentType))
+	assert.Contains(t, res.Body.String(),
+		"<li><code>GET /users/&lt;id&gt;</code> (user): Returns the &lt;b&gt;user&lt;/b&gt; &amp; &#34;friends&#34;</li>")
+	assert.NotContains(t, res.Body.String(), "<script>")
+	assert.NotContains(t, res.Body.String(), "<b>")
+
+	// a handler answering 404 on a matching route gets no suggestions
+	res = serve("/users/2", MIMEApplicationJSON)
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.NotContains(t, res.Body.String(), "suggestions")
+
+	// nor the clients accepting neither HTML nor JSON, the requests without near route, and the other methods
+	assert.Equal(t, StatusText(StatusNotFound), serve("/user/1", "").Body.String())
+	assert.NotContains(t, serve("/products", MIMEApplicationJSON).Body.String(), "suggestions")
+	res = httptest.NewRecorder()
+	req := httptest.NewRequest(DELETE, "/user/1", nil)
+	req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+	m.ServeHTTP(res, req)
+	assert.NotContains(t, res.Body.String(), "suggestions")
+
+	// never in production
+	m.Debug = false
+	res = serve("/user/1", MIMEApplicationJSON)
+	assert.Equal(t, StatusNotFound, res.Code)
+	assert.NotContains(t, res.Body.String(), "suggestions")
+	assert.NotContains(t, serve("/user/1", "text/html").Body.String(), "users")
+}
@@ -0,0 +1,162 @@
+package tenant
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// TenantConfig defines the config for Tenant middleware.
+	TenantConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Resolver returns the tenant named by the request, or "" if it names none.
+		// Optional. Default value FromHeader(HeaderXTenant).
+		Resolver Resolver
+
+		// Lookup reports whether the tenant exists, e.g. from the tenants table.
+		// Required.
+		Lookup func(ctx context.Context, tenant string) (bool, error)
+	}
+
+	// Resolver returns the tenant named by a request, or "" if it names none.
+	Resolver func(c *makross.Context) string
+
+	// ContextKey is the type of the keys of the values stored in the standard context by the middleware.
+	ContextKey string
+)
+
+const (
+	// HeaderXTenant is the header naming the tenant of a request.
+	HeaderXTenant = "X-Tenant"
+
+	// TenantKey is the key of the tenant in the standard context returned by Context.Kontext.
+	TenantKey ContextKey = "tenant"
+)
+
+var (
+	// DefaultTenantConfig is the default Tenant middleware config.
+	DefaultTenantConfig = TenantConfig{
+		Skipper:  skipper.DefaultSkipper,
+		Resolver: FromHeader(HeaderXTenant),
+	}
+
+	// ErrTenantMissing is returned when the request names no tenant.
+	ErrTenantMissing = makross.NewHTTPError(makross.StatusBadRequest, "tenant missing")
+
+	// ErrUnknownTenant is returned when the tenant named by the request doesn't exist.
+	ErrUnknownTenant = makross.NewHTTPError(makross.StatusNotFound, "unknown tenant")
+)
+
+// Tenant returns a Tenant middleware, resolving the tenant from the X-Tenant header.
+//
+// Tenant middleware resolves the tenant of the request with the Resolver, checks that it exists with
+// Lookup, and stores it in the context, so that the handlers can scope their queries with
+// `Context#Tenant()`, in the standard context under TenantKey, for the services called with
+// `Context#Kontext()`, and in the fields of the request logger. The requests naming no tenant are
+// rejected with "400 - Bad Request", and the ones naming an unknown tenant with "404 - Not Found":
+//
+//	m.Use(tenant.TenantWithConfig(tenant.TenantConfig{
+//		Resolver: tenant.First(tenant.FromSubdomain("app.com"), tenant.FromHeader(tenant.HeaderXTenant)),
+//		Lookup:   tenants.Exists,
+//	}))
+//
+// The subdomains are taken from `Context#Host()`, so that the tenants are resolved from the host
+// requested by the client behind a proxy trusted by `Makross#SetTrustedProxies()`. The routes served
+// on the bare domain, e.g. the sign up page, can be excluded with the Skipper.
+func Tenant(lookup func(ctx context.Context, tenant string) (bool, error)) makross.Handler {
+	c := DefaultTenantConfig
+	c.Lookup = lookup
+	return TenantWithConfig(c)
+}
+
+// TenantWithConfig returns a Tenant middleware with config.
+// See: `Tenant()`.
+func TenantWithConfig(config TenantConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultTenantConfig.Skipper
+	}
+	if config.Resolver == nil {
+		config.Resolver = DefaultTenantConfig.Resolver
+	}
+	if config.Lookup == nil {
+		panic("tenant: lookup is required")
+	}
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		tenant := config.Resolver(c)
+		if tenant == "" {
+			return ErrTenantMissing
+		}
+		ok, err := config.Lookup(c.Kontext(), tenant)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrUnknownTenant
+		}
+		c.Set(makross.TenantKey, tenant)
+		c.WithValue(TenantKey, tenant)
+		c.SetLogger(c.Logger().With(map[string]interface{}{string(TenantKey): tenant}))
+		return c.Next()
+	}
+}
+
+// FromSubdomain returns a Resolver taking the tenant from the subdomain of the domain,
+// e.g. "acme" for "acme.app.com" with the "app.com" domain. The hosts outside of the domain,
+// and the nested subdomains such as "eu.acme.app.com", name no tenant.
+func FromSubdomain(domain string) Resolver {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(c *makross.Context) string {
+		host := c.Host()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := host[:len(host)-len(suffix)]
+		if strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// FromHeader returns a Resolver taking the tenant from the header. The header is added to the
+// Vary header of the response, as the responses differ by tenant.
+func FromHeader(header string) Resolver {
+	return func(c *makross.Context) string {
+		c.Response.Header().Add(makross.HeaderVary, header)
+		return strings.TrimSpace(c.Request.Header.Get(header))
+	}
+}
+
+// First returns a Resolver returning the first tenant resolved by the resolvers, in order.
+func First(resolvers ...Resolver) Resolver {
+	return func(c *makross.Context) string {
+		for _, r := range resolvers {
+			if tenant := r(c); tenant != "" {
+				return tenant
+			}
+		}
+		return ""
+	}
+}
+
+// FromContext returns the tenant stored in the given standard context, or an empty string if there is none.
+func FromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(TenantKey).(string)
+	return tenant
+}
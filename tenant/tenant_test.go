@@ -0,0 +1,87 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenant(t *testing.T) {
+	tenants := map[string]bool{"acme": true, "globex": true}
+	lookup := func(ctx context.Context, tenant string) (bool, error) {
+		if tenant == "broken" {
+			return false, errors.New("database down")
+		}
+		return tenants[tenant], nil
+	}
+	handler := func(c *makross.Context) error {
+		assert.Equal(t, c.Tenant(), FromContext(c.Kontext()))
+		return c.String(c.Tenant())
+	}
+	m := makross.New()
+	m.Get("/header", Tenant(lookup), handler)
+	m.Get("/host", TenantWithConfig(TenantConfig{
+		Resolver: First(FromSubdomain("App.com"), FromHeader(HeaderXTenant)),
+		Lookup:   lookup,
+	}), handler)
+
+	tests := []struct {
+		path, host, header string
+		code               int
+		body               string
+	}{
+		{"/header", "", "acme", makross.StatusOK, "acme"},
+		{"/header", "globex.app.com", "acme", makross.StatusOK, "acme"},
+		{"/header", "", "initech", makross.StatusNotFound, ""},
+		{"/header", "acme.app.com", "", makross.StatusBadRequest, ""},
+		{"/header", "", "broken", makross.StatusInternalServerError, ""},
+		{"/host", "acme.app.com", "", makross.StatusOK, "acme"},
+		{"/host", "GLOBEX.app.com:8080", "", makross.StatusOK, "globex"},
+		{"/host", "acme.app.com", "globex", makross.StatusOK, "acme"},
+		{"/host", "initech.app.com", "", makross.StatusNotFound, ""},
+		{"/host", "app.com", "", makross.StatusBadRequest, ""},
+		{"/host", "eu.acme.app.com", "", makross.StatusBadRequest, ""},
+		{"/host", "acme.example.com", "", makross.StatusBadRequest, ""},
+		{"/host", "app.com", "globex", makross.StatusOK, "globex"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(makross.GET, test.path, nil)
+		if test.host != "" {
+			req.Host = test.host
+		}
+		if test.header != "" {
+			req.Header.Set(HeaderXTenant, test.header)
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, test.code, res.Code, test.host+" "+test.header)
+		if test.code == makross.StatusOK {
+			assert.Equal(t, test.body, res.Body.String(), test.host+" "+test.header)
+		}
+	}
+
+	// the tenant is resolved from the host requested through a trusted proxy only
+	req := httptest.NewRequest(makross.GET, "/host", nil)
+	req.Host = "internal:8080"
+	req.Header.Set(makross.HeaderXForwardedHost, "acme.app.com")
+	m.SetTrustedProxies("198.51.100.1")
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, makross.StatusBadRequest, res.Code)
+	m.SetTrustedProxies("192.0.2.1")
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	assert.Equal(t, "acme", res.Body.String())
+
+	res = httptest.NewRecorder()
+	req = httptest.NewRequest(makross.GET, "/header", nil)
+	req.Header.Set(HeaderXTenant, "acme")
+	m.ServeHTTP(res, req)
+	assert.Equal(t, HeaderXTenant, res.Header().Get(makross.HeaderVary))
+
+	assert.Panics(t, func() { TenantWithConfig(TenantConfig{}) })
+}
@@ -0,0 +1,119 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timing is the duration of a named section of the handling of a request, see `Context#StartTimer()`.
+type Timing struct {
+	Name        string
+	Duration    time.Duration
+	Description string // optional, see `Context#AddServerTiming()`
+}
+
+// StartTimer starts measuring the named section of the handling of the request, and returns the function
+// stopping it. The durations of the sections of the same name add up. It is a lightweight alternative to
+// the tracing spans, for the performance debugging:
+//
+//	stop := c.StartTimer("db")
+//	users, err := db.Users(ctx)
+//	stop()
+//
+// The timings are returned by Timings, and by ServerTiming in the format of the Server-Timing header,
+// which the Logger middleware can send. The name should be a token, e.g. "db" or "render.page".
+// The returned function may be called from another goroutine, and only its first call counts.
+func (c *Context) StartTimer(name string) func() {
+	start := time.Now()
+	stopped := false
+	return func() {
+		d := time.Since(start)
+		c.timingMu.Lock()
+		defer c.timingMu.Unlock()
+		if stopped {
+			return
+		}
+		stopped = true
+		c.addTiming(name, d, "")
+	}
+}
+
+// AddServerTiming adds the duration of the named phase of the handling of the request, e.g. measured
+// by a database client, with an optional description, to the Server-Timing header of the response,
+// which the browser devtools show. The header is written with the header of the response, and holds
+// all the timings of the request, including the ones measured with StartTimer:
+//
+//	c.AddServerTiming("db", queryDuration, "Database")
+//	c.AddServerTiming("cache", 0, "miss")
+//	// Server-Timing: db;dur=53.2;desc="Database", cache;dur=0;desc="miss"
+//
+// Like with StartTimer, the durations of the same name add up, and the description is replaced.
+func (c *Context) AddServerTiming(name string, dur time.Duration, desc string) {
+	c.timingMu.Lock()
+	defer c.timingMu.Unlock()
+	c.addTiming(name, dur, desc)
+	if !c.sendTiming {
+		c.sendTiming = true
+		c.Response.Before(c.writeServerTiming)
+	}
+}
+
+// addTiming adds the duration to the timing of the name. It must be called with the timingMu held.
+func (c *Context) addTiming(name string, d time.Duration, desc string) {
+	for i := range c.timings {
+		if c.timings[i].Name == name {
+			c.timings[i].Duration += d
+			if desc != "" {
+				c.timings[i].Description = desc
+			}
+			return
+		}
+	}
+	c.timings = append(c.timings, Timing{Name: name, Duration: d, Description: desc})
+}
+
+// writeServerTiming sets the Server-Timing header of the response.
+func (c *Context) writeServerTiming() {
+	if timing := c.ServerTiming(); timing != "" {
+		c.Response.Header().Set(HeaderServerTiming, timing)
+	}
+}
+
+// Timings returns the durations of the sections measured with StartTimer by name, or nil if there are none.
+func (c *Context) Timings() map[string]time.Duration {
+	c.timingMu.Lock()
+	defer c.timingMu.Unlock()
+	if len(c.timings) == 0 {
+		return nil
+	}
+	timings := make(map[string]time.Duration, len(c.timings))
+	for _, t := range c.timings {
+		timings[t.Name] = t.Duration
+	}
+	return timings
+}
+
+// ServerTiming returns the timings in the format of the Server-Timing header, in milliseconds
+// and in the order the sections were stopped first, e.g. `db;dur=12.5;desc="Database", render;dur=3.25`.
+func (c *Context) ServerTiming() string {
+	c.timingMu.Lock()
+	defer c.timingMu.Unlock()
+	var b strings.Builder
+	for i, t := range c.timings {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		ms := float64(t.Duration.Round(time.Microsecond)) / float64(time.Millisecond)
+		b.WriteString(t.Name)
+		b.WriteString(";dur=")
+		b.WriteString(strconv.FormatFloat(ms, 'f', -1, 64))
+		if t.Description != "" {
+			b.WriteString(";desc=")
+			writeQuoted(&b, t.Description)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/insionng/makross"
+)
+
+// RenameKeys returns a Transformer renaming the keys of the JSON objects at any depth, e.g.
+// {"firstName": "first_name"}. Bodies which aren't JSON objects or arrays are left unchanged.
+func RenameKeys(names map[string]string) Transformer {
+	return JSON(func(v interface{}) interface{} {
+		return walk(v, func(obj map[string]interface{}) {
+			// remove all the keys first, so that keys can be swapped
+			renamed := make(map[string]interface{})
+			for from, to := range names {
+				if value, ok := obj[from]; ok {
+					delete(obj, from)
+					renamed[to] = value
+				}
+			}
+			for key, value := range renamed {
+				obj[key] = value
+			}
+		})
+	})
+}
+
+// DropFields returns a Transformer removing the fields of the JSON objects at any depth.
+// Bodies which aren't JSON objects or arrays are left unchanged.
+func DropFields(names ...string) Transformer {
+	return JSON(func(v interface{}) interface{} {
+		return walk(v, func(obj map[string]interface{}) {
+			for _, name := range names {
+				delete(obj, name)
+			}
+		})
+	})
+}
+
+// JSON returns a Transformer decoding the JSON body, passing it to fn and encoding the result.
+// The numbers are decoded as json.Number, so that they are kept as is.
+// Bodies which aren't JSON objects or arrays are left unchanged.
+func JSON(fn func(v interface{}) interface{}) Transformer {
+	return func(c *makross.Context, body []byte) ([]byte, error) {
+		trimmed := bytes.TrimSpace(body)
+		if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+			return body, nil
+		}
+		d := json.NewDecoder(bytes.NewReader(trimmed))
+		d.UseNumber()
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		e := json.NewEncoder(&buf)
+		e.SetEscapeHTML(false)
+		if err := e.Encode(fn(v)); err != nil {
+			return nil, err
+		}
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+	}
+}
+
+// Chain returns a Transformer applying the transformers in order.
+func Chain(transformers ...Transformer) Transformer {
+	return func(c *makross.Context, body []byte) ([]byte, error) {
+		var err error
+		for _, t := range transformers {
+			if body, err = t(c, body); err != nil {
+				return nil, err
+			}
+		}
+		return body, nil
+	}
+}
+
+// walk calls fn with every JSON object of v, parents first.
+func walk(v interface{}, fn func(map[string]interface{})) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		fn(v)
+		for _, value := range v {
+			walk(value, fn)
+		}
+	case []interface{}:
+		for _, value := range v {
+			walk(value, fn)
+		}
+	}
+	return v
+}
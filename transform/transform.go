@@ -0,0 +1,169 @@
+package transform
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/buffer"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// Transformer rewrites a request or a response body.
+	Transformer func(c *makross.Context, body []byte) ([]byte, error)
+
+	// TransformConfig defines the config for Transform middleware.
+	TransformConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// MaxSize is the maximum size of the bodies transformed. A larger request body gets a
+		// "413 - Request Entity Too Large" response, and a larger response body, which can't be sent
+		// untransformed, a "500 - Internal Server Error" one.
+		// Optional. Default value 1MB.
+		MaxSize int64 `json:"max_size"`
+	}
+)
+
+const (
+	// RequestKey is the route metadata key of the Transformer of the request bodies.
+	RequestKey = "transform.request"
+
+	// ResponseKey is the route metadata key of the Transformer of the response bodies.
+	ResponseKey = "transform.response"
+)
+
+var (
+	// DefaultTransformConfig is the default Transform middleware config.
+	DefaultTransformConfig = TransformConfig{
+		Skipper: skipper.DefaultSkipper,
+		MaxSize: 1 << 20,
+	}
+
+	errTooLarge = errors.New("body too large")
+)
+
+// Transform returns a Transform middleware.
+//
+// Transform middleware rewrites the request and response bodies of the routes with transformers
+// in their metadata, e.g. to rename legacy fields during an API migration without touching the handlers:
+//
+//	m.Use(transform.Transform())
+//	m.Post("/users", createUser).
+//		Meta(transform.RequestKey, transform.RenameKeys(map[string]string{"firstName": "first_name"})).
+//		Meta(transform.ResponseKey, transform.RenameKeys(map[string]string{"first_name": "firstName"}))
+//
+// The request body is read and replaced before the handler binds it, and the response is buffered
+// until the handler returns, both up to TransformConfig.MaxSize. A transformer error gets a "500 - Internal Server Error" response and is logged.
+//
+// The transformers see the bodies as the handlers do, so Transform must run after the middlewares
+// changing the encoding: register it after compress.Gzip, otherwise it gets the compressed response.
+func Transform() makross.Handler {
+	return TransformWithConfig(DefaultTransformConfig)
+}
+
+// TransformWithConfig returns a Transform middleware with config.
+// See: `Transform()`.
+func TransformWithConfig(config TransformConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultTransformConfig.Skipper
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultTransformConfig.MaxSize
+	}
+
+	return func(c *makross.Context) error {
+		route := c.Route()
+		if config.Skipper(c) || route == nil {
+			return c.Next()
+		}
+		reqT, _ := route.GetMeta(RequestKey).(Transformer)
+		resT, _ := route.GetMeta(ResponseKey).(Transformer)
+
+		if reqT != nil {
+			if err := transformRequest(c, reqT, config.MaxSize); err != nil {
+				if err == errTooLarge {
+					return makross.ErrStatusRequestEntityTooLarge
+				}
+				c.Makross().Logger().Errorf("transform: %s request: %v", route.String(), err)
+				return makross.NewHTTPError(makross.StatusInternalServerError)
+			}
+		}
+		if resT == nil {
+			return c.Next()
+		}
+
+		res := c.Response
+		rw := res.Writer
+		// the flushes are ignored, the response being sent once transformed
+		w := buffer.NewWriter(rw, config.MaxSize, false)
+		res.Writer = w
+		err := c.Next()
+		res.Writer = rw
+		// nothing was sent yet
+		discard := func() {
+			res.Committed = false
+			res.Size = 0
+			rw.Header().Del(makross.HeaderContentLength)
+		}
+		if w.TooLarge() {
+			c.Makross().Logger().Errorf("transform: %s response: %v", route.String(), errTooLarge)
+			discard()
+			return makross.NewHTTPError(makross.StatusInternalServerError)
+		}
+		if err != nil {
+			// let the error handler respond, unless the handler already did
+			if res.Committed {
+				send(w, w.Body())
+			}
+			return err
+		}
+		if !res.Committed {
+			return nil
+		}
+
+		body, err := resT(c, w.Body())
+		if err != nil {
+			c.Makross().Logger().Errorf("transform: %s response: %v", route.String(), err)
+			discard()
+			return makross.NewHTTPError(makross.StatusInternalServerError)
+		}
+		res.Size = int64(len(body))
+		send(w, body)
+		return nil
+	}
+}
+
+// transformRequest replaces the request body with the transformed one, which can be read again with GetBody.
+// It returns errTooLarge if the body is larger than max.
+func transformRequest(c *makross.Context, t Transformer, max int64) error {
+	req := c.Request
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	req.Body = http.MaxBytesReader(c.Response, req.Body, max)
+	body, err := makross.ReadBody(req, nil)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return errTooLarge
+	}
+	if err != nil {
+		return err
+	}
+	if body, err = t(c, body); err != nil {
+		return err
+	}
+	makross.SetBody(req, body)
+	return nil
+}
+
+// send sends the response with the body.
+func send(w *buffer.Writer, body []byte) {
+	if w.Header().Get(makross.HeaderContentLength) != "" {
+		w.Header().Set(makross.HeaderContentLength, strconv.Itoa(len(body)))
+	}
+	w.Send(w.Status(), body)
+}
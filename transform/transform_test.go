@@ -0,0 +1,176 @@
+package transform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/compress"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Password  string `json:"password"`
+}
+
+var (
+	toSnake = RenameKeys(map[string]string{"firstName": "first_name", "lastName": "last_name"})
+	toCamel = Chain(
+		RenameKeys(map[string]string{"first_name": "firstName", "last_name": "lastName"}),
+		DropFields("password"),
+	)
+)
+
+func TestJSONTransformers(t *testing.T) {
+	tests := []struct {
+		t       Transformer
+		in, out string
+	}{
+		{toSnake, `{"firstName":"Ada","n":12345678901234567890}`, `{"first_name":"Ada","n":12345678901234567890}`},
+		{toSnake, `[{"firstName":"Ada"},{"nested":{"lastName":"L<o>"}}]`, `[{"first_name":"Ada"},{"nested":{"last_name":"L<o>"}}]`},
+		{RenameKeys(map[string]string{"a": "b", "b": "a"}), `{"a":1,"b":2}`, `{"a":2,"b":1}`},
+		{DropFields("password", "token"), `{"name":"x","password":"p","items":[{"token":"t"}]}`, `{"items":[{}],"name":"x"}`},
+		{toSnake, `plain text`, `plain text`},
+		{toSnake, ``, ``},
+	}
+	for _, test := range tests {
+		out, err := test.t(nil, []byte(test.in))
+		assert.Nil(t, err, test.in)
+		assert.Equal(t, test.out, string(out), test.in)
+	}
+
+	_, err := toSnake(nil, []byte(`{"firstName":`))
+	assert.NotNil(t, err)
+}
+
+func newMakross(handlers ...makross.Handler) *makross.Makross {
+	m := makross.New()
+	m.Use(handlers...)
+	m.Post("/users", func(c *makross.Context) error {
+		u := new(user)
+		if err := c.Bind(u); err != nil {
+			return err
+		}
+		u.Password = "secret"
+		return c.JSON(u, makross.StatusCreated)
+	}).Meta(RequestKey, toSnake).Meta(ResponseKey, toCamel)
+	m.Post("/v2/users", func(c *makross.Context) error {
+		u := new(user)
+		if err := c.Bind(u); err != nil {
+			return err
+		}
+		return c.JSON(u)
+	})
+	m.Get("/broken", func(c *makross.Context) error {
+		return c.JSON(map[string]string{"a": "b"})
+	}).Meta(ResponseKey, Transformer(func(c *makross.Context, body []byte) ([]byte, error) {
+		return nil, errors.New("boom")
+	}))
+	m.Get("/failing", func(c *makross.Context) error {
+		return makross.NewHTTPError(makross.StatusTeapot, "teapot")
+	}).Meta(ResponseKey, toCamel)
+	return m
+}
+
+func post(m *makross.Makross, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(makross.POST, path, strings.NewReader(body))
+	req.Header.Set(makross.HeaderContentType, makross.MIMEApplicationJSON)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	return res
+}
+
+func TestTransform(t *testing.T) {
+	m := newMakross(Transform())
+
+	res := post(m, "/users", `{"firstName":"Ada","lastName":"Lovelace"}`)
+	assert.Equal(t, makross.StatusCreated, res.Code)
+	assert.Equal(t, `{"firstName":"Ada","lastName":"Lovelace"}`, res.Body.String())
+
+	// routes without transformers are left alone
+	res = post(m, "/v2/users", `{"first_name":"Ada","firstName":"Grace"}`)
+	assert.Equal(t, makross.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"first_name":"Ada"`)
+
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/broken", nil))
+	assert.Equal(t, makross.StatusInternalServerError, res.Code)
+	assert.Equal(t, makross.StatusText(makross.StatusInternalServerError), res.Body.String())
+
+	// the errors of the handlers are handled as usual
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/failing", nil))
+	assert.Equal(t, makross.StatusTeapot, res.Code)
+	assert.Equal(t, "teapot", res.Body.String())
+
+	// a malformed request body fails the request transformer
+	res = post(m, "/users", `{"firstName":`)
+	assert.Equal(t, makross.StatusInternalServerError, res.Code)
+}
+
+func TestTransformReplayableBody(t *testing.T) {
+	m := makross.New()
+	m.Use(Transform())
+	m.Post("/echo", func(c *makross.Context) error {
+		first, _ := ioutil.ReadAll(c.Request.Body)
+		body, err := c.Request.GetBody()
+		assert.Nil(t, err)
+		second, _ := ioutil.ReadAll(body)
+		assert.Equal(t, string(first), string(second))
+		assert.Equal(t, int64(len(first)), c.Request.ContentLength)
+		return c.String(string(first))
+	}).Meta(RequestKey, toSnake)
+	assert.Equal(t, `{"first_name":"Ada"}`, post(m, "/echo", `{"firstName":"Ada"}`).Body.String())
+}
+
+func TestTransformMaxSize(t *testing.T) {
+	m := newMakross(TransformWithConfig(TransformConfig{MaxSize: 60}))
+	m.Get("/large", func(c *makross.Context) error {
+		return c.JSON(map[string]string{"first_name": strings.Repeat("a", 60)})
+	}).Meta(ResponseKey, toCamel)
+
+	res := post(m, "/users", `{"firstName":"`+strings.Repeat("a", 60)+`"}`)
+	assert.Equal(t, makross.StatusRequestEntityTooLarge, res.Code)
+
+	res = post(m, "/users", `{"firstName":"Ada"}`)
+	assert.Equal(t, makross.StatusCreated, res.Code)
+	assert.Equal(t, `{"firstName":"Ada","lastName":""}`, res.Body.String())
+
+	// the response is never sent untransformed
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/large", nil))
+	assert.Equal(t, makross.StatusInternalServerError, res.Code)
+	assert.NotContains(t, res.Body.String(), "first_name")
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadAll(r)
+	return string(out)
+}
+
+func TestTransformWithGzip(t *testing.T) {
+	// Transform after Gzip sees the uncompressed response
+	m := newMakross(compress.Gzip(), Transform())
+	res := post(m, "/users", `{"firstName":"Ada"}`, makross.HeaderAcceptEncoding, "gzip")
+	assert.Equal(t, "gzip", res.Header().Get(makross.HeaderContentEncoding))
+	assert.Equal(t, `{"firstName":"Ada","lastName":""}`, gunzip(t, res.Body.Bytes()))
+
+	// Transform before Gzip gets the compressed response, which the JSON transformers leave alone
+	m = newMakross(Transform(), compress.Gzip())
+	res = post(m, "/users", `{"firstName":"Ada"}`, makross.HeaderAcceptEncoding, "gzip")
+	assert.Equal(t, `{"first_name":"Ada","last_name":"","password":"secret"}`, gunzip(t, res.Body.Bytes()))
+}
@@ -0,0 +1,94 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SaveUploadedFiles saves the uploaded files in the directory, and returns their paths, in order.
+// The file names sent by the client are sanitized, keeping only their base name made of letters,
+// digits, '.', '-' and '_', and made unique by a numeric suffix, so that an existing file is never
+// overwritten, e.g. "report.pdf", then "report-1.pdf". On error, the files already saved are removed.
+//
+//	files, err := c.FormFiles("attachments")
+//	if err != nil {
+//		return err
+//	}
+//	paths, err := c.SaveUploadedFiles(files, "/var/uploads")
+func (c *Context) SaveUploadedFiles(files []*multipart.FileHeader, dir string) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for _, fh := range files {
+		path, err := saveUploadedFile(fh, dir)
+		if err != nil {
+			for _, p := range paths {
+				os.Remove(p)
+			}
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// saveUploadedFile saves the uploaded file in the directory under a unique sanitized name.
+func saveUploadedFile(fh *multipart.FileHeader, dir string) (string, error) {
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	name := sanitizeFilename(fh.Filename)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, name)
+		if i > 0 {
+			path = filepath.Join(dir, base+"-"+strconv.Itoa(i)+ext)
+		}
+		dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(dst, src)
+		if cerr := dst.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", err
+		}
+		return path, nil
+	}
+}
+
+// sanitizeFilename returns the base name of a client file name, without the path separators of
+// any platform, the leading dots and the characters other than letters, digits, '.', '-' and '_'.
+func sanitizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '_'
+		}
+		return -1
+	}, name)
+	name = strings.TrimLeft(name, ".")
+	if name == "" {
+		return "file"
+	}
+	return name
+}
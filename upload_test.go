@@ -0,0 +1,82 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextSaveUploadedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "makross-upload")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("existing"), 0644))
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	for name, content := range map[string]string{
+		"a.txt":            "first",
+		"../../etc/passwd": "second",
+		`..\..\b c.txt`:    "third",
+	} {
+		w, _ := mw.CreateFormFile("files", name)
+		w.Write([]byte(content))
+	}
+	mw.Close()
+	req := httptest.NewRequest(POST, "/", body)
+	req.Header.Set(HeaderContentType, mw.FormDataContentType())
+	c := New().NewContext(req, httptest.NewRecorder())
+
+	files, err := c.FormFiles("files")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, files, 3)
+	paths, err := c.SaveUploadedFiles(files, dir)
+	assert.Nil(t, err)
+	if !assert.Len(t, paths, 3) {
+		return
+	}
+	saved := map[string]string{}
+	for _, p := range paths {
+		assert.Equal(t, dir, filepath.Dir(p))
+		b, _ := ioutil.ReadFile(p)
+		saved[filepath.Base(p)] = string(b)
+	}
+	assert.Equal(t, map[string]string{"a-1.txt": "first", "passwd": "second", "b_c.txt": "third"}, saved)
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "a.txt"))
+	assert.Equal(t, "existing", string(b))
+
+	_, err = c.FormFiles("missing")
+	assert.Equal(t, http.ErrMissingFile, err)
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"report.pdf":          "report.pdf",
+		"../../../etc/passwd": "passwd",
+		`C:\Windows\win.ini`:  "win.ini",
+		"..":                  "file",
+		".htaccess":           "htaccess",
+		"a/..":                "file",
+		"résumé 2.doc":        "rsum_2.doc",
+		"":                    "file",
+	}
+	for in, want := range tests {
+		got := sanitizeFilename(in)
+		assert.Equal(t, want, got, in)
+		assert.False(t, strings.ContainsAny(got, `/\`), in)
+	}
+}
@@ -0,0 +1,68 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"sort"
+	"strings"
+)
+
+type (
+	// Validator is the interface that wraps the Validate method.
+	// To report errors per field, Validate returns FieldErrors.
+	Validator interface {
+		Validate(i interface{}) error
+	}
+
+	// Validatable is implemented by the data which validates itself.
+	// It is used by `Context#Validate()` when no validator is registered.
+	Validatable interface {
+		Validate() error
+	}
+
+	// FieldErrors maps the path of the invalid fields, e.g. "address.city", to their error message.
+	FieldErrors map[string]string
+)
+
+// Error implements the error interface.
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for i, field := range fields {
+		fields[i] = field + ": " + e[field]
+	}
+	return strings.Join(fields, "; ")
+}
+
+// Validate validates the data with the registered validator, or with its own Validate method
+// if no validator is registered. It returns nil if there is nothing to validate it with.
+func (c *Context) Validate(i interface{}) error {
+	if v := c.makross.validator; v != nil {
+		return v.Validate(i)
+	}
+	if v, ok := i.(Validatable); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// BindAndValidate binds the request data into the given struct and validates it.
+// On validation failure, it returns the messages of the invalid fields along with the error,
+// ready to be rendered next to the inputs of a form. On success, the map is empty and the error nil.
+// A binding error, or a validation error not reported per field, is returned with an empty map.
+func (c *Context) BindAndValidate(i interface{}) (map[string]string, error) {
+	fields := map[string]string{}
+	if err := c.Bind(i); err != nil {
+		return fields, err
+	}
+	err := c.Validate(i)
+	if fe, ok := err.(FieldErrors); ok {
+		for field, msg := range fe {
+			fields[field] = msg
+		}
+	}
+	return fields, err
+}
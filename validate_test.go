@@ -0,0 +1,82 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signupForm struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Address struct {
+		City string `json:"city"`
+	} `json:"address"`
+}
+
+type signupValidator struct{}
+
+func (signupValidator) Validate(i interface{}) error {
+	f := i.(*signupForm)
+	errs := FieldErrors{}
+	if f.Name == "" {
+		errs["name"] = "is required"
+	}
+	if !strings.Contains(f.Email, "@") {
+		errs["email"] = "must be an email address"
+	}
+	if f.Address.City == "" {
+		errs["address.city"] = "is required"
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func TestContextBindAndValidate(t *testing.T) {
+	m := New()
+	m.SetValidator(signupValidator{})
+	bind := func(body string) (map[string]string, error) {
+		req := httptest.NewRequest(POST, "/signup", strings.NewReader(body))
+		req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		c := m.NewContext(req, httptest.NewRecorder())
+		return c.BindAndValidate(&signupForm{})
+	}
+
+	fields, err := bind(`{"name":"John","email":"john","address":{}}`)
+	assert.NotNil(t, err)
+	assert.Equal(t, map[string]string{"email": "must be an email address", "address.city": "is required"}, fields)
+	assert.Equal(t, "address.city: is required; email: must be an email address", err.Error())
+
+	fields, err = bind(`{"name":"John","email":"john@example.com","address":{"city":"Paris"}}`)
+	assert.Nil(t, err)
+	assert.Empty(t, fields)
+
+	fields, err = bind(`{"name":`)
+	assert.Equal(t, http.StatusBadRequest, err.(*HTTPError).Status)
+	assert.Empty(t, fields)
+}
+
+type selfValidated struct {
+	Age int `json:"age"`
+}
+
+func (s *selfValidated) Validate() error {
+	if s.Age < 18 {
+		return FieldErrors{"age": "must be 18 or over"}
+	}
+	return nil
+}
+
+func TestContextValidate(t *testing.T) {
+	c, _ := testNewContext()
+	assert.Nil(t, c.Validate(&signupForm{}))
+	assert.Equal(t, FieldErrors{"age": "must be 18 or over"}, c.Validate(&selfValidated{Age: 3}))
+	assert.Nil(t, c.Validate(&selfValidated{Age: 30}))
+}
@@ -0,0 +1,117 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Warmup registers functions loading what the application needs before accepting traffic,
+// such as the templates or the caches. Start, StartMultiple, Listen and ListenTLS run them while serving,
+// and until they complete, the requests get a "503 - Service Unavailable" response with a Retry-After
+// header, except the requests to the DrainExcludePaths, such as the health endpoints, and the Ready
+// handler reports the instance as not ready. The functions run one after the other, or concurrently
+// with WarmupConcurrent, and their context is canceled after the WarmupTimeout.
+// A failure is returned by Start, or makes Listen exit, after closing the server. Warmup should be
+// called before serving.
+//
+//	m.Warmup(func(ctx context.Context) error {
+//		return cache.Load(ctx)
+//	})
+//	m.Get("/readyz", m.Ready)
+//	err := m.Start(makross.ServerConfig{Addr: ":8080"})
+func (m *Makross) Warmup(fns ...func(context.Context) error) {
+	m.warmups = append(m.warmups, fns...)
+}
+
+// WarmingUp reports whether the Warmup functions are running, or have failed.
+func (m *Makross) WarmingUp() bool {
+	return atomic.LoadInt32(&m.warming) == 1
+}
+
+// RunWarmup runs the Warmup functions, refusing the requests until they complete, and returns the
+// first error. Start, StartMultiple, Listen and ListenTLS call it, it is needed only for the servers
+// started otherwise.
+// After a failure, the requests are still refused.
+func (m *Makross) RunWarmup() error {
+	if len(m.warmups) == 0 {
+		return nil
+	}
+	atomic.StoreInt32(&m.warming, 1)
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if m.WarmupTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), m.WarmupTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.runWarmups(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// the functions ignoring the context aren't waited for
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("makross: warmup: %w", err)
+	}
+	atomic.StoreInt32(&m.warming, 0)
+	return nil
+}
+
+// runWarmups runs the Warmup functions and returns the first error.
+func (m *Makross) runWarmups(ctx context.Context) error {
+	if !m.WarmupConcurrent {
+		for _, fn := range m.warmups {
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	errs := make(chan error, len(m.warmups))
+	var wg sync.WaitGroup
+	for _, fn := range m.warmups {
+		wg.Add(1)
+		go func(fn func(context.Context) error) {
+			defer wg.Done()
+			errs <- fn(ctx)
+		}(fn)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serveWarm serves while running the Warmup functions, and closes the servers if they fail.
+func (m *Makross) serveWarm(serve func() error, closeServers func() error) error {
+	if len(m.warmups) == 0 {
+		return serve()
+	}
+	// refuse the requests from the start
+	atomic.StoreInt32(&m.warming, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve()
+	}()
+	if err := m.RunWarmup(); err != nil {
+		closeServers()
+		<-served
+		return err
+	}
+	return <-served
+}
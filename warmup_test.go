@@ -0,0 +1,93 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmup(t *testing.T) {
+	m := New()
+	m.Get("/", func(c *Context) error {
+		return c.String("home")
+	})
+	m.Get("/readyz", m.Ready)
+	release := make(chan struct{})
+	m.Warmup(func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- m.StartMultiple([]ListenerSpec{{Listener: l}})
+	}()
+	get := func(path string) (*http.Response, string) {
+		res, err := http.Get("http://" + l.Addr().String() + path)
+		if !assert.Nil(t, err) {
+			return &http.Response{}, ""
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	res, _ := get("/")
+	assert.Equal(t, StatusServiceUnavailable, res.StatusCode)
+	assert.NotEqual(t, "", res.Header.Get(HeaderRetryAfter))
+	assert.True(t, m.WarmingUp())
+	res, body := get("/readyz")
+	assert.Equal(t, StatusServiceUnavailable, res.StatusCode)
+	assert.Contains(t, body, "warming up")
+
+	close(release)
+	for i := 0; i < 100 && m.WarmingUp(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	res, body = get("/")
+	assert.Equal(t, StatusOK, res.StatusCode)
+	assert.Equal(t, "home", body)
+	res, _ = get("/readyz")
+	assert.Equal(t, StatusOK, res.StatusCode)
+
+	assert.Nil(t, m.Shutdown(1))
+	assert.Equal(t, http.ErrServerClosed, <-done)
+}
+
+func TestWarmupError(t *testing.T) {
+	errLoad := errors.New("load failed")
+	m := New()
+	m.WarmupConcurrent = true
+	m.Warmup(func(ctx context.Context) error {
+		return nil
+	}, func(ctx context.Context) error {
+		return errLoad
+	})
+	err := m.Start(ServerConfig{Addr: "127.0.0.1:0"})
+	assert.True(t, errors.Is(err, errLoad))
+	assert.True(t, m.WarmingUp())
+
+	m = New()
+	m.WarmupTimeout = 10 * time.Millisecond
+	m.Warmup(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.True(t, errors.Is(m.RunWarmup(), context.DeadlineExceeded))
+
+	m = New()
+	assert.Nil(t, m.RunWarmup())
+	assert.False(t, m.WarmingUp())
+}
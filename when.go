@@ -0,0 +1,68 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import "strings"
+
+// When returns a middleware running the handler h only for the requests for which pred returns true,
+// the other requests going straight to the next handler:
+//
+//	m.Use(makross.When(makross.PathPrefix("/api/"), cors.CORS()))
+//
+// h runs as if it were in the chain itself: it calls Next to run the rest of the handlers, may Abort
+// them, and its error is returned to the previous handler.
+func When(pred func(*Context) bool, h Handler) Handler {
+	return func(c *Context) error {
+		if !pred(c) {
+			return c.Next()
+		}
+		return h(c)
+	}
+}
+
+// Unless returns a middleware running the handler h for the requests for which pred returns false,
+// e.g. to authenticate the requests of the clients without a certificate:
+//
+//	m.Use(makross.Unless(makross.HasClientCert, bauth.BasicAuth(validate)))
+//
+// See `When()`.
+func Unless(pred func(*Context) bool, h Handler) Handler {
+	return func(c *Context) error {
+		if pred(c) {
+			return c.Next()
+		}
+		return h(c)
+	}
+}
+
+// HasClientCert reports whether the request came over TLS with a client certificate verified by the server,
+// see the ClientAuth option of the tls.Config of the server.
+func HasClientCert(c *Context) bool {
+	return c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0
+}
+
+// HasHeader returns a predicate reporting whether the request has a non-empty header of the name.
+func HasHeader(name string) func(*Context) bool {
+	return func(c *Context) bool {
+		return c.Request.Header.Get(name) != ""
+	}
+}
+
+// PathPrefix returns a predicate reporting whether the request path starts with the prefix.
+func PathPrefix(prefix string) func(*Context) bool {
+	return func(c *Context) bool {
+		return strings.HasPrefix(c.Request.URL.Path, prefix)
+	}
+}
+
+// MethodIn returns a predicate reporting whether the request method is one of the methods.
+func MethodIn(methods ...string) func(*Context) bool {
+	return func(c *Context) bool {
+		for _, method := range methods {
+			if c.Request.Method == method {
+				return true
+			}
+		}
+		return false
+	}
+}
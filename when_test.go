@@ -0,0 +1,91 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhenUnless(t *testing.T) {
+	var trace string
+	mw := func(name string) Handler {
+		return func(c *Context) error {
+			trace += name
+			return nil
+		}
+	}
+	m := New()
+	m.Use(
+		When(HasHeader("X-A"), mw("a")),
+		Unless(HasHeader("X-B"), func(c *Context) error {
+			trace += "b("
+			err := c.Next()
+			trace += ")"
+			return err
+		}),
+		When(HasHeader("X-Abort"), func(c *Context) error {
+			trace += "abort"
+			return c.Abort()
+		}),
+		When(HasHeader("X-Error"), func(c *Context) error {
+			return errors.New("failed")
+		}),
+		mw("c"),
+	)
+	m.Get("/", mw("h"))
+
+	tests := []struct {
+		headers []string
+		code    int
+		trace   string
+	}{
+		{nil, StatusOK, "b(ch)"},
+		{[]string{"X-A"}, StatusOK, "ab(ch)"},
+		{[]string{"X-B"}, StatusOK, "ch"},
+		{[]string{"X-A", "X-B"}, StatusOK, "ach"},
+		{[]string{"X-Abort"}, StatusOK, "b(abort)"},
+		{[]string{"X-B", "X-Error"}, StatusInternalServerError, ""},
+		{[]string{"X-Error"}, StatusInternalServerError, "b()"},
+	}
+	for _, test := range tests {
+		trace = ""
+		req := httptest.NewRequest(GET, "/", nil)
+		for _, h := range test.headers {
+			req.Header.Set(h, "1")
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, req)
+		assert.Equal(t, test.code, res.Code, test.headers)
+		assert.Equal(t, test.trace, trace, test.headers)
+	}
+}
+
+func TestWhenPredicates(t *testing.T) {
+	newContext := func(method, path string) *Context {
+		return New().NewContext(httptest.NewRequest(method, path, nil), httptest.NewRecorder())
+	}
+
+	c := newContext(GET, "/api/users")
+	assert.True(t, PathPrefix("/api/")(c))
+	assert.False(t, PathPrefix("/admin/")(c))
+	assert.True(t, MethodIn(POST, GET)(c))
+	assert.False(t, MethodIn(POST, PUT)(c))
+	assert.False(t, MethodIn()(c))
+	assert.False(t, HasHeader("X-Token")(c))
+	c.Request.Header.Set("X-Token", "secret")
+	assert.True(t, HasHeader("x-token")(c))
+
+	assert.False(t, HasClientCert(c))
+	c.Request.TLS = &tls.ConnectionState{}
+	assert.False(t, HasClientCert(c))
+	c.Request.TLS.PeerCertificates = []*x509.Certificate{{}}
+	assert.False(t, HasClientCert(c))
+	c.Request.TLS.VerifiedChains = [][]*x509.Certificate{c.Request.TLS.PeerCertificates}
+	assert.True(t, HasClientCert(c))
+}
@@ -0,0 +1,63 @@
+// Package makross is a high productive and modular web framework in Golang.
+
+package makross
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// SlowClientError is returned by the helpers sending a response body, such as Stream, SendFile,
+// ServeContent and PartialContent, when the write deadline of the response passed before the client
+// read the whole body. Its message is "slow client".
+type SlowClientError struct {
+	Err error // the write error
+}
+
+func (e *SlowClientError) Error() string {
+	return "slow client"
+}
+
+// Unwrap returns the write error.
+func (e *SlowClientError) Unwrap() error {
+	return e.Err
+}
+
+// SetWriteDeadline sets the deadline of the writes of the response, overriding the WriteTimeout of the
+// server, e.g. to give more time to the large downloads or to release the connections of the slow
+// clients sooner. A zero time removes the deadline. Once it has passed, the writes fail and the helpers
+// sending the body return a *SlowClientError.
+//
+// Over HTTP/1.x the deadline is the one of the connection, over HTTP/2 the one of the stream of
+// the request only. An error wrapping http.ErrNotSupported is returned if the response writer
+// can't set deadlines, e.g. a httptest.ResponseRecorder.
+func (c *Context) SetWriteDeadline(t time.Time) error {
+	if err := http.NewResponseController(c.Response).SetWriteDeadline(t); err != nil {
+		return err
+	}
+	c.deadline = t
+	return nil
+}
+
+// WriteDeadline returns the deadline set with SetWriteDeadline, the zero time if none is set.
+func (c *Context) WriteDeadline() time.Time {
+	return c.deadline
+}
+
+// slowClient returns a *SlowClientError for a write error caused by the write deadline.
+func (c *Context) slowClient(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *SlowClientError
+	if errors.As(err, &se) {
+		return err
+	}
+	// the HTTP/2 writes fail with other errors once the stream is reset by the deadline
+	if errors.Is(err, os.ErrDeadlineExceeded) || !c.deadline.IsZero() && !time.Now().Before(c.deadline) {
+		return &SlowClientError{Err: err}
+	}
+	return err
+}
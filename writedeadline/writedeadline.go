@@ -0,0 +1,123 @@
+package writedeadline
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/insionng/makross/skipper"
+)
+
+type (
+	// WriteDeadlineConfig defines the config for WriteDeadline middleware.
+	WriteDeadlineConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper skipper.Skipper
+
+		// Budget is the time allowed to write the responses of unknown size, or smaller than
+		// the MinSize of all the Classes.
+		// Optional. Default value 30s.
+		Budget time.Duration `json:"budget"`
+
+		// Classes are the budgets of the responses by size, taken from their Content-Length:
+		// a response gets the budget of the class with the largest MinSize not above its size.
+		// Optional.
+		Classes []SizeClass `json:"classes"`
+	}
+
+	// SizeClass is the write budget of the responses of at least MinSize bytes.
+	SizeClass struct {
+		MinSize int64         `json:"min_size"`
+		Budget  time.Duration `json:"budget"`
+	}
+)
+
+// MetaKey is the route metadata key of the write budget of the route, a time.Duration,
+// which takes precedence over the size classes.
+const MetaKey = "write_budget"
+
+var (
+	// DefaultWriteDeadlineConfig is the default WriteDeadline middleware config.
+	DefaultWriteDeadlineConfig = WriteDeadlineConfig{
+		Skipper: skipper.DefaultSkipper,
+		Budget:  30 * time.Second,
+	}
+)
+
+// WriteDeadline returns a WriteDeadline middleware with the budget of the responses of unknown size.
+//
+// WriteDeadline middleware sets the write deadline of each response when its header is written,
+// see `Context#SetWriteDeadline()`, so that a slow client reading a large response doesn't hold
+// the connection and the handler goroutine for longer than the budget of the response, while the
+// large downloads get more time than the WriteTimeout of the server:
+//
+//	m.Use(writedeadline.WriteDeadlineWithConfig(writedeadline.WriteDeadlineConfig{
+//		Budget: 10 * time.Second,
+//		Classes: []writedeadline.SizeClass{
+//			{MinSize: 1 << 20, Budget: time.Minute},
+//			{MinSize: 100 << 20, Budget: 10 * time.Minute},
+//		},
+//	}))
+//	m.Get("/live", liveVideo).Meta(writedeadline.MetaKey, time.Hour)
+//
+// Once the deadline has passed, the helpers sending the body, such as Stream, return a
+// *makross.SlowClientError, logged as "slow client" by the ${error} tag of the logger.
+// The response writers which can't set deadlines are left as they are.
+func WriteDeadline(budget time.Duration) makross.Handler {
+	c := DefaultWriteDeadlineConfig
+	c.Budget = budget
+	return WriteDeadlineWithConfig(c)
+}
+
+// WriteDeadlineWithConfig returns a WriteDeadline middleware with config.
+// See: `WriteDeadline()`.
+func WriteDeadlineWithConfig(config WriteDeadlineConfig) makross.Handler {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultWriteDeadlineConfig.Skipper
+	}
+	if config.Budget == 0 {
+		config.Budget = DefaultWriteDeadlineConfig.Budget
+	}
+	classes := append([]SizeClass(nil), config.Classes...)
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].MinSize < classes[j].MinSize
+	})
+
+	return func(c *makross.Context) error {
+		if config.Skipper(c) {
+			return c.Next()
+		}
+
+		c.Response.Before(func() {
+			if !c.WriteDeadline().IsZero() {
+				// set by the handler
+				return
+			}
+			budget := classBudget(classes, c.Response.Header().Get(makross.HeaderContentLength), config.Budget)
+			if route := c.Route(); route != nil {
+				if d, ok := route.GetMeta(MetaKey).(time.Duration); ok {
+					budget = d
+				}
+			}
+			c.SetWriteDeadline(time.Now().Add(budget))
+		})
+		return c.Next()
+	}
+}
+
+// classBudget returns the budget of the size class of the content length, or the default budget.
+func classBudget(classes []SizeClass, contentLength string, budget time.Duration) time.Duration {
+	size, err := strconv.ParseInt(contentLength, 10, 64)
+	if err != nil {
+		return budget
+	}
+	for _, class := range classes {
+		if size < class.MinSize {
+			break
+		}
+		budget = class.Budget
+	}
+	return budget
+}
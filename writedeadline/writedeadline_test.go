@@ -0,0 +1,152 @@
+package writedeadline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insionng/makross"
+	"github.com/stretchr/testify/assert"
+)
+
+// zeros is an endless reader.
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+func newMakross(errs chan<- error, budget time.Duration) *makross.Makross {
+	m := makross.New()
+	m.Use(func(c *makross.Context) error {
+		err := c.Next()
+		errs <- err
+		return err
+	}, WriteDeadline(budget))
+	m.Get("/stream", func(c *makross.Context) error {
+		return c.Stream(makross.MIMEOctetStream, io.LimitReader(zeros{}, 1<<30))
+	})
+	m.Get("/small", func(c *makross.Context) error {
+		return c.String("small")
+	})
+	return m
+}
+
+func TestWriteDeadlineSlowClient(t *testing.T) {
+	errs := make(chan error, 2)
+	srv := httptest.NewServer(newMakross(errs, 200*time.Millisecond))
+	defer srv.Close()
+
+	// a client which doesn't read the response
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /stream HTTP/1.1\r\nHost: example.com\r\n\r\n")
+
+	select {
+	case err = <-errs:
+		var se *makross.SlowClientError
+		assert.True(t, errors.As(err, &se), fmt.Sprint(err))
+		assert.Equal(t, "slow client", err.Error())
+	case <-time.After(10 * time.Second):
+		t.Fatal("the stream wasn't aborted")
+	}
+
+	// the responses written in time aren't affected
+	res, err := http.Get(srv.URL + "/small")
+	if assert.Nil(t, err) {
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, "small", string(body))
+		assert.Nil(t, <-errs)
+	}
+}
+
+func TestWriteDeadlineHTTP2(t *testing.T) {
+	errs := make(chan error, 2)
+	srv := httptest.NewUnstartedServer(newMakross(errs, 200*time.Millisecond))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// the body isn't read, the flow control of the stream blocks the writes
+	res, err := srv.Client().Get(srv.URL + "/stream")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer res.Body.Close()
+	assert.Equal(t, 2, res.ProtoMajor)
+
+	select {
+	case err = <-errs:
+		var se *makross.SlowClientError
+		assert.True(t, errors.As(err, &se), fmt.Sprint(err))
+	case <-time.After(10 * time.Second):
+		t.Fatal("the stream wasn't aborted")
+	}
+}
+
+func TestWriteDeadlineBudget(t *testing.T) {
+	classes := []SizeClass{{MinSize: 100, Budget: time.Minute}, {MinSize: 1000, Budget: time.Hour}}
+	assert.Equal(t, time.Second, classBudget(classes, "", time.Second))
+	assert.Equal(t, time.Second, classBudget(classes, "99", time.Second))
+	assert.Equal(t, time.Minute, classBudget(classes, "100", time.Second))
+	assert.Equal(t, time.Hour, classBudget(classes, "5000", time.Second))
+
+	// the deadline is set when the header is written, unless the handler set it
+	m := makross.New()
+	var deadlines []time.Time
+	m.Use(func(c *makross.Context) error {
+		err := c.Next()
+		deadlines = append(deadlines, c.WriteDeadline())
+		return err
+	}, WriteDeadlineWithConfig(WriteDeadlineConfig{Budget: time.Second, Classes: classes}))
+	m.Get("/large", func(c *makross.Context) error {
+		c.Response.Header().Set(makross.HeaderContentLength, "5000")
+		return c.String(strings.Repeat("x", 5000))
+	})
+	m.Get("/live", func(c *makross.Context) error {
+		return c.String("live")
+	}).Meta(MetaKey, 24*time.Hour)
+	deadline := time.Now().Add(time.Minute)
+	m.Get("/custom", func(c *makross.Context) error {
+		if err := c.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+		return c.String("custom")
+	})
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	start := time.Now()
+	for _, path := range []string{"/large", "/live", "/custom"} {
+		res, err := http.Get(srv.URL + path)
+		if assert.Nil(t, err) {
+			io.Copy(io.Discard, bufio.NewReader(res.Body))
+			res.Body.Close()
+		}
+	}
+	if assert.Len(t, deadlines, 3) {
+		assert.WithinDuration(t, start.Add(time.Hour), deadlines[0], 10*time.Second)
+		assert.WithinDuration(t, start.Add(24*time.Hour), deadlines[1], 10*time.Second)
+		assert.Equal(t, deadline, deadlines[2])
+	}
+
+	// the response writers without deadlines are left as they are
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest(makross.GET, "/live", nil))
+	assert.Equal(t, "live", res.Body.String())
+	assert.True(t, deadlines[3].IsZero())
+}